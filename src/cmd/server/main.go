@@ -11,29 +11,69 @@ package main
 
 import (
 	"context"
+	"database/sql"
+	"flag"
+	"fmt"
 	"log"
 	"net/http"
 	"os"
 	"os/signal"
+	"sync"
 	"syscall"
 	"time"
 
+	"example.com/go-hello/src/internal/config"
+	"example.com/go-hello/src/internal/models"
+	"example.com/go-hello/src/internal/notifications"
 	"example.com/go-hello/src/internal/routes"
+	"example.com/go-hello/src/internal/services"
 	"example.com/go-hello/src/repos"
 )
 
 func main() {
-	// récupère l'URL de connexion PostgreSQL
-	dsn := os.Getenv("DATABASE_URL")
-	if dsn == "" {
-		log.Println("[ERREUR] DATABASE_URL non défini dans les variables d'environnement")
-		log.Fatal("Impossible de démarrer sans connexion à la base de données")
+	// fichier de configuration optionnel (format .env)
+	cheminConfig := flag.String("config", os.Getenv("CONFIG_FICHIER"), "fichier de configuration optionnel (CLE=valeur)")
+	flag.Usage = func() {
+		fmt.Fprintf(flag.CommandLine.Output(), "Utilisation : %s [-config fichier] [migrate up|down [n]|status]\n", os.Args[0])
+		flag.PrintDefaults()
 	}
+	flag.Parse()
 
-	// connexion à PostgreSQL
-	depot, err := repos.NouvelleConnexion(dsn)
+	// charge et valide toute la configuration
+	cfg, err := config.Charger(*cheminConfig)
 	if err != nil {
-		log.Fatalf("Erreur connexion base de données : %v", err)
+		log.Printf("[ERREUR] Configuration invalide :\n%v", err)
+		log.Fatal("Impossible de démarrer avec une configuration invalide")
+	}
+
+	// sous-commande migrate : gère le schéma PostgreSQL (ou SQLite) puis quitte
+	if flag.Arg(0) == "migrate" {
+		if cfg.BaseDeDonnees.Stockage == config.StockageMemoire {
+			log.Fatal("La sous-commande migrate demande une base SQL (STOCKAGE=postgres ou sqlite), pas STOCKAGE=memoire")
+		}
+		base, err := ouvrirDepotSQL(cfg.BaseDeDonnees, repos.Reglages{})
+		if err != nil {
+			log.Fatalf("Erreur connexion base de données : %v", err)
+		}
+		err = migrer(context.Background(), cfg.BaseDeDonnees, base.DB(), flag.Args()[1:])
+		base.Fermer()
+		if err != nil {
+			log.Fatalf("Erreur migrations : %v", err)
+		}
+		return
+	}
+
+	// timeout d'une tentative de vérification (requête + marge)
+	timeoutVerification := cfg.Surveillance.TimeoutRequete + 5*time.Second
+
+	// connexion au stockage (PostgreSQL, SQLite ou mémoire)
+	// la consolidation attend qu'une vérification avec toutes ses tentatives ait pu se terminer
+	depot, err := ouvrirDepot(cfg.BaseDeDonnees, repos.Reglages{
+		IntervalleParDefaut: cfg.Surveillance.IntervalleVerification,
+		DelaiGrace:          services.DureeMaxVerification(timeoutVerification),
+	})
+	if err != nil {
+		log.Fatalf("Erreur base de données : %v", err)
 	}
 	defer func() {
 		if errFermeture := depot.Fermer(); errFermeture != nil {
@@ -41,26 +81,82 @@ func main() {
 		}
 	}()
 
+	// contexte pour gérer l'arrêt propre
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	// pool borné de vérifications concurrentes
+	pool := services.NouveauPool(cfg.Surveillance.WorkersMaxParalleles, 0)
+	verificateur := services.NouveauVerificateur(services.VerificateurHTTP{
+		Client:                services.NouveauClientHTTP(cfg.Surveillance.TimeoutRequete),
+		JoursAlerteCertificat: cfg.Surveillance.JoursAlerteCertificat,
+	})
+	verificateur.SeuilLatenceLente = cfg.Surveillance.SeuilLatenceLente
+	pool.Verificateur = verificateur
+	pool.TimeoutVerification = timeoutVerification
+	pool.Demarrer(ctx)
+
+	// purge de l'historique selon la rétention (0 = conserver)
+	purgeur := services.NouveauPurgeur(depot, models.PolitiqueRetention{
+		models.CibleStatuts:            cfg.Historique.RetentionStatuts,
+		models.CibleAlertes:            cfg.Historique.RetentionAlertes,
+		models.CibleAgregatsHoraires:   cfg.Historique.RetentionAgregatsHoraires,
+		models.CibleAgregatsQuotidiens: cfg.Historique.RetentionAgregatsQuotidiens,
+	})
+	purgeur.Intervalle = cfg.Historique.IntervallePurge
+	purgeur.TailleLot = cfg.Historique.TailleLotPurge
+
 	// setup de l'application avec les dépendances
 	app := routes.ServicesApp{
-		Depot: depot,
+		Depot:   depot,
+		Pool:    pool,
+		Purgeur: purgeur,
 	}
 
 	// création du router HTTP
 	mux := routes.EnregistrerRoutes(app)
 
-	// contexte pour gérer l'arrêt propre
-	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
-	defer stop()
+	// intervalle par défaut des vérifications en arrière-plan
+	intervalle := cfg.Surveillance.IntervalleVerification
+
+	// démarrage du planificateur dans une goroutine
+	var wg sync.WaitGroup
+	planificateur := services.NouveauPlanificateur(depot, pool, intervalle)
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		log.Printf("Planificateur démarré (intervalle par défaut %s)", intervalle)
+		planificateur.Demarrer(ctx)
+	}()
+
+	// consolidation de l'historique en agrégats horaires et quotidiens
+	consolidateur := services.NouveauConsolidateur(depot, cfg.Historique.IntervalleConsolidation)
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		log.Printf("Consolidation démarrée (intervalle %s)", consolidateur.Intervalle)
+		consolidateur.Demarrer(ctx)
+	}()
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		log.Printf("Purge démarrée (toutes les %s)", purgeur.Intervalle)
+		purgeur.Demarrer(ctx)
+	}()
+
+	// envoi des alertes de monitoring.alertes vers les canaux configurés et routés
+	if err := demarrerNotifications(ctx, &wg, cfg.Notifications, depot); err != nil {
+		log.Fatalf("Erreur configuration des notifications : %v", err)
+	}
 
 	serveur := &http.Server{
-		Addr:    ":8080",
+		Addr:    cfg.Serveur.Adresse(),
 		Handler: mux,
 	}
 
 	// démarrage du serveur dans une goroutine
 	go func() {
-		log.Println("Serveur démarré sur :8080")
+		log.Printf("Serveur démarré sur %s", serveur.Addr)
 		if err := serveur.ListenAndServe(); err != nil && err != http.ErrServerClosed {
 			log.Fatalf("Erreur démarrage serveur : %v", err)
 		}
@@ -71,12 +167,116 @@ func main() {
 	log.Println("Arrêt du serveur en cours...")
 
 	// timeout pour l'arrêt du serveur
-	ctxShutdown, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	ctxShutdown, cancel := context.WithTimeout(context.Background(), cfg.Serveur.TimeoutArret)
 	defer cancel()
 
 	if err := serveur.Shutdown(ctxShutdown); err != nil {
 		log.Fatalf("Erreur lors de l'arrêt du serveur : %v", err)
 	}
 
+	// attend la fin du planificateur et des vérifications en cours
+	wg.Wait()
+	pool.Attendre()
+
 	log.Println("Serveur arrêté")
-}
\ No newline at end of file
+}
+
+// depotApp regroupe tout ce que le serveur demande au stockage
+type depotApp interface {
+	repos.Repo
+	notifications.DepotAlertes
+	notifications.DepotRoutes
+	Fermer() error
+}
+
+// depotSQL est un stockage dans une base SQL (PostgreSQL ou SQLite)
+type depotSQL interface {
+	depotApp
+	DB() *sql.DB
+}
+
+// Ouvre le stockage choisi par STOCKAGE
+// PostgreSQL ou SQLite : connexion puis migrations du schéma (si MIGRATIONS_AU_DEMARRAGE)
+func ouvrirDepot(cfg config.ConfigBaseDeDonnees, reglages repos.Reglages) (depotApp, error) {
+	if cfg.Stockage == config.StockageMemoire {
+		log.Println("Stockage en mémoire : les données seront perdues à l'arrêt")
+		return repos.NouvelleMemoire(reglages), nil
+	}
+
+	base, err := ouvrirDepotSQL(cfg, reglages)
+	if err != nil {
+		return nil, err
+	}
+	if cfg.MigrerAuDemarrage {
+		if err := migrer(context.Background(), cfg, base.DB(), []string{"up"}); err != nil {
+			base.Fermer()
+			return nil, fmt.Errorf("migrations : %w", err)
+		}
+	}
+	return base, nil
+}
+
+// Ouvre la base de DATABASE_URL selon le stockage choisi (SQLite ou PostgreSQL)
+func ouvrirDepotSQL(cfg config.ConfigBaseDeDonnees, reglages repos.Reglages) (depotSQL, error) {
+	if cfg.Stockage == config.StockageSQLite {
+		sqlite, err := repos.NouvelleSQLite(cfg, reglages)
+		if err != nil {
+			return nil, err
+		}
+		chemin, _ := cfg.CheminSQLite()
+		log.Printf("Stockage SQLite : %s", chemin)
+		return sqlite, nil
+	}
+
+	postgres, err := repos.NouvelleConnexion(cfg, reglages)
+	if err != nil {
+		return nil, err
+	}
+	return postgres, nil
+}
+
+// Démarre le répartiteur de notifications (canaux de la configuration + canaux routés par l'API)
+func demarrerNotifications(ctx context.Context, wg *sync.WaitGroup, cfg config.ConfigNotifications, depot depotApp) error {
+	var canaux []notifications.Canal
+
+	if len(cfg.WebhookURLs) > 0 {
+		modele, err := notifications.ChargerModele(cfg.ModeleWebhook)
+		if err != nil {
+			return err
+		}
+		for _, lien := range cfg.WebhookURLs {
+			canaux = append(canaux, notifications.NouveauWebhook(lien, cfg.SecretWebhook, modele, cfg.TimeoutEnvoi))
+		}
+	}
+
+	if cfg.SMTP.Hote != "" {
+		texte, html, err := notifications.ChargerModelesEmail(cfg.SMTP.ModeleTexte, cfg.SMTP.ModeleHTML)
+		if err != nil {
+			return err
+		}
+		email := notifications.NouvelEmail(cfg.SMTP.Hote, cfg.SMTP.Port, cfg.SMTP.Expediteur, cfg.SMTP.Destinataires)
+		email.StartTLS = cfg.SMTP.StartTLS
+		email.Utilisateur = cfg.SMTP.Utilisateur
+		email.MotDePasse = cfg.SMTP.MotDePasse
+		email.ModeleTexte = texte
+		email.ModeleHTML = html
+		email.Timeout = cfg.TimeoutEnvoi
+		canaux = append(canaux, email)
+	}
+
+	repartiteur := notifications.NouveauRepartiteur(depot, canaux...)
+	repartiteur.Routes = depot
+	repartiteur.TimeoutEnvoi = cfg.TimeoutEnvoi
+	repartiteur.Intervalle = cfg.IntervalleScrutation
+	repartiteur.TentativesMax = cfg.TentativesMax
+	repartiteur.DelaiInitial = cfg.DelaiReessai
+	repartiteur.AgeMaxAlerte = cfg.AgeMaxAlerte
+
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		log.Printf("Notifications démarrées (%d canal(aux) fixe(s) + routage par moniteur)", len(canaux))
+		repartiteur.Demarrer(ctx)
+	}()
+	return nil
+}
@@ -0,0 +1,94 @@
+/* Sous-commande migrate du serveur
+ * Projet de session A25
+ * By : Leandre Kanmegne
+ *
+ * server migrate up        : applique les migrations en attente (par défaut)
+ * server migrate down [n]  : annule les n dernières migrations (1 par défaut)
+ * server migrate status    : liste les migrations et leur date d'application
+ * Les migrations SQLite (src/database/migrations/sqlite) sont utilisées avec STOCKAGE=sqlite (ou une DATABASE_URL sqlite://)
+ */
+
+package main
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"log"
+	"strconv"
+
+	"example.com/go-hello/src/database"
+	"example.com/go-hello/src/internal/config"
+)
+
+// Exécute une commande de migration sur la base
+func migrer(ctx context.Context, cfg config.ConfigBaseDeDonnees, db *sql.DB, args []string) error {
+	nouveauMigrateur, charger := database.NouveauMigrateur, database.Embarquees
+	if cfg.Stockage == config.StockageSQLite {
+		nouveauMigrateur, charger = database.NouveauMigrateurSQLite, database.EmbarqueesSQLite
+	}
+	migrations, err := charger()
+	if err != nil {
+		return err
+	}
+	migrateur := nouveauMigrateur(db, migrations)
+
+	commande := "up"
+	if len(args) > 0 {
+		commande = args[0]
+	}
+
+	switch commande {
+	case "up":
+		if len(args) > 1 {
+			return fmt.Errorf("migrate up ne prend pas d'argument")
+		}
+		appliquees, err := migrateur.Monter(ctx)
+		for _, migration := range appliquees {
+			log.Printf("[MIGRATION] %04d_%s appliquée", migration.Version, migration.Nom)
+		}
+		if err == nil && len(appliquees) == 0 {
+			log.Printf("[MIGRATION] Schéma à jour (%d migration(s))", len(migrations))
+		}
+		return err
+
+	case "down":
+		etapes := 1
+		if len(args) > 2 {
+			return fmt.Errorf("migrate down prend au plus un argument")
+		}
+		if len(args) == 2 {
+			if etapes, err = strconv.Atoi(args[1]); err != nil || etapes <= 0 {
+				return fmt.Errorf("nombre de migrations à annuler invalide : %q", args[1])
+			}
+		}
+		annulees, err := migrateur.Descendre(ctx, etapes)
+		for _, migration := range annulees {
+			log.Printf("[MIGRATION] %04d_%s annulée", migration.Version, migration.Nom)
+		}
+		if err == nil && len(annulees) == 0 {
+			log.Printf("[MIGRATION] Aucune migration à annuler")
+		}
+		return err
+
+	case "status":
+		if len(args) > 1 {
+			return fmt.Errorf("migrate status ne prend pas d'argument")
+		}
+		etats, err := migrateur.Statut(ctx)
+		if err != nil {
+			return err
+		}
+		for _, etat := range etats {
+			appliquee := "en attente"
+			if etat.AppliqueeA != nil {
+				appliquee = "appliquée le " + etat.AppliqueeA.Format("2006-01-02 15:04:05 MST")
+			}
+			fmt.Printf("%04d_%-30s %s\n", etat.Version, etat.Nom, appliquee)
+		}
+		return nil
+
+	default:
+		return fmt.Errorf("commande de migration inconnue : %q (attendu up, down [n] ou status)", commande)
+	}
+}
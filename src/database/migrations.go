@@ -0,0 +1,312 @@
+/* Migrations versionnées du schéma PostgreSQL et SQLite
+ * Projet de session A25
+ * By : Leandre Kanmegne
+ *
+ * Les fichiers migrations/NNNN_nom.up.sql et NNNN_nom.down.sql sont embarqués dans le binaire (go:embed)
+ * SQLite a ses propres scripts dans migrations/sqlite (même format de noms)
+ * Les versions appliquées sont notées dans public.schema_migrations (schema_migrations pour SQLite)
+ * Un verrou consultatif (pg_advisory_lock) empêche deux instances de migrer en même temps
+ * (SQLite n'en a pas besoin : une seule transaction écrit à la fois dans le fichier)
+ * Chaque migration est appliquée dans sa propre transaction avec sa ligne de schema_migrations
+ *
+ * Sources:
+ * https://pkg.go.dev/embed
+ * https://www.postgresql.org/docs/current/explicit-locking.html#ADVISORY-LOCKS
+ */
+package database
+
+import (
+	"context"
+	"database/sql"
+	"embed"
+	"errors"
+	"fmt"
+	"io/fs"
+	"path"
+	"regexp"
+	"sort"
+	"strconv"
+	"time"
+)
+
+//go:embed migrations/*.sql migrations/sqlite/*.sql
+var fichiers embed.FS
+
+// Clé du verrou consultatif pris pendant les migrations
+const cleVerrou int64 = 0x6d6f6e69746f72 // "monitor"
+
+// Nom attendu des fichiers : 0001_schema_initial.up.sql, 0001_schema_initial.down.sql
+var motifFichier = regexp.MustCompile(`^(\d+)_([a-z0-9_]+)\.(up|down)\.sql$`)
+
+// Migration représente une version du schéma et ses scripts de montée et de retour
+type Migration struct {
+	Version int64
+	Nom     string
+	Haut    string
+	Bas     string
+}
+
+// EtatMigration indique si une migration est appliquée (et quand)
+type EtatMigration struct {
+	Migration
+	AppliqueeA *time.Time
+}
+
+// Embarquees retourne les migrations PostgreSQL embarquées dans le binaire, triées par version
+func Embarquees() ([]Migration, error) {
+	return Charger(fichiers, "migrations")
+}
+
+// EmbarqueesSQLite retourne les migrations SQLite embarquées dans le binaire, triées par version
+func EmbarqueesSQLite() ([]Migration, error) {
+	return Charger(fichiers, "migrations/sqlite")
+}
+
+// Charger lit les migrations d'un dossier, triées par version
+// Chaque version doit avoir un fichier .up.sql et un fichier .down.sql
+func Charger(fsys fs.FS, dossier string) ([]Migration, error) {
+	entrees, err := fs.ReadDir(fsys, dossier)
+	if err != nil {
+		return nil, err
+	}
+
+	parVersion := map[int64]*Migration{}
+	for _, entree := range entrees {
+		if entree.IsDir() {
+			continue
+		}
+		parties := motifFichier.FindStringSubmatch(entree.Name())
+		if parties == nil {
+			return nil, fmt.Errorf("nom de migration invalide : %q (attendu NNNN_nom.up.sql ou NNNN_nom.down.sql)", entree.Name())
+		}
+		version, err := strconv.ParseInt(parties[1], 10, 64)
+		if err != nil || version <= 0 {
+			return nil, fmt.Errorf("version de migration invalide : %q", entree.Name())
+		}
+		contenu, err := fs.ReadFile(fsys, path.Join(dossier, entree.Name()))
+		if err != nil {
+			return nil, err
+		}
+
+		migration, ok := parVersion[version]
+		if !ok {
+			migration = &Migration{Version: version, Nom: parties[2]}
+			parVersion[version] = migration
+		} else if migration.Nom != parties[2] {
+			return nil, fmt.Errorf("version %d utilisée par deux migrations : %q et %q", version, migration.Nom, parties[2])
+		}
+		if parties[3] == "up" {
+			migration.Haut = string(contenu)
+		} else {
+			migration.Bas = string(contenu)
+		}
+	}
+
+	migrations := make([]Migration, 0, len(parVersion))
+	for _, migration := range parVersion {
+		if migration.Haut == "" || migration.Bas == "" {
+			return nil, fmt.Errorf("migration %04d_%s : fichiers .up.sql et .down.sql requis (et non vides)", migration.Version, migration.Nom)
+		}
+		migrations = append(migrations, *migration)
+	}
+	sort.Slice(migrations, func(i, j int) bool { return migrations[i].Version < migrations[j].Version })
+	return migrations, nil
+}
+
+// Requêtes propres au moteur pour le verrou et le suivi des versions
+type dialecte struct {
+	verrouiller   string // vide : pas de verrou
+	deverrouiller string
+	creerSuivi    string
+	lireSuivi     string
+	noter         string // paramètres : version, nom
+	oublier       string // paramètre : version
+}
+
+var (
+	dialectePostgres = dialecte{
+		verrouiller:   `SELECT pg_advisory_lock($1)`,
+		deverrouiller: `SELECT pg_advisory_unlock($1)`,
+		creerSuivi: `
+			CREATE TABLE IF NOT EXISTS public.schema_migrations (
+				version BIGINT PRIMARY KEY,
+				nom TEXT NOT NULL,
+				appliquee_a TIMESTAMPTZ NOT NULL DEFAULT NOW()
+			)
+		`,
+		lireSuivi: `SELECT version, appliquee_a FROM public.schema_migrations`,
+		noter:     `INSERT INTO public.schema_migrations (version, nom) VALUES ($1, $2)`,
+		oublier:   `DELETE FROM public.schema_migrations WHERE version = $1`,
+	}
+
+	// appliquee_a en texte ISO 8601 : le pilote le lit en time.Time grâce au type TIMESTAMP
+	dialecteSQLite = dialecte{
+		creerSuivi: `
+			CREATE TABLE IF NOT EXISTS schema_migrations (
+				version INTEGER PRIMARY KEY,
+				nom TEXT NOT NULL,
+				appliquee_a TIMESTAMP NOT NULL DEFAULT (strftime('%Y-%m-%dT%H:%M:%fZ', 'now'))
+			)
+		`,
+		lireSuivi: `SELECT version, appliquee_a FROM schema_migrations`,
+		noter:     `INSERT INTO schema_migrations (version, nom) VALUES (?, ?)`,
+		oublier:   `DELETE FROM schema_migrations WHERE version = ?`,
+	}
+)
+
+// Migrateur applique ou annule les migrations sur une base PostgreSQL ou SQLite
+type Migrateur struct {
+	db         *sql.DB
+	migrations []Migration
+	dialecte   dialecte
+}
+
+// NouveauMigrateur crée un migrateur PostgreSQL pour les migrations données (voir Embarquees)
+func NouveauMigrateur(db *sql.DB, migrations []Migration) *Migrateur {
+	return &Migrateur{db: db, migrations: migrations, dialecte: dialectePostgres}
+}
+
+// NouveauMigrateurSQLite crée un migrateur SQLite pour les migrations données (voir EmbarqueesSQLite)
+func NouveauMigrateurSQLite(db *sql.DB, migrations []Migration) *Migrateur {
+	return &Migrateur{db: db, migrations: migrations, dialecte: dialecteSQLite}
+}
+
+// Monter applique toutes les migrations pas encore appliquées, dans l'ordre des versions
+// Retourne les migrations appliquées
+func (m *Migrateur) Monter(ctx context.Context) ([]Migration, error) {
+	var appliquees []Migration
+	err := m.avecVerrou(ctx, func(conn *sql.Conn, faites map[int64]time.Time) error {
+		for _, migration := range m.migrations {
+			if _, ok := faites[migration.Version]; ok {
+				continue
+			}
+			err := executer(ctx, conn, migration.Haut, m.dialecte.noter, migration.Version, migration.Nom)
+			if err != nil {
+				return fmt.Errorf("migration %04d_%s : %w", migration.Version, migration.Nom, err)
+			}
+			appliquees = append(appliquees, migration)
+		}
+		return nil
+	})
+	return appliquees, err
+}
+
+// Descendre annule les etapes dernières migrations appliquées, de la plus récente à la plus ancienne
+// Retourne les migrations annulées
+func (m *Migrateur) Descendre(ctx context.Context, etapes int) ([]Migration, error) {
+	if etapes <= 0 {
+		return nil, errors.New("le nombre de migrations à annuler doit être positif")
+	}
+
+	var annulees []Migration
+	err := m.avecVerrou(ctx, func(conn *sql.Conn, faites map[int64]time.Time) error {
+		versions := make([]int64, 0, len(faites))
+		for version := range faites {
+			versions = append(versions, version)
+		}
+		sort.Slice(versions, func(i, j int) bool { return versions[i] > versions[j] })
+
+		for _, version := range versions[:min(etapes, len(versions))] {
+			migration, ok := m.trouver(version)
+			if !ok {
+				return fmt.Errorf("la version %d est appliquée mais inconnue de ce binaire", version)
+			}
+			err := executer(ctx, conn, migration.Bas, m.dialecte.oublier, migration.Version)
+			if err != nil {
+				return fmt.Errorf("retour de la migration %04d_%s : %w", migration.Version, migration.Nom, err)
+			}
+			annulees = append(annulees, migration)
+		}
+		return nil
+	})
+	return annulees, err
+}
+
+// Statut retourne l'état de chaque migration connue, dans l'ordre des versions
+func (m *Migrateur) Statut(ctx context.Context) ([]EtatMigration, error) {
+	var etats []EtatMigration
+	err := m.avecVerrou(ctx, func(conn *sql.Conn, faites map[int64]time.Time) error {
+		for _, migration := range m.migrations {
+			etat := EtatMigration{Migration: migration}
+			if date, ok := faites[migration.Version]; ok {
+				etat.AppliqueeA = &date
+			}
+			etats = append(etats, etat)
+		}
+		return nil
+	})
+	return etats, err
+}
+
+// Retourne la migration d'une version
+func (m *Migrateur) trouver(version int64) (Migration, bool) {
+	for _, migration := range m.migrations {
+		if migration.Version == version {
+			return migration, true
+		}
+	}
+	return Migration{}, false
+}
+
+// Prend le verrou consultatif (PostgreSQL) sur une connexion dédiée, crée schema_migrations au besoin
+// et appelle fn avec les versions déjà appliquées
+func (m *Migrateur) avecVerrou(ctx context.Context, fn func(conn *sql.Conn, faites map[int64]time.Time) error) error {
+	// le verrou consultatif appartient à la session : toutes les requêtes passent par la même connexion
+	conn, err := m.db.Conn(ctx)
+	if err != nil {
+		return err
+	}
+	defer conn.Close()
+
+	if m.dialecte.verrouiller != "" {
+		if _, err := conn.ExecContext(ctx, m.dialecte.verrouiller, cleVerrou); err != nil {
+			return fmt.Errorf("verrou des migrations : %w", err)
+		}
+		defer conn.ExecContext(context.Background(), m.dialecte.deverrouiller, cleVerrou)
+	}
+
+	if _, err := conn.ExecContext(ctx, m.dialecte.creerSuivi); err != nil {
+		return err
+	}
+
+	lignes, err := conn.QueryContext(ctx, m.dialecte.lireSuivi)
+	if err != nil {
+		return err
+	}
+	defer lignes.Close()
+
+	faites := map[int64]time.Time{}
+	for lignes.Next() {
+		var version int64
+		var date time.Time
+		if err := lignes.Scan(&version, &date); err != nil {
+			return err
+		}
+		faites[version] = date
+	}
+	if err := lignes.Err(); err != nil {
+		return err
+	}
+	lignes.Close()
+
+	return fn(conn, faites)
+}
+
+// Exécute un script et met à jour schema_migrations dans la même transaction
+// Le script est envoyé sans paramètres (protocole simple de pgx) : il peut contenir plusieurs instructions
+// (le pilote SQLite exécute aussi toutes les instructions du script)
+func executer(ctx context.Context, conn *sql.Conn, script, suivi string, args ...any) error {
+	tx, err := conn.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	if _, err := tx.ExecContext(ctx, script); err != nil {
+		return err
+	}
+	if _, err := tx.ExecContext(ctx, suivi, args...); err != nil {
+		return err
+	}
+	return tx.Commit()
+}
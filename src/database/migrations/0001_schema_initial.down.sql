@@ -0,0 +1,5 @@
+-- Migration 0001 (retour) : supprime tout le schéma de monitoring et ses données
+-- Projet de session A25
+-- By : Leandre Kanmegne
+
+DROP SCHEMA IF EXISTS monitoring CASCADE;
@@ -0,0 +1,133 @@
+-- Migration 0001 : schéma initial (moniteurs, statuts, alertes et trigger de transition)
+-- Projet de session A25
+-- By : Leandre Kanmegne
+--
+-- Reprend init.sql et dbtrigger.sql de la première version du projet
+-- Idempotente : une base déjà créée par docker-entrypoint-initdb.d est adoptée sans erreur
+
+SET
+    client_min_messages TO WARNING;
+
+-- création du schéma
+CREATE SCHEMA IF NOT EXISTS monitoring;
+
+-- table des moniteurs (services à surveiller)
+CREATE TABLE IF NOT EXISTS monitoring.moniteurs (
+    id BIGSERIAL PRIMARY KEY,
+    nom TEXT NOT NULL,
+    url TEXT NOT NULL UNIQUE,
+    type TEXT NOT NULL DEFAULT 'http',
+    actif BOOLEAN NOT NULL DEFAULT TRUE,
+    cree_a TIMESTAMPTZ NOT NULL DEFAULT NOW()
+);
+
+-- table des statuts (historique des vérifications)
+CREATE TABLE IF NOT EXISTS monitoring.statuts (
+    id BIGSERIAL PRIMARY KEY,
+    moniteur_id BIGINT REFERENCES monitoring.moniteurs(id) ON DELETE CASCADE,
+    url TEXT NOT NULL,
+    code_http INTEGER,
+    est_disponible BOOLEAN NOT NULL,
+    message_erreur TEXT,
+    latence_ms INTEGER,
+    verifie_a TIMESTAMPTZ NOT NULL DEFAULT NOW()
+);
+
+-- index pour les requêtes fréquentes
+CREATE INDEX IF NOT EXISTS idx_moniteurs_url ON monitoring.moniteurs (url);
+
+CREATE INDEX IF NOT EXISTS idx_statuts_moniteur_ts ON monitoring.statuts (moniteur_id, verifie_a DESC);
+
+-- vue pour récupérer le dernier statut de chaque moniteur
+-- (recréée : une base plus récente peut avoir une vue avec plus de colonnes)
+DROP VIEW IF EXISTS monitoring.v_dernier_statut;
+
+CREATE VIEW monitoring.v_dernier_statut AS
+SELECT
+    DISTINCT ON (s.moniteur_id) s.moniteur_id,
+    s.code_http,
+    s.est_disponible,
+    s.message_erreur,
+    s.latence_ms,
+    s.verifie_a,
+    m.url,
+    m.nom
+FROM
+    monitoring.statuts AS s
+    JOIN monitoring.moniteurs AS m ON m.id = s.moniteur_id
+ORDER BY
+    s.moniteur_id,
+    s.verifie_a DESC;
+
+-- Table pour stocker les alertes
+CREATE TABLE IF NOT EXISTS monitoring.alertes (
+    id BIGSERIAL PRIMARY KEY,
+    moniteur_id BIGINT NOT NULL REFERENCES monitoring.moniteurs(id) ON DELETE CASCADE,
+    type TEXT NOT NULL CHECK (type IN ('DOWN', 'UP')),
+    details TEXT,
+    cree_a TIMESTAMPTZ NOT NULL DEFAULT NOW()
+);
+
+-- Détecte les changements d'état et insère une alerte
+CREATE
+OR REPLACE FUNCTION monitoring.detecter_transition() RETURNS TRIGGER AS $$ DECLARE ancien_etat BOOLEAN;
+
+BEGIN -- récupère l'état précédent
+SELECT
+    est_disponible INTO ancien_etat
+FROM
+    monitoring.statuts
+WHERE
+    moniteur_id = NEW.moniteur_id
+    AND id <> NEW.id
+ORDER BY
+    verifie_a DESC
+LIMIT
+    1;
+
+-- Vérifie si un état précédent existe
+IF ancien_etat IS NULL THEN RETURN NEW;
+
+END IF;
+
+-- si passage de disponible à indisponible
+IF ancien_etat = TRUE
+AND NEW.est_disponible = FALSE THEN
+INSERT INTO
+    monitoring.alertes (moniteur_id, type, details)
+VALUES
+    (
+        NEW.moniteur_id,
+        'DOWN',
+        'Indisponible - HTTP ' || COALESCE(NEW.code_http :: TEXT, 'erreur')
+    );
+
+END IF;
+
+-- si passage de indisponible à disponible
+IF ancien_etat = FALSE
+AND NEW.est_disponible = TRUE THEN
+INSERT INTO
+    monitoring.alertes (moniteur_id, type, details)
+VALUES
+    (
+        NEW.moniteur_id,
+        'UP',
+        'Rétabli - HTTP ' || COALESCE(NEW.code_http :: TEXT, '200')
+    );
+
+END IF;
+
+RETURN NEW;
+
+END;
+
+$$ LANGUAGE plpgsql;
+
+-- Trigger qui s'exécute après chaque insertion de statut
+DROP TRIGGER IF EXISTS trigger_alerte_statut ON monitoring.statuts;
+
+CREATE TRIGGER trigger_alerte_statut
+AFTER
+INSERT
+    ON monitoring.statuts FOR EACH ROW EXECUTE FUNCTION monitoring.detecter_transition();
@@ -0,0 +1,41 @@
+-- Migration 0002 (retour) : revient aux moniteurs et statuts du schéma initial
+-- Projet de session A25
+-- By : Leandre Kanmegne
+
+DROP VIEW IF EXISTS monitoring.v_dernier_statut;
+
+CREATE VIEW monitoring.v_dernier_statut AS
+SELECT
+    DISTINCT ON (s.moniteur_id) s.moniteur_id,
+    s.code_http,
+    s.est_disponible,
+    s.message_erreur,
+    s.latence_ms,
+    s.verifie_a,
+    m.url,
+    m.nom
+FROM
+    monitoring.statuts AS s
+    JOIN monitoring.moniteurs AS m ON m.id = s.moniteur_id
+ORDER BY
+    s.moniteur_id,
+    s.verifie_a DESC;
+
+DROP TABLE IF EXISTS monitoring.certificats;
+
+ALTER TABLE monitoring.statuts
+    DROP COLUMN IF EXISTS etat,
+    DROP COLUMN IF EXISTS url_finale,
+    DROP COLUMN IF EXISTS redirections,
+    DROP COLUMN IF EXISTS dns_ms,
+    DROP COLUMN IF EXISTS connexion_ms,
+    DROP COLUMN IF EXISTS tls_ms,
+    DROP COLUMN IF EXISTS premier_octet_ms,
+    DROP COLUMN IF EXISTS transfert_ms,
+    DROP COLUMN IF EXISTS en_maintenance;
+
+ALTER TABLE monitoring.moniteurs
+    DROP COLUMN IF EXISTS intervalle_secondes,
+    DROP COLUMN IF EXISTS parametres,
+    DROP COLUMN IF EXISTS requete,
+    DROP COLUMN IF EXISTS etat_confirme;
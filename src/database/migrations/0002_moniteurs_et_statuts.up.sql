@@ -0,0 +1,71 @@
+-- Migration 0002 : configuration des moniteurs et détail des statuts
+-- Projet de session A25
+-- By : Leandre Kanmegne
+--
+-- Moniteurs : intervalle propre, paramètres (seuils, assertions, confirmation), requête HTTP, état confirmé
+-- Statuts : état (up, degrade, down), redirections, latence par phase, maintenance
+-- Ajoute la table des certificats TLS observés
+
+ALTER TABLE monitoring.moniteurs
+    ADD COLUMN IF NOT EXISTS intervalle_secondes INTEGER CHECK (intervalle_secondes > 0),
+    ADD COLUMN IF NOT EXISTS parametres JSONB NOT NULL DEFAULT '{}' :: jsonb,
+    ADD COLUMN IF NOT EXISTS requete JSONB,
+    -- état après confirmation (echecs_avant_down / succes_avant_up), tenu par le trigger de transition
+    ADD COLUMN IF NOT EXISTS etat_confirme TEXT CHECK (etat_confirme IN ('up', 'degrade', 'down'));
+
+ALTER TABLE monitoring.statuts
+    ADD COLUMN IF NOT EXISTS etat TEXT NOT NULL DEFAULT 'up' CHECK (etat IN ('up', 'degrade', 'down')),
+    ADD COLUMN IF NOT EXISTS url_finale TEXT,
+    ADD COLUMN IF NOT EXISTS redirections JSONB,
+    -- détail de la latence (http et https seulement)
+    ADD COLUMN IF NOT EXISTS dns_ms INTEGER,
+    ADD COLUMN IF NOT EXISTS connexion_ms INTEGER,
+    ADD COLUMN IF NOT EXISTS tls_ms INTEGER,
+    ADD COLUMN IF NOT EXISTS premier_octet_ms INTEGER,
+    ADD COLUMN IF NOT EXISTS transfert_ms INTEGER,
+    -- vérifié pendant une fenêtre de maintenance : pas d'alerte, exclu de la disponibilité
+    ADD COLUMN IF NOT EXISTS en_maintenance BOOLEAN NOT NULL DEFAULT FALSE;
+
+-- les statuts existants étaient seulement disponibles ou non
+UPDATE
+    monitoring.statuts
+SET
+    etat = 'down'
+WHERE
+    NOT est_disponible
+    AND etat = 'up';
+
+-- table des certificats TLS (un par statut https, à côté de monitoring.statuts)
+CREATE TABLE IF NOT EXISTS monitoring.certificats (
+    id BIGSERIAL PRIMARY KEY,
+    statut_id BIGINT NOT NULL UNIQUE REFERENCES monitoring.statuts(id) ON DELETE CASCADE,
+    moniteur_id BIGINT REFERENCES monitoring.moniteurs(id) ON DELETE CASCADE,
+    sujet TEXT NOT NULL,
+    emetteur TEXT NOT NULL,
+    sans JSONB NOT NULL DEFAULT '[]' :: jsonb,
+    expire_a TIMESTAMPTZ NOT NULL
+);
+
+CREATE INDEX IF NOT EXISTS idx_certificats_moniteur ON monitoring.certificats (moniteur_id, expire_a);
+
+-- vue pour récupérer le dernier statut de chaque moniteur (avec l'état et la maintenance)
+DROP VIEW IF EXISTS monitoring.v_dernier_statut;
+
+CREATE VIEW monitoring.v_dernier_statut AS
+SELECT
+    DISTINCT ON (s.moniteur_id) s.moniteur_id,
+    s.code_http,
+    s.est_disponible,
+    s.message_erreur,
+    s.latence_ms,
+    s.verifie_a,
+    m.url,
+    m.nom,
+    s.etat,
+    s.en_maintenance
+FROM
+    monitoring.statuts AS s
+    JOIN monitoring.moniteurs AS m ON m.id = s.moniteur_id
+ORDER BY
+    s.moniteur_id,
+    s.verifie_a DESC;
@@ -1,23 +1,8 @@
--- Script de triggers pour alertes automatiques
+-- Migration 0003 (retour) : revient aux alertes UP/DOWN du schéma initial
 -- Projet de session A25
 -- By : Leandre Kanmegne
 --
--- Crée une table d'alertes et un trigger qui détecte quand
--- un moniteur passe de UP à DOWN (ou l'inverse)
--- À exécuter APRÈS init.sql
---
--- Source:
--- https: / / www.postgresql.org / docs / current / sql - createtrigger.html
-
-
--- Table pour stocker les alertes
-CREATE TABLE IF NOT EXISTS monitoring.alertes (
-    id BIGSERIAL PRIMARY KEY,
-    moniteur_id BIGINT NOT NULL REFERENCES monitoring.moniteurs(id) ON DELETE CASCADE,
-    type TEXT NOT NULL CHECK (type IN ('DOWN', 'UP')),
-    details TEXT,
-    cree_a TIMESTAMPTZ NOT NULL DEFAULT NOW()
-);
+-- Les alertes DEGRADE sont supprimées pour pouvoir remettre la contrainte d'origine
 
 -- Détecte les changements d'état et insère une alerte
 CREATE
@@ -75,10 +60,27 @@ END;
 
 $$ LANGUAGE plpgsql;
 
--- Trigger qui s'exécute après chaque insertion de statut
-DROP TRIGGER IF EXISTS trigger_alerte_statut ON monitoring.statuts;
+DROP TABLE IF EXISTS monitoring.incidents;
+
+DROP TABLE IF EXISTS monitoring.livraisons;
+
+DROP TABLE IF EXISTS monitoring.routes_notification;
+
+DROP TABLE IF EXISTS monitoring.canaux;
+
+DROP TABLE IF EXISTS monitoring.maintenances_moniteurs;
+
+DROP TABLE IF EXISTS monitoring.maintenances;
+
+DROP INDEX IF EXISTS monitoring.idx_alertes_non_traitees;
+
+DELETE FROM
+    monitoring.alertes
+WHERE
+    type = 'DEGRADE';
 
-CREATE TRIGGER trigger_alerte_statut
-AFTER
-INSERT
-    ON monitoring.statuts FOR EACH ROW EXECUTE FUNCTION monitoring.detecter_transition();
\ No newline at end of file
+ALTER TABLE monitoring.alertes
+    DROP CONSTRAINT IF EXISTS alertes_type_check,
+    ADD CONSTRAINT alertes_type_check CHECK (type IN ('DOWN', 'UP')),
+    DROP COLUMN IF EXISTS code_http,
+    DROP COLUMN IF EXISTS traitee_a;
@@ -0,0 +1,290 @@
+-- Migration 0003 : alertes dégradées, notifications, maintenances et incidents
+-- Projet de session A25
+-- By : Leandre Kanmegne
+--
+-- Alertes : type DEGRADE, code HTTP, date de traitement par le répartiteur
+-- Ajoute les livraisons, les canaux et le routage des notifications, les maintenances et les incidents
+-- Remplace le trigger de transition (états up, degrade, down avec confirmation)
+
+-- Alertes
+ALTER TABLE monitoring.alertes
+    DROP CONSTRAINT IF EXISTS alertes_type_check,
+    ADD CONSTRAINT alertes_type_check CHECK (type IN ('DOWN', 'UP', 'DEGRADE')),
+    ADD COLUMN IF NOT EXISTS code_http INTEGER,
+    -- rempli par le répartiteur de notifications une fois l'alerte envoyée
+    ADD COLUMN IF NOT EXISTS traitee_a TIMESTAMPTZ;
+
+CREATE INDEX IF NOT EXISTS idx_alertes_non_traitees ON monitoring.alertes (id)
+WHERE
+    traitee_a IS NULL;
+
+-- table des maintenances : ponctuelles (debut -> fin) ou récurrentes (cron + duree_minutes)
+CREATE TABLE IF NOT EXISTS monitoring.maintenances (
+    id BIGSERIAL PRIMARY KEY,
+    nom TEXT NOT NULL,
+    debut TIMESTAMPTZ,
+    fin TIMESTAMPTZ,
+    cron TEXT,
+    duree_minutes INTEGER CHECK (duree_minutes > 0),
+    fuseau TEXT NOT NULL DEFAULT 'UTC',
+    cree_a TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    CHECK (
+        (cron IS NULL AND debut IS NOT NULL AND fin IS NOT NULL)
+        OR (cron IS NOT NULL AND duree_minutes IS NOT NULL)
+    ),
+    CHECK (debut IS NULL OR fin IS NULL OR fin > debut)
+);
+
+-- moniteurs concernés par chaque maintenance
+CREATE TABLE IF NOT EXISTS monitoring.maintenances_moniteurs (
+    maintenance_id BIGINT NOT NULL REFERENCES monitoring.maintenances(id) ON DELETE CASCADE,
+    moniteur_id BIGINT NOT NULL REFERENCES monitoring.moniteurs(id) ON DELETE CASCADE,
+    PRIMARY KEY (maintenance_id, moniteur_id)
+);
+
+-- table des canaux de notification (webhook, email, slack, discord)
+CREATE TABLE IF NOT EXISTS monitoring.canaux (
+    id BIGSERIAL PRIMARY KEY,
+    nom TEXT NOT NULL UNIQUE,
+    type TEXT NOT NULL CHECK (type IN ('webhook', 'email', 'slack', 'discord')),
+    actif BOOLEAN NOT NULL DEFAULT TRUE,
+    config JSONB NOT NULL DEFAULT '{}' :: jsonb,
+    cree_a TIMESTAMPTZ NOT NULL DEFAULT NOW()
+);
+
+-- routage : quels moniteurs envoient quels types d'alertes vers quels canaux
+CREATE TABLE IF NOT EXISTS monitoring.routes_notification (
+    moniteur_id BIGINT NOT NULL REFERENCES monitoring.moniteurs(id) ON DELETE CASCADE,
+    canal_id BIGINT NOT NULL REFERENCES monitoring.canaux(id) ON DELETE CASCADE,
+    types JSONB NOT NULL DEFAULT '["DOWN", "UP"]' :: jsonb,
+    PRIMARY KEY (moniteur_id, canal_id)
+);
+
+CREATE INDEX IF NOT EXISTS idx_routes_canal ON monitoring.routes_notification (canal_id);
+
+CREATE INDEX IF NOT EXISTS idx_maintenances_moniteur ON monitoring.maintenances_moniteurs (moniteur_id);
+
+-- Table des tentatives d'envoi des alertes (une ligne par tentative et par canal)
+CREATE TABLE IF NOT EXISTS monitoring.livraisons (
+    id BIGSERIAL PRIMARY KEY,
+    alerte_id BIGINT NOT NULL REFERENCES monitoring.alertes(id) ON DELETE CASCADE,
+    canal TEXT NOT NULL,
+    tentative INTEGER NOT NULL,
+    succes BOOLEAN NOT NULL,
+    code_http INTEGER,
+    erreur TEXT,
+    duree_ms INTEGER,
+    cree_a TIMESTAMPTZ NOT NULL DEFAULT NOW()
+);
+
+CREATE INDEX IF NOT EXISTS idx_livraisons_alerte ON monitoring.livraisons (alerte_id);
+
+-- Table des incidents : ouvert par une alerte DOWN, fermé par l'alerte UP suivante
+CREATE TABLE IF NOT EXISTS monitoring.incidents (
+    id BIGSERIAL PRIMARY KEY,
+    moniteur_id BIGINT NOT NULL REFERENCES monitoring.moniteurs(id) ON DELETE CASCADE,
+    alerte_down_id BIGINT REFERENCES monitoring.alertes(id) ON DELETE SET NULL,
+    alerte_up_id BIGINT REFERENCES monitoring.alertes(id) ON DELETE SET NULL,
+    ouvert_a TIMESTAMPTZ NOT NULL,
+    ferme_a TIMESTAMPTZ,
+    -- erreur et code HTTP du statut qui a confirmé la panne
+    message_erreur TEXT,
+    code_http INTEGER,
+    acquitte_a TIMESTAMPTZ,
+    acquitte_par TEXT,
+    note_acquittement TEXT
+);
+
+-- un seul incident ouvert à la fois par moniteur
+CREATE UNIQUE INDEX IF NOT EXISTS idx_incidents_ouvert ON monitoring.incidents (moniteur_id)
+WHERE
+    ferme_a IS NULL;
+
+CREATE INDEX IF NOT EXISTS idx_incidents_moniteur_ts ON monitoring.incidents (moniteur_id, ouvert_a DESC);
+
+-- Détecte les changements d'état (up, degrade, down) et insère une alerte
+-- up/degrade -> down : DOWN, down -> up/degrade : UP, up -> degrade : DEGRADE, degrade -> up : UP
+-- Un changement n'est confirmé qu'après N échecs (echecs_avant_down) ou M succès (succes_avant_up)
+-- consécutifs, lus dans moniteurs.parametres -> 'confirmation' (1 par défaut)
+-- Les statuts en maintenance ne créent pas d'alerte et ne comptent pas dans la confirmation
+-- Une alerte DOWN ouvre un incident, l'alerte UP qui suit le ferme
+CREATE
+OR REPLACE FUNCTION monitoring.detecter_transition() RETURNS TRIGGER AS $$ DECLARE ancien_etat TEXT;
+
+echecs_requis INTEGER;
+
+succes_requis INTEGER;
+
+requis INTEGER;
+
+confirmes INTEGER;
+
+alerte_id BIGINT;
+
+BEGIN IF NEW.moniteur_id IS NULL
+OR NEW.en_maintenance THEN RETURN NEW;
+
+END IF;
+
+-- récupère l'état confirmé et la politique du moniteur (verrou : un statut à la fois par moniteur)
+SELECT
+    etat_confirme,
+    GREATEST(COALESCE((parametres -> 'confirmation' ->> 'echecs_avant_down') :: INTEGER, 1), 1),
+    GREATEST(COALESCE((parametres -> 'confirmation' ->> 'succes_avant_up') :: INTEGER, 1), 1) INTO ancien_etat,
+    echecs_requis,
+    succes_requis
+FROM
+    monitoring.moniteurs
+WHERE
+    id = NEW.moniteur_id FOR
+UPDATE;
+
+-- premier statut : l'état est confirmé sans alerte
+IF ancien_etat IS NULL THEN
+UPDATE
+    monitoring.moniteurs
+SET
+    etat_confirme = NEW.etat
+WHERE
+    id = NEW.moniteur_id;
+
+RETURN NEW;
+
+END IF;
+
+IF ancien_etat = NEW.etat THEN RETURN NEW;
+
+END IF;
+
+-- nombre de vérifications consécutives nécessaires pour confirmer le changement
+requis := CASE
+    WHEN NEW.etat IN ('down', 'degrade')
+    AND ancien_etat <> 'down' THEN echecs_requis
+    ELSE succes_requis
+END;
+
+-- compte les derniers statuts (NEW compris) qui vont dans le sens du changement
+SELECT
+    COUNT(*) FILTER (
+        WHERE
+            CASE
+                WHEN NEW.etat = 'down' THEN derniers.etat = 'down'
+                WHEN ancien_etat = 'down' THEN derniers.etat <> 'down'
+                ELSE derniers.etat = NEW.etat
+            END
+    ) INTO confirmes
+FROM
+    (
+        SELECT
+            etat
+        FROM
+            monitoring.statuts
+        WHERE
+            moniteur_id = NEW.moniteur_id
+            AND NOT en_maintenance
+        ORDER BY
+            verifie_a DESC,
+            id DESC
+        LIMIT
+            requis
+    ) AS derniers;
+
+IF confirmes < requis THEN RETURN NEW;
+
+END IF;
+
+UPDATE
+    monitoring.moniteurs
+SET
+    etat_confirme = NEW.etat
+WHERE
+    id = NEW.moniteur_id;
+
+-- si passage à indisponible
+IF NEW.etat = 'down' THEN
+INSERT INTO
+    monitoring.alertes (moniteur_id, type, details, code_http)
+VALUES
+    (
+        NEW.moniteur_id,
+        'DOWN',
+        'Indisponible - HTTP ' || COALESCE(NEW.code_http :: TEXT, 'erreur'),
+        NEW.code_http
+    ) RETURNING id INTO alerte_id;
+
+INSERT INTO
+    monitoring.incidents (moniteur_id, alerte_down_id, ouvert_a, message_erreur, code_http)
+VALUES
+    (
+        NEW.moniteur_id,
+        alerte_id,
+        NEW.verifie_a,
+        NEW.message_erreur,
+        NULLIF(NEW.code_http, 0)
+    ) ON CONFLICT (moniteur_id)
+WHERE
+    ferme_a IS NULL DO NOTHING;
+
+-- si passage de indisponible à disponible (même dégradé)
+ELSIF ancien_etat = 'down' THEN
+INSERT INTO
+    monitoring.alertes (moniteur_id, type, details, code_http)
+VALUES
+    (
+        NEW.moniteur_id,
+        'UP',
+        'Rétabli - HTTP ' || COALESCE(NEW.code_http :: TEXT, '200') || CASE
+            WHEN NEW.etat = 'degrade' THEN ' (dégradé : ' || COALESCE(NEW.message_erreur, 'lent') || ')'
+            ELSE ''
+        END,
+        NEW.code_http
+    ) RETURNING id INTO alerte_id;
+
+UPDATE
+    monitoring.incidents
+SET
+    ferme_a = NEW.verifie_a,
+    alerte_up_id = alerte_id
+WHERE
+    moniteur_id = NEW.moniteur_id
+    AND ferme_a IS NULL;
+
+-- si passage de up à dégradé
+ELSIF NEW.etat = 'degrade' THEN
+INSERT INTO
+    monitoring.alertes (moniteur_id, type, details, code_http)
+VALUES
+    (
+        NEW.moniteur_id,
+        'DEGRADE',
+        'Dégradé - ' || COALESCE(NEW.message_erreur, 'latence ' || NEW.latence_ms || ' ms'),
+        NEW.code_http
+    );
+
+-- si passage de dégradé à up
+ELSE
+INSERT INTO
+    monitoring.alertes (moniteur_id, type, details, code_http)
+VALUES
+    (
+        NEW.moniteur_id,
+        'UP',
+        'Performances rétablies - latence ' || COALESCE(NEW.latence_ms :: TEXT, '?') || ' ms',
+        NEW.code_http
+    );
+
+END IF;
+
+RETURN NEW;
+
+END;
+
+$$ LANGUAGE plpgsql;
+
+-- Trigger qui s'exécute après chaque insertion de statut
+DROP TRIGGER IF EXISTS trigger_alerte_statut ON monitoring.statuts;
+
+CREATE TRIGGER trigger_alerte_statut
+AFTER
+INSERT
+    ON monitoring.statuts FOR EACH ROW EXECUTE FUNCTION monitoring.detecter_transition();
@@ -0,0 +1,13 @@
+-- Migration 0004 (retour) : supprime l'historique consolidé et les index de purge
+-- Projet de session A25
+-- By : Leandre Kanmegne
+
+DROP INDEX IF EXISTS monitoring.idx_alertes_cree_a;
+
+DROP INDEX IF EXISTS monitoring.idx_statuts_ts;
+
+DROP TABLE IF EXISTS monitoring.consolidations;
+
+DROP TABLE IF EXISTS monitoring.statuts_quotidiens;
+
+DROP TABLE IF EXISTS monitoring.statuts_horaires;
@@ -0,0 +1,44 @@
+-- Migration 0004 : historique consolidé et rétention
+-- Projet de session A25
+-- By : Leandre Kanmegne
+--
+-- Statuts consolidés par heure et par jour (remplis par le consolidateur) et sa progression
+-- Index par date pour la purge des statuts et des alertes
+
+-- statuts consolidés par heure (une ligne par moniteur et par heure, remplie par le consolidateur)
+-- les durées sont pondérées dans le temps comme pour /api/moniteurs/{id}/uptime
+CREATE TABLE IF NOT EXISTS monitoring.statuts_horaires (
+    moniteur_id BIGINT NOT NULL REFERENCES monitoring.moniteurs(id) ON DELETE CASCADE,
+    debut TIMESTAMPTZ NOT NULL,
+    verifications INTEGER NOT NULL,
+    succes INTEGER NOT NULL,
+    echecs INTEGER NOT NULL,
+    secondes_observees BIGINT NOT NULL,
+    secondes_indisponibles BIGINT NOT NULL,
+    secondes_maintenance BIGINT NOT NULL,
+    latence_min_ms DOUBLE PRECISION,
+    latence_max_ms DOUBLE PRECISION,
+    latence_moyenne_ms DOUBLE PRECISION,
+    latence_p50_ms DOUBLE PRECISION,
+    latence_p90_ms DOUBLE PRECISION,
+    latence_p95_ms DOUBLE PRECISION,
+    latence_p99_ms DOUBLE PRECISION,
+    PRIMARY KEY (moniteur_id, debut)
+);
+
+-- statuts consolidés par jour (minuit UTC), mêmes colonnes
+CREATE TABLE IF NOT EXISTS monitoring.statuts_quotidiens (
+    LIKE monitoring.statuts_horaires INCLUDING ALL,
+    FOREIGN KEY (moniteur_id) REFERENCES monitoring.moniteurs(id) ON DELETE CASCADE
+);
+
+-- progression du consolidateur : les statuts avant "jusqua" sont consolidés pour cette granularité
+CREATE TABLE IF NOT EXISTS monitoring.consolidations (
+    granularite TEXT PRIMARY KEY CHECK (granularite IN ('horaire', 'quotidienne')),
+    jusqua TIMESTAMPTZ NOT NULL
+);
+
+-- purge de l'historique par date (rétention)
+CREATE INDEX IF NOT EXISTS idx_statuts_ts ON monitoring.statuts (verifie_a);
+
+CREATE INDEX IF NOT EXISTS idx_alertes_cree_a ON monitoring.alertes (cree_a);
@@ -0,0 +1,185 @@
+-- Migration 0005 : fin de dégradation
+-- Projet de session A25
+-- By : Leandre Kanmegne
+--
+-- Le retour de dégradé à up n'est pas une fin de panne : il crée une alerte DEGRADE_FIN au lieu de UP
+-- (les abonnés DOWN/UP et les canaux fixes ne reçoivent plus de rétablissement sans panne)
+
+ALTER TABLE monitoring.alertes
+    DROP CONSTRAINT IF EXISTS alertes_type_check,
+    ADD CONSTRAINT alertes_type_check CHECK (type IN ('DOWN', 'UP', 'DEGRADE', 'DEGRADE_FIN'));
+
+-- up/degrade -> down : DOWN, down -> up/degrade : UP, up -> degrade : DEGRADE, degrade -> up : DEGRADE_FIN
+-- Le reste de la fonction est inchangé depuis la migration 0003
+CREATE
+OR REPLACE FUNCTION monitoring.detecter_transition() RETURNS TRIGGER AS $$ DECLARE ancien_etat TEXT;
+
+echecs_requis INTEGER;
+
+succes_requis INTEGER;
+
+requis INTEGER;
+
+confirmes INTEGER;
+
+alerte_id BIGINT;
+
+BEGIN IF NEW.moniteur_id IS NULL
+OR NEW.en_maintenance THEN RETURN NEW;
+
+END IF;
+
+-- récupère l'état confirmé et la politique du moniteur (verrou : un statut à la fois par moniteur)
+SELECT
+    etat_confirme,
+    GREATEST(COALESCE((parametres -> 'confirmation' ->> 'echecs_avant_down') :: INTEGER, 1), 1),
+    GREATEST(COALESCE((parametres -> 'confirmation' ->> 'succes_avant_up') :: INTEGER, 1), 1) INTO ancien_etat,
+    echecs_requis,
+    succes_requis
+FROM
+    monitoring.moniteurs
+WHERE
+    id = NEW.moniteur_id FOR
+UPDATE;
+
+-- premier statut : l'état est confirmé sans alerte
+IF ancien_etat IS NULL THEN
+UPDATE
+    monitoring.moniteurs
+SET
+    etat_confirme = NEW.etat
+WHERE
+    id = NEW.moniteur_id;
+
+RETURN NEW;
+
+END IF;
+
+IF ancien_etat = NEW.etat THEN RETURN NEW;
+
+END IF;
+
+-- nombre de vérifications consécutives nécessaires pour confirmer le changement
+requis := CASE
+    WHEN NEW.etat IN ('down', 'degrade')
+    AND ancien_etat <> 'down' THEN echecs_requis
+    ELSE succes_requis
+END;
+
+-- compte les derniers statuts (NEW compris) qui vont dans le sens du changement
+SELECT
+    COUNT(*) FILTER (
+        WHERE
+            CASE
+                WHEN NEW.etat = 'down' THEN derniers.etat = 'down'
+                WHEN ancien_etat = 'down' THEN derniers.etat <> 'down'
+                ELSE derniers.etat = NEW.etat
+            END
+    ) INTO confirmes
+FROM
+    (
+        SELECT
+            etat
+        FROM
+            monitoring.statuts
+        WHERE
+            moniteur_id = NEW.moniteur_id
+            AND NOT en_maintenance
+        ORDER BY
+            verifie_a DESC,
+            id DESC
+        LIMIT
+            requis
+    ) AS derniers;
+
+IF confirmes < requis THEN RETURN NEW;
+
+END IF;
+
+UPDATE
+    monitoring.moniteurs
+SET
+    etat_confirme = NEW.etat
+WHERE
+    id = NEW.moniteur_id;
+
+-- si passage à indisponible
+IF NEW.etat = 'down' THEN
+INSERT INTO
+    monitoring.alertes (moniteur_id, type, details, code_http)
+VALUES
+    (
+        NEW.moniteur_id,
+        'DOWN',
+        'Indisponible - HTTP ' || COALESCE(NEW.code_http :: TEXT, 'erreur'),
+        NEW.code_http
+    ) RETURNING id INTO alerte_id;
+
+INSERT INTO
+    monitoring.incidents (moniteur_id, alerte_down_id, ouvert_a, message_erreur, code_http)
+VALUES
+    (
+        NEW.moniteur_id,
+        alerte_id,
+        NEW.verifie_a,
+        NEW.message_erreur,
+        NULLIF(NEW.code_http, 0)
+    ) ON CONFLICT (moniteur_id)
+WHERE
+    ferme_a IS NULL DO NOTHING;
+
+-- si passage de indisponible à disponible (même dégradé)
+ELSIF ancien_etat = 'down' THEN
+INSERT INTO
+    monitoring.alertes (moniteur_id, type, details, code_http)
+VALUES
+    (
+        NEW.moniteur_id,
+        'UP',
+        'Rétabli - HTTP ' || COALESCE(NEW.code_http :: TEXT, '200') || CASE
+            WHEN NEW.etat = 'degrade' THEN ' (dégradé : ' || COALESCE(NEW.message_erreur, 'lent') || ')'
+            ELSE ''
+        END,
+        NEW.code_http
+    ) RETURNING id INTO alerte_id;
+
+UPDATE
+    monitoring.incidents
+SET
+    ferme_a = NEW.verifie_a,
+    alerte_up_id = alerte_id
+WHERE
+    moniteur_id = NEW.moniteur_id
+    AND ferme_a IS NULL;
+
+-- si passage de up à dégradé
+ELSIF NEW.etat = 'degrade' THEN
+INSERT INTO
+    monitoring.alertes (moniteur_id, type, details, code_http)
+VALUES
+    (
+        NEW.moniteur_id,
+        'DEGRADE',
+        'Dégradé - ' || COALESCE(NEW.message_erreur, 'latence ' || NEW.latence_ms || ' ms'),
+        NEW.code_http
+    );
+
+-- si passage de dégradé à up
+ELSE
+INSERT INTO
+    monitoring.alertes (moniteur_id, type, details, code_http)
+VALUES
+    (
+        NEW.moniteur_id,
+        'DEGRADE_FIN',
+        'Performances rétablies - latence ' || COALESCE(NEW.latence_ms :: TEXT, '?') || ' ms',
+        NEW.code_http
+    );
+
+END IF;
+
+RETURN NEW;
+
+END;
+
+$$ LANGUAGE plpgsql;
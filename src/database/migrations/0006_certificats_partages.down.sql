@@ -0,0 +1,36 @@
+-- Migration 0006 (retour) : redonne à chaque statut https sa propre ligne de certificat
+-- Projet de session A25
+-- By : Leandre Kanmegne
+
+ALTER TABLE monitoring.certificats
+    ADD COLUMN IF NOT EXISTS statut_id BIGINT;
+
+-- une copie du certificat partagé pour chaque statut qui le référence
+INSERT INTO
+    monitoring.certificats (statut_id, moniteur_id, sujet, emetteur, sans, expire_a)
+SELECT
+    s.id,
+    c.moniteur_id,
+    c.sujet,
+    c.emetteur,
+    c.sans,
+    c.expire_a
+FROM
+    monitoring.statuts AS s
+    JOIN monitoring.certificats AS c ON c.id = s.certificat_id;
+
+DELETE FROM
+    monitoring.certificats
+WHERE
+    statut_id IS NULL;
+
+DROP INDEX IF EXISTS monitoring.idx_statuts_certificat;
+
+ALTER TABLE monitoring.statuts DROP COLUMN IF EXISTS certificat_id;
+
+ALTER TABLE monitoring.certificats
+    ALTER COLUMN statut_id SET NOT NULL,
+    ADD CONSTRAINT certificats_statut_id_key UNIQUE (statut_id),
+    ADD CONSTRAINT certificats_statut_id_fkey FOREIGN KEY (statut_id) REFERENCES monitoring.statuts(id) ON DELETE CASCADE,
+    DROP COLUMN IF EXISTS vu_a,
+    DROP COLUMN IF EXISTS empreinte;
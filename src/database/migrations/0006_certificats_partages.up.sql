@@ -0,0 +1,37 @@
+-- Migration 0006 : un certificat par changement, pas par vérification
+-- Projet de session A25
+-- By : Leandre Kanmegne
+--
+-- Les statuts pointent vers le certificat observé (statuts.certificat_id) : tant que l'empreinte
+-- et l'expiration ne changent pas, les vérifications réutilisent la même ligne et avancent vu_a
+
+ALTER TABLE monitoring.certificats
+    ADD COLUMN IF NOT EXISTS empreinte TEXT,
+    ADD COLUMN IF NOT EXISTS vu_a TIMESTAMPTZ;
+
+-- la purge des statuts ne touche plus aux certificats, la suppression d'un certificat détache ses statuts
+ALTER TABLE monitoring.statuts
+    ADD COLUMN IF NOT EXISTS certificat_id BIGINT REFERENCES monitoring.certificats(id) ON DELETE SET NULL;
+
+UPDATE
+    monitoring.statuts AS s
+SET
+    certificat_id = c.id
+FROM
+    monitoring.certificats AS c
+WHERE
+    c.statut_id = s.id;
+
+UPDATE
+    monitoring.certificats AS c
+SET
+    vu_a = s.verifie_a
+FROM
+    monitoring.statuts AS s
+WHERE
+    s.id = c.statut_id;
+
+-- les lignes déjà en double sont gardées : seules les prochaines vérifications sont dédupliquées
+ALTER TABLE monitoring.certificats DROP COLUMN IF EXISTS statut_id;
+
+CREATE INDEX IF NOT EXISTS idx_statuts_certificat ON monitoring.statuts (certificat_id);
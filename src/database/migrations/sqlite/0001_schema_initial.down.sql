@@ -0,0 +1,35 @@
+-- Retour de la migration SQLite 0001 : supprime tout le schéma
+-- Projet de session A25
+-- By : Leandre Kanmegne
+
+DROP TRIGGER IF EXISTS trigger_transition;
+
+DROP TRIGGER IF EXISTS trigger_premier_etat;
+
+DROP VIEW IF EXISTS v_dernier_statut;
+
+DROP TABLE IF EXISTS consolidations;
+
+DROP TABLE IF EXISTS statuts_quotidiens;
+
+DROP TABLE IF EXISTS statuts_horaires;
+
+DROP TABLE IF EXISTS incidents;
+
+DROP TABLE IF EXISTS livraisons;
+
+DROP TABLE IF EXISTS routes_notification;
+
+DROP TABLE IF EXISTS canaux;
+
+DROP TABLE IF EXISTS maintenances_moniteurs;
+
+DROP TABLE IF EXISTS maintenances;
+
+DROP TABLE IF EXISTS alertes;
+
+DROP TABLE IF EXISTS statuts;
+
+DROP TABLE IF EXISTS certificats;
+
+DROP TABLE IF EXISTS moniteurs;
@@ -0,0 +1,327 @@
+-- Migration SQLite 0001 : schéma complet (équivalent des migrations PostgreSQL 0001 à 0004)
+-- Projet de session A25
+-- By : Leandre Kanmegne
+--
+-- Mêmes tables que le schéma monitoring de PostgreSQL, sans le préfixe de schéma
+-- Les dates sont des entiers en microsecondes Unix (UTC) : tri et calculs de durées en entiers
+-- Les colonnes JSON sont du texte lu avec json_extract et json_each
+-- Les clés étrangères demandent PRAGMA foreign_keys = ON (posé à l'ouverture, voir repos/sqlite.go)
+-- Le trigger de transition PL/pgSQL est refait en deux triggers SQLite (premier état, transition confirmée)
+
+-- table des moniteurs (services à surveiller)
+CREATE TABLE IF NOT EXISTS moniteurs (
+    id INTEGER PRIMARY KEY AUTOINCREMENT,
+    nom TEXT NOT NULL,
+    url TEXT NOT NULL UNIQUE,
+    type TEXT NOT NULL DEFAULT 'http',
+    actif BOOLEAN NOT NULL DEFAULT TRUE,
+    cree_a INTEGER NOT NULL DEFAULT (CAST(unixepoch('subsec') * 1000000 AS INTEGER)),
+    intervalle_secondes INTEGER CHECK (intervalle_secondes > 0),
+    parametres TEXT NOT NULL DEFAULT '{}',
+    requete TEXT,
+    -- état après confirmation (echecs_avant_down / succes_avant_up), tenu par les triggers de transition
+    etat_confirme TEXT CHECK (etat_confirme IN ('up', 'degrade', 'down'))
+);
+
+-- table des statuts (historique des vérifications)
+CREATE TABLE IF NOT EXISTS statuts (
+    id INTEGER PRIMARY KEY AUTOINCREMENT,
+    moniteur_id INTEGER REFERENCES moniteurs(id) ON DELETE CASCADE,
+    url TEXT NOT NULL,
+    code_http INTEGER,
+    est_disponible BOOLEAN NOT NULL,
+    message_erreur TEXT,
+    latence_ms INTEGER,
+    verifie_a INTEGER NOT NULL,
+    etat TEXT NOT NULL DEFAULT 'up' CHECK (etat IN ('up', 'degrade', 'down')),
+    url_finale TEXT,
+    redirections TEXT,
+    -- détail de la latence (http et https seulement)
+    dns_ms INTEGER,
+    connexion_ms INTEGER,
+    tls_ms INTEGER,
+    premier_octet_ms INTEGER,
+    transfert_ms INTEGER,
+    -- vérifié pendant une fenêtre de maintenance : pas d'alerte, exclu de la disponibilité
+    en_maintenance BOOLEAN NOT NULL DEFAULT FALSE,
+    -- certificat observé (https seulement), partagé tant qu'il ne change pas
+    certificat_id INTEGER REFERENCES certificats(id) ON DELETE SET NULL
+);
+
+CREATE INDEX IF NOT EXISTS idx_statuts_moniteur_ts ON statuts (moniteur_id, verifie_a DESC);
+
+CREATE INDEX IF NOT EXISTS idx_statuts_ts ON statuts (verifie_a);
+
+-- table des certificats TLS (une ligne par certificat observé, vu_a avance à chaque vérification)
+CREATE TABLE IF NOT EXISTS certificats (
+    id INTEGER PRIMARY KEY AUTOINCREMENT,
+    moniteur_id INTEGER REFERENCES moniteurs(id) ON DELETE CASCADE,
+    empreinte TEXT,
+    sujet TEXT NOT NULL,
+    emetteur TEXT NOT NULL,
+    sans TEXT NOT NULL DEFAULT '[]',
+    expire_a INTEGER NOT NULL,
+    vu_a INTEGER
+);
+
+CREATE INDEX IF NOT EXISTS idx_certificats_moniteur ON certificats (moniteur_id, expire_a);
+
+CREATE INDEX IF NOT EXISTS idx_statuts_certificat ON statuts (certificat_id);
+
+-- vue pour récupérer le dernier statut de chaque moniteur
+CREATE VIEW IF NOT EXISTS v_dernier_statut AS
+SELECT
+    s.moniteur_id,
+    s.code_http,
+    s.est_disponible,
+    s.message_erreur,
+    s.latence_ms,
+    s.verifie_a,
+    m.url,
+    m.nom,
+    s.etat,
+    s.en_maintenance
+FROM
+    statuts AS s
+    JOIN moniteurs AS m ON m.id = s.moniteur_id
+WHERE
+    s.id = (
+        SELECT d.id FROM statuts AS d
+        WHERE d.moniteur_id = s.moniteur_id
+        ORDER BY d.verifie_a DESC, d.id DESC
+        LIMIT 1
+    );
+
+-- table des alertes (remplie par les triggers de transition)
+CREATE TABLE IF NOT EXISTS alertes (
+    id INTEGER PRIMARY KEY AUTOINCREMENT,
+    moniteur_id INTEGER NOT NULL REFERENCES moniteurs(id) ON DELETE CASCADE,
+    type TEXT NOT NULL CHECK (type IN ('DOWN', 'UP', 'DEGRADE', 'DEGRADE_FIN')),
+    details TEXT,
+    cree_a INTEGER NOT NULL DEFAULT (CAST(unixepoch('subsec') * 1000000 AS INTEGER)),
+    code_http INTEGER,
+    -- rempli par le répartiteur de notifications une fois l'alerte envoyée
+    traitee_a INTEGER
+);
+
+CREATE INDEX IF NOT EXISTS idx_alertes_non_traitees ON alertes (id) WHERE traitee_a IS NULL;
+
+CREATE INDEX IF NOT EXISTS idx_alertes_cree_a ON alertes (cree_a);
+
+-- table des maintenances : ponctuelles (debut -> fin) ou récurrentes (cron + duree_minutes)
+CREATE TABLE IF NOT EXISTS maintenances (
+    id INTEGER PRIMARY KEY AUTOINCREMENT,
+    nom TEXT NOT NULL,
+    debut INTEGER,
+    fin INTEGER,
+    cron TEXT,
+    duree_minutes INTEGER CHECK (duree_minutes > 0),
+    fuseau TEXT NOT NULL DEFAULT 'UTC',
+    cree_a INTEGER NOT NULL DEFAULT (CAST(unixepoch('subsec') * 1000000 AS INTEGER)),
+    CHECK (
+        (cron IS NULL AND debut IS NOT NULL AND fin IS NOT NULL)
+        OR (cron IS NOT NULL AND duree_minutes IS NOT NULL)
+    ),
+    CHECK (debut IS NULL OR fin IS NULL OR fin > debut)
+);
+
+-- moniteurs concernés par chaque maintenance
+CREATE TABLE IF NOT EXISTS maintenances_moniteurs (
+    maintenance_id INTEGER NOT NULL REFERENCES maintenances(id) ON DELETE CASCADE,
+    moniteur_id INTEGER NOT NULL REFERENCES moniteurs(id) ON DELETE CASCADE,
+    PRIMARY KEY (maintenance_id, moniteur_id)
+);
+
+CREATE INDEX IF NOT EXISTS idx_maintenances_moniteur ON maintenances_moniteurs (moniteur_id);
+
+-- table des canaux de notification (webhook, email, slack, discord)
+CREATE TABLE IF NOT EXISTS canaux (
+    id INTEGER PRIMARY KEY AUTOINCREMENT,
+    nom TEXT NOT NULL UNIQUE,
+    type TEXT NOT NULL CHECK (type IN ('webhook', 'email', 'slack', 'discord')),
+    actif BOOLEAN NOT NULL DEFAULT TRUE,
+    config TEXT NOT NULL DEFAULT '{}',
+    cree_a INTEGER NOT NULL DEFAULT (CAST(unixepoch('subsec') * 1000000 AS INTEGER))
+);
+
+-- routage : quels moniteurs envoient quels types d'alertes vers quels canaux
+CREATE TABLE IF NOT EXISTS routes_notification (
+    moniteur_id INTEGER NOT NULL REFERENCES moniteurs(id) ON DELETE CASCADE,
+    canal_id INTEGER NOT NULL REFERENCES canaux(id) ON DELETE CASCADE,
+    types TEXT NOT NULL DEFAULT '["DOWN", "UP"]',
+    PRIMARY KEY (moniteur_id, canal_id)
+);
+
+CREATE INDEX IF NOT EXISTS idx_routes_canal ON routes_notification (canal_id);
+
+-- tentatives d'envoi des alertes (une ligne par tentative et par canal)
+CREATE TABLE IF NOT EXISTS livraisons (
+    id INTEGER PRIMARY KEY AUTOINCREMENT,
+    alerte_id INTEGER NOT NULL REFERENCES alertes(id) ON DELETE CASCADE,
+    canal TEXT NOT NULL,
+    tentative INTEGER NOT NULL,
+    succes BOOLEAN NOT NULL,
+    code_http INTEGER,
+    erreur TEXT,
+    duree_ms INTEGER,
+    cree_a INTEGER NOT NULL DEFAULT (CAST(unixepoch('subsec') * 1000000 AS INTEGER))
+);
+
+CREATE INDEX IF NOT EXISTS idx_livraisons_alerte ON livraisons (alerte_id);
+
+-- incidents : ouvert par une alerte DOWN, fermé par l'alerte UP suivante
+CREATE TABLE IF NOT EXISTS incidents (
+    id INTEGER PRIMARY KEY AUTOINCREMENT,
+    moniteur_id INTEGER NOT NULL REFERENCES moniteurs(id) ON DELETE CASCADE,
+    alerte_down_id INTEGER REFERENCES alertes(id) ON DELETE SET NULL,
+    alerte_up_id INTEGER REFERENCES alertes(id) ON DELETE SET NULL,
+    ouvert_a INTEGER NOT NULL,
+    ferme_a INTEGER,
+    -- erreur et code HTTP du statut qui a confirmé la panne
+    message_erreur TEXT,
+    code_http INTEGER,
+    acquitte_a INTEGER,
+    acquitte_par TEXT,
+    note_acquittement TEXT
+);
+
+-- un seul incident ouvert à la fois par moniteur
+CREATE UNIQUE INDEX IF NOT EXISTS idx_incidents_ouvert ON incidents (moniteur_id) WHERE ferme_a IS NULL;
+
+CREATE INDEX IF NOT EXISTS idx_incidents_moniteur_ts ON incidents (moniteur_id, ouvert_a DESC);
+
+-- statuts consolidés par heure (une ligne par moniteur et par heure, remplie par le consolidateur)
+CREATE TABLE IF NOT EXISTS statuts_horaires (
+    moniteur_id INTEGER NOT NULL REFERENCES moniteurs(id) ON DELETE CASCADE,
+    debut INTEGER NOT NULL,
+    verifications INTEGER NOT NULL,
+    succes INTEGER NOT NULL,
+    echecs INTEGER NOT NULL,
+    secondes_observees INTEGER NOT NULL,
+    secondes_indisponibles INTEGER NOT NULL,
+    secondes_maintenance INTEGER NOT NULL,
+    latence_min_ms REAL,
+    latence_max_ms REAL,
+    latence_moyenne_ms REAL,
+    latence_p50_ms REAL,
+    latence_p90_ms REAL,
+    latence_p95_ms REAL,
+    latence_p99_ms REAL,
+    PRIMARY KEY (moniteur_id, debut)
+);
+
+-- statuts consolidés par jour (minuit UTC), mêmes colonnes
+CREATE TABLE IF NOT EXISTS statuts_quotidiens (
+    moniteur_id INTEGER NOT NULL REFERENCES moniteurs(id) ON DELETE CASCADE,
+    debut INTEGER NOT NULL,
+    verifications INTEGER NOT NULL,
+    succes INTEGER NOT NULL,
+    echecs INTEGER NOT NULL,
+    secondes_observees INTEGER NOT NULL,
+    secondes_indisponibles INTEGER NOT NULL,
+    secondes_maintenance INTEGER NOT NULL,
+    latence_min_ms REAL,
+    latence_max_ms REAL,
+    latence_moyenne_ms REAL,
+    latence_p50_ms REAL,
+    latence_p90_ms REAL,
+    latence_p95_ms REAL,
+    latence_p99_ms REAL,
+    PRIMARY KEY (moniteur_id, debut)
+);
+
+-- progression du consolidateur : les statuts avant "jusqua" sont consolidés pour cette granularité
+CREATE TABLE IF NOT EXISTS consolidations (
+    granularite TEXT PRIMARY KEY CHECK (granularite IN ('horaire', 'quotidienne')),
+    jusqua INTEGER NOT NULL
+);
+
+-- Premier statut d'un moniteur (hors maintenance) : l'état est confirmé sans alerte
+CREATE TRIGGER IF NOT EXISTS trigger_premier_etat
+AFTER INSERT ON statuts
+WHEN NEW.moniteur_id IS NOT NULL
+    AND NOT NEW.en_maintenance
+    AND (SELECT etat_confirme FROM moniteurs WHERE id = NEW.moniteur_id) IS NULL
+BEGIN
+    UPDATE moniteurs SET etat_confirme = NEW.etat WHERE id = NEW.moniteur_id;
+END;
+
+-- Changement d'état confirmé : alerte, incident, puis nouvel état confirmé
+-- Mêmes règles que monitoring.detecter_transition() (migrations PostgreSQL 0003 et 0005) :
+-- up/degrade -> down : DOWN, down -> up/degrade : UP, up -> degrade : DEGRADE, degrade -> up : DEGRADE_FIN
+-- Le changement n'est confirmé que si les N derniers statuts hors maintenance (NEW compris) vont dans son sens,
+-- N = echecs_avant_down (vers down ou degrade) ou succes_avant_up (sinon), 1 par défaut
+CREATE TRIGGER IF NOT EXISTS trigger_transition
+AFTER INSERT ON statuts
+WHEN NEW.moniteur_id IS NOT NULL
+    AND NOT NEW.en_maintenance
+    AND (SELECT etat_confirme FROM moniteurs WHERE id = NEW.moniteur_id) <> NEW.etat
+    AND (
+        SELECT COUNT(*)
+        FROM (
+            SELECT etat
+            FROM statuts
+            WHERE moniteur_id = NEW.moniteur_id AND NOT en_maintenance
+            ORDER BY verifie_a DESC, id DESC
+            LIMIT (
+                SELECT MAX(COALESCE(CASE
+                    WHEN NEW.etat IN ('down', 'degrade') AND etat_confirme <> 'down'
+                    THEN json_extract(parametres, '$.confirmation.echecs_avant_down')
+                    ELSE json_extract(parametres, '$.confirmation.succes_avant_up')
+                END, 1), 1)
+                FROM moniteurs WHERE id = NEW.moniteur_id
+            )
+        ) AS derniers
+        WHERE CASE
+            WHEN NEW.etat = 'down' THEN derniers.etat = 'down'
+            WHEN (SELECT etat_confirme FROM moniteurs WHERE id = NEW.moniteur_id) = 'down' THEN derniers.etat <> 'down'
+            ELSE derniers.etat = NEW.etat
+        END
+    ) >= (
+        SELECT MAX(COALESCE(CASE
+            WHEN NEW.etat IN ('down', 'degrade') AND etat_confirme <> 'down'
+            THEN json_extract(parametres, '$.confirmation.echecs_avant_down')
+            ELSE json_extract(parametres, '$.confirmation.succes_avant_up')
+        END, 1), 1)
+        FROM moniteurs WHERE id = NEW.moniteur_id
+    )
+BEGIN
+    -- etat_confirme est encore l'ancien état
+    INSERT INTO alertes (moniteur_id, type, details, code_http)
+    SELECT
+        NEW.moniteur_id,
+        CASE
+            WHEN NEW.etat = 'down' THEN 'DOWN'
+            WHEN etat_confirme = 'down' THEN 'UP'
+            WHEN NEW.etat = 'degrade' THEN 'DEGRADE'
+            ELSE 'DEGRADE_FIN'
+        END,
+        CASE
+            WHEN NEW.etat = 'down' THEN 'Indisponible - HTTP ' || COALESCE(NEW.code_http, 'erreur')
+            WHEN etat_confirme = 'down' THEN 'Rétabli - HTTP ' || COALESCE(NEW.code_http, '200') || CASE
+                WHEN NEW.etat = 'degrade' THEN ' (dégradé : ' || COALESCE(NEW.message_erreur, 'lent') || ')'
+                ELSE ''
+            END
+            WHEN NEW.etat = 'degrade' THEN 'Dégradé - ' || COALESCE(NEW.message_erreur, 'latence ' || NEW.latence_ms || ' ms')
+            ELSE 'Performances rétablies - latence ' || COALESCE(NEW.latence_ms, '?') || ' ms'
+        END,
+        NEW.code_http
+    FROM moniteurs
+    WHERE id = NEW.moniteur_id;
+
+    -- passage à indisponible : ouvre un incident (last_insert_rowid = l'alerte DOWN)
+    INSERT INTO incidents (moniteur_id, alerte_down_id, ouvert_a, message_erreur, code_http)
+    SELECT NEW.moniteur_id, last_insert_rowid(), NEW.verifie_a, NEW.message_erreur, NULLIF(NEW.code_http, 0)
+    WHERE NEW.etat = 'down'
+    ON CONFLICT (moniteur_id) WHERE ferme_a IS NULL DO NOTHING;
+
+    -- retour après une panne : ferme l'incident ouvert (last_insert_rowid = l'alerte UP)
+    UPDATE incidents
+    SET ferme_a = NEW.verifie_a, alerte_up_id = last_insert_rowid()
+    WHERE moniteur_id = NEW.moniteur_id
+        AND ferme_a IS NULL
+        AND NEW.etat <> 'down'
+        AND (SELECT etat_confirme FROM moniteurs WHERE id = NEW.moniteur_id) = 'down';
+
+    UPDATE moniteurs SET etat_confirme = NEW.etat WHERE id = NEW.moniteur_id;
+END;
@@ -0,0 +1,133 @@
+/* Tests pour le chargement des migrations
+ * Projet de session A25
+ * By : Leandre Kanmegne
+ */
+package database
+
+import (
+	"context"
+	"database/sql"
+	"path/filepath"
+	"strings"
+	"testing"
+	"testing/fstest"
+
+	_ "modernc.org/sqlite"
+)
+
+// test : les migrations sont triées par version et appariées haut/bas
+func TestCharger_Ordre(t *testing.T) {
+	fsys := fstest.MapFS{
+		"m/0010_index.up.sql":    {Data: []byte("CREATE INDEX i;")},
+		"m/0010_index.down.sql":  {Data: []byte("DROP INDEX i;")},
+		"m/0002_tables.up.sql":   {Data: []byte("CREATE TABLE t;")},
+		"m/0002_tables.down.sql": {Data: []byte("DROP TABLE t;")},
+		"m/0001_schema.up.sql":   {Data: []byte("CREATE SCHEMA s;")},
+		"m/0001_schema.down.sql": {Data: []byte("DROP SCHEMA s;")},
+	}
+
+	migrations, err := Charger(fsys, "m")
+	if err != nil {
+		t.Fatalf("erreur inattendue : %v", err)
+	}
+	if len(migrations) != 3 || migrations[0].Version != 1 || migrations[1].Version != 2 || migrations[2].Version != 10 {
+		t.Fatalf("ordre inattendu : %+v", migrations)
+	}
+	if migrations[1].Nom != "tables" || migrations[1].Haut != "CREATE TABLE t;" || migrations[1].Bas != "DROP TABLE t;" {
+		t.Errorf("migration 2 inattendue : %+v", migrations[1])
+	}
+}
+
+// test : fichiers manquants, noms invalides et versions en double sont refusés
+func TestCharger_Invalide(t *testing.T) {
+	cas := map[string]fstest.MapFS{
+		"bas manquant": {
+			"m/0001_schema.up.sql": {Data: []byte("CREATE SCHEMA s;")},
+		},
+		"nom invalide": {
+			"m/schema.sql": {Data: []byte("CREATE SCHEMA s;")},
+		},
+		"version en double": {
+			"m/0001_a.up.sql":   {Data: []byte("SELECT 1;")},
+			"m/0001_a.down.sql": {Data: []byte("SELECT 1;")},
+			"m/0001_b.up.sql":   {Data: []byte("SELECT 1;")},
+			"m/0001_b.down.sql": {Data: []byte("SELECT 1;")},
+		},
+		"version zéro": {
+			"m/0000_a.up.sql":   {Data: []byte("SELECT 1;")},
+			"m/0000_a.down.sql": {Data: []byte("SELECT 1;")},
+		},
+	}
+	for nom, fsys := range cas {
+		if _, err := Charger(fsys, "m"); err == nil {
+			t.Errorf("%s : erreur attendue", nom)
+		}
+	}
+}
+
+// test : les migrations embarquées se chargent et se suivent sans trou
+func TestEmbarquees(t *testing.T) {
+	migrations, err := Embarquees()
+	if err != nil {
+		t.Fatalf("erreur inattendue : %v", err)
+	}
+	if len(migrations) == 0 {
+		t.Fatal("aucune migration embarquée")
+	}
+	for i, migration := range migrations {
+		if migration.Version != int64(i+1) {
+			t.Errorf("version %d attendue, obtenue %d (%s)", i+1, migration.Version, migration.Nom)
+		}
+	}
+	if !strings.Contains(migrations[0].Haut, "CREATE SCHEMA IF NOT EXISTS monitoring") {
+		t.Error("la première migration devrait créer le schéma monitoring")
+	}
+}
+
+// test : les migrations SQLite montent, se listent, descendent puis remontent sur un fichier vide
+func TestMigrateurSQLite(t *testing.T) {
+	ctx := context.Background()
+	db, err := sql.Open("sqlite", filepath.Join(t.TempDir(), "monitoring.db")+"?_pragma=foreign_keys(1)")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer db.Close()
+
+	migrations, err := EmbarqueesSQLite()
+	if err != nil {
+		t.Fatalf("erreur inattendue : %v", err)
+	}
+	migrateur := NouveauMigrateurSQLite(db, migrations)
+
+	appliquees, err := migrateur.Monter(ctx)
+	if err != nil {
+		t.Fatalf("montée : %v", err)
+	}
+	if len(appliquees) != len(migrations) {
+		t.Errorf("%d migration(s) appliquée(s), attendu %d", len(appliquees), len(migrations))
+	}
+	etats, err := migrateur.Statut(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, etat := range etats {
+		if etat.AppliqueeA == nil {
+			t.Errorf("%04d_%s devrait être appliquée", etat.Version, etat.Nom)
+		}
+	}
+
+	if _, err := migrateur.Descendre(ctx, len(migrations)); err != nil {
+		t.Fatalf("retour : %v", err)
+	}
+	var tables int
+	if err := db.QueryRowContext(ctx, `SELECT COUNT(*) FROM sqlite_master WHERE type = 'table' AND name = 'moniteurs'`).Scan(&tables); err != nil {
+		t.Fatal(err)
+	}
+	if tables != 0 {
+		t.Error("la table moniteurs devrait être supprimée par le retour")
+	}
+
+	if appliquees, err := migrateur.Monter(ctx); err != nil || len(appliquees) != len(migrations) {
+		t.Fatalf("remontée : %d migration(s), %v", len(appliquees), err)
+	}
+}
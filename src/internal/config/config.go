@@ -0,0 +1,430 @@
+/* Chargement de la configuration de l'application
+ * Projet de session A25
+ * By : Leandre Kanmegne
+ *
+ * Lit les variables d'environnement listées dans .env.example
+ * Peut aussi lire un fichier optionnel au format .env (CLE=valeur)
+ * Priorité : variable d'environnement > fichier > valeur par défaut
+ * Valide toutes les valeurs et retourne toutes les erreurs d'un coup (errors.Join)
+ *
+ * Source: https://pkg.go.dev/errors#Join
+ */
+package config
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"net/mail"
+	"net/url"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Config regroupe toute la configuration de l'application
+type Config struct {
+	Serveur       ConfigServeur
+	Surveillance  ConfigSurveillance
+	BaseDeDonnees ConfigBaseDeDonnees
+	Notifications ConfigNotifications
+	Historique    ConfigHistorique
+}
+
+// ConfigServeur contient les paramètres du serveur HTTP
+type ConfigServeur struct {
+	Port         int
+	TimeoutArret time.Duration
+}
+
+// ConfigSurveillance contient les paramètres des vérifications
+type ConfigSurveillance struct {
+	IntervalleVerification time.Duration
+	WorkersMaxParalleles   int
+	SeuilLatenceLente      time.Duration
+	TimeoutRequete         time.Duration
+	JoursAlerteCertificat  int
+}
+
+// Stockages disponibles (STOCKAGE)
+const (
+	StockagePostgres = "postgres"
+	StockageSQLite   = "sqlite"  // aussi choisi par une DATABASE_URL sqlite://
+	StockageMemoire  = "memoire" // démo et tests : rien n'est conservé à l'arrêt
+)
+
+// Préfixe de DATABASE_URL pour une base SQLite embarquée (sqlite://chemin/vers/fichier.db)
+const PrefixeSQLite = "sqlite://"
+
+// ConfigBaseDeDonnees contient le stockage choisi, sa connexion et le pool PostgreSQL
+type ConfigBaseDeDonnees struct {
+	Stockage              string
+	URL                   string
+	MaxConnexionsOuvertes int
+	MaxConnexionsIdle     int
+	DureeVieConnexion     time.Duration
+	TimeoutConnexion      time.Duration
+	MigrerAuDemarrage     bool // applique les migrations embarquées avant de démarrer
+}
+
+// ConfigNotifications contient les canaux d'alerte et la politique d'envoi
+type ConfigNotifications struct {
+	WebhookURLs          []string
+	SecretWebhook        string // signe les envois (HMAC-SHA256), vide = pas de signature
+	ModeleWebhook        string // chemin d'un modèle text/template, vide = modèle par défaut
+	SMTP                 ConfigSMTP
+	IntervalleScrutation time.Duration
+	TentativesMax        int
+	DelaiReessai         time.Duration // premier délai, doublé à chaque échec
+	TimeoutEnvoi         time.Duration
+	AgeMaxAlerte         time.Duration // une alerte plus ancienne est abandonnée (livraison en échec notée)
+}
+
+// ConfigSMTP contient le serveur et les adresses des alertes par email
+type ConfigSMTP struct {
+	Hote          string // vide = pas d'email
+	Port          int
+	StartTLS      bool
+	Utilisateur   string // vide = pas d'authentification
+	MotDePasse    string
+	Expediteur    string
+	Destinataires []string
+	ModeleTexte   string // chemin d'un modèle text/template, vide = modèle par défaut
+	ModeleHTML    string // chemin d'un modèle html/template, vide = modèle par défaut
+}
+
+// ConfigHistorique contient la consolidation et la rétention de l'historique
+type ConfigHistorique struct {
+	IntervalleConsolidation     time.Duration
+	RetentionStatuts            time.Duration // 0 = conserver indéfiniment (défaut de toutes les rétentions : la purge est à activer)
+	RetentionAlertes            time.Duration
+	RetentionAgregatsHoraires   time.Duration
+	RetentionAgregatsQuotidiens time.Duration
+	IntervallePurge             time.Duration
+	TailleLotPurge              int
+}
+
+// Adresse retourne l'adresse d'écoute du serveur HTTP
+func (c ConfigServeur) Adresse() string {
+	return ":" + strconv.Itoa(c.Port)
+}
+
+// CheminSQLite retourne le chemin du fichier si DATABASE_URL désigne une base SQLite
+func (c ConfigBaseDeDonnees) CheminSQLite() (string, bool) {
+	return strings.CutPrefix(c.URL, PrefixeSQLite)
+}
+
+// Charger lit la configuration depuis l'environnement et le fichier optionnel
+func Charger(cheminFichier string) (Config, error) {
+	valeursFichier := map[string]string{}
+	if cheminFichier != "" {
+		var err error
+		valeursFichier, err = lireFichier(cheminFichier)
+		if err != nil {
+			return Config{}, fmt.Errorf("lecture du fichier de configuration : %w", err)
+		}
+	}
+
+	l := &lecteur{fichier: valeursFichier}
+
+	cfg := Config{
+		Serveur: ConfigServeur{
+			Port:         l.entier("PORT", 8080),
+			TimeoutArret: l.secondes("TIMEOUT_ARRET_SERVEUR_SECONDES", 5),
+		},
+		Surveillance: ConfigSurveillance{
+			IntervalleVerification: l.secondes("INTERVALLE_VERIFICATION_SECONDES", 60),
+			WorkersMaxParalleles:   l.entier("WORKERS_MAX_PARALLELES", 5),
+			SeuilLatenceLente:      l.millisecondes("SEUIL_LATENCE_LENTE_MS", 800),
+			TimeoutRequete:         l.secondes("TIMEOUT_REQUETE_SECONDES", 10),
+			JoursAlerteCertificat:  l.entier("JOURS_ALERTE_CERTIFICAT", 14),
+		},
+		BaseDeDonnees: ConfigBaseDeDonnees{
+			Stockage:              l.texte("STOCKAGE", StockagePostgres),
+			URL:                   l.texte("DATABASE_URL", ""),
+			MaxConnexionsOuvertes: l.entier("DB_MAX_CONNEXIONS_OUVERTES", 15),
+			MaxConnexionsIdle:     l.entier("DB_MAX_CONNEXIONS_IDLE", 15),
+			DureeVieConnexion:     l.minutes("DB_DUREE_VIE_CONNEXION_MINUTES", 30),
+			TimeoutConnexion:      l.secondes("TIMEOUT_CONNEXION_DB_SECONDES", 5),
+			MigrerAuDemarrage:     l.booleen("MIGRATIONS_AU_DEMARRAGE", true),
+		},
+		Notifications: ConfigNotifications{
+			WebhookURLs:          l.liste("WEBHOOK_URLS"),
+			SecretWebhook:        l.texte("WEBHOOK_SECRET", ""),
+			ModeleWebhook:        l.texte("WEBHOOK_MODELE", ""),
+			SMTP: ConfigSMTP{
+				Hote:          l.texte("SMTP_HOTE", ""),
+				Port:          l.entier("SMTP_PORT", 587),
+				StartTLS:      l.booleen("SMTP_STARTTLS", true),
+				Utilisateur:   l.texte("SMTP_UTILISATEUR", ""),
+				MotDePasse:    l.texte("SMTP_MOT_DE_PASSE", ""),
+				Expediteur:    l.texte("SMTP_EXPEDITEUR", ""),
+				Destinataires: l.liste("SMTP_DESTINATAIRES"),
+				ModeleTexte:   l.texte("SMTP_MODELE_TEXTE", ""),
+				ModeleHTML:    l.texte("SMTP_MODELE_HTML", ""),
+			},
+			IntervalleScrutation: l.secondes("INTERVALLE_NOTIFICATIONS_SECONDES", 10),
+			TentativesMax:        l.entier("NOTIFICATIONS_TENTATIVES_MAX", 5),
+			DelaiReessai:         l.secondes("NOTIFICATIONS_DELAI_REESSAI_SECONDES", 2),
+			TimeoutEnvoi:         l.secondes("TIMEOUT_NOTIFICATION_SECONDES", 10),
+			AgeMaxAlerte:         l.minutes("NOTIFICATIONS_AGE_MAX_ALERTE_MINUTES", 60),
+		},
+		Historique: ConfigHistorique{
+			IntervalleConsolidation:     l.secondes("INTERVALLE_CONSOLIDATION_SECONDES", 300),
+			RetentionStatuts:            l.jours("RETENTION_STATUTS_JOURS", 0),
+			RetentionAlertes:            l.jours("RETENTION_ALERTES_JOURS", 0),
+			RetentionAgregatsHoraires:   l.jours("RETENTION_AGREGATS_HORAIRES_JOURS", 0),
+			RetentionAgregatsQuotidiens: l.jours("RETENTION_AGREGATS_QUOTIDIENS_JOURS", 0),
+			IntervallePurge:             l.minutes("INTERVALLE_PURGE_MINUTES", 60),
+			TailleLotPurge:              l.entier("PURGE_TAILLE_LOT", 5000),
+		},
+	}
+
+	// construit DATABASE_URL à partir des variables DB_* si absente
+	if cfg.BaseDeDonnees.URL == "" && cfg.BaseDeDonnees.Stockage == StockagePostgres {
+		cfg.BaseDeDonnees.URL = l.urlDepuisParties()
+	}
+	// une DATABASE_URL sqlite:// suffit à choisir SQLite, même avec STOCKAGE=postgres (valeur par défaut)
+	if _, ok := cfg.BaseDeDonnees.CheminSQLite(); ok && cfg.BaseDeDonnees.Stockage == StockagePostgres {
+		cfg.BaseDeDonnees.Stockage = StockageSQLite
+	}
+
+	l.valider(cfg)
+
+	return cfg, errors.Join(l.erreurs...)
+}
+
+// Vérifie la cohérence des valeurs lues
+func (l *lecteur) valider(cfg Config) {
+	switch cfg.BaseDeDonnees.Stockage {
+	case StockagePostgres:
+		if cfg.BaseDeDonnees.URL == "" {
+			l.ajouterErreur("DATABASE_URL", "obligatoire (ou DB_USER, DB_PASSWORD et DB_NAME)")
+		}
+	case StockageSQLite:
+		if chemin, ok := cfg.BaseDeDonnees.CheminSQLite(); !ok || chemin == "" {
+			l.ajouterErreur("DATABASE_URL", "attendu "+PrefixeSQLite+"chemin/vers/fichier.db avec STOCKAGE=sqlite")
+		}
+	case StockageMemoire:
+	default:
+		l.ajouterErreur("STOCKAGE", fmt.Sprintf("attendu %q, %q ou %q, reçu %q",
+			StockagePostgres, StockageSQLite, StockageMemoire, cfg.BaseDeDonnees.Stockage))
+	}
+	if cfg.Serveur.Port < 1 || cfg.Serveur.Port > 65535 {
+		l.ajouterErreur("PORT", "doit être entre 1 et 65535")
+	}
+	l.positif("TIMEOUT_ARRET_SERVEUR_SECONDES", int64(cfg.Serveur.TimeoutArret))
+	l.positif("INTERVALLE_VERIFICATION_SECONDES", int64(cfg.Surveillance.IntervalleVerification))
+	l.positif("WORKERS_MAX_PARALLELES", int64(cfg.Surveillance.WorkersMaxParalleles))
+	l.positif("SEUIL_LATENCE_LENTE_MS", int64(cfg.Surveillance.SeuilLatenceLente))
+	l.positif("TIMEOUT_REQUETE_SECONDES", int64(cfg.Surveillance.TimeoutRequete))
+	l.positif("JOURS_ALERTE_CERTIFICAT", int64(cfg.Surveillance.JoursAlerteCertificat))
+	l.positif("DB_MAX_CONNEXIONS_OUVERTES", int64(cfg.BaseDeDonnees.MaxConnexionsOuvertes))
+	l.positif("DB_DUREE_VIE_CONNEXION_MINUTES", int64(cfg.BaseDeDonnees.DureeVieConnexion))
+	l.positif("TIMEOUT_CONNEXION_DB_SECONDES", int64(cfg.BaseDeDonnees.TimeoutConnexion))
+
+	l.positif("INTERVALLE_NOTIFICATIONS_SECONDES", int64(cfg.Notifications.IntervalleScrutation))
+	l.positif("NOTIFICATIONS_TENTATIVES_MAX", int64(cfg.Notifications.TentativesMax))
+	l.positif("NOTIFICATIONS_DELAI_REESSAI_SECONDES", int64(cfg.Notifications.DelaiReessai))
+	l.positif("TIMEOUT_NOTIFICATION_SECONDES", int64(cfg.Notifications.TimeoutEnvoi))
+	l.positif("NOTIFICATIONS_AGE_MAX_ALERTE_MINUTES", int64(cfg.Notifications.AgeMaxAlerte))
+	l.positif("INTERVALLE_CONSOLIDATION_SECONDES", int64(cfg.Historique.IntervalleConsolidation))
+	l.positifOuNul("RETENTION_STATUTS_JOURS", int64(cfg.Historique.RetentionStatuts))
+	l.positifOuNul("RETENTION_ALERTES_JOURS", int64(cfg.Historique.RetentionAlertes))
+	l.positifOuNul("RETENTION_AGREGATS_HORAIRES_JOURS", int64(cfg.Historique.RetentionAgregatsHoraires))
+	l.positifOuNul("RETENTION_AGREGATS_QUOTIDIENS_JOURS", int64(cfg.Historique.RetentionAgregatsQuotidiens))
+	l.positif("INTERVALLE_PURGE_MINUTES", int64(cfg.Historique.IntervallePurge))
+	l.positif("PURGE_TAILLE_LOT", int64(cfg.Historique.TailleLotPurge))
+	for _, lien := range cfg.Notifications.WebhookURLs {
+		if u, err := url.Parse(lien); err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+			l.ajouterErreur("WEBHOOK_URLS", fmt.Sprintf("URL http(s) invalide : %q", lien))
+		}
+	}
+	if smtp := cfg.Notifications.SMTP; smtp.Hote != "" {
+		if smtp.Port < 1 || smtp.Port > 65535 {
+			l.ajouterErreur("SMTP_PORT", "doit être entre 1 et 65535")
+		}
+		if _, err := mail.ParseAddress(smtp.Expediteur); err != nil {
+			l.ajouterErreur("SMTP_EXPEDITEUR", fmt.Sprintf("adresse invalide : %q", smtp.Expediteur))
+		}
+		if len(smtp.Destinataires) == 0 {
+			l.ajouterErreur("SMTP_DESTINATAIRES", "obligatoire quand SMTP_HOTE est défini")
+		}
+		for _, adresse := range smtp.Destinataires {
+			if _, err := mail.ParseAddress(adresse); err != nil {
+				l.ajouterErreur("SMTP_DESTINATAIRES", fmt.Sprintf("adresse invalide : %q", adresse))
+			}
+		}
+	}
+
+	if cfg.BaseDeDonnees.MaxConnexionsIdle < 0 {
+		l.ajouterErreur("DB_MAX_CONNEXIONS_IDLE", "ne peut pas être négatif")
+	} else if cfg.BaseDeDonnees.MaxConnexionsIdle > cfg.BaseDeDonnees.MaxConnexionsOuvertes {
+		l.ajouterErreur("DB_MAX_CONNEXIONS_IDLE", "ne peut pas dépasser DB_MAX_CONNEXIONS_OUVERTES")
+	}
+}
+
+// lecteur accumule les erreurs pendant la lecture des variables
+// Une valeur mal formée est signalée puis remplacée par la valeur par défaut
+type lecteur struct {
+	fichier map[string]string
+	erreurs []error
+}
+
+// Retourne la valeur brute d'une variable (environnement puis fichier)
+func (l *lecteur) brut(cle string) (string, bool) {
+	if valeur, ok := os.LookupEnv(cle); ok && strings.TrimSpace(valeur) != "" {
+		return strings.TrimSpace(valeur), true
+	}
+	if valeur, ok := l.fichier[cle]; ok && valeur != "" {
+		return valeur, true
+	}
+	return "", false
+}
+
+// Ajoute une erreur pour une variable
+func (l *lecteur) ajouterErreur(cle, message string) {
+	l.erreurs = append(l.erreurs, fmt.Errorf("%s : %s", cle, message))
+}
+
+// Lit une chaîne
+func (l *lecteur) texte(cle, defaut string) string {
+	if valeur, ok := l.brut(cle); ok {
+		return valeur
+	}
+	return defaut
+}
+
+// Lit une liste de valeurs séparées par des virgules
+func (l *lecteur) liste(cle string) []string {
+	valeur, ok := l.brut(cle)
+	if !ok {
+		return nil
+	}
+	var elements []string
+	for _, element := range strings.Split(valeur, ",") {
+		if element = strings.TrimSpace(element); element != "" {
+			elements = append(elements, element)
+		}
+	}
+	return elements
+}
+
+// Lit un booléen (true/false, 1/0...)
+func (l *lecteur) booleen(cle string, defaut bool) bool {
+	valeur, ok := l.brut(cle)
+	if !ok {
+		return defaut
+	}
+	b, err := strconv.ParseBool(valeur)
+	if err != nil {
+		l.ajouterErreur(cle, fmt.Sprintf("booléen attendu, reçu %q", valeur))
+		return defaut
+	}
+	return b
+}
+
+// Lit un entier
+func (l *lecteur) entier(cle string, defaut int) int {
+	valeur, ok := l.brut(cle)
+	if !ok {
+		return defaut
+	}
+	n, err := strconv.Atoi(valeur)
+	if err != nil {
+		l.ajouterErreur(cle, fmt.Sprintf("entier attendu, reçu %q", valeur))
+		return defaut
+	}
+	return n
+}
+
+// Lit une durée exprimée dans une unité donnée
+func (l *lecteur) duree(cle string, defaut int, unite time.Duration) time.Duration {
+	return time.Duration(l.entier(cle, defaut)) * unite
+}
+
+func (l *lecteur) secondes(cle string, defaut int) time.Duration {
+	return l.duree(cle, defaut, time.Second)
+}
+
+func (l *lecteur) millisecondes(cle string, defaut int) time.Duration {
+	return l.duree(cle, defaut, time.Millisecond)
+}
+
+func (l *lecteur) minutes(cle string, defaut int) time.Duration {
+	return l.duree(cle, defaut, time.Minute)
+}
+
+func (l *lecteur) jours(cle string, defaut int) time.Duration {
+	return l.duree(cle, defaut, 24*time.Hour)
+}
+
+// Vérifie qu'une valeur est strictement positive
+func (l *lecteur) positif(cle string, valeur int64) {
+	if valeur <= 0 {
+		l.ajouterErreur(cle, "doit être supérieur à 0")
+	}
+}
+
+// Vérifie qu'une valeur n'est pas négative (0 = désactivé)
+func (l *lecteur) positifOuNul(cle string, valeur int64) {
+	if valeur < 0 {
+		l.ajouterErreur(cle, "ne peut pas être négatif (0 = désactivé)")
+	}
+}
+
+// Construit l'URL PostgreSQL depuis DB_USER, DB_PASSWORD, DB_NAME, DB_HOST et DB_PORT
+func (l *lecteur) urlDepuisParties() string {
+	utilisateur := l.texte("DB_USER", "")
+	nom := l.texte("DB_NAME", "")
+	if utilisateur == "" || nom == "" {
+		return ""
+	}
+
+	lien := url.URL{
+		Scheme: "postgres",
+		User:   url.UserPassword(utilisateur, l.texte("DB_PASSWORD", "")),
+		Host:   l.texte("DB_HOST", "postgres") + ":" + l.texte("DB_PORT", "5432"),
+		Path:   "/" + nom,
+	}
+	return lien.String()
+}
+
+// Lit un fichier au format .env (CLE=valeur, commentaires avec #)
+func lireFichier(chemin string) (map[string]string, error) {
+	fichier, err := os.Open(chemin)
+	if err != nil {
+		return nil, err
+	}
+	defer fichier.Close()
+
+	valeurs := make(map[string]string)
+	scanner := bufio.NewScanner(fichier)
+	numero := 0
+	for scanner.Scan() {
+		numero++
+		ligne := strings.TrimSpace(scanner.Text())
+		if ligne == "" || strings.HasPrefix(ligne, "#") {
+			continue
+		}
+
+		cle, valeur, ok := strings.Cut(ligne, "=")
+		if !ok {
+			return nil, fmt.Errorf("ligne %d : format CLE=valeur attendu", numero)
+		}
+
+		valeur = strings.TrimSpace(valeur)
+		if len(valeur) >= 2 && (valeur[0] == '"' || valeur[0] == '\'') && valeur[len(valeur)-1] == valeur[0] {
+			// valeur entre guillemets : gardée telle quelle
+			valeur = valeur[1 : len(valeur)-1]
+		} else if i := strings.Index(valeur, "#"); i >= 0 && (i == 0 || valeur[i-1] == ' ') {
+			// commentaire en fin de ligne
+			valeur = strings.TrimSpace(valeur[:i])
+		}
+
+		valeurs[strings.TrimSpace(cle)] = valeur
+	}
+
+	return valeurs, scanner.Err()
+}
@@ -0,0 +1,56 @@
+/* Rapports de disponibilité (uptime / SLA)
+ * Projet de session A25
+ * By : Leandre Kanmegne
+ *
+ * Chaque statut couvre le temps jusqu'au statut suivant (borné pour ne pas compter les pauses)
+ * Les statuts en maintenance ne comptent ni comme disponibles ni comme indisponibles
+ * MTTR = temps indisponible / incidents, MTBF = temps disponible / incidents
+ *
+ * Source: https://www.atlassian.com/incident-management/kpis/common-metrics
+ */
+package models
+
+import "time"
+
+// Périodes cumulées retournées avec chaque rapport (clé -> durée)
+var PeriodesDisponibilite = []struct {
+	Cle   string
+	Duree time.Duration
+}{
+	{"24h", 24 * time.Hour},
+	{"7d", 7 * 24 * time.Hour},
+	{"30d", 30 * 24 * time.Hour},
+	{"90d", 90 * 24 * time.Hour},
+}
+
+// RapportDisponibilite résume la disponibilité d'un moniteur entre deux dates
+type RapportDisponibilite struct {
+	Depuis                time.Time `json:"depuis"`
+	Jusqua                time.Time `json:"jusqua"`
+	Verifications         int       `json:"verifications"`
+	SecondesObservees     int64     `json:"secondes_observees"` // hors maintenance et hors trous
+	SecondesIndisponibles int64     `json:"secondes_indisponibles"`
+	SecondesMaintenance   int64     `json:"secondes_maintenance"`
+	Incidents             int       `json:"incidents"`
+	DisponibilitePct      *float64  `json:"disponibilite_pct"` // nil si aucune donnée sur la période
+	MTTRSecondes          *int64    `json:"mttr_secondes"`     // nil sans incident
+	MTBFSecondes          *int64    `json:"mtbf_secondes"`     // nil sans incident
+}
+
+// Calculer remplit le pourcentage, le MTTR et le MTBF à partir des durées et du nombre d'incidents
+func (r *RapportDisponibilite) Calculer() {
+	r.DisponibilitePct, r.MTTRSecondes, r.MTBFSecondes = nil, nil, nil
+	if r.SecondesObservees <= 0 {
+		return
+	}
+
+	disponibles := r.SecondesObservees - r.SecondesIndisponibles
+	pourcentage := float64(disponibles) * 100 / float64(r.SecondesObservees)
+	r.DisponibilitePct = &pourcentage
+
+	if r.Incidents > 0 {
+		mttr := r.SecondesIndisponibles / int64(r.Incidents)
+		mtbf := disponibles / int64(r.Incidents)
+		r.MTTRSecondes, r.MTBFSecondes = &mttr, &mtbf
+	}
+}
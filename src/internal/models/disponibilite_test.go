@@ -0,0 +1,38 @@
+/* Tests pour les rapports de disponibilité
+ * Projet de session A25
+ * By : Leandre Kanmegne
+ */
+package models
+
+import "testing"
+
+// test : pourcentage, MTTR et MTBF
+func TestRapportDisponibilite_Calculer(t *testing.T) {
+	// 1 jour observé, 2 pannes pour un total de 864 s
+	rapport := RapportDisponibilite{SecondesObservees: 86400, SecondesIndisponibles: 864, Incidents: 2}
+	rapport.Calculer()
+
+	if rapport.DisponibilitePct == nil || *rapport.DisponibilitePct != 99 {
+		t.Fatalf("disponibilité attendue 99 %%, obtenue %v", rapport.DisponibilitePct)
+	}
+	if rapport.MTTRSecondes == nil || *rapport.MTTRSecondes != 432 {
+		t.Errorf("MTTR attendu 432 s, obtenu %v", rapport.MTTRSecondes)
+	}
+	if rapport.MTBFSecondes == nil || *rapport.MTBFSecondes != 42768 {
+		t.Errorf("MTBF attendu 42768 s, obtenu %v", rapport.MTBFSecondes)
+	}
+
+	// sans incident : pas de MTTR ni de MTBF
+	rapport = RapportDisponibilite{SecondesObservees: 3600}
+	rapport.Calculer()
+	if *rapport.DisponibilitePct != 100 || rapport.MTTRSecondes != nil || rapport.MTBFSecondes != nil {
+		t.Errorf("attendu 100 %% sans MTTR/MTBF, obtenu %+v", rapport)
+	}
+
+	// sans donnée (ex: toute la période en maintenance) : pas de pourcentage
+	rapport = RapportDisponibilite{SecondesMaintenance: 3600}
+	rapport.Calculer()
+	if rapport.DisponibilitePct != nil {
+		t.Errorf("aucune disponibilité attendue sans donnée, obtenu %v", *rapport.DisponibilitePct)
+	}
+}
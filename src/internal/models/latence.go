@@ -0,0 +1,33 @@
+/* Agrégats de latence par intervalle de temps
+ * Projet de session A25
+ * By : Leandre Kanmegne
+ *
+ * Les statuts d'un moniteur sont regroupés par intervalle (1m, 5m, 1h, 1d)
+ * et résumés par les percentiles, le min, le max, la moyenne et le taux de succès
+ */
+package models
+
+import "time"
+
+// Intervalles d'agrégation acceptés (clé de l'API -> durée)
+var IntervallesAgregat = map[string]time.Duration{
+	"1m": time.Minute,
+	"5m": 5 * time.Minute,
+	"1h": time.Hour,
+	"1d": 24 * time.Hour,
+}
+
+// AgregatLatence résume les statuts d'un moniteur commencés dans [Debut, Debut + intervalle[
+// Les champs de latence sont nil si aucun statut de l'intervalle n'a de latence
+type AgregatLatence struct {
+	Debut         time.Time `json:"debut"`
+	Verifications int       `json:"verifications"`
+	TauxSucces    float64   `json:"taux_succes"` // entre 0 et 1
+	MinMs         *float64  `json:"min_ms"`
+	MaxMs         *float64  `json:"max_ms"`
+	MoyenneMs     *float64  `json:"moyenne_ms"`
+	P50Ms         *float64  `json:"p50_ms"`
+	P90Ms         *float64  `json:"p90_ms"`
+	P95Ms         *float64  `json:"p95_ms"`
+	P99Ms         *float64  `json:"p99_ms"`
+}
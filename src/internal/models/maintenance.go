@@ -0,0 +1,176 @@
+/* Fenêtres de maintenance
+ * Projet de session A25
+ * By : Leandre Kanmegne
+ *
+ * Une maintenance est ponctuelle (debut -> fin) ou récurrente (expression cron + durée)
+ * Pendant une maintenance, les statuts sont marqués en_maintenance et aucune alerte n'est créée
+ * Les expressions cron ont 5 champs : minute heure jour-du-mois mois jour-de-la-semaine
+ * Chaque champ accepte l'étoile, une valeur, une plage (1-5), une liste (1,15) et un pas (8-18/2)
+ *
+ * Source: https://man7.org/linux/man-pages/man5/crontab.5.html
+ */
+package models
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Durée max d'une occurrence de maintenance récurrente
+const DureeMaxMaintenanceMinutes = 7 * 24 * 60
+
+// Maintenance suspend les alertes d'un ou plusieurs moniteurs (table monitoring.maintenances)
+type Maintenance struct {
+	ID           int        `json:"id"`
+	Nom          string     `json:"nom"`
+	Debut        *time.Time `json:"debut,omitempty"`         // ponctuelle : début, récurrente : début de validité (optionnel)
+	Fin          *time.Time `json:"fin,omitempty"`           // ponctuelle : fin, récurrente : fin de validité (optionnel)
+	Cron         string     `json:"cron,omitempty"`          // vide = ponctuelle
+	DureeMinutes int        `json:"duree_minutes,omitempty"` // durée de chaque occurrence récurrente
+	Fuseau       string     `json:"fuseau"`                  // fuseau horaire de l'expression cron (UTC par défaut)
+	MoniteurIDs  []int      `json:"moniteurs"`
+	CreeA        time.Time  `json:"cree_a"`
+}
+
+// Location retourne le fuseau de la maintenance (UTC si vide ou inconnu)
+func (m Maintenance) Location() *time.Location {
+	if m.Fuseau == "" {
+		return time.UTC
+	}
+	location, err := time.LoadLocation(m.Fuseau)
+	if err != nil {
+		return time.UTC
+	}
+	return location
+}
+
+// ActiveA indique si la maintenance couvre l'instant donné
+func (m Maintenance) ActiveA(instant time.Time) bool {
+	if m.Debut != nil && instant.Before(*m.Debut) {
+		return false
+	}
+	if m.Fin != nil && !instant.Before(*m.Fin) {
+		return false
+	}
+	if m.Cron == "" {
+		return m.Debut != nil && m.Fin != nil
+	}
+
+	expression, err := ParserCron(m.Cron)
+	if err != nil {
+		return false
+	}
+	// cherche une occurrence commencée dans les DureeMinutes dernières minutes
+	minute := instant.In(m.Location()).Truncate(time.Minute)
+	for i := 0; i < m.DureeMinutes; i++ {
+		if expression.Correspond(minute.Add(-time.Duration(i) * time.Minute)) {
+			return true
+		}
+	}
+	return false
+}
+
+// ExpressionCron est une expression cron à 5 champs déjà analysée
+type ExpressionCron struct {
+	minutes, heures, jours, mois, joursSemaine map[int]bool
+	joursLibres, joursSemaineLibres            bool // champ commençant par * (pour la règle jour OU jour de la semaine)
+}
+
+// Bornes de chaque champ, dans l'ordre de l'expression
+var champsCron = []struct {
+	nom      string
+	min, max int
+}{
+	{"minute", 0, 59},
+	{"heure", 0, 23},
+	{"jour", 1, 31},
+	{"mois", 1, 12},
+	{"jour de la semaine", 0, 7}, // 0 et 7 = dimanche
+}
+
+// ParserCron analyse une expression cron à 5 champs
+func ParserCron(expression string) (*ExpressionCron, error) {
+	champs := strings.Fields(expression)
+	if len(champs) != len(champsCron) {
+		return nil, fmt.Errorf("expression cron %q : 5 champs attendus", expression)
+	}
+
+	valeurs := make([]map[int]bool, len(champs))
+	for i, champ := range champs {
+		ensemble, err := parserChampCron(champ, champsCron[i].min, champsCron[i].max)
+		if err != nil {
+			return nil, fmt.Errorf("expression cron %q, %s : %w", expression, champsCron[i].nom, err)
+		}
+		valeurs[i] = ensemble
+	}
+	if valeurs[4][7] {
+		valeurs[4][0] = true
+	}
+
+	return &ExpressionCron{
+		minutes:            valeurs[0],
+		heures:             valeurs[1],
+		jours:              valeurs[2],
+		mois:               valeurs[3],
+		joursSemaine:       valeurs[4],
+		joursLibres:        strings.HasPrefix(champs[2], "*"),
+		joursSemaineLibres: strings.HasPrefix(champs[4], "*"),
+	}, nil
+}
+
+// Analyse un champ (liste de plages avec pas optionnel) et retourne les valeurs acceptées
+func parserChampCron(champ string, min, max int) (map[int]bool, error) {
+	ensemble := map[int]bool{}
+	for _, partie := range strings.Split(champ, ",") {
+		plage, pasTexte, avecPas := strings.Cut(partie, "/")
+		pas := 1
+		if avecPas {
+			n, err := strconv.Atoi(pasTexte)
+			if err != nil || n <= 0 {
+				return nil, fmt.Errorf("pas %q invalide", pasTexte)
+			}
+			pas = n
+		}
+
+		debut, fin := min, max
+		if plage != "*" {
+			debutTexte, finTexte, estPlage := strings.Cut(plage, "-")
+			var err error
+			if debut, err = strconv.Atoi(debutTexte); err != nil {
+				return nil, fmt.Errorf("valeur %q invalide", debutTexte)
+			}
+			fin = debut
+			if estPlage {
+				if fin, err = strconv.Atoi(finTexte); err != nil {
+					return nil, fmt.Errorf("valeur %q invalide", finTexte)
+				}
+			} else if avecPas {
+				fin = max // "5/15" = de 5 à max par pas de 15
+			}
+		}
+		if debut < min || fin > max || debut > fin {
+			return nil, fmt.Errorf("%q hors des bornes %d-%d", partie, min, max)
+		}
+
+		for valeur := debut; valeur <= fin; valeur += pas {
+			ensemble[valeur] = true
+		}
+	}
+	return ensemble, nil
+}
+
+// Correspond indique si la minute donnée fait partie de l'expression
+// Comme cron, si le jour et le jour de la semaine sont restreints, l'un ou l'autre suffit
+func (e *ExpressionCron) Correspond(instant time.Time) bool {
+	if !e.minutes[instant.Minute()] || !e.heures[instant.Hour()] || !e.mois[int(instant.Month())] {
+		return false
+	}
+	jour := e.jours[instant.Day()]
+	jourSemaine := e.joursSemaine[int(instant.Weekday())]
+	if e.joursLibres || e.joursSemaineLibres {
+		return jour && jourSemaine
+	}
+	return jour || jourSemaine
+}
@@ -0,0 +1,75 @@
+/* Tests pour les fenêtres de maintenance et les expressions cron
+ * Projet de session A25
+ * By : Leandre Kanmegne
+ */
+package models
+
+import (
+	"testing"
+	"time"
+)
+
+// raccourci pour une date UTC
+func date(jour, heure, minute int) time.Time {
+	return time.Date(2025, time.October, jour, heure, minute, 0, 0, time.UTC)
+}
+
+// test : analyse des expressions cron valides et invalides
+func TestParserCron(t *testing.T) {
+	// le 6 octobre 2025 est un lundi
+	cas := []struct {
+		expression string
+		instant    time.Time
+		attendu    bool
+	}{
+		{"* * * * *", date(6, 3, 17), true},
+		{"*/15 * * * *", date(6, 3, 30), true},
+		{"*/15 * * * *", date(6, 3, 31), false},
+		{"0 2 * * 1-5", date(6, 2, 0), true},
+		{"0 2 * * 1-5", date(5, 2, 0), false}, // dimanche
+		{"0 2 * * 7", date(5, 2, 0), true},
+		{"30 8-18/2 * * *", date(6, 10, 30), true},
+		{"30 8-18/2 * * *", date(6, 11, 30), false},
+		{"0 0 1,15 * *", date(15, 0, 0), true},
+		{"0 0 1 * 1", date(6, 0, 0), true}, // jour OU jour de la semaine
+	}
+	for _, c := range cas {
+		expression, err := ParserCron(c.expression)
+		if err != nil {
+			t.Fatalf("%q : erreur inattendue %v", c.expression, err)
+		}
+		if obtenu := expression.Correspond(c.instant); obtenu != c.attendu {
+			t.Errorf("%q à %s : attendu %v, obtenu %v", c.expression, c.instant, c.attendu, obtenu)
+		}
+	}
+
+	for _, invalide := range []string{"", "* * * *", "60 * * * *", "* * 0 * *", "*/0 * * * *", "5-1 * * * *", "a * * * *"} {
+		if _, err := ParserCron(invalide); err == nil {
+			t.Errorf("%q devrait être refusée", invalide)
+		}
+	}
+}
+
+// test : maintenance ponctuelle et récurrente (avec fuseau horaire)
+func TestMaintenance_ActiveA(t *testing.T) {
+	debut, fin := date(6, 22, 0), date(6, 23, 0)
+	ponctuelle := Maintenance{Debut: &debut, Fin: &fin}
+	if !ponctuelle.ActiveA(date(6, 22, 30)) || ponctuelle.ActiveA(date(6, 23, 0)) || ponctuelle.ActiveA(date(6, 21, 59)) {
+		t.Error("la maintenance ponctuelle devrait couvrir [debut, fin[")
+	}
+
+	// tous les jours à 2h (heure de Toronto, UTC-4 en octobre) pendant 30 minutes
+	recurrente := Maintenance{Cron: "0 2 * * *", DureeMinutes: 30, Fuseau: "America/Toronto"}
+	if !recurrente.ActiveA(date(6, 6, 29)) {
+		t.Error("6h29 UTC = 2h29 à Toronto, la maintenance devrait être active")
+	}
+	if recurrente.ActiveA(date(6, 6, 30)) || recurrente.ActiveA(date(6, 2, 10)) {
+		t.Error("la maintenance ne devrait pas être active en dehors de l'occurrence")
+	}
+
+	// la période de validité borne les occurrences
+	recurrente.Fin = &debut
+	if recurrente.ActiveA(date(7, 6, 10)) {
+		t.Error("la maintenance récurrente ne devrait plus être active après sa fin de validité")
+	}
+}
@@ -0,0 +1,36 @@
+/* Politique de rétention de l'historique
+ * Projet de session A25
+ * By : Leandre Kanmegne
+ *
+ * Chaque cible (statuts bruts, alertes, agrégats) a sa propre durée de conservation
+ * Une durée nulle conserve la cible indéfiniment
+ */
+package models
+
+import "time"
+
+// CiblePurge identifie un historique purgeable
+type CiblePurge string
+
+// Cibles de la purge
+const (
+	CibleStatuts            CiblePurge = "statuts"
+	CibleAlertes            CiblePurge = "alertes"
+	CibleAgregatsHoraires   CiblePurge = "statuts_horaires"
+	CibleAgregatsQuotidiens CiblePurge = "statuts_quotidiens"
+)
+
+// CiblesPurge liste toutes les cibles dans l'ordre de purge
+var CiblesPurge = []CiblePurge{CibleStatuts, CibleAlertes, CibleAgregatsHoraires, CibleAgregatsQuotidiens}
+
+// PolitiqueRetention associe une durée de conservation à chaque cible (0 = conserver)
+type PolitiqueRetention map[CiblePurge]time.Duration
+
+// RapportPurge résume une purge : lignes supprimées par cible
+type RapportPurge struct {
+	DebutA  time.Time            `json:"debut_a"`
+	DureeMs int64                `json:"duree_ms"`
+	Lignes  map[CiblePurge]int64 `json:"lignes"`
+	Total   int64                `json:"total"`
+	Erreur  string               `json:"erreur,omitempty"`
+}
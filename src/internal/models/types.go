@@ -7,25 +7,381 @@
  */
 package models
 
-import "time"
+import (
+	"math"
+	"strings"
+	"time"
+)
 
 // Moniteur représente un service à surveiller
 type Moniteur struct {
-	ID   int    `json:"id"`
-	Nom  string `json:"nom"`
-	URL  string `json:"url"`
-	Type string `json:"type"` // http, https, tcp
+	ID                 int                `json:"id"`
+	Nom                string             `json:"nom"`
+	URL                string             `json:"url"`
+	Type               string             `json:"type"` // http, https, tcp, dns
+	Actif              bool               `json:"actif"`
+	IntervalleSecondes int                `json:"intervalle_secondes"` // 0 = intervalle par défaut
+	Parametres         ParametresMoniteur `json:"parametres"`
+	Requete            *RequeteHTTP       `json:"requete,omitempty"`       // nil = GET sans en-têtes particuliers
+	EtatConfirme       string             `json:"etat_confirme,omitempty"` // dernier état confirmé par le trigger (lecture seule)
 }
 
+// ParametresMoniteur regroupe les options propres à chaque type de moniteur
+type ParametresMoniteur struct {
+	SeuilLatenceMs int                     `json:"seuil_latence_ms,omitempty"` // au-delà, le moniteur est dégradé (0 = seuil global)
+	Confirmation   *ParametresConfirmation `json:"confirmation,omitempty"`
+
+	TCP     *ParametresTCP     `json:"tcp,omitempty"`
+	DNS     *ParametresDNS     `json:"dns,omitempty"`
+	TLS     *ParametresTLS     `json:"tls,omitempty"`
+	Contenu *ParametresContenu `json:"contenu,omitempty"`
+	HTTP    *ParametresHTTP    `json:"http,omitempty"`
+}
+
+// ParametresConfirmation évite les fausses alertes sur un réseau instable
+type ParametresConfirmation struct {
+	Reessais        int `json:"reessais,omitempty"`          // nouvelles tentatives immédiates après un échec
+	DelaiReessaiMs  int `json:"delai_reessai_ms,omitempty"`  // attente entre deux tentatives
+	EchecsAvantDown int `json:"echecs_avant_down,omitempty"` // vérifications en échec consécutives avant l'alerte DOWN (1 par défaut)
+	SuccesAvantUp   int `json:"succes_avant_up,omitempty"`   // vérifications réussies consécutives avant l'alerte UP (1 par défaut)
+}
+
+// ParametresTCP configure un moniteur de type tcp
+type ParametresTCP struct {
+	Envoi            string `json:"envoi,omitempty"`             // données envoyées après la connexion
+	BanniereAttendue string `json:"banniere_attendue,omitempty"` // texte attendu dans la réponse
+}
+
+// ParametresDNS configure un moniteur de type dns (l'URL est le nom à résoudre)
+type ParametresDNS struct {
+	Resolveur          string   `json:"resolveur,omitempty"`           // hôte:port, vide = résolveur du système
+	TypeEnregistrement string   `json:"type_enregistrement,omitempty"` // A (défaut), AAAA, CNAME, MX, TXT
+	ValeursAttendues   []string `json:"valeurs_attendues,omitempty"`   // doivent toutes être présentes
+}
+
+// ParametresTLS configure la surveillance du certificat d'un moniteur https
+type ParametresTLS struct {
+	JoursAlerteExpiration int `json:"jours_alerte_expiration,omitempty"` // 0 = seuil par défaut
+}
+
+// ParametresHTTP configure les codes acceptés et les redirections d'un moniteur http
+type ParametresHTTP struct {
+	CodesAcceptes      string `json:"codes_acceptes,omitempty"`      // ex: "200,204,401" ou "200-299", vide = 200-399
+	SuivreRedirections *bool  `json:"suivre_redirections,omitempty"` // nil = oui
+	MaxRedirections    int    `json:"max_redirections,omitempty"`    // 0 = 10
+}
+
+// ParametresContenu définit les assertions sur le corps d'une réponse http
+type ParametresContenu struct {
+	Contient      []string        `json:"contient,omitempty"`        // textes qui doivent apparaître
+	NeContientPas []string        `json:"ne_contient_pas,omitempty"` // textes interdits (ex: "Maintenance")
+	Regex         string          `json:"regex,omitempty"`           // expression que le corps doit satisfaire
+	JSON          []AssertionJSON `json:"json,omitempty"`            // valeurs attendues dans un corps JSON
+	TailleMax     int             `json:"taille_max_octets,omitempty"`
+	Souple        bool            `json:"souple,omitempty"` // un échec dégrade le moniteur au lieu de le rendre indisponible
+}
+
+// AssertionJSON compare la valeur trouvée au chemin (ex: "data.items.0.etat") à la valeur attendue
+type AssertionJSON struct {
+	Chemin string `json:"chemin"`
+	Valeur any    `json:"valeur"`
+}
+
+// RequeteHTTP décrit la requête envoyée par un moniteur http
+type RequeteHTTP struct {
+	Methode string            `json:"methode,omitempty"` // GET par défaut
+	EnTetes map[string]string `json:"en_tetes,omitempty"`
+	Corps   string            `json:"corps,omitempty"`
+	Auth    *AuthHTTP         `json:"auth,omitempty"`
+}
+
+// AuthHTTP configure l'authentification d'une requête (bearer ou basic)
+type AuthHTTP struct {
+	Type        string `json:"type"`
+	Jeton       string `json:"jeton,omitempty"`
+	Utilisateur string `json:"utilisateur,omitempty"`
+	MotDePasse  string `json:"mot_de_passe,omitempty"`
+}
+
+// Valeur affichée à la place d'un secret dans l'API
+const SecretMasque = "********"
+
+// Indique si un en-tête transporte probablement un secret
+func EnTeteSensible(nom string) bool {
+	nom = strings.ToLower(nom)
+	switch nom {
+	case "authorization", "proxy-authorization", "cookie":
+		return true
+	}
+	for _, motCle := range []string{"token", "secret", "key", "password"} {
+		if strings.Contains(nom, motCle) {
+			return true
+		}
+	}
+	return false
+}
+
+// Masquee retourne une copie où les secrets sont remplacés par SecretMasque
+// Le corps est toujours masqué : il transporte souvent des identifiants (ex: demande de jeton OAuth)
+func (r *RequeteHTTP) Masquee() *RequeteHTTP {
+	if r == nil {
+		return nil
+	}
+	copie := *r
+	if r.Corps != "" {
+		copie.Corps = SecretMasque
+	}
+	if r.EnTetes != nil {
+		copie.EnTetes = make(map[string]string, len(r.EnTetes))
+		for nom, valeur := range r.EnTetes {
+			if EnTeteSensible(nom) && valeur != "" {
+				valeur = SecretMasque
+			}
+			copie.EnTetes[nom] = valeur
+		}
+	}
+	if r.Auth != nil {
+		auth := *r.Auth
+		if auth.Jeton != "" {
+			auth.Jeton = SecretMasque
+		}
+		if auth.MotDePasse != "" {
+			auth.MotDePasse = SecretMasque
+		}
+		copie.Auth = &auth
+	}
+	return &copie
+}
+
+// RestaurerSecrets remet les secrets de l'ancienne requête là où le client a renvoyé SecretMasque
+func (r *RequeteHTTP) RestaurerSecrets(ancienne *RequeteHTTP) {
+	if r == nil || ancienne == nil {
+		return
+	}
+	if r.Corps == SecretMasque {
+		r.Corps = ancienne.Corps
+	}
+	for nom, valeur := range r.EnTetes {
+		if valeur == SecretMasque {
+			r.EnTetes[nom] = ancienne.EnTetes[nom]
+		}
+	}
+	if r.Auth != nil && ancienne.Auth != nil {
+		if r.Auth.Jeton == SecretMasque {
+			r.Auth.Jeton = ancienne.Auth.Jeton
+		}
+		if r.Auth.MotDePasse == SecretMasque {
+			r.Auth.MotDePasse = ancienne.Auth.MotDePasse
+		}
+	}
+}
+
+// CertificatTLS contient les métadonnées du certificat présenté par le serveur
+type CertificatTLS struct {
+	Sujet     string    `json:"sujet"`
+	Emetteur  string    `json:"emetteur"`
+	SANs      []string  `json:"sans"`
+	ExpireA   time.Time `json:"expire_a"`
+	Empreinte string    `json:"empreinte,omitempty"` // SHA-256 du certificat DER, en hexadécimal
+}
+
+// JoursAvantExpiration retourne le nombre de jours entiers restants (négatif si expiré)
+func (c CertificatTLS) JoursAvantExpiration(maintenant time.Time) int {
+	return int(math.Floor(c.ExpireA.Sub(maintenant).Hours() / 24))
+}
+
+// États possibles d'un moniteur après une vérification
+const (
+	EtatUp      = "up"
+	EtatDegrade = "degrade" // disponible mais lent ou avec un avertissement
+	EtatDown    = "down"
+)
+
 // StatutMoniteur représente le résultat d'une vérification
 type StatutMoniteur struct {
-	MoniteurID     int           `json:"moniteur_id"`
-	EstDisponible  bool          `json:"est_disponible"`
-	MessageErreur  string        `json:"message_erreur"`
-	CodeStatutHTTP int           `json:"code_statut_http"`
-	VerifieA       time.Time     `json:"verifie_a"`
-	URL            string        `json:"url"`
-	Latence        time.Duration `json:"latence"`
+	MoniteurID     int            `json:"moniteur_id"`
+	EstDisponible  bool           `json:"est_disponible"`
+	MessageErreur  string         `json:"message_erreur"`
+	CodeStatutHTTP int            `json:"code_statut_http"`
+	VerifieA       time.Time      `json:"verifie_a"`
+	URL            string         `json:"url"`
+	Latence        time.Duration  `json:"latence"`
+	Etat           string         `json:"etat"`                     // up, degrade ou down
+	Certificat     *CertificatTLS `json:"certificat,omitempty"`     // seulement pour https
+	URLFinale      string         `json:"url_finale,omitempty"`     // URL de la dernière réponse après redirections
+	Redirections   []string       `json:"redirections,omitempty"`   // URLs suivies dans l'ordre
+	DetailLatence  *DetailLatence `json:"detail_latence,omitempty"` // seulement pour http et https
+	EnMaintenance  bool           `json:"en_maintenance"`           // vérifié pendant une maintenance (pas d'alerte)
+}
+
+// DetailLatence découpe la latence d'une requête http par phase
+type DetailLatence struct {
+	DNS          time.Duration `json:"dns"`
+	Connexion    time.Duration `json:"connexion"`
+	TLS          time.Duration `json:"tls"`
+	PremierOctet time.Duration `json:"premier_octet"` // attente du serveur après l'envoi de la requête
+	Transfert    time.Duration `json:"transfert"`     // lecture du corps
+}
+
+// Degrader passe un statut disponible en état dégradé et ajoute la raison au message
+func (s *StatutMoniteur) Degrader(raison string) {
+	if !s.EstDisponible {
+		return
+	}
+	s.Etat = EtatDegrade
+	if s.MessageErreur == "" {
+		s.MessageErreur = raison
+	} else {
+		s.MessageErreur += " ; " + raison
+	}
+}
+
+// EtatEffectif retourne l'état du statut, déduit de EstDisponible s'il n'a pas été calculé
+func (s StatutMoniteur) EtatEffectif() string {
+	switch {
+	case !s.EstDisponible:
+		return EtatDown
+	case s.Etat == EtatDegrade:
+		return EtatDegrade
+	default:
+		return EtatUp
+	}
+}
+
+// Types d'alertes insérés par le trigger de transition
+const (
+	AlerteDown    = "DOWN"
+	AlerteUp      = "UP"
+	AlerteDegrade = "DEGRADE"
+	// retour de dégradé à up, envoyé aux routes qui suivent DEGRADE
+	AlerteDegradeFin = "DEGRADE_FIN"
+)
+
+// Alerte représente un changement d'état confirmé (table monitoring.alertes)
+type Alerte struct {
+	ID          int           `json:"id"`
+	MoniteurID  int           `json:"moniteur_id"`
+	NomMoniteur string        `json:"nom_moniteur"`
+	URL         string        `json:"url"`
+	Type        string        `json:"type"` // DOWN, UP, DEGRADE ou DEGRADE_FIN
+	Details     string        `json:"details"`
+	CodeHTTP    int           `json:"code_http"`
+	DureePanne  time.Duration `json:"duree_panne"` // pour UP : temps écoulé depuis le DOWN précédent
+	CreeA       time.Time     `json:"cree_a"`
+}
+
+// Livraison est une tentative d'envoi d'une alerte sur un canal (table monitoring.livraisons)
+type Livraison struct {
+	AlerteID  int           `json:"alerte_id"`
+	Canal     string        `json:"canal"`
+	Tentative int           `json:"tentative"`
+	Succes    bool          `json:"succes"`
+	CodeHTTP  int           `json:"code_http"`
+	Erreur    string        `json:"erreur"`
+	Duree     time.Duration `json:"duree"`
+	CreeA     time.Time     `json:"cree_a"`
+}
+
+// Incident est une panne d'un moniteur, du DOWN au UP suivant (table monitoring.incidents)
+type Incident struct {
+	ID               int        `json:"id"`
+	MoniteurID       int        `json:"moniteur_id"`
+	NomMoniteur      string     `json:"nom_moniteur"`
+	URL              string     `json:"url"`
+	OuvertA          time.Time  `json:"ouvert_a"`
+	FermeA           *time.Time `json:"ferme_a,omitempty"` // nil = panne en cours
+	MessageErreur    string     `json:"message_erreur"`
+	CodeHTTP         int        `json:"code_http"`
+	AcquitteA        *time.Time `json:"acquitte_a,omitempty"`
+	AcquittePar      string     `json:"acquitte_par,omitempty"`
+	NoteAcquittement string     `json:"note_acquittement,omitempty"`
+}
+
+// Duree retourne la durée de l'incident (jusqu'à maintenant s'il est encore ouvert)
+func (i Incident) Duree(maintenant time.Time) time.Duration {
+	if i.FermeA != nil {
+		return i.FermeA.Sub(i.OuvertA)
+	}
+	return maintenant.Sub(i.OuvertA)
+}
+
+// FiltreIncidents restreint la liste des incidents (valeurs zéro = pas de filtre)
+type FiltreIncidents struct {
+	MoniteurID int
+	Depuis     time.Time // incidents encore ouverts ou fermés après cette date
+	Jusqua     time.Time // incidents ouverts avant cette date
+	Ouverts    *bool     // true = en cours seulement, false = fermés seulement
+	Limite     int
+}
+
+// Types de canaux de notification gérés par l'API
+const (
+	CanalWebhook = "webhook"
+	CanalEmail   = "email"
+	CanalSlack   = "slack"
+	CanalDiscord = "discord"
+)
+
+// CanalNotification est une destination d'alertes (table monitoring.canaux)
+type CanalNotification struct {
+	ID     int         `json:"id"`
+	Nom    string      `json:"nom"`
+	Type   string      `json:"type"` // webhook, email, slack ou discord
+	Actif  bool        `json:"actif"`
+	Config ConfigCanal `json:"config"`
+	CreeA  time.Time   `json:"cree_a"`
+}
+
+// ConfigCanal regroupe les réglages de tous les types, seuls ceux du type du canal sont utilisés
+type ConfigCanal struct {
+	URL           string   `json:"url,omitempty"`    // webhook, slack, discord
+	Secret        string   `json:"secret,omitempty"` // webhook : signature HMAC
+	Modele        string   `json:"modele,omitempty"` // webhook : source text/template du corps JSON
+	Hote          string   `json:"hote,omitempty"`   // email
+	Port          int      `json:"port,omitempty"`
+	StartTLS      *bool    `json:"starttls,omitempty"` // nil = activé
+	Utilisateur   string   `json:"utilisateur,omitempty"`
+	MotDePasse    string   `json:"mot_de_passe,omitempty"`
+	Expediteur    string   `json:"expediteur,omitempty"`
+	Destinataires []string `json:"destinataires,omitempty"`
+}
+
+// Masquee retourne une copie où les secrets sont remplacés par SecretMasque
+// Pour slack et discord, l'URL du webhook est elle-même le secret
+func (c ConfigCanal) Masquee(typeCanal string) ConfigCanal {
+	if c.URL != "" && (typeCanal == CanalSlack || typeCanal == CanalDiscord) {
+		c.URL = SecretMasque
+	}
+	if c.Secret != "" {
+		c.Secret = SecretMasque
+	}
+	if c.MotDePasse != "" {
+		c.MotDePasse = SecretMasque
+	}
+	return c
+}
+
+// RestaurerSecrets remet les secrets de l'ancienne config là où le client a renvoyé SecretMasque
+func (c *ConfigCanal) RestaurerSecrets(ancienne ConfigCanal) {
+	if c.URL == SecretMasque {
+		c.URL = ancienne.URL
+	}
+	if c.Secret == SecretMasque {
+		c.Secret = ancienne.Secret
+	}
+	if c.MotDePasse == SecretMasque {
+		c.MotDePasse = ancienne.MotDePasse
+	}
+}
+
+// Types d'alertes envoyés quand une route n'en précise pas
+var TypesAlerteParDefaut = []string{AlerteDown, AlerteUp}
+
+// RouteNotification envoie les alertes d'un moniteur vers un canal (table monitoring.routes_notification)
+type RouteNotification struct {
+	MoniteurID int      `json:"moniteur_id"`
+	CanalID    int      `json:"canal_id"`
+	Types      []string `json:"types"` // ex: ["DOWN"], ["DOWN", "UP"], ["DEGRADE"]
 }
 
 // NouveauStatutMoniteur crée un nouveau statut
@@ -0,0 +1,28 @@
+/* Tests pour le masquage des secrets des canaux
+ * Projet de session A25
+ * By : Leandre Kanmegne
+ */
+package models
+
+import "testing"
+
+// test : l'URL d'un webhook slack ou discord est masquée puis restaurée, celle d'un webhook générique reste visible
+func TestConfigCanal_MasquerEtRestaurer(t *testing.T) {
+	originale := ConfigCanal{URL: "https://hooks.slack.com/services/T000/B000/jeton"}
+
+	for _, typeCanal := range []string{CanalSlack, CanalDiscord} {
+		masquee := originale.Masquee(typeCanal)
+		if masquee.URL != SecretMasque {
+			t.Errorf("URL %s attendue masquée, reçu %q", typeCanal, masquee.URL)
+		}
+		masquee.RestaurerSecrets(originale)
+		if masquee.URL != originale.URL {
+			t.Errorf("URL %s attendue restaurée, reçu %q", typeCanal, masquee.URL)
+		}
+	}
+
+	webhook := ConfigCanal{URL: "https://exemple.com/hook", Secret: "cle"}.Masquee(CanalWebhook)
+	if webhook.URL != "https://exemple.com/hook" || webhook.Secret != SecretMasque {
+		t.Errorf("webhook : URL visible et secret masqué attendus, reçu %+v", webhook)
+	}
+}
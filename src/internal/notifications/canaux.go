@@ -0,0 +1,82 @@
+/* Canaux de notification configurés par l'API
+ * Projet de session A25
+ * By : Leandre Kanmegne
+ *
+ * Construit un Canal (webhook, email, slack, discord) depuis un models.CanalNotification
+ * Slack et Discord sont des webhooks avec leur propre modèle de corps JSON
+ */
+package notifications
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"example.com/go-hello/src/internal/models"
+)
+
+// Modèles des webhooks de messagerie (une ligne de texte)
+const (
+	ModeleSlack   = `{"text": {{resume . | json}}}`
+	ModeleDiscord = `{"content": {{resume . | json}}}`
+)
+
+// DepotRoutes donne les canaux routés pour un moniteur et un type d'alerte
+type DepotRoutes interface {
+	CanauxPourAlerte(ctx context.Context, moniteurID int, typeAlerte string) ([]models.CanalNotification, error)
+}
+
+// canalNomme remplace le nom technique du canal par celui choisi dans l'API
+type canalNomme struct {
+	Canal
+	nom string
+}
+
+func (c canalNomme) Nom() string {
+	return c.nom
+}
+
+// NouveauCanal construit le canal correspondant à la configuration
+func NouveauCanal(config models.CanalNotification, timeout time.Duration) (Canal, error) {
+	var canal Canal
+	switch config.Type {
+	case models.CanalWebhook:
+		modele, err := ParserModele(ModeleWebhookParDefaut)
+		if config.Config.Modele != "" {
+			modele, err = ParserModele(config.Config.Modele)
+		}
+		if err != nil {
+			return nil, fmt.Errorf("modèle du canal %q : %w", config.Nom, err)
+		}
+		canal = NouveauWebhook(config.Config.URL, config.Config.Secret, modele, timeout)
+
+	case models.CanalSlack, models.CanalDiscord:
+		source := ModeleSlack
+		if config.Type == models.CanalDiscord {
+			source = ModeleDiscord
+		}
+		modele, err := ParserModele(source)
+		if err != nil {
+			return nil, err
+		}
+		canal = NouveauWebhook(config.Config.URL, "", modele, timeout)
+
+	case models.CanalEmail:
+		port := config.Config.Port
+		if port == 0 {
+			port = PortSMTPParDefaut
+		}
+		email := NouvelEmail(config.Config.Hote, port, config.Config.Expediteur, config.Config.Destinataires)
+		if config.Config.StartTLS != nil {
+			email.StartTLS = *config.Config.StartTLS
+		}
+		email.Utilisateur = config.Config.Utilisateur
+		email.MotDePasse = config.Config.MotDePasse
+		email.Timeout = timeout
+		canal = email
+
+	default:
+		return nil, fmt.Errorf("type de canal %q non supporté", config.Type)
+	}
+	return canalNomme{Canal: canal, nom: config.Type + ":" + config.Nom}, nil
+}
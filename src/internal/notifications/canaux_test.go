@@ -0,0 +1,124 @@
+/* Tests pour les canaux configurés par l'API et le routage
+ * Projet de session A25
+ * By : Leandre Kanmegne
+ */
+package notifications
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"slices"
+	"sync"
+	"testing"
+	"time"
+
+	"example.com/go-hello/src/internal/models"
+)
+
+// fausseRoute relie un canal aux types d'alertes qu'il reçoit
+type fausseRoute struct {
+	canal models.CanalNotification
+	types []string
+}
+
+// faussesRoutes donne les routes de chaque moniteur
+type faussesRoutes map[int][]fausseRoute
+
+func (f faussesRoutes) CanauxPourAlerte(ctx context.Context, moniteurID int, typeAlerte string) ([]models.CanalNotification, error) {
+	var canaux []models.CanalNotification
+	for _, route := range f[moniteurID] {
+		if slices.Contains(route.types, typeAlerte) {
+			canaux = append(canaux, route.canal)
+		}
+	}
+	return canaux, nil
+}
+
+// test : corps Slack et Discord, type inconnu refusé
+func TestNouveauCanal_Messagerie(t *testing.T) {
+	var mu sync.Mutex
+	corps := map[string]map[string]string{}
+	serveur := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		donnees, _ := io.ReadAll(r.Body)
+		var recu map[string]string
+		json.Unmarshal(donnees, &recu)
+		mu.Lock()
+		corps[r.URL.Path] = recu
+		mu.Unlock()
+	}))
+	defer serveur.Close()
+
+	for _, typeCanal := range []string{models.CanalSlack, models.CanalDiscord} {
+		canal, err := NouveauCanal(models.CanalNotification{
+			Nom:    "equipe",
+			Type:   typeCanal,
+			Config: models.ConfigCanal{URL: serveur.URL + "/" + typeCanal},
+		}, time.Second)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if canal.Nom() != typeCanal+":equipe" {
+			t.Errorf("nom inattendu : %q", canal.Nom())
+		}
+		if err := canal.Envoyer(context.Background(), alerteTest()); err != nil {
+			t.Fatalf("envoi %s en échec : %v", typeCanal, err)
+		}
+	}
+
+	attendu := `[UP] API "paiements" (https://api.exemple.test/sante) - Rétabli - HTTP 200 - panne de 1m30s`
+	if corps["/slack"]["text"] != attendu {
+		t.Errorf("message Slack inattendu : %q", corps["/slack"]["text"])
+	}
+	if corps["/discord"]["content"] != attendu {
+		t.Errorf("message Discord inattendu : %q", corps["/discord"]["content"])
+	}
+
+	if _, err := NouveauCanal(models.CanalNotification{Type: "pigeon"}, time.Second); err == nil {
+		t.Error("un type de canal inconnu devrait être refusé")
+	}
+}
+
+// test : les canaux routés s'ajoutent aux canaux fixes selon le moniteur et le type d'alerte
+func TestRepartiteur_Routage(t *testing.T) {
+	var mu sync.Mutex
+	recus := map[string]int{}
+	serveur := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		recus[r.URL.Path]++
+		mu.Unlock()
+	}))
+	defer serveur.Close()
+
+	depot := &fauxDepotAlertes{
+		traitees: map[int]bool{},
+		alertes: []models.Alerte{
+			{ID: 1, MoniteurID: 7, Type: models.AlerteDown, CreeA: time.Now()},
+			{ID: 2, MoniteurID: 7, Type: models.AlerteUp, CreeA: time.Now()},
+			{ID: 3, MoniteurID: 8, Type: models.AlerteDown, CreeA: time.Now()},
+		},
+	}
+	fixe := &fauxCanal{}
+	repartiteur := NouveauRepartiteur(depot, fixe)
+	repartiteur.Routes = faussesRoutes{
+		7: {
+			{models.CanalNotification{Nom: "tous", Type: models.CanalWebhook, Config: models.ConfigCanal{URL: serveur.URL + "/tous"}}, models.TypesAlerteParDefaut},
+			{models.CanalNotification{Nom: "pannes", Type: models.CanalWebhook, Config: models.ConfigCanal{URL: serveur.URL + "/pannes"}}, []string{models.AlerteDown}},
+		},
+	}
+
+	repartiteur.Traiter(context.Background())
+	repartiteur.Attendre()
+
+	if fixe.envois != 3 {
+		t.Errorf("le canal fixe devrait recevoir les 3 alertes, reçu %d", fixe.envois)
+	}
+	if recus["/tous"] != 2 || recus["/pannes"] != 1 {
+		t.Errorf("routage inattendu : %v", recus)
+	}
+	if len(depot.traitees) != 3 {
+		t.Errorf("les 3 alertes devraient être traitées : %v", depot.traitees)
+	}
+}
@@ -0,0 +1,284 @@
+/* Canal de notification par email (SMTP)
+ * Projet de session A25
+ * By : Leandre Kanmegne
+ *
+ * Envoie l'alerte à une liste de destinataires via un serveur SMTP
+ * STARTTLS (activé par défaut) puis authentification PLAIN si un utilisateur est configuré
+ * Le message contient une partie texte et une partie HTML (multipart/alternative)
+ * Les modèles reçoivent models.Alerte (nom, URL, code HTTP, durée de la panne...)
+ *
+ * Source: https://pkg.go.dev/net/smtp
+ * Source: https://pkg.go.dev/html/template
+ */
+package notifications
+
+import (
+	"bytes"
+	"context"
+	"crypto/tls"
+	"errors"
+	"fmt"
+	htmltemplate "html/template"
+	"mime"
+	"mime/multipart"
+	"mime/quotedprintable"
+	"net"
+	"net/smtp"
+	"net/textproto"
+	"os"
+	"strconv"
+	"strings"
+	"text/template"
+	"time"
+
+	"example.com/go-hello/src/internal/models"
+)
+
+// Port de soumission SMTP utilisé par défaut
+const PortSMTPParDefaut = 587
+
+// Modèle du sujet (texte brut, encodé ensuite pour l'en-tête)
+const ModeleSujetEmail = `[{{.Type}}] {{.NomMoniteur}}`
+
+var modeleSujet = template.Must(template.New("sujet").Parse(ModeleSujetEmail))
+
+// Modèle texte utilisé si aucun n'est configuré
+const ModeleTexteEmailParDefaut = `Alerte {{.Type}} pour {{.NomMoniteur}}
+
+URL : {{.URL}}
+Code HTTP : {{if .CodeHTTP}}{{.CodeHTTP}}{{else}}-{{end}}
+{{- if .DureePanne}}
+Durée de la panne : {{duree .DureePanne}}
+{{- end}}
+Détails : {{.Details}}
+Date : {{.CreeA.Format "2006-01-02 15:04:05 MST"}}
+`
+
+// Modèle HTML utilisé si aucun n'est configuré
+const ModeleHTMLEmailParDefaut = `<!DOCTYPE html>
+<html>
+<body style="font-family: sans-serif">
+  <h2>Alerte {{.Type}} pour {{.NomMoniteur}}</h2>
+  <table>
+    <tr><td>URL</td><td><a href="{{.URL}}">{{.URL}}</a></td></tr>
+    <tr><td>Code HTTP</td><td>{{if .CodeHTTP}}{{.CodeHTTP}}{{else}}-{{end}}</td></tr>
+    {{- if .DureePanne}}
+    <tr><td>Durée de la panne</td><td>{{duree .DureePanne}}</td></tr>
+    {{- end}}
+    <tr><td>Détails</td><td>{{.Details}}</td></tr>
+    <tr><td>Date</td><td>{{.CreeA.Format "2006-01-02 15:04:05 MST"}}</td></tr>
+  </table>
+</body>
+</html>
+`
+
+// Fonctions disponibles dans les modèles d'email
+var fonctionsEmail = map[string]any{
+	"duree": func(d time.Duration) string {
+		return d.Round(time.Second).String()
+	},
+}
+
+// ChargerModelesEmail lit les modèles texte et HTML (chemin vide = modèle par défaut)
+func ChargerModelesEmail(cheminTexte, cheminHTML string) (*template.Template, *htmltemplate.Template, error) {
+	sourceTexte, err := lireModele(cheminTexte, ModeleTexteEmailParDefaut)
+	if err != nil {
+		return nil, nil, err
+	}
+	sourceHTML, err := lireModele(cheminHTML, ModeleHTMLEmailParDefaut)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	texte, err := template.New("email-texte").Funcs(fonctionsEmail).Parse(sourceTexte)
+	if err != nil {
+		return nil, nil, err
+	}
+	html, err := htmltemplate.New("email-html").Funcs(fonctionsEmail).Parse(sourceHTML)
+	if err != nil {
+		return nil, nil, err
+	}
+	return texte, html, nil
+}
+
+// Retourne le contenu du fichier, ou la source par défaut si le chemin est vide
+func lireModele(chemin, defaut string) (string, error) {
+	if chemin == "" {
+		return defaut, nil
+	}
+	donnees, err := os.ReadFile(chemin)
+	return string(donnees), err
+}
+
+// ErreurSMTP est retournée quand le serveur refuse une commande
+type ErreurSMTP struct {
+	Code    int
+	Message string
+}
+
+func (e *ErreurSMTP) Error() string {
+	return fmt.Sprintf("réponse SMTP %d : %s", e.Code, e.Message)
+}
+
+// Permanente indique qu'il ne sert à rien de réessayer (codes 5xx)
+func (e *ErreurSMTP) Permanente() bool {
+	return e.Code >= 500
+}
+
+// Email implémente Canal pour une liste de destinataires
+type Email struct {
+	Hote          string
+	Port          int
+	StartTLS      bool
+	Utilisateur   string // vide = pas d'authentification
+	MotDePasse    string
+	Expediteur    string
+	Destinataires []string
+	ModeleTexte   *template.Template
+	ModeleHTML    *htmltemplate.Template
+	Timeout       time.Duration
+	TLS           *tls.Config // nil = vérification standard avec le nom de l'hôte
+}
+
+// NouvelEmail crée un canal email avec STARTTLS et les modèles par défaut
+func NouvelEmail(hote string, port int, expediteur string, destinataires []string) *Email {
+	texte, html, _ := ChargerModelesEmail("", "")
+	return &Email{
+		Hote:          hote,
+		Port:          port,
+		StartTLS:      true,
+		Expediteur:    expediteur,
+		Destinataires: destinataires,
+		ModeleTexte:   texte,
+		ModeleHTML:    html,
+		Timeout:       10 * time.Second,
+	}
+}
+
+// Nom identifie le canal dans monitoring.livraisons
+func (e *Email) Nom() string {
+	return "email:" + strings.Join(e.Destinataires, ",")
+}
+
+// Envoyer transmet l'alerte au serveur SMTP
+func (e *Email) Envoyer(ctx context.Context, alerte models.Alerte) error {
+	message, err := e.Message(alerte)
+	if err != nil {
+		return err
+	}
+	return convertirErreurSMTP(e.transmettre(ctx, message))
+}
+
+// Déroule la session SMTP : STARTTLS, AUTH, MAIL, RCPT, DATA, QUIT
+func (e *Email) transmettre(ctx context.Context, message []byte) error {
+	dialer := net.Dialer{Timeout: e.Timeout}
+	conn, err := dialer.DialContext(ctx, "tcp", net.JoinHostPort(e.Hote, strconv.Itoa(e.Port)))
+	if err != nil {
+		return err
+	}
+	echeance := time.Now().Add(e.Timeout)
+	if limite, ok := ctx.Deadline(); ok && limite.Before(echeance) {
+		echeance = limite
+	}
+	conn.SetDeadline(echeance)
+
+	client, err := smtp.NewClient(conn, e.Hote)
+	if err != nil {
+		conn.Close()
+		return err
+	}
+	defer client.Close()
+
+	if e.StartTLS {
+		if ok, _ := client.Extension("STARTTLS"); !ok {
+			return errors.New("le serveur SMTP ne supporte pas STARTTLS")
+		}
+		configTLS := e.TLS
+		if configTLS == nil {
+			configTLS = &tls.Config{ServerName: e.Hote}
+		}
+		if err := client.StartTLS(configTLS); err != nil {
+			return err
+		}
+	}
+	if e.Utilisateur != "" {
+		if err := client.Auth(smtp.PlainAuth("", e.Utilisateur, e.MotDePasse, e.Hote)); err != nil {
+			return err
+		}
+	}
+
+	if err := client.Mail(e.Expediteur); err != nil {
+		return err
+	}
+	for _, destinataire := range e.Destinataires {
+		if err := client.Rcpt(destinataire); err != nil {
+			return err
+		}
+	}
+	ecrivain, err := client.Data()
+	if err != nil {
+		return err
+	}
+	if _, err := ecrivain.Write(message); err != nil {
+		return err
+	}
+	if err := ecrivain.Close(); err != nil {
+		return err
+	}
+	return client.Quit()
+}
+
+// Message construit le message MIME complet (en-têtes, partie texte, partie HTML)
+func (e *Email) Message(alerte models.Alerte) ([]byte, error) {
+	var sujet, texte, html bytes.Buffer
+	if err := modeleSujet.Execute(&sujet, alerte); err != nil {
+		return nil, err
+	}
+	if err := e.ModeleTexte.Execute(&texte, alerte); err != nil {
+		return nil, fmt.Errorf("modèle email texte : %w", err)
+	}
+	if err := e.ModeleHTML.Execute(&html, alerte); err != nil {
+		return nil, fmt.Errorf("modèle email HTML : %w", err)
+	}
+
+	var corps bytes.Buffer
+	parties := multipart.NewWriter(&corps)
+	for _, partie := range []struct {
+		typeContenu string
+		contenu     []byte
+	}{
+		{"text/plain; charset=utf-8", texte.Bytes()},
+		{"text/html; charset=utf-8", html.Bytes()},
+	} {
+		ecrivain, err := parties.CreatePart(textproto.MIMEHeader{
+			"Content-Type":              {partie.typeContenu},
+			"Content-Transfer-Encoding": {"quoted-printable"},
+		})
+		if err != nil {
+			return nil, err
+		}
+		encodeur := quotedprintable.NewWriter(ecrivain)
+		encodeur.Write(partie.contenu)
+		encodeur.Close()
+	}
+	parties.Close()
+
+	var message bytes.Buffer
+	fmt.Fprintf(&message, "From: %s\r\n", e.Expediteur)
+	fmt.Fprintf(&message, "To: %s\r\n", strings.Join(e.Destinataires, ", "))
+	fmt.Fprintf(&message, "Subject: %s\r\n", mime.QEncoding.Encode("utf-8", sujet.String()))
+	fmt.Fprintf(&message, "Date: %s\r\n", alerte.CreeA.Format(time.RFC1123Z))
+	fmt.Fprintf(&message, "MIME-Version: 1.0\r\n")
+	fmt.Fprintf(&message, "Content-Type: multipart/alternative; boundary=%q\r\n\r\n", parties.Boundary())
+	message.Write(corps.Bytes())
+	return message.Bytes(), nil
+}
+
+// Convertit les refus du serveur en ErreurSMTP pour que le répartiteur sache s'il faut réessayer
+func convertirErreurSMTP(err error) error {
+	var erreurProtocole *textproto.Error
+	if errors.As(err, &erreurProtocole) {
+		return &ErreurSMTP{Code: erreurProtocole.Code, Message: erreurProtocole.Msg}
+	}
+	return err
+}
@@ -0,0 +1,223 @@
+/* Tests pour le canal email
+ * Projet de session A25
+ * By : Leandre Kanmegne
+ *
+ * Un faux serveur SMTP local (EHLO, STARTTLS, AUTH, MAIL, RCPT, DATA) enregistre la session
+ * Le certificat de httptest sert pour STARTTLS
+ */
+package notifications
+
+import (
+	"context"
+	"crypto/tls"
+	"encoding/base64"
+	"errors"
+	"io"
+	"mime"
+	"mime/multipart"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"net/mail"
+	"net/textproto"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+// fauxServeurSMTP accepte des sessions et garde ce qu'il a reçu
+type fauxServeurSMTP struct {
+	ecoute      net.Listener
+	configTLS   *tls.Config // nil = STARTTLS non annoncé
+	rejeterRcpt bool
+
+	mu      sync.Mutex
+	chiffre bool
+	auth    string
+	de      string
+	pour    []string
+	donnees []byte
+}
+
+func demarrerFauxSMTP(t *testing.T, configTLS *tls.Config) *fauxServeurSMTP {
+	t.Helper()
+	ecoute, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	serveur := &fauxServeurSMTP{ecoute: ecoute, configTLS: configTLS}
+	t.Cleanup(func() { ecoute.Close() })
+
+	go func() {
+		for {
+			conn, err := ecoute.Accept()
+			if err != nil {
+				return
+			}
+			go serveur.session(conn)
+		}
+	}()
+	return serveur
+}
+
+// canal email pointant vers le faux serveur
+func (f *fauxServeurSMTP) email() *Email {
+	hote, port, _ := net.SplitHostPort(f.ecoute.Addr().String())
+	numero, _ := strconv.Atoi(port)
+	email := NouvelEmail(hote, numero, "moniteur@exemple.test", []string{"garde@exemple.test", "equipe@exemple.test"})
+	email.StartTLS = false
+	email.Timeout = 2 * time.Second
+	return email
+}
+
+func (f *fauxServeurSMTP) session(conn net.Conn) {
+	defer conn.Close()
+	tp := textproto.NewConn(conn)
+	tp.PrintfLine("220 faux ESMTP")
+
+	for {
+		ligne, err := tp.ReadLine()
+		if err != nil {
+			return
+		}
+		commande, argument, _ := strings.Cut(ligne, " ")
+
+		f.mu.Lock()
+		switch strings.ToUpper(commande) {
+		case "EHLO", "HELO":
+			tp.PrintfLine("250-faux")
+			if f.configTLS != nil && !f.chiffre {
+				tp.PrintfLine("250-STARTTLS")
+			}
+			tp.PrintfLine("250 AUTH PLAIN")
+		case "STARTTLS":
+			tp.PrintfLine("220 prêt")
+			conn = tls.Server(conn, f.configTLS)
+			tp = textproto.NewConn(conn)
+			f.chiffre = true
+		case "AUTH":
+			f.auth = argument
+			tp.PrintfLine("235 ok")
+		case "MAIL":
+			f.de = argument
+			tp.PrintfLine("250 ok")
+		case "RCPT":
+			if f.rejeterRcpt {
+				tp.PrintfLine("550 boîte inconnue")
+			} else {
+				f.pour = append(f.pour, argument)
+				tp.PrintfLine("250 ok")
+			}
+		case "DATA":
+			tp.PrintfLine("354 envoyez")
+			f.mu.Unlock()
+			donnees, _ := tp.ReadDotBytes()
+			f.mu.Lock()
+			f.donnees = donnees
+			tp.PrintfLine("250 reçu")
+		case "QUIT":
+			tp.PrintfLine("221 au revoir")
+			f.mu.Unlock()
+			return
+		default:
+			tp.PrintfLine("250 ok")
+		}
+		f.mu.Unlock()
+	}
+}
+
+// test : session complète avec authentification, en-têtes et parties texte/HTML
+func TestEmail_Envoi(t *testing.T) {
+	serveur := demarrerFauxSMTP(t, nil)
+	email := serveur.email()
+	email.Utilisateur = "moniteur"
+	email.MotDePasse = "secret"
+
+	if err := email.Envoyer(context.Background(), alerteTest()); err != nil {
+		t.Fatalf("envoi inattendu en échec : %v", err)
+	}
+
+	serveur.mu.Lock()
+	defer serveur.mu.Unlock()
+	if auth, _ := base64.StdEncoding.DecodeString(strings.TrimPrefix(serveur.auth, "PLAIN ")); string(auth) != "\x00moniteur\x00secret" {
+		t.Errorf("authentification inattendue : %q", auth)
+	}
+	if serveur.de != "FROM:<moniteur@exemple.test>" || len(serveur.pour) != 2 {
+		t.Errorf("enveloppe inattendue : %q -> %v", serveur.de, serveur.pour)
+	}
+
+	message, err := mail.ReadMessage(strings.NewReader(string(serveur.donnees)))
+	if err != nil {
+		t.Fatal(err)
+	}
+	sujet, _ := new(mime.WordDecoder).DecodeHeader(message.Header.Get("Subject"))
+	if sujet != `[UP] API "paiements"` {
+		t.Errorf("sujet inattendu : %q", sujet)
+	}
+
+	_, params, _ := mime.ParseMediaType(message.Header.Get("Content-Type"))
+	lecteur := multipart.NewReader(message.Body, params["boundary"])
+	var types []string
+	for {
+		partie, err := lecteur.NextPart()
+		if err != nil {
+			break
+		}
+		contenu, _ := io.ReadAll(partie)
+		types = append(types, partie.Header.Get("Content-Type"))
+		for _, attendu := range []string{"https://api.exemple.test/sante", "200", "1m30s"} {
+			if !strings.Contains(string(contenu), attendu) {
+				t.Errorf("%s devrait contenir %q :\n%s", partie.Header.Get("Content-Type"), attendu, contenu)
+			}
+		}
+		if strings.HasPrefix(partie.Header.Get("Content-Type"), "text/html") && !strings.Contains(string(contenu), "API &#34;paiements&#34;") {
+			t.Errorf("le nom devrait être échappé dans la partie HTML :\n%s", contenu)
+		}
+	}
+	if len(types) != 2 {
+		t.Errorf("2 parties attendues, reçu %v", types)
+	}
+}
+
+// test : STARTTLS avec un certificat de confiance, refus si le serveur ne l'annonce pas
+func TestEmail_StartTLS(t *testing.T) {
+	serveurTLS := httptest.NewTLSServer(http.NotFoundHandler())
+	defer serveurTLS.Close()
+	racines := serveurTLS.Client().Transport.(*http.Transport).TLSClientConfig.RootCAs
+
+	serveur := demarrerFauxSMTP(t, &tls.Config{Certificates: serveurTLS.TLS.Certificates})
+	email := serveur.email()
+	email.StartTLS = true
+	email.TLS = &tls.Config{ServerName: "127.0.0.1", RootCAs: racines}
+
+	if err := email.Envoyer(context.Background(), alerteTest()); err != nil {
+		t.Fatalf("envoi STARTTLS inattendu en échec : %v", err)
+	}
+	serveur.mu.Lock()
+	if !serveur.chiffre || len(serveur.donnees) == 0 {
+		t.Error("le message aurait dû être reçu sur la connexion chiffrée")
+	}
+	serveur.mu.Unlock()
+
+	sansTLS := demarrerFauxSMTP(t, nil).email()
+	sansTLS.StartTLS = true
+	if err := sansTLS.Envoyer(context.Background(), alerteTest()); err == nil {
+		t.Error("l'envoi devrait échouer si STARTTLS est exigé mais absent")
+	}
+}
+
+// test : un destinataire refusé donne une erreur permanente
+func TestEmail_Refus(t *testing.T) {
+	serveur := demarrerFauxSMTP(t, nil)
+	serveur.mu.Lock()
+	serveur.rejeterRcpt = true
+	serveur.mu.Unlock()
+
+	err := serveur.email().Envoyer(context.Background(), alerteTest())
+	var erreurSMTP *ErreurSMTP
+	if !errors.As(err, &erreurSMTP) || erreurSMTP.Code != 550 || !erreurSMTP.Permanente() {
+		t.Errorf("erreur SMTP 550 permanente attendue, reçu %v", err)
+	}
+}
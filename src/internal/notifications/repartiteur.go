@@ -0,0 +1,349 @@
+/* Répartiteur des alertes vers les canaux de notification
+ * Projet de session A25
+ * By : Leandre Kanmegne
+ *
+ * Scrute monitoring.alertes à intervalle régulier (alertes pas encore traitées)
+ * Envoie chaque alerte sur les canaux fixes (configuration, alertes UP et DOWN seulement)
+ * et sur les canaux routés pour son moniteur (API)
+ * Chaque alerte est envoyée en arrière-plan, sur tous ses canaux en parallèle :
+ * un canal injoignable ne retarde ni les autres canaux ni les alertes suivantes
+ * Les tentatives sont espacées de façon exponentielle
+ * Enregistre chaque tentative dans monitoring.livraisons puis marque l'alerte traitée
+ * Un canal qui a déjà une livraison réussie n'est pas relancé (ex: redémarrage pendant un envoi)
+ * Les alertes trop anciennes (ex: service arrêté longtemps) sont marquées sans envoi, avec une livraison
+ * en échec (tentative 0) par canal pour que la perte apparaisse dans l'historique des livraisons
+ * S'arrête proprement quand le contexte est annulé, après la fin des envois en cours
+ */
+package notifications
+
+import (
+	"context"
+	"errors"
+	"log"
+	"slices"
+	"sync"
+	"time"
+
+	"example.com/go-hello/src/internal/models"
+)
+
+// Valeurs par défaut du répartiteur
+const (
+	IntervalleParDefaut    = 10 * time.Second
+	TentativesMaxParDefaut = 5
+	DelaiInitialParDefaut  = 2 * time.Second
+	TimeoutEnvoiParDefaut  = 10 * time.Second
+	AgeMaxAlerteParDefaut  = time.Hour
+	delaiMaxReessai        = 5 * time.Minute
+	taillePaquetAlertes    = 100
+)
+
+// Types d'alertes envoyés aux canaux fixes (WEBHOOK_URLS, SMTP) : les transitions UP/DOWN
+var typesCanauxFixes = []string{models.AlerteDown, models.AlerteUp}
+
+// Canal envoie une alerte vers une destination (webhook, email...)
+type Canal interface {
+	Nom() string
+	Envoyer(ctx context.Context, alerte models.Alerte) error
+}
+
+// Implémentée par les erreurs des canaux qui savent si un nouvel essai est inutile
+type erreurPermanente interface {
+	Permanente() bool
+}
+
+// DepotAlertes regroupe les opérations de persistance dont le répartiteur a besoin
+type DepotAlertes interface {
+	AlertesNonTraitees(ctx context.Context, depuis time.Time, limite int) ([]models.Alerte, error)
+	MarquerAlerteTraitee(ctx context.Context, id int) error
+	CanauxLivres(ctx context.Context, alerteID int) ([]string, error)
+	EnregistrerLivraison(ctx context.Context, livraison models.Livraison) error
+}
+
+// Repartiteur lit les nouvelles alertes et les envoie sur les canaux
+type Repartiteur struct {
+	Depot         DepotAlertes
+	Canaux        []Canal     // reçoivent toutes les alertes
+	Routes        DepotRoutes // nil = pas de routage par moniteur
+	TimeoutEnvoi  time.Duration
+	Intervalle    time.Duration // fréquence de scrutation de monitoring.alertes
+	TentativesMax int
+	DelaiInitial  time.Duration // doublé après chaque échec
+	AgeMaxAlerte  time.Duration // au-delà, l'alerte est abandonnée sans envoi
+
+	mu        sync.Mutex
+	enCours   map[int]bool // alertes en cours d'envoi
+	terminees map[int]bool // alertes marquées, tant qu'une lecture peut encore les retourner
+	envois    sync.WaitGroup
+}
+
+// NouveauRepartiteur crée un répartiteur avec les valeurs par défaut
+func NouveauRepartiteur(depot DepotAlertes, canaux ...Canal) *Repartiteur {
+	return &Repartiteur{
+		Depot:         depot,
+		Canaux:        canaux,
+		TimeoutEnvoi:  TimeoutEnvoiParDefaut,
+		Intervalle:    IntervalleParDefaut,
+		TentativesMax: TentativesMaxParDefaut,
+		DelaiInitial:  DelaiInitialParDefaut,
+		AgeMaxAlerte:  AgeMaxAlerteParDefaut,
+	}
+}
+
+// Demarrer bloque jusqu'à l'annulation du contexte puis attend les envois en cours
+func (r *Repartiteur) Demarrer(ctx context.Context) {
+	ticker := time.NewTicker(r.Intervalle)
+	defer ticker.Stop()
+	defer r.Attendre()
+
+	for {
+		r.Traiter(ctx)
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// Attendre bloque jusqu'à la fin des envois lancés par Traiter
+func (r *Repartiteur) Attendre() {
+	r.envois.Wait()
+}
+
+// Traiter lance l'envoi des alertes en attente (sans attendre la fin des envois)
+// et abandonne celles qui ont dépassé AgeMaxAlerte
+func (r *Repartiteur) Traiter(ctx context.Context) {
+	alertes, err := r.Depot.AlertesNonTraitees(ctx, time.Time{}, taillePaquetAlertes)
+	if err != nil {
+		if ctx.Err() == nil {
+			log.Printf("[NOTIFICATIONS] Erreur lecture des alertes : %v", err)
+		}
+		return
+	}
+
+	r.oublierTerminees(alertes)
+	limite := time.Now().Add(-r.AgeMaxAlerte)
+	for _, alerte := range alertes {
+		if !r.reserver(alerte.ID) {
+			// déjà en cours d'envoi
+			continue
+		}
+		if alerte.CreeA.Before(limite) {
+			r.liberer(alerte.ID, r.expirer(ctx, alerte))
+			continue
+		}
+
+		r.envois.Add(1)
+		go func() {
+			defer r.envois.Done()
+			r.liberer(alerte.ID, r.traiterAlerte(ctx, alerte))
+		}()
+	}
+}
+
+// Note l'alerte comme en cours d'envoi, retourne false si elle l'est déjà (ou vient d'être marquée)
+func (r *Repartiteur) reserver(id int) bool {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if r.enCours == nil {
+		r.enCours, r.terminees = map[int]bool{}, map[int]bool{}
+	}
+	if r.enCours[id] || r.terminees[id] {
+		return false
+	}
+	r.enCours[id] = true
+	return true
+}
+
+// Termine l'envoi d'une alerte ; une alerte marquée n'est plus relancée même si une lecture en cours la retourne encore
+func (r *Repartiteur) liberer(id int, marquee bool) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	delete(r.enCours, id)
+	if marquee {
+		r.terminees[id] = true
+	}
+}
+
+// Oublie les alertes marquées qui ne sont plus retournées par la lecture
+func (r *Repartiteur) oublierTerminees(alertes []models.Alerte) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	for id := range r.terminees {
+		if !slices.ContainsFunc(alertes, func(a models.Alerte) bool { return a.ID == id }) {
+			delete(r.terminees, id)
+		}
+	}
+}
+
+// Abandonne une alerte trop ancienne : une livraison en échec est notée pour chaque canal
+// pas encore livré, puis l'alerte est marquée sans être envoyée
+// Retourne true si l'alerte a été marquée
+func (r *Repartiteur) expirer(ctx context.Context, alerte models.Alerte) bool {
+	canaux, err := r.canauxAlerte(ctx, alerte)
+	if err != nil {
+		if ctx.Err() == nil {
+			log.Printf("[NOTIFICATIONS] Erreur lecture des routes de l'alerte %d : %v", alerte.ID, err)
+		}
+		return false
+	}
+	livres, err := r.Depot.CanauxLivres(ctx, alerte.ID)
+	if err != nil {
+		if ctx.Err() == nil {
+			log.Printf("[NOTIFICATIONS] Erreur lecture des livraisons de l'alerte %d : %v", alerte.ID, err)
+		}
+		return false
+	}
+
+	log.Printf("[NOTIFICATIONS] Alerte %d (%s, moniteur %d) abandonnée : créée le %s, plus de %s sans être envoyée",
+		alerte.ID, alerte.Type, alerte.MoniteurID, alerte.CreeA.Format(time.RFC3339), r.AgeMaxAlerte)
+	for _, canal := range canaux {
+		if slices.Contains(livres, canal.Nom()) {
+			continue
+		}
+		livraison := models.Livraison{
+			AlerteID: alerte.ID,
+			Canal:    canal.Nom(),
+			Succes:   false,
+			Erreur:   "alerte expirée : plus de " + r.AgeMaxAlerte.String() + " sans être envoyée",
+			CreeA:    time.Now(),
+		}
+		if err := r.Depot.EnregistrerLivraison(ctx, livraison); err != nil {
+			// l'alerte reste non traitée : la perte sera notée au prochain passage
+			if ctx.Err() == nil {
+				log.Printf("[NOTIFICATIONS] Erreur enregistrement livraison : %v", err)
+			}
+			return false
+		}
+	}
+	return r.marquer(ctx, alerte.ID)
+}
+
+// Marque une alerte traitée, retourne false en cas d'erreur
+func (r *Repartiteur) marquer(ctx context.Context, id int) bool {
+	if err := r.Depot.MarquerAlerteTraitee(ctx, id); err != nil {
+		if ctx.Err() == nil {
+			log.Printf("[NOTIFICATIONS] Erreur marquage alerte %d : %v", id, err)
+		}
+		return false
+	}
+	return true
+}
+
+// Envoie une alerte sur ses canaux pas encore livrés, en parallèle, puis la marque traitée
+// Retourne true si l'alerte a été marquée
+func (r *Repartiteur) traiterAlerte(ctx context.Context, alerte models.Alerte) bool {
+	canaux, err := r.canauxAlerte(ctx, alerte)
+	if err != nil {
+		// l'alerte reste non traitée et sera reprise au prochain passage
+		if ctx.Err() == nil {
+			log.Printf("[NOTIFICATIONS] Erreur lecture des routes de l'alerte %d : %v", alerte.ID, err)
+		}
+		return false
+	}
+	livres, err := r.Depot.CanauxLivres(ctx, alerte.ID)
+	if err != nil {
+		if ctx.Err() == nil {
+			log.Printf("[NOTIFICATIONS] Erreur lecture des livraisons de l'alerte %d : %v", alerte.ID, err)
+		}
+		return false
+	}
+
+	var wg sync.WaitGroup
+	for _, canal := range canaux {
+		if slices.Contains(livres, canal.Nom()) {
+			continue
+		}
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			r.livrer(ctx, canal, alerte)
+		}()
+	}
+	wg.Wait()
+
+	if ctx.Err() != nil {
+		// arrêt en cours : l'alerte sera reprise au prochain démarrage (sans les canaux déjà livrés)
+		return false
+	}
+	return r.marquer(ctx, alerte.ID)
+}
+
+// Retourne les canaux fixes (selon le type de l'alerte) suivis des canaux routés pour le moniteur de l'alerte
+func (r *Repartiteur) canauxAlerte(ctx context.Context, alerte models.Alerte) ([]Canal, error) {
+	var canaux []Canal
+	if slices.Contains(typesCanauxFixes, alerte.Type) {
+		canaux = append(canaux, r.Canaux...)
+	}
+	if r.Routes == nil {
+		return canaux, nil
+	}
+	// la fin d'une dégradation va aux routes qui suivent DEGRADE
+	typeRoute := alerte.Type
+	if typeRoute == models.AlerteDegradeFin {
+		typeRoute = models.AlerteDegrade
+	}
+	configs, err := r.Routes.CanauxPourAlerte(ctx, alerte.MoniteurID, typeRoute)
+	if err != nil {
+		return nil, err
+	}
+
+	for _, config := range configs {
+		canal, err := NouveauCanal(config, r.TimeoutEnvoi)
+		if err != nil {
+			log.Printf("[NOTIFICATIONS] Canal %q ignoré : %v", config.Nom, err)
+			continue
+		}
+		canaux = append(canaux, canal)
+	}
+	return canaux, nil
+}
+
+// Envoie une alerte sur un canal avec des tentatives espacées (2s, 4s, 8s...)
+func (r *Repartiteur) livrer(ctx context.Context, canal Canal, alerte models.Alerte) {
+	delai := r.DelaiInitial
+	for tentative := 1; tentative <= r.TentativesMax; tentative++ {
+		debut := time.Now()
+		err := canal.Envoyer(ctx, alerte)
+
+		livraison := models.Livraison{
+			AlerteID:  alerte.ID,
+			Canal:     canal.Nom(),
+			Tentative: tentative,
+			Succes:    err == nil,
+			Duree:     time.Since(debut),
+			CreeA:     debut,
+		}
+		var erreurReponse *ErreurReponse
+		if err != nil {
+			livraison.Erreur = err.Error()
+			if errors.As(err, &erreurReponse) {
+				livraison.CodeHTTP = erreurReponse.Code
+			}
+		}
+		if errEnregistrement := r.Depot.EnregistrerLivraison(ctx, livraison); errEnregistrement != nil && ctx.Err() == nil {
+			log.Printf("[NOTIFICATIONS] Erreur enregistrement livraison : %v", errEnregistrement)
+		}
+
+		if err == nil {
+			return
+		}
+		var permanente erreurPermanente
+		if errors.As(err, &permanente) && permanente.Permanente() {
+			log.Printf("[NOTIFICATIONS] %s a refusé l'alerte %d : %v", canal.Nom(), alerte.ID, err)
+			return
+		}
+		if tentative == r.TentativesMax {
+			log.Printf("[NOTIFICATIONS] Échec de l'alerte %d sur %s après %d tentatives : %v", alerte.ID, canal.Nom(), tentative, err)
+			return
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-time.After(delai):
+		}
+		delai = min(delai*2, delaiMaxReessai)
+	}
+}
@@ -0,0 +1,266 @@
+/* Tests pour le répartiteur des alertes
+ * Projet de session A25
+ * By : Leandre Kanmegne
+ *
+ * Utilise un faux dépôt en mémoire et des canaux qui échouent un certain nombre de fois
+ */
+package notifications
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"example.com/go-hello/src/internal/models"
+)
+
+// fauxDepotAlertes garde les alertes et les livraisons en mémoire
+type fauxDepotAlertes struct {
+	mu         sync.Mutex
+	alertes    []models.Alerte
+	traitees   map[int]bool
+	livraisons []models.Livraison
+}
+
+func (f *fauxDepotAlertes) AlertesNonTraitees(ctx context.Context, depuis time.Time, limite int) ([]models.Alerte, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	var resultat []models.Alerte
+	for _, alerte := range f.alertes {
+		if !f.traitees[alerte.ID] && !alerte.CreeA.Before(depuis) && len(resultat) < limite {
+			resultat = append(resultat, alerte)
+		}
+	}
+	return resultat, nil
+}
+
+func (f *fauxDepotAlertes) MarquerAlerteTraitee(ctx context.Context, id int) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.traitees[id] = true
+	return nil
+}
+
+func (f *fauxDepotAlertes) CanauxLivres(ctx context.Context, alerteID int) ([]string, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	var canaux []string
+	for _, livraison := range f.livraisons {
+		if livraison.AlerteID == alerteID && livraison.Succes {
+			canaux = append(canaux, livraison.Canal)
+		}
+	}
+	return canaux, nil
+}
+
+func (f *fauxDepotAlertes) EnregistrerLivraison(ctx context.Context, livraison models.Livraison) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.livraisons = append(f.livraisons, livraison)
+	return nil
+}
+
+// Retourne les livraisons enregistrées pour une alerte
+func (f *fauxDepotAlertes) livraisonsAlerte(alerteID int) []models.Livraison {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	var livraisons []models.Livraison
+	for _, livraison := range f.livraisons {
+		if livraison.AlerteID == alerteID {
+			livraisons = append(livraisons, livraison)
+		}
+	}
+	return livraisons
+}
+
+// fauxCanal échoue les N premiers envois avec l'erreur donnée
+type fauxCanal struct {
+	mu     sync.Mutex
+	echecs int
+	erreur error
+	envois int
+}
+
+func (f *fauxCanal) Nom() string { return "faux" }
+
+func (f *fauxCanal) Envoyer(ctx context.Context, alerte models.Alerte) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.envois++
+	if f.envois <= f.echecs {
+		return f.erreur
+	}
+	return nil
+}
+
+// canalBloque n'aboutit qu'à l'annulation du contexte (destinataire qui ne répond pas)
+type canalBloque struct{}
+
+func (canalBloque) Nom() string { return "bloque" }
+
+func (canalBloque) Envoyer(ctx context.Context, alerte models.Alerte) error {
+	<-ctx.Done()
+	return ctx.Err()
+}
+
+// crée un répartiteur rapide avec une alerte récente et une trop ancienne
+func creerRepartiteurTest(canal Canal) (*Repartiteur, *fauxDepotAlertes) {
+	depot := &fauxDepotAlertes{
+		traitees: map[int]bool{},
+		alertes: []models.Alerte{
+			{ID: 1, Type: models.AlerteDown, CreeA: time.Now()},
+			{ID: 2, Type: models.AlerteDown, CreeA: time.Now().Add(-2 * AgeMaxAlerteParDefaut)},
+		},
+	}
+	repartiteur := NouveauRepartiteur(depot, canal)
+	repartiteur.DelaiInitial = time.Millisecond
+	repartiteur.TentativesMax = 4
+	return repartiteur, depot
+}
+
+// test : échecs temporaires puis succès, chaque tentative est enregistrée
+func TestRepartiteur_ReessaisPuisSucces(t *testing.T) {
+	canal := &fauxCanal{echecs: 2, erreur: &ErreurReponse{Code: 503}}
+	repartiteur, depot := creerRepartiteurTest(canal)
+
+	repartiteur.Traiter(context.Background())
+	repartiteur.Attendre()
+
+	livraisons := depot.livraisonsAlerte(1)
+	if len(livraisons) != 3 {
+		t.Fatalf("3 tentatives attendues, reçu %d", len(livraisons))
+	}
+	if livraisons[0].CodeHTTP != 503 || livraisons[0].Succes || !livraisons[2].Succes {
+		t.Errorf("livraisons inattendues : %+v", livraisons)
+	}
+	if livraisons[2].Tentative != 3 {
+		t.Errorf("numéro de tentative 3 attendu, reçu %d", livraisons[2].Tentative)
+	}
+	if !depot.traitees[1] || !depot.traitees[2] {
+		t.Errorf("les deux alertes devraient être marquées : %v", depot.traitees)
+	}
+}
+
+// test : une alerte expirée n'est pas envoyée mais laisse une livraison en échec ; la limite est réglable
+func TestRepartiteur_AlerteExpiree(t *testing.T) {
+	canal := &fauxCanal{}
+	repartiteur, depot := creerRepartiteurTest(canal)
+	repartiteur.Traiter(context.Background())
+	repartiteur.Attendre()
+
+	expirees := depot.livraisonsAlerte(2)
+	if canal.envois != 1 || !depot.traitees[2] || len(expirees) != 1 {
+		t.Fatalf("l'alerte expirée devrait être marquée sans envoi avec une livraison, reçu %d envois %+v", canal.envois, expirees)
+	}
+	if e := expirees[0]; e.Succes || e.Tentative != 0 || e.Canal != canal.Nom() || !strings.Contains(e.Erreur, "expirée") {
+		t.Errorf("livraison en échec pour expiration attendue, reçu %+v", e)
+	}
+
+	canal = &fauxCanal{}
+	repartiteur, depot = creerRepartiteurTest(canal)
+	repartiteur.AgeMaxAlerte = 3 * AgeMaxAlerteParDefaut
+	repartiteur.Traiter(context.Background())
+	repartiteur.Attendre()
+	if livraisons := depot.livraisonsAlerte(2); canal.envois != 2 || len(livraisons) != 1 || !livraisons[0].Succes {
+		t.Errorf("avec une limite plus longue, l'alerte devrait être envoyée, reçu %d envois %+v", canal.envois, livraisons)
+	}
+}
+
+// test : une erreur permanente arrête les tentatives, une erreur réseau va jusqu'au max
+func TestRepartiteur_Abandon(t *testing.T) {
+	permanente := &fauxCanal{echecs: 10, erreur: &ErreurReponse{Code: 404}}
+	repartiteur, depot := creerRepartiteurTest(permanente)
+	repartiteur.Traiter(context.Background())
+	repartiteur.Attendre()
+	if livraisons := depot.livraisonsAlerte(1); len(livraisons) != 1 || !depot.traitees[1] {
+		t.Errorf("une seule tentative pour un 404, reçu %d", len(livraisons))
+	}
+
+	reseau := &fauxCanal{echecs: 10, erreur: errors.New("connexion refusée")}
+	repartiteur, depot = creerRepartiteurTest(reseau)
+	repartiteur.Traiter(context.Background())
+	repartiteur.Attendre()
+	if livraisons := depot.livraisonsAlerte(1); len(livraisons) != 4 || livraisons[3].Erreur != "connexion refusée" {
+		t.Errorf("4 tentatives attendues, reçu %+v", livraisons)
+	}
+}
+
+// test : les canaux fixes ne reçoivent que les alertes UP et DOWN
+func TestRepartiteur_CanauxFixesSansDegrade(t *testing.T) {
+	canal := &fauxCanal{}
+	repartiteur, depot := creerRepartiteurTest(canal)
+	depot.alertes = append(depot.alertes,
+		models.Alerte{ID: 3, Type: models.AlerteDegrade, CreeA: time.Now()},
+		models.Alerte{ID: 4, Type: models.AlerteDegradeFin, CreeA: time.Now()},
+	)
+
+	repartiteur.Traiter(context.Background())
+	repartiteur.Attendre()
+
+	if canal.envois != 1 {
+		t.Errorf("seule l'alerte DOWN devrait être envoyée, reçu %d envois", canal.envois)
+	}
+	if !depot.traitees[3] || !depot.traitees[4] {
+		t.Error("les alertes DEGRADE et DEGRADE_FIN devraient être marquées traitées")
+	}
+}
+
+// test : un canal déjà livré (ex: avant un redémarrage) n'est pas relancé
+func TestRepartiteur_CanalDejaLivre(t *testing.T) {
+	canal := &fauxCanal{}
+	repartiteur, depot := creerRepartiteurTest(canal)
+	depot.livraisons = []models.Livraison{{AlerteID: 1, Canal: canal.Nom(), Tentative: 1, Succes: true}}
+
+	repartiteur.Traiter(context.Background())
+	repartiteur.Attendre()
+
+	if canal.envois != 0 || len(depot.livraisonsAlerte(1)) != 1 {
+		t.Errorf("aucun nouvel envoi attendu, reçu %d envois", canal.envois)
+	}
+	if !depot.traitees[1] {
+		t.Error("l'alerte devrait être marquée traitée")
+	}
+}
+
+// test : un canal qui ne répond pas ne bloque ni les autres canaux ni les alertes suivantes
+func TestRepartiteur_CanalBloqueNeBloquePasLesAutres(t *testing.T) {
+	canal := &fauxCanal{}
+	repartiteur, depot := creerRepartiteurTest(canal)
+	repartiteur.Canaux = append(repartiteur.Canaux, canalBloque{})
+	depot.alertes = append(depot.alertes, models.Alerte{ID: 3, Type: models.AlerteUp, CreeA: time.Now()})
+
+	ctx, annuler := context.WithCancel(context.Background())
+	repartiteur.Traiter(ctx)
+
+	// les deux alertes arrivent sur le canal qui répond pendant que l'autre est bloqué
+	limite := time.Now().Add(2 * time.Second)
+	for {
+		canal.mu.Lock()
+		envois := canal.envois
+		canal.mu.Unlock()
+		if envois == 2 {
+			break
+		}
+		if time.Now().After(limite) {
+			t.Fatalf("2 envois attendus malgré le canal bloqué, reçu %d", envois)
+		}
+		time.Sleep(5 * time.Millisecond)
+	}
+
+	// un nouveau passage ne relance pas les alertes en cours
+	repartiteur.Traiter(ctx)
+	annuler()
+	repartiteur.Attendre()
+
+	canal.mu.Lock()
+	defer canal.mu.Unlock()
+	if canal.envois != 2 {
+		t.Errorf("les alertes en cours ne devraient pas être relancées, reçu %d envois", canal.envois)
+	}
+	if depot.traitees[1] || depot.traitees[3] {
+		t.Errorf("arrêt pendant l'envoi : les alertes devraient rester non traitées, reçu %v", depot.traitees)
+	}
+}
@@ -0,0 +1,184 @@
+/* Canal de notification par webhook
+ * Projet de session A25
+ * By : Leandre Kanmegne
+ *
+ * Envoie l'alerte en POST JSON vers une URL
+ * Le corps est produit par un modèle text/template (modèle par défaut ou fichier)
+ * Signe le corps avec HMAC-SHA256 si un secret est configuré (en-tête X-Signature-256)
+ *
+ * Source: https://pkg.go.dev/text/template
+ * Source: https://pkg.go.dev/crypto/hmac
+ */
+package notifications
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"text/template"
+	"time"
+
+	"example.com/go-hello/src/internal/models"
+)
+
+// En-tête contenant la signature du corps
+const EnTeteSignature = "X-Signature-256"
+
+// Modèle JSON utilisé si aucun n'est configuré
+const ModeleWebhookParDefaut = `{
+  "type": {{json .Type}},
+  "details": {{json .Details}},
+  "code_http": {{.CodeHTTP}},
+  "duree_panne_secondes": {{.DureePanne.Seconds | printf "%.0f"}},
+  "cree_a": {{json .CreeA}},
+  "moniteur": {
+    "id": {{.MoniteurID}},
+    "nom": {{json .NomMoniteur}},
+    "url": {{json .URL}}
+  }
+}`
+
+// Fonctions disponibles dans les modèles
+var fonctionsModele = template.FuncMap{
+	"json": func(valeur any) (string, error) {
+		donnees, err := json.Marshal(valeur)
+		return string(donnees), err
+	},
+	"resume": resumerAlerte,
+}
+
+// Résume l'alerte en une ligne (ex: "[UP] API (https://...) - Rétabli - panne de 1m30s")
+func resumerAlerte(alerte models.Alerte) string {
+	message := fmt.Sprintf("[%s] %s (%s)", alerte.Type, alerte.NomMoniteur, alerte.URL)
+	if alerte.Details != "" {
+		message += " - " + alerte.Details
+	}
+	if alerte.DureePanne > 0 {
+		message += " - panne de " + alerte.DureePanne.Round(time.Second).String()
+	}
+	return message
+}
+
+// ChargerModele lit un modèle depuis un fichier, ou retourne le modèle par défaut si le chemin est vide
+func ChargerModele(chemin string) (*template.Template, error) {
+	source, err := lireModele(chemin, ModeleWebhookParDefaut)
+	if err != nil {
+		return nil, err
+	}
+	return ParserModele(source)
+}
+
+// ParserModele compile la source d'un modèle de corps JSON (ex: config d'un canal)
+func ParserModele(source string) (*template.Template, error) {
+	return template.New("webhook").Funcs(fonctionsModele).Parse(source)
+}
+
+// ErreurReponse est retournée quand le destinataire répond avec un code d'erreur
+type ErreurReponse struct {
+	Code  int
+	Corps string
+}
+
+func (e *ErreurReponse) Error() string {
+	return fmt.Sprintf("réponse HTTP %d : %s", e.Code, e.Corps)
+}
+
+// Permanente indique qu'il ne sert à rien de réessayer (4xx sauf 408 et 429)
+func (e *ErreurReponse) Permanente() bool {
+	return e.Code >= 400 && e.Code < 500 && e.Code != http.StatusRequestTimeout && e.Code != http.StatusTooManyRequests
+}
+
+// Webhook implémente Canal pour une URL
+type Webhook struct {
+	URL    string
+	Secret string             // vide = pas de signature
+	Modele *template.Template // nil = modèle par défaut
+	Client *http.Client
+}
+
+// NouveauWebhook crée un webhook avec le modèle donné (nil = modèle par défaut)
+func NouveauWebhook(url, secret string, modele *template.Template, timeout time.Duration) *Webhook {
+	if modele == nil {
+		modele, _ = ChargerModele("")
+	}
+	return &Webhook{
+		URL:    url,
+		Secret: secret,
+		Modele: modele,
+		Client: &http.Client{Timeout: timeout},
+	}
+}
+
+// Nom identifie le canal dans monitoring.livraisons et les logs
+// L'URL contient souvent un jeton : seuls l'hôte et une empreinte courte de l'URL sont gardés
+func (w *Webhook) Nom() string {
+	empreinte := sha256.Sum256([]byte(w.URL))
+	hote := "?"
+	if lien, err := url.Parse(w.URL); err == nil && lien.Host != "" {
+		hote = lien.Host
+	}
+	return "webhook:" + hote + "#" + hex.EncodeToString(empreinte[:4])
+}
+
+// Envoyer poste l'alerte et retourne une erreur si la réponse n'est pas 2xx
+func (w *Webhook) Envoyer(ctx context.Context, alerte models.Alerte) error {
+	corps, err := w.Corps(alerte)
+	if err != nil {
+		return err
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, w.URL, bytes.NewReader(corps))
+	if err != nil {
+		return fmt.Errorf("URL du webhook invalide")
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("User-Agent", "go-hello-monitoring/1.0")
+	if w.Secret != "" {
+		req.Header.Set(EnTeteSignature, Signer(w.Secret, corps))
+	}
+
+	resp, err := w.Client.Do(req)
+	if err != nil {
+		// l'erreur de net/http répète l'URL complète : on ne garde que la cause
+		var erreurURL *url.Error
+		if errors.As(err, &erreurURL) {
+			return fmt.Errorf("POST webhook : %w", erreurURL.Err)
+		}
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		extrait, _ := io.ReadAll(io.LimitReader(resp.Body, 512))
+		return &ErreurReponse{Code: resp.StatusCode, Corps: string(extrait)}
+	}
+	io.Copy(io.Discard, io.LimitReader(resp.Body, 64*1024))
+	return nil
+}
+
+// Corps applique le modèle et vérifie que le résultat est du JSON valide
+func (w *Webhook) Corps(alerte models.Alerte) ([]byte, error) {
+	var tampon bytes.Buffer
+	if err := w.Modele.Execute(&tampon, alerte); err != nil {
+		return nil, fmt.Errorf("modèle webhook : %w", err)
+	}
+	if !json.Valid(tampon.Bytes()) {
+		return nil, fmt.Errorf("modèle webhook : le résultat n'est pas du JSON valide")
+	}
+	return tampon.Bytes(), nil
+}
+
+// Signer retourne la signature HMAC-SHA256 du corps au format "sha256=<hex>"
+func Signer(secret string, corps []byte) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write(corps)
+	return "sha256=" + hex.EncodeToString(mac.Sum(nil))
+}
@@ -0,0 +1,119 @@
+/* Tests pour le canal webhook
+ * Projet de session A25
+ * By : Leandre Kanmegne
+ *
+ * Un serveur httptest reçoit le POST et vérifie le corps et la signature
+ */
+package notifications
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"example.com/go-hello/src/internal/models"
+)
+
+// alerte utilisée par les tests
+func alerteTest() models.Alerte {
+	return models.Alerte{
+		ID:          42,
+		MoniteurID:  7,
+		NomMoniteur: `API "paiements"`,
+		URL:         "https://api.exemple.test/sante",
+		Type:        models.AlerteUp,
+		Details:     "Rétabli - HTTP 200",
+		CodeHTTP:    200,
+		DureePanne:  90 * time.Second,
+		CreeA:       time.Date(2025, 10, 1, 12, 0, 0, 0, time.UTC),
+	}
+}
+
+// test : corps JSON du modèle par défaut et signature HMAC
+func TestWebhook_EnvoiSigne(t *testing.T) {
+	var corps []byte
+	var signature string
+	serveur := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		corps, _ = io.ReadAll(r.Body)
+		signature = r.Header.Get(EnTeteSignature)
+	}))
+	defer serveur.Close()
+
+	webhook := NouveauWebhook(serveur.URL, "secret", nil, time.Second)
+	if err := webhook.Envoyer(context.Background(), alerteTest()); err != nil {
+		t.Fatalf("envoi inattendu en échec : %v", err)
+	}
+
+	var recu struct {
+		Type               string `json:"type"`
+		DureePanneSecondes int    `json:"duree_panne_secondes"`
+		Moniteur           struct {
+			Nom string `json:"nom"`
+		} `json:"moniteur"`
+	}
+	if err := json.Unmarshal(corps, &recu); err != nil {
+		t.Fatalf("corps JSON invalide : %v\n%s", err, corps)
+	}
+	if recu.Type != "UP" || recu.DureePanneSecondes != 90 || recu.Moniteur.Nom != `API "paiements"` {
+		t.Errorf("corps inattendu : %s", corps)
+	}
+	if signature != Signer("secret", corps) {
+		t.Errorf("signature %q ne correspond pas au corps", signature)
+	}
+}
+
+// test : modèle personnalisé, modèle invalide et réponse en erreur
+func TestWebhook_ModeleEtErreurs(t *testing.T) {
+	chemin := filepath.Join(t.TempDir(), "modele.tmpl")
+	os.WriteFile(chemin, []byte(`{"text": {{printf "%s : %s" .Type .NomMoniteur | json}}}`), 0o600)
+	modele, err := ChargerModele(chemin)
+	if err != nil {
+		t.Fatal(err)
+	}
+	corps, err := (&Webhook{Modele: modele}).Corps(alerteTest())
+	if err != nil || string(corps) != `{"text": "UP : API \"paiements\""}` {
+		t.Errorf("corps personnalisé inattendu : %s (%v)", corps, err)
+	}
+
+	pasJSON, _ := ChargerModele("")
+	pasJSON.Parse(`texte {{.Type}}`)
+	if _, err := (&Webhook{Modele: pasJSON}).Corps(alerteTest()); err == nil {
+		t.Error("un modèle qui ne produit pas du JSON devrait être refusé")
+	}
+
+	serveur := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		http.Error(w, "jeton invalide", http.StatusUnauthorized)
+	}))
+	defer serveur.Close()
+
+	err = NouveauWebhook(serveur.URL, "", nil, time.Second).Envoyer(context.Background(), alerteTest())
+	var erreurReponse *ErreurReponse
+	if !errors.As(err, &erreurReponse) || erreurReponse.Code != http.StatusUnauthorized || !erreurReponse.Permanente() {
+		t.Errorf("erreur 401 permanente attendue, reçu %v", err)
+	}
+}
+
+// test : le nom du canal et les erreurs d'envoi ne contiennent pas l'URL (souvent porteuse d'un jeton)
+func TestWebhook_NomSansJeton(t *testing.T) {
+	premier := NouveauWebhook("https://hooks.slack.com/services/T000/B000/jeton1", "", nil, time.Second)
+	second := NouveauWebhook("https://hooks.slack.com/services/T000/B000/jeton2", "", nil, time.Second)
+	if strings.Contains(premier.Nom(), "jeton") || !strings.HasPrefix(premier.Nom(), "webhook:hooks.slack.com#") {
+		t.Errorf("nom inattendu : %q", premier.Nom())
+	}
+	if premier.Nom() == second.Nom() {
+		t.Errorf("deux URL différentes devraient donner deux noms : %q", premier.Nom())
+	}
+
+	injoignable := NouveauWebhook("http://127.0.0.1:1/jeton", "", nil, time.Second)
+	if err := injoignable.Envoyer(context.Background(), alerteTest()); err == nil || strings.Contains(err.Error(), "jeton") {
+		t.Errorf("erreur sans l'URL attendue, reçu %v", err)
+	}
+}
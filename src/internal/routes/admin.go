@@ -0,0 +1,67 @@
+/* Routes HTTP d'administration
+ * Projet de session A25
+ * By : Leandre Kanmegne
+ *
+ * - GET  /api/admin/purge : politique de rétention (en jours, 0 = conserver) et rapport de la dernière purge
+ * - POST /api/admin/purge : lance une purge tout de suite et retourne le rapport (lignes supprimées par cible)
+ * Retourne 409 si une purge est déjà en cours, 503 si le purgeur n'est pas configuré
+ */
+
+package routes
+
+import (
+	"errors"
+	"net/http"
+	"time"
+
+	"example.com/go-hello/src/internal/models"
+	"example.com/go-hello/src/internal/services"
+)
+
+// Représente la politique de rétention et la dernière purge pour l'API
+type PurgeVue struct {
+	RetentionJours map[models.CiblePurge]int `json:"retention_jours"`
+	Dernier        *models.RapportPurge      `json:"dernier"`
+}
+
+// Lance une purge ou retourne la politique et la dernière purge
+func HandlerPurge(app ServicesApp) http.HandlerFunc {
+	return func(w http.ResponseWriter, req *http.Request) {
+		activerCORS(w)
+
+		if req.Method == http.MethodOptions {
+			w.WriteHeader(http.StatusNoContent)
+			return
+		}
+		if app.Purgeur == nil {
+			ecrireErreur(w, http.StatusServiceUnavailable, "purge non configurée")
+			return
+		}
+
+		switch req.Method {
+		case http.MethodGet:
+			vue := PurgeVue{RetentionJours: map[models.CiblePurge]int{}, Dernier: app.Purgeur.DernierRapport()}
+			for _, cible := range models.CiblesPurge {
+				vue.RetentionJours[cible] = int(app.Purgeur.Politique[cible] / (24 * time.Hour))
+			}
+			ecrireJSON(w, http.StatusOK, vue)
+
+		case http.MethodPost:
+			rapport, err := app.Purgeur.Purger(req.Context())
+			if errors.Is(err, services.ErrPurgeEnCours) {
+				ecrireErreur(w, http.StatusConflict, err.Error())
+				return
+			}
+			if err != nil {
+				// le rapport contient l'erreur et les lignes déjà supprimées
+				ecrireJSON(w, http.StatusInternalServerError, rapport)
+				return
+			}
+			ecrireJSON(w, http.StatusOK, rapport)
+
+		default:
+			w.Header().Set("Allow", "GET, POST, OPTIONS")
+			ecrireErreur(w, http.StatusMethodNotAllowed, "méthode non autorisée")
+		}
+	}
+}
@@ -0,0 +1,65 @@
+/* Tests de la route de purge manuelle
+ * Projet de session A25
+ * By : Leandre Kanmegne
+ */
+package routes
+
+import (
+	"context"
+	"net/http"
+	"testing"
+	"time"
+
+	"example.com/go-hello/src/internal/models"
+	"example.com/go-hello/src/internal/services"
+)
+
+// test : purge non configurée (503), politique de rétention et purge des statuts consolidés
+func TestHandlerPurge(t *testing.T) {
+	depot, routes := nouvelleAppTest(t)
+	if code := appeler(t, routes, http.MethodGet, "/api/admin/purge", "", nil); code != http.StatusServiceUnavailable {
+		t.Errorf("sans purgeur : 503 attendu, obtenu %d", code)
+	}
+
+	purgeur := services.NouveauPurgeur(depot, models.PolitiqueRetention{models.CibleStatuts: 24 * time.Hour})
+	purgeur.PauseEntreLots = 0
+	routes = EnregistrerRoutes(ServicesApp{Depot: depot, Purgeur: purgeur})
+
+	// deux statuts vieux de 3 jours, déjà consolidés
+	ctx := context.Background()
+	id := ajouterMoniteurTest(t, depot, models.Moniteur{Nom: "Ancien", URL: "https://ancien.test", Actif: true})
+	ancien := time.Now().Add(-72 * time.Hour)
+	for i := range 2 {
+		statut := models.StatutMoniteur{MoniteurID: id, URL: "https://ancien.test", EstDisponible: true, Etat: models.EtatUp,
+			VerifieA: ancien.Add(time.Duration(i) * time.Minute)}
+		if err := depot.EnregistrerStatutMoniteur(ctx, statut); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if _, err := depot.ConsoliderStatuts(ctx, time.Now()); err != nil {
+		t.Fatal(err)
+	}
+
+	var politique PurgeVue
+	if code := appeler(t, routes, http.MethodGet, "/api/admin/purge", "", &politique); code != http.StatusOK {
+		t.Fatalf("GET : 200 attendu, obtenu %d", code)
+	}
+	if politique.RetentionJours[models.CibleStatuts] != 1 || politique.RetentionJours[models.CibleAlertes] != 0 || politique.Dernier != nil {
+		t.Errorf("rétention d'un jour pour les statuts attendue, obtenu %+v", politique)
+	}
+
+	var rapport models.RapportPurge
+	if code := appeler(t, routes, http.MethodPost, "/api/admin/purge", "", &rapport); code != http.StatusOK {
+		t.Fatalf("POST : 200 attendu, obtenu %d", code)
+	}
+	if rapport.Lignes[models.CibleStatuts] != 2 || rapport.Total != 2 || rapport.Erreur != "" {
+		t.Errorf("2 statuts purgés attendus, obtenu %+v", rapport)
+	}
+
+	if appeler(t, routes, http.MethodGet, "/api/admin/purge", "", &politique); politique.Dernier == nil || politique.Dernier.Total != 2 {
+		t.Errorf("rapport de la dernière purge attendu, obtenu %+v", politique.Dernier)
+	}
+	if code := appeler(t, routes, http.MethodDelete, "/api/admin/purge", "", nil); code != http.StatusMethodNotAllowed {
+		t.Errorf("DELETE : 405 attendu, obtenu %d", code)
+	}
+}
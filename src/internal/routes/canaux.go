@@ -0,0 +1,362 @@
+/* Routes HTTP des canaux de notification et du routage des alertes
+ * Projet de session A25
+ * By : Leandre Kanmegne
+ *
+ * - GET    /api/canaux                 : liste des canaux
+ * - POST   /api/canaux                 : crée un canal (webhook, email, slack, discord)
+ * - GET    /api/canaux/{id}            : détail d'un canal
+ * - PUT    /api/canaux/{id}            : remplace un canal
+ * - DELETE /api/canaux/{id}            : supprime un canal et ses routes
+ * - GET    /api/moniteurs/{id}/routes  : canaux qui reçoivent les alertes du moniteur
+ * - PUT    /api/moniteurs/{id}/routes  : remplace les routes du moniteur
+ * Une route filtre les types d'alertes : ["DOWN"], ["DOWN", "UP"] (par défaut), ["DEGRADE"]...
+ * DEGRADE couvre aussi la fin de la dégradation (alerte DEGRADE_FIN)
+ * Les secrets (secret du webhook, mot de passe SMTP) sont masqués dans les réponses
+ */
+
+package routes
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/mail"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	"example.com/go-hello/src/internal/models"
+	"example.com/go-hello/src/internal/notifications"
+)
+
+// Types d'alertes qu'une route peut filtrer
+var typesAlerte = map[string]bool{
+	models.AlerteDown:    true,
+	models.AlerteUp:      true,
+	models.AlerteDegrade: true,
+}
+
+// Représente le body pour créer ou remplacer un canal
+type RequeteCanal struct {
+	Nom    string             `json:"nom"`
+	Type   string             `json:"type"`
+	Actif  *bool              `json:"actif"` // true par défaut
+	Config models.ConfigCanal `json:"config"`
+}
+
+// Représente le body des routes d'un moniteur
+type RequeteRoutes struct {
+	Routes []models.RouteNotification `json:"routes"`
+}
+
+// Construit le canal décrit par la requête
+func (r RequeteCanal) canal() models.CanalNotification {
+	canal := models.CanalNotification{
+		Nom:    strings.TrimSpace(r.Nom),
+		Type:   strings.ToLower(strings.TrimSpace(r.Type)),
+		Actif:  true,
+		Config: r.Config,
+	}
+	if r.Actif != nil {
+		canal.Actif = *r.Actif
+	}
+	return canal
+}
+
+// Valide un canal, retourne la liste des problèmes
+func validerCanal(canal models.CanalNotification) error {
+	var problemes []string
+
+	if canal.Nom == "" {
+		problemes = append(problemes, "nom obligatoire")
+	} else if len(canal.Nom) > longueurMaxNom {
+		problemes = append(problemes, fmt.Sprintf("nom trop long (max %d caractères)", longueurMaxNom))
+	}
+
+	config := canal.Config
+	switch canal.Type {
+	case models.CanalWebhook, models.CanalSlack, models.CanalDiscord:
+		if lien, err := url.Parse(config.URL); err != nil || (lien.Scheme != "http" && lien.Scheme != "https") || lien.Host == "" {
+			problemes = append(problemes, "config.url http(s) obligatoire")
+		}
+		if config.Modele != "" {
+			if canal.Type != models.CanalWebhook {
+				problemes = append(problemes, "config.modele réservé aux canaux webhook")
+			} else if modele, err := notifications.ParserModele(config.Modele); err != nil {
+				problemes = append(problemes, fmt.Sprintf("config.modele invalide : %v", err))
+			} else if _, err := (&notifications.Webhook{Modele: modele}).Corps(alerteExemple()); err != nil {
+				problemes = append(problemes, fmt.Sprintf("config.modele invalide : %v", err))
+			}
+		}
+	case models.CanalEmail:
+		if config.Hote == "" {
+			problemes = append(problemes, "config.hote obligatoire")
+		}
+		if config.Port < 0 || config.Port > 65535 {
+			problemes = append(problemes, "config.port doit être entre 1 et 65535")
+		}
+		if _, err := mail.ParseAddress(config.Expediteur); err != nil {
+			problemes = append(problemes, "config.expediteur invalide")
+		}
+		if len(config.Destinataires) == 0 {
+			problemes = append(problemes, "config.destinataires obligatoire")
+		}
+		for _, adresse := range config.Destinataires {
+			if _, err := mail.ParseAddress(adresse); err != nil {
+				problemes = append(problemes, fmt.Sprintf("destinataire %q invalide", adresse))
+			}
+		}
+	default:
+		problemes = append(problemes, fmt.Sprintf("type %q non supporté (webhook, email, slack, discord)", canal.Type))
+	}
+
+	if len(problemes) > 0 {
+		return errors.New(strings.Join(problemes, ", "))
+	}
+	return nil
+}
+
+// Alerte fictive utilisée pour vérifier qu'un modèle produit du JSON valide
+func alerteExemple() models.Alerte {
+	return models.Alerte{
+		ID:          1,
+		MoniteurID:  1,
+		NomMoniteur: "exemple",
+		URL:         "https://exemple.com",
+		Type:        models.AlerteUp,
+		Details:     "Rétabli",
+		CodeHTTP:    http.StatusOK,
+		DureePanne:  time.Minute,
+		CreeA:       time.Now(),
+	}
+}
+
+// Copie du canal renvoyée par l'API, sans les secrets
+func canalVue(canal models.CanalNotification) models.CanalNotification {
+	canal.Config = canal.Config.Masquee(canal.Type)
+	return canal
+}
+
+// Décode un body JSON en refusant les champs inconnus
+func lireJSON(w http.ResponseWriter, req *http.Request, destination any) error {
+	req.Body = http.MaxBytesReader(w, req.Body, 1<<20)
+	defer req.Body.Close()
+
+	decodeur := json.NewDecoder(req.Body)
+	decodeur.DisallowUnknownFields()
+	if err := decodeur.Decode(destination); err != nil {
+		return fmt.Errorf("JSON invalide: %v", err)
+	}
+	return nil
+}
+
+// Liste ou crée des canaux
+func HandlerCanaux(app ServicesApp) http.HandlerFunc {
+	return func(w http.ResponseWriter, req *http.Request) {
+		activerCORS(w)
+
+		switch req.Method {
+		case http.MethodOptions:
+			w.WriteHeader(http.StatusNoContent)
+
+		case http.MethodGet:
+			canaux, err := app.Depot.ListerCanaux(req.Context())
+			if err != nil {
+				ecrireErreurDepot(w, err)
+				return
+			}
+			vues := make([]models.CanalNotification, 0, len(canaux))
+			for _, canal := range canaux {
+				vues = append(vues, canalVue(canal))
+			}
+			ecrireJSON(w, http.StatusOK, map[string]any{"canaux": vues})
+
+		case http.MethodPost:
+			var body RequeteCanal
+			if err := lireJSON(w, req, &body); err != nil {
+				ecrireErreur(w, http.StatusBadRequest, err.Error())
+				return
+			}
+			canal := body.canal()
+			if err := validerCanal(canal); err != nil {
+				ecrireErreur(w, http.StatusBadRequest, err.Error())
+				return
+			}
+
+			cree, err := app.Depot.AjouterCanal(req.Context(), canal)
+			if err != nil {
+				ecrireErreurDepot(w, err)
+				return
+			}
+			w.Header().Set("Location", "/api/canaux/"+strconv.Itoa(cree.ID))
+			ecrireJSON(w, http.StatusCreated, canalVue(cree))
+
+		default:
+			w.Header().Set("Allow", "GET, POST, OPTIONS")
+			ecrireErreur(w, http.StatusMethodNotAllowed, "méthode non autorisée")
+		}
+	}
+}
+
+// Lit, remplace ou supprime un canal
+func HandlerCanal(app ServicesApp) http.HandlerFunc {
+	return func(w http.ResponseWriter, req *http.Request) {
+		activerCORS(w)
+
+		if req.Method == http.MethodOptions {
+			w.WriteHeader(http.StatusNoContent)
+			return
+		}
+
+		id, err := strconv.Atoi(req.PathValue("id"))
+		if err != nil || id <= 0 {
+			ecrireErreur(w, http.StatusBadRequest, "id de canal invalide")
+			return
+		}
+
+		existant, err := app.Depot.ObtenirCanal(req.Context(), id)
+		if err != nil {
+			ecrireErreurDepot(w, err)
+			return
+		}
+
+		switch req.Method {
+		case http.MethodGet:
+			ecrireJSON(w, http.StatusOK, canalVue(existant))
+
+		case http.MethodPut:
+			var body RequeteCanal
+			if err := lireJSON(w, req, &body); err != nil {
+				ecrireErreur(w, http.StatusBadRequest, err.Error())
+				return
+			}
+			canal := body.canal()
+			canal.ID = id
+			canal.CreeA = existant.CreeA
+			// le client renvoie les secrets masqués tels quels : on garde ceux en base
+			canal.Config.RestaurerSecrets(existant.Config)
+
+			if err := validerCanal(canal); err != nil {
+				ecrireErreur(w, http.StatusBadRequest, err.Error())
+				return
+			}
+			if err := app.Depot.MettreAJourCanal(req.Context(), canal); err != nil {
+				ecrireErreurDepot(w, err)
+				return
+			}
+			ecrireJSON(w, http.StatusOK, canalVue(canal))
+
+		case http.MethodDelete:
+			if err := app.Depot.SupprimerCanal(req.Context(), id); err != nil {
+				ecrireErreurDepot(w, err)
+				return
+			}
+			w.WriteHeader(http.StatusNoContent)
+
+		default:
+			w.Header().Set("Allow", "GET, PUT, DELETE, OPTIONS")
+			ecrireErreur(w, http.StatusMethodNotAllowed, "méthode non autorisée")
+		}
+	}
+}
+
+// Valide et normalise les routes d'un moniteur
+func validerRoutes(moniteurID int, routes []models.RouteNotification, canaux []models.CanalNotification) error {
+	var problemes []string
+
+	existe := map[int]bool{}
+	for _, canal := range canaux {
+		existe[canal.ID] = true
+	}
+	vus := map[int]bool{}
+	for i := range routes {
+		route := &routes[i]
+		route.MoniteurID = moniteurID
+		if !existe[route.CanalID] {
+			problemes = append(problemes, fmt.Sprintf("canal %d introuvable", route.CanalID))
+		} else if vus[route.CanalID] {
+			problemes = append(problemes, fmt.Sprintf("canal %d en double", route.CanalID))
+		}
+		vus[route.CanalID] = true
+
+		if len(route.Types) == 0 {
+			route.Types = append([]string(nil), models.TypesAlerteParDefaut...)
+		}
+		for j, typeAlerte := range route.Types {
+			route.Types[j] = strings.ToUpper(strings.TrimSpace(typeAlerte))
+			if !typesAlerte[route.Types[j]] {
+				problemes = append(problemes, fmt.Sprintf("type d'alerte %q non supporté (DOWN, UP, DEGRADE)", typeAlerte))
+			}
+		}
+	}
+
+	if len(problemes) > 0 {
+		return errors.New(strings.Join(problemes, ", "))
+	}
+	return nil
+}
+
+// Lit ou remplace les routes de notification d'un moniteur
+func HandlerRoutesMoniteur(app ServicesApp) http.HandlerFunc {
+	return func(w http.ResponseWriter, req *http.Request) {
+		activerCORS(w)
+
+		if req.Method == http.MethodOptions {
+			w.WriteHeader(http.StatusNoContent)
+			return
+		}
+
+		id, err := strconv.Atoi(req.PathValue("id"))
+		if err != nil || id <= 0 {
+			ecrireErreur(w, http.StatusBadRequest, "id de moniteur invalide")
+			return
+		}
+		if _, err := app.Depot.ObtenirMoniteur(req.Context(), id); err != nil {
+			ecrireErreurDepot(w, err)
+			return
+		}
+
+		switch req.Method {
+		case http.MethodGet:
+			routes, err := app.Depot.RoutesMoniteur(req.Context(), id)
+			if err != nil {
+				ecrireErreurDepot(w, err)
+				return
+			}
+			if routes == nil {
+				routes = []models.RouteNotification{}
+			}
+			ecrireJSON(w, http.StatusOK, RequeteRoutes{Routes: routes})
+
+		case http.MethodPut:
+			var body RequeteRoutes
+			if err := lireJSON(w, req, &body); err != nil {
+				ecrireErreur(w, http.StatusBadRequest, err.Error())
+				return
+			}
+			canaux, err := app.Depot.ListerCanaux(req.Context())
+			if err != nil {
+				ecrireErreurDepot(w, err)
+				return
+			}
+			if err := validerRoutes(id, body.Routes, canaux); err != nil {
+				ecrireErreur(w, http.StatusBadRequest, err.Error())
+				return
+			}
+			if err := app.Depot.DefinirRoutesMoniteur(req.Context(), id, body.Routes); err != nil {
+				ecrireErreurDepot(w, err)
+				return
+			}
+			if body.Routes == nil {
+				body.Routes = []models.RouteNotification{}
+			}
+			ecrireJSON(w, http.StatusOK, body)
+
+		default:
+			w.Header().Set("Allow", "GET, PUT, OPTIONS")
+			ecrireErreur(w, http.StatusMethodNotAllowed, "méthode non autorisée")
+		}
+	}
+}
@@ -0,0 +1,135 @@
+/* Tests des routes des canaux de notification et du routage des alertes
+ * Projet de session A25
+ * By : Leandre Kanmegne
+ */
+package routes
+
+import (
+	"context"
+	"net/http"
+	"slices"
+	"strconv"
+	"testing"
+
+	"example.com/go-hello/src/internal/models"
+)
+
+// test : création d'un canal slack (URL masquée), doublon, remplacement sans perdre l'URL, suppression
+func TestHandlerCanaux_CRUD(t *testing.T) {
+	depot, routes := nouvelleAppTest(t)
+	const lien = "https://hooks.slack.test/services/T000/B000/secret"
+
+	var cree models.CanalNotification
+	if code := appeler(t, routes, http.MethodPost, "/api/canaux", `{"nom": "ops", "type": "slack", "config": {"url": "`+lien+`"}}`, &cree); code != http.StatusCreated {
+		t.Fatalf("201 attendu, obtenu %d", code)
+	}
+	if cree.ID == 0 || !cree.Actif || cree.Config.URL != models.SecretMasque {
+		t.Errorf("canal créé avec l'URL masquée attendu, obtenu %+v", cree)
+	}
+	chemin := "/api/canaux/" + strconv.Itoa(cree.ID)
+
+	if code := appeler(t, routes, http.MethodPost, "/api/canaux", `{"nom": "ops", "type": "slack", "config": {"url": "`+lien+`"}}`, nil); code != http.StatusConflict {
+		t.Errorf("nom en double : 409 attendu, obtenu %d", code)
+	}
+
+	// le client renvoie l'URL masquée : celle en base est conservée
+	var remplace models.CanalNotification
+	corps := `{"nom": "ops-renomme", "type": "slack", "actif": false, "config": {"url": "` + models.SecretMasque + `"}}`
+	if code := appeler(t, routes, http.MethodPut, chemin, corps, &remplace); code != http.StatusOK {
+		t.Fatalf("PUT : 200 attendu, obtenu %d", code)
+	}
+	enBase, err := depot.ObtenirCanal(context.Background(), cree.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if enBase.Nom != "ops-renomme" || enBase.Actif || enBase.Config.URL != lien {
+		t.Errorf("canal remplacé en gardant l'URL attendu, obtenu %+v", enBase)
+	}
+
+	var liste struct {
+		Canaux []models.CanalNotification `json:"canaux"`
+	}
+	if code := appeler(t, routes, http.MethodGet, "/api/canaux", "", &liste); code != http.StatusOK ||
+		len(liste.Canaux) != 1 || liste.Canaux[0].Config.URL != models.SecretMasque {
+		t.Errorf("un canal masqué listé attendu, obtenu %d %+v", code, liste.Canaux)
+	}
+
+	if code := appeler(t, routes, http.MethodDelete, chemin, "", nil); code != http.StatusNoContent {
+		t.Errorf("DELETE : 204 attendu, obtenu %d", code)
+	}
+	if code := appeler(t, routes, http.MethodGet, chemin, "", nil); code != http.StatusNotFound {
+		t.Errorf("après suppression : 404 attendu, obtenu %d", code)
+	}
+}
+
+// test : canaux invalides (400) et inconnus (404)
+func TestHandlerCanaux_Erreurs(t *testing.T) {
+	_, routes := nouvelleAppTest(t)
+
+	cas := []struct {
+		methode, chemin, corps string
+		attendu                int
+	}{
+		{http.MethodPost, "/api/canaux", `{"nom": "x", "type": "sms"}`, http.StatusBadRequest},
+		{http.MethodPost, "/api/canaux", `{"nom": "", "type": "webhook", "config": {"url": "https://hook.test"}}`, http.StatusBadRequest},
+		{http.MethodPost, "/api/canaux", `{"nom": "x", "type": "webhook", "config": {"url": "ftp://hook.test"}}`, http.StatusBadRequest},
+		{http.MethodPost, "/api/canaux", `{"nom": "x", "type": "discord", "config": {"url": "https://hook.test", "modele": "{}"}}`, http.StatusBadRequest},
+		{http.MethodPost, "/api/canaux", `{"nom": "x", "type": "email", "config": {"hote": "smtp.test", "expediteur": "pas une adresse", "destinataires": ["a@b.test"]}}`, http.StatusBadRequest},
+		{http.MethodPost, "/api/canaux", `{"nom": "x", "type": "email", "config": {"hote": "smtp.test", "expediteur": "a@b.test"}}`, http.StatusBadRequest},
+		{http.MethodGet, "/api/canaux/abc", "", http.StatusBadRequest},
+		{http.MethodGet, "/api/canaux/999", "", http.StatusNotFound},
+		{http.MethodDelete, "/api/canaux/999", "", http.StatusNotFound},
+		{http.MethodPatch, "/api/canaux", `{}`, http.StatusMethodNotAllowed},
+	}
+	for _, c := range cas {
+		if code := appeler(t, routes, c.methode, c.chemin, c.corps, nil); code != c.attendu {
+			t.Errorf("%s %s %s : %d attendu, obtenu %d", c.methode, c.chemin, c.corps, c.attendu, code)
+		}
+	}
+}
+
+// test : routes d'un moniteur (types par défaut, canal inconnu, type d'alerte invalide)
+func TestHandlerRoutesMoniteur(t *testing.T) {
+	depot, routes := nouvelleAppTest(t)
+	id := ajouterMoniteurTest(t, depot, models.Moniteur{Nom: "Routé", URL: "https://route.test", Actif: true})
+	canal, err := depot.AjouterCanal(context.Background(), models.CanalNotification{
+		Nom: "hook", Type: models.CanalWebhook, Actif: true, Config: models.ConfigCanal{URL: "https://hook.test"},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	chemin := "/api/moniteurs/" + strconv.Itoa(id) + "/routes"
+
+	var definies RequeteRoutes
+	if code := appeler(t, routes, http.MethodPut, chemin, `{"routes": [{"canal_id": `+strconv.Itoa(canal.ID)+`}]}`, &definies); code != http.StatusOK {
+		t.Fatalf("PUT : 200 attendu, obtenu %d", code)
+	}
+	if len(definies.Routes) != 1 || !slices.Equal(definies.Routes[0].Types, models.TypesAlerteParDefaut) || definies.Routes[0].MoniteurID != id {
+		t.Errorf("route avec les types par défaut attendue, obtenu %+v", definies.Routes)
+	}
+
+	var lues RequeteRoutes
+	if code := appeler(t, routes, http.MethodGet, chemin, "", &lues); code != http.StatusOK || len(lues.Routes) != 1 {
+		t.Errorf("GET : une route attendue, obtenu %d %+v", code, lues.Routes)
+	}
+
+	cas := []struct {
+		chemin, corps string
+		attendu       int
+	}{
+		{chemin, `{"routes": [{"canal_id": 999}]}`, http.StatusBadRequest},
+		{chemin, `{"routes": [{"canal_id": ` + strconv.Itoa(canal.ID) + `, "types": ["PANNE"]}]}`, http.StatusBadRequest},
+		{chemin, `{"routes": [{"canal_id": ` + strconv.Itoa(canal.ID) + `}, {"canal_id": ` + strconv.Itoa(canal.ID) + `}]}`, http.StatusBadRequest},
+		{"/api/moniteurs/999/routes", `{"routes": []}`, http.StatusNotFound},
+	}
+	for _, c := range cas {
+		if code := appeler(t, routes, http.MethodPut, c.chemin, c.corps, nil); code != c.attendu {
+			t.Errorf("PUT %s %s : %d attendu, obtenu %d", c.chemin, c.corps, c.attendu, code)
+		}
+	}
+
+	// une liste vide supprime les routes
+	if code := appeler(t, routes, http.MethodPut, chemin, `{"routes": []}`, &definies); code != http.StatusOK || len(definies.Routes) != 0 {
+		t.Errorf("routes vidées attendues, obtenu %d %+v", code, definies.Routes)
+	}
+}
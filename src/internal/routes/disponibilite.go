@@ -0,0 +1,112 @@
+/* Route HTTP de disponibilité (uptime / SLA)
+ * Projet de session A25
+ * By : Leandre Kanmegne
+ *
+ * - GET /api/moniteurs/{id}/uptime : disponibilité, temps indisponible, incidents, MTTR et MTBF
+ *       ?from=2025-10-01T00:00:00Z&to=2025-11-01T00:00:00Z (par défaut : les 30 derniers jours)
+ * La réponse contient aussi les cumuls 24h, 7d, 30d et 90d jusqu'à maintenant
+ * Les statuts vérifiés pendant une maintenance sont exclus du calcul
+ */
+
+package routes
+
+import (
+	"fmt"
+	"net/http"
+	"net/url"
+	"strconv"
+	"time"
+
+	"example.com/go-hello/src/internal/models"
+)
+
+// Période du rapport si from et to ne sont pas donnés
+const periodeDisponibiliteParDefaut = 30 * 24 * time.Hour
+
+// Période max d'un rapport (from -> to)
+const periodeDisponibiliteMax = 366 * 24 * time.Hour
+
+// Représente la disponibilité d'un moniteur pour l'API
+type DisponibiliteVue struct {
+	MoniteurID int                                    `json:"moniteur_id"`
+	Periode    models.RapportDisponibilite            `json:"periode"`
+	Cumuls     map[string]models.RapportDisponibilite `json:"cumuls"`
+}
+
+// Lit une date RFC 3339 de la query string (zéro si absente)
+func lireDateRequete(requete url.Values, cle string) (time.Time, error) {
+	valeur := requete.Get(cle)
+	if valeur == "" {
+		return time.Time{}, nil
+	}
+	date, err := time.Parse(time.RFC3339, valeur)
+	if err != nil {
+		return time.Time{}, fmt.Errorf("%s : date RFC 3339 attendue (ex: 2025-10-01T00:00:00Z)", cle)
+	}
+	return date, nil
+}
+
+// Retourne la disponibilité d'un moniteur
+func HandlerDisponibiliteMoniteur(app ServicesApp) http.HandlerFunc {
+	return func(w http.ResponseWriter, req *http.Request) {
+		activerCORS(w)
+
+		switch req.Method {
+		case http.MethodOptions:
+			w.WriteHeader(http.StatusNoContent)
+
+		case http.MethodGet:
+			id, err := strconv.Atoi(req.PathValue("id"))
+			if err != nil || id <= 0 {
+				ecrireErreur(w, http.StatusBadRequest, "id de moniteur invalide")
+				return
+			}
+
+			maintenant := time.Now().UTC()
+			depuis, err := lireDateRequete(req.URL.Query(), "from")
+			if err != nil {
+				ecrireErreur(w, http.StatusBadRequest, err.Error())
+				return
+			}
+			jusqua, err := lireDateRequete(req.URL.Query(), "to")
+			if err != nil {
+				ecrireErreur(w, http.StatusBadRequest, err.Error())
+				return
+			}
+			if jusqua.IsZero() {
+				jusqua = maintenant
+			}
+			if depuis.IsZero() {
+				depuis = jusqua.Add(-periodeDisponibiliteParDefaut)
+			}
+			if !jusqua.After(depuis) || jusqua.Sub(depuis) > periodeDisponibiliteMax {
+				ecrireErreur(w, http.StatusBadRequest, "to doit être après from (période max 366 jours)")
+				return
+			}
+
+			if _, err := app.Depot.ObtenirMoniteur(req.Context(), id); err != nil {
+				ecrireErreurDepot(w, err)
+				return
+			}
+
+			vue := DisponibiliteVue{MoniteurID: id, Cumuls: map[string]models.RapportDisponibilite{}}
+			if vue.Periode, err = app.Depot.DisponibiliteMoniteur(req.Context(), id, depuis, jusqua); err != nil {
+				ecrireErreurDepot(w, err)
+				return
+			}
+			for _, periode := range models.PeriodesDisponibilite {
+				rapport, err := app.Depot.DisponibiliteMoniteur(req.Context(), id, maintenant.Add(-periode.Duree), maintenant)
+				if err != nil {
+					ecrireErreurDepot(w, err)
+					return
+				}
+				vue.Cumuls[periode.Cle] = rapport
+			}
+			ecrireJSON(w, http.StatusOK, vue)
+
+		default:
+			w.Header().Set("Allow", "GET, OPTIONS")
+			ecrireErreur(w, http.StatusMethodNotAllowed, "méthode non autorisée")
+		}
+	}
+}
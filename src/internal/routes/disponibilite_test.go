@@ -0,0 +1,48 @@
+/* Tests de la route de disponibilité (uptime)
+ * Projet de session A25
+ * By : Leandre Kanmegne
+ */
+package routes
+
+import (
+	"net/http"
+	"strconv"
+	"testing"
+
+	"example.com/go-hello/src/internal/models"
+)
+
+// test : validation de from et to, moniteur inconnu, rapport et cumuls
+func TestHandlerDisponibiliteMoniteur(t *testing.T) {
+	depot, routes := nouvelleAppTest(t)
+	id := ajouterMoniteurTest(t, depot, models.Moniteur{Nom: "Uptime", URL: "https://uptime.test", Actif: true})
+	chemin := "/api/moniteurs/" + strconv.Itoa(id) + "/uptime"
+
+	cas := []struct {
+		chemin  string
+		attendu int
+	}{
+		{chemin + "?from=hier", http.StatusBadRequest},
+		{chemin + "?to=2025-10-01", http.StatusBadRequest},
+		{chemin + "?from=2025-10-02T00:00:00Z&to=2025-10-01T00:00:00Z", http.StatusBadRequest},
+		{chemin + "?from=2024-01-01T00:00:00Z&to=2025-10-01T00:00:00Z", http.StatusBadRequest}, // plus de 366 jours
+		{"/api/moniteurs/abc/uptime", http.StatusBadRequest},
+		{"/api/moniteurs/999/uptime", http.StatusNotFound},
+	}
+	for _, c := range cas {
+		if code := appeler(t, routes, http.MethodGet, c.chemin, "", nil); code != c.attendu {
+			t.Errorf("%s : %d attendu, obtenu %d", c.chemin, c.attendu, code)
+		}
+	}
+
+	var vue DisponibiliteVue
+	if code := appeler(t, routes, http.MethodGet, chemin+"?from=2025-10-01T00:00:00Z&to=2025-10-02T00:00:00Z", "", &vue); code != http.StatusOK {
+		t.Fatalf("200 attendu, obtenu %d", code)
+	}
+	if vue.MoniteurID != id || len(vue.Cumuls) != len(models.PeriodesDisponibilite) {
+		t.Errorf("rapport avec tous les cumuls attendu, obtenu %+v", vue)
+	}
+	if code := appeler(t, routes, http.MethodPost, chemin, "", nil); code != http.StatusMethodNotAllowed {
+		t.Errorf("POST : 405 attendu, obtenu %d", code)
+	}
+}
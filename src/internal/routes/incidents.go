@@ -0,0 +1,208 @@
+/* Routes HTTP des incidents
+ * Projet de session A25
+ * By : Leandre Kanmegne
+ *
+ * - GET  /api/incidents                   : liste filtrée des incidents
+ *        ?moniteur_id=1&depuis=2025-10-01T00:00:00Z&jusqua=...&statut=ouvert|ferme&limit=50
+ * - GET  /api/incidents/{id}              : détail d'un incident
+ * - POST /api/incidents/{id}/acquittement : acquitte l'incident {"par": "alice", "note": "redémarrage en cours"}
+ * Un incident s'ouvre sur une alerte DOWN et se ferme sur l'alerte UP suivante (trigger de transition)
+ * Retourne 409 si l'incident est déjà acquitté
+ */
+
+package routes
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	"example.com/go-hello/src/internal/models"
+)
+
+// Nombre max d'incidents retournés par requête
+const limiteMaxIncidents = 500
+
+// Longueur max de la note d'acquittement
+const longueurMaxNote = 2000
+
+// Représente un incident pour l'API (avec sa durée)
+type IncidentVue struct {
+	models.Incident
+	EnCours       bool  `json:"en_cours"`
+	DureeSecondes int64 `json:"duree_secondes"`
+}
+
+// Représente le body d'un acquittement
+type RequeteAcquittement struct {
+	Par  string `json:"par"`
+	Note string `json:"note"`
+}
+
+// Ajoute la durée et l'indicateur en cours à un incident
+func incidentVue(incident models.Incident, maintenant time.Time) IncidentVue {
+	return IncidentVue{
+		Incident:      incident,
+		EnCours:       incident.FermeA == nil,
+		DureeSecondes: int64(incident.Duree(maintenant).Seconds()),
+	}
+}
+
+// Lit les filtres de la query string
+func lireFiltreIncidents(requete url.Values) (models.FiltreIncidents, error) {
+	var filtre models.FiltreIncidents
+	var problemes []string
+
+	if valeur := requete.Get("moniteur_id"); valeur != "" {
+		id, err := strconv.Atoi(valeur)
+		if err != nil || id <= 0 {
+			problemes = append(problemes, "moniteur_id invalide")
+		}
+		filtre.MoniteurID = id
+	}
+	for cle, destination := range map[string]*time.Time{"depuis": &filtre.Depuis, "jusqua": &filtre.Jusqua} {
+		date, err := lireDateRequete(requete, cle)
+		if err != nil {
+			problemes = append(problemes, err.Error())
+		}
+		*destination = date
+	}
+	if !filtre.Depuis.IsZero() && !filtre.Jusqua.IsZero() && !filtre.Jusqua.After(filtre.Depuis) {
+		problemes = append(problemes, "jusqua doit être après depuis")
+	}
+	switch requete.Get("statut") {
+	case "":
+	case "ouvert":
+		ouverts := true
+		filtre.Ouverts = &ouverts
+	case "ferme":
+		ouverts := false
+		filtre.Ouverts = &ouverts
+	default:
+		problemes = append(problemes, "statut doit être ouvert ou ferme")
+	}
+	if valeur := requete.Get("limit"); valeur != "" {
+		n, err := strconv.Atoi(valeur)
+		if err != nil || n <= 0 {
+			problemes = append(problemes, "limit invalide")
+		}
+		filtre.Limite = min(n, limiteMaxIncidents)
+	}
+
+	if len(problemes) > 0 {
+		return models.FiltreIncidents{}, errors.New(strings.Join(problemes, ", "))
+	}
+	return filtre, nil
+}
+
+// Liste les incidents
+func HandlerIncidents(app ServicesApp) http.HandlerFunc {
+	return func(w http.ResponseWriter, req *http.Request) {
+		activerCORS(w)
+
+		switch req.Method {
+		case http.MethodOptions:
+			w.WriteHeader(http.StatusNoContent)
+
+		case http.MethodGet:
+			filtre, err := lireFiltreIncidents(req.URL.Query())
+			if err != nil {
+				ecrireErreur(w, http.StatusBadRequest, err.Error())
+				return
+			}
+			incidents, err := app.Depot.ListerIncidents(req.Context(), filtre)
+			if err != nil {
+				ecrireErreurDepot(w, err)
+				return
+			}
+			maintenant := time.Now()
+			vues := make([]IncidentVue, 0, len(incidents))
+			for _, incident := range incidents {
+				vues = append(vues, incidentVue(incident, maintenant))
+			}
+			ecrireJSON(w, http.StatusOK, map[string]any{"incidents": vues})
+
+		default:
+			w.Header().Set("Allow", "GET, OPTIONS")
+			ecrireErreur(w, http.StatusMethodNotAllowed, "méthode non autorisée")
+		}
+	}
+}
+
+// Lit un incident
+func HandlerIncident(app ServicesApp) http.HandlerFunc {
+	return func(w http.ResponseWriter, req *http.Request) {
+		activerCORS(w)
+
+		switch req.Method {
+		case http.MethodOptions:
+			w.WriteHeader(http.StatusNoContent)
+
+		case http.MethodGet:
+			id, err := strconv.Atoi(req.PathValue("id"))
+			if err != nil || id <= 0 {
+				ecrireErreur(w, http.StatusBadRequest, "id d'incident invalide")
+				return
+			}
+			incident, err := app.Depot.ObtenirIncident(req.Context(), id)
+			if err != nil {
+				ecrireErreurDepot(w, err)
+				return
+			}
+			ecrireJSON(w, http.StatusOK, incidentVue(incident, time.Now()))
+
+		default:
+			w.Header().Set("Allow", "GET, OPTIONS")
+			ecrireErreur(w, http.StatusMethodNotAllowed, "méthode non autorisée")
+		}
+	}
+}
+
+// Acquitte un incident
+func HandlerAcquittementIncident(app ServicesApp) http.HandlerFunc {
+	return func(w http.ResponseWriter, req *http.Request) {
+		activerCORS(w)
+
+		switch req.Method {
+		case http.MethodOptions:
+			w.WriteHeader(http.StatusNoContent)
+
+		case http.MethodPost:
+			id, err := strconv.Atoi(req.PathValue("id"))
+			if err != nil || id <= 0 {
+				ecrireErreur(w, http.StatusBadRequest, "id d'incident invalide")
+				return
+			}
+			var body RequeteAcquittement
+			if err := lireJSON(w, req, &body); err != nil {
+				ecrireErreur(w, http.StatusBadRequest, err.Error())
+				return
+			}
+			body.Par = strings.TrimSpace(body.Par)
+			body.Note = strings.TrimSpace(body.Note)
+			if body.Par == "" || len(body.Par) > longueurMaxNom {
+				ecrireErreur(w, http.StatusBadRequest, fmt.Sprintf("par obligatoire (max %d caractères)", longueurMaxNom))
+				return
+			}
+			if len(body.Note) > longueurMaxNote {
+				ecrireErreur(w, http.StatusBadRequest, fmt.Sprintf("note trop longue (max %d caractères)", longueurMaxNote))
+				return
+			}
+
+			incident, err := app.Depot.AcquitterIncident(req.Context(), id, body.Par, body.Note)
+			if err != nil {
+				ecrireErreurDepot(w, err)
+				return
+			}
+			ecrireJSON(w, http.StatusOK, incidentVue(incident, time.Now()))
+
+		default:
+			w.Header().Set("Allow", "POST, OPTIONS")
+			ecrireErreur(w, http.StatusMethodNotAllowed, "méthode non autorisée")
+		}
+	}
+}
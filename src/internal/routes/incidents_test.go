@@ -0,0 +1,120 @@
+/* Tests des routes des incidents et de leur acquittement
+ * Projet de session A25
+ * By : Leandre Kanmegne
+ */
+package routes
+
+import (
+	"context"
+	"net/http"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"example.com/go-hello/src/internal/models"
+	"example.com/go-hello/src/repos"
+)
+
+// Ouvre un incident sur un nouveau moniteur (statut up puis down) et retourne son ID
+func ouvrirIncidentTest(t *testing.T, depot *repos.Memoire) int {
+	t.Helper()
+	ctx := context.Background()
+	id := ajouterMoniteurTest(t, depot, models.Moniteur{Nom: "En panne", URL: "https://panne.test", Actif: true})
+	debut := time.Now().Add(-time.Hour)
+	for i, etat := range []string{models.EtatUp, models.EtatDown} {
+		statut := models.StatutMoniteur{
+			MoniteurID:    id,
+			URL:           "https://panne.test",
+			EstDisponible: etat == models.EtatUp,
+			Etat:          etat,
+			VerifieA:      debut.Add(time.Duration(i) * time.Minute),
+		}
+		if err := depot.EnregistrerStatutMoniteur(ctx, statut); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	ouverts := true
+	incidents, err := depot.ListerIncidents(ctx, models.FiltreIncidents{MoniteurID: id, Ouverts: &ouverts})
+	if err != nil || len(incidents) != 1 {
+		t.Fatalf("un incident ouvert attendu, obtenu %+v (%v)", incidents, err)
+	}
+	return incidents[0].ID
+}
+
+// test : acquittement d'un incident puis refus du second acquittement (409)
+func TestHandlerAcquittementIncident(t *testing.T) {
+	depot, routes := nouvelleAppTest(t)
+	id := ouvrirIncidentTest(t, depot)
+	chemin := "/api/incidents/" + strconv.Itoa(id) + "/acquittement"
+
+	cas := []struct {
+		chemin, corps string
+		attendu       int
+	}{
+		{chemin, `{"note": "sans auteur"}`, http.StatusBadRequest},
+		{chemin, `{"par": "alice", "note": "` + strings.Repeat("x", longueurMaxNote+1) + `"}`, http.StatusBadRequest},
+		{chemin, `{"par": "alice", "inconnu": true}`, http.StatusBadRequest},
+		{"/api/incidents/abc/acquittement", `{"par": "alice"}`, http.StatusBadRequest},
+		{"/api/incidents/999/acquittement", `{"par": "alice"}`, http.StatusNotFound},
+	}
+	for _, c := range cas {
+		if code := appeler(t, routes, http.MethodPost, c.chemin, c.corps, nil); code != c.attendu {
+			t.Errorf("POST %s %.40s : %d attendu, obtenu %d", c.chemin, c.corps, c.attendu, code)
+		}
+	}
+
+	var acquitte IncidentVue
+	if code := appeler(t, routes, http.MethodPost, chemin, `{"par": " alice ", "note": "redémarrage en cours"}`, &acquitte); code != http.StatusOK {
+		t.Fatalf("200 attendu, obtenu %d", code)
+	}
+	if acquitte.AcquittePar != "alice" || acquitte.AcquitteA == nil || !acquitte.EnCours || acquitte.NoteAcquittement != "redémarrage en cours" {
+		t.Errorf("incident acquitté par alice attendu, obtenu %+v", acquitte)
+	}
+
+	if code := appeler(t, routes, http.MethodPost, chemin, `{"par": "bob"}`, nil); code != http.StatusConflict {
+		t.Errorf("second acquittement : 409 attendu, obtenu %d", code)
+	}
+	if code := appeler(t, routes, http.MethodGet, chemin, "", nil); code != http.StatusMethodNotAllowed {
+		t.Errorf("GET : 405 attendu, obtenu %d", code)
+	}
+}
+
+// test : liste filtrée des incidents et validation des filtres
+func TestHandlerIncidents_Filtres(t *testing.T) {
+	depot, routes := nouvelleAppTest(t)
+	id := ouvrirIncidentTest(t, depot)
+
+	var liste struct {
+		Incidents []IncidentVue `json:"incidents"`
+	}
+	if code := appeler(t, routes, http.MethodGet, "/api/incidents?statut=ouvert&limit=10", "", &liste); code != http.StatusOK ||
+		len(liste.Incidents) != 1 || liste.Incidents[0].ID != id {
+		t.Errorf("un incident ouvert attendu, obtenu %d %+v", code, liste.Incidents)
+	}
+	if code := appeler(t, routes, http.MethodGet, "/api/incidents?statut=ferme", "", &liste); code != http.StatusOK || len(liste.Incidents) != 0 {
+		t.Errorf("aucun incident fermé attendu, obtenu %d %+v", code, liste.Incidents)
+	}
+
+	var incident IncidentVue
+	if code := appeler(t, routes, http.MethodGet, "/api/incidents/"+strconv.Itoa(id), "", &incident); code != http.StatusOK || incident.DureeSecondes <= 0 {
+		t.Errorf("incident en cours avec une durée attendu, obtenu %d %+v", code, incident)
+	}
+
+	for _, chemin := range []string{
+		"/api/incidents?statut=resolu",
+		"/api/incidents?moniteur_id=abc",
+		"/api/incidents?limit=0",
+		"/api/incidents?depuis=hier",
+		"/api/incidents?depuis=2025-10-02T00:00:00Z&jusqua=2025-10-01T00:00:00Z",
+		"/api/incidents/abc",
+	} {
+		if code := appeler(t, routes, http.MethodGet, chemin, "", nil); code != http.StatusBadRequest {
+			t.Errorf("%s : 400 attendu, obtenu %d", chemin, code)
+		}
+	}
+	if code := appeler(t, routes, http.MethodGet, "/api/incidents/999", "", nil); code != http.StatusNotFound {
+		t.Errorf("incident inconnu : 404 attendu, obtenu %d", code)
+	}
+}
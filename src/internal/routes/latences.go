@@ -0,0 +1,122 @@
+/* Route HTTP des agrégats de latence
+ * Projet de session A25
+ * By : Leandre Kanmegne
+ *
+ * - GET /api/moniteurs/{id}/latences : p50, p90, p95, p99, min, max, moyenne et taux de succès par intervalle
+ *       ?intervalle=1m|5m|1h|1d&from=2025-10-01T00:00:00Z&to=2025-10-02T00:00:00Z
+ * Par défaut : les dernières 24h, avec l'intervalle le plus fin qui donne au plus 300 points (5m sur 24h)
+ * Les intervalles 1h et 1d sont lus dans les agrégats consolidés quand ils existent
+ * Le calcul est fait dans PostgreSQL pour que le tableau de bord ne télécharge pas tous les statuts
+ */
+
+package routes
+
+import (
+	"fmt"
+	"net/http"
+	"strconv"
+	"time"
+
+	"example.com/go-hello/src/internal/models"
+)
+
+// Période par défaut des agrégats
+const periodeAgregatsParDefaut = 24 * time.Hour
+
+// Intervalles du plus fin au plus grossier, pour le choix automatique
+var intervallesAuto = []string{"1m", "5m", "1h", "1d"}
+
+// Nombre de points visé quand l'intervalle n'est pas donné
+const pointsAuto = 300
+
+// Nombre max d'intervalles par requête (ex: 1m sur 24h = 1440)
+const intervallesMaxParRequete = 1500
+
+// Choisit l'intervalle le plus fin qui donne au plus pointsAuto intervalles sur la période
+func intervalleAuto(periode time.Duration) string {
+	for _, cle := range intervallesAuto {
+		if periode/models.IntervallesAgregat[cle] <= pointsAuto {
+			return cle
+		}
+	}
+	return intervallesAuto[len(intervallesAuto)-1]
+}
+
+// Retourne les agrégats de latence d'un moniteur
+func HandlerLatencesMoniteur(app ServicesApp) http.HandlerFunc {
+	return func(w http.ResponseWriter, req *http.Request) {
+		activerCORS(w)
+
+		switch req.Method {
+		case http.MethodOptions:
+			w.WriteHeader(http.StatusNoContent)
+
+		case http.MethodGet:
+			id, err := strconv.Atoi(req.PathValue("id"))
+			if err != nil || id <= 0 {
+				ecrireErreur(w, http.StatusBadRequest, "id de moniteur invalide")
+				return
+			}
+
+			requete := req.URL.Query()
+			depuis, err := lireDateRequete(requete, "from")
+			if err != nil {
+				ecrireErreur(w, http.StatusBadRequest, err.Error())
+				return
+			}
+			jusqua, err := lireDateRequete(requete, "to")
+			if err != nil {
+				ecrireErreur(w, http.StatusBadRequest, err.Error())
+				return
+			}
+			if jusqua.IsZero() {
+				jusqua = time.Now().UTC()
+			}
+			if depuis.IsZero() {
+				depuis = jusqua.Add(-periodeAgregatsParDefaut)
+			}
+			if !jusqua.After(depuis) {
+				ecrireErreur(w, http.StatusBadRequest, "to doit être après from")
+				return
+			}
+			cle := requete.Get("intervalle")
+			if cle == "" {
+				cle = intervalleAuto(jusqua.Sub(depuis))
+			}
+			intervalle, ok := models.IntervallesAgregat[cle]
+			if !ok {
+				ecrireErreur(w, http.StatusBadRequest, "intervalle doit être 1m, 5m, 1h ou 1d")
+				return
+			}
+			if jusqua.Sub(depuis)/intervalle > intervallesMaxParRequete {
+				ecrireErreur(w, http.StatusBadRequest,
+					fmt.Sprintf("période trop longue pour l'intervalle %s (max %d intervalles)", cle, intervallesMaxParRequete))
+				return
+			}
+
+			if _, err := app.Depot.ObtenirMoniteur(req.Context(), id); err != nil {
+				ecrireErreurDepot(w, err)
+				return
+			}
+			agregats, err := app.Depot.AgregatsLatence(req.Context(), id, depuis, jusqua, intervalle)
+			if err != nil {
+				ecrireErreurDepot(w, err)
+				return
+			}
+			if agregats == nil {
+				agregats = []models.AgregatLatence{}
+			}
+			ecrireJSON(w, http.StatusOK, map[string]any{
+				"moniteur_id": id,
+				"intervalle":  cle,
+				"depuis":      depuis,
+				"jusqua":      jusqua,
+				"agregats":    agregats,
+			})
+
+		default:
+			w.Header().Set("Allow", "GET, OPTIONS")
+			ecrireErreur(w, http.StatusMethodNotAllowed, "méthode non autorisée")
+		}
+	}
+}
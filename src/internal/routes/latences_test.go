@@ -0,0 +1,52 @@
+/* Tests de la route des agrégats de latence
+ * Projet de session A25
+ * By : Leandre Kanmegne
+ */
+package routes
+
+import (
+	"net/http"
+	"strconv"
+	"testing"
+	"time"
+
+	"example.com/go-hello/src/internal/models"
+)
+
+// test : validation de l'intervalle et de la période, choix automatique de l'intervalle
+func TestHandlerLatencesMoniteur(t *testing.T) {
+	depot, routes := nouvelleAppTest(t)
+	id := ajouterMoniteurTest(t, depot, models.Moniteur{Nom: "Latences", URL: "https://latences.test", Actif: true})
+	chemin := "/api/moniteurs/" + strconv.Itoa(id) + "/latences"
+
+	cas := []struct {
+		chemin  string
+		attendu int
+	}{
+		{chemin + "?intervalle=2h", http.StatusBadRequest},
+		{chemin + "?from=hier", http.StatusBadRequest},
+		{chemin + "?from=2025-10-02T00:00:00Z&to=2025-10-01T00:00:00Z", http.StatusBadRequest},
+		{chemin + "?intervalle=1m&from=2025-10-01T00:00:00Z&to=2025-10-03T00:00:00Z", http.StatusBadRequest}, // 2880 intervalles
+		{"/api/moniteurs/abc/latences", http.StatusBadRequest},
+		{"/api/moniteurs/999/latences", http.StatusNotFound},
+	}
+	for _, c := range cas {
+		if code := appeler(t, routes, http.MethodGet, c.chemin, "", nil); code != c.attendu {
+			t.Errorf("%s : %d attendu, obtenu %d", c.chemin, c.attendu, code)
+		}
+	}
+
+	var reponse struct {
+		MoniteurID int                     `json:"moniteur_id"`
+		Intervalle string                  `json:"intervalle"`
+		Depuis     time.Time               `json:"depuis"`
+		Agregats   []models.AgregatLatence `json:"agregats"`
+	}
+	if code := appeler(t, routes, http.MethodGet, chemin, "", &reponse); code != http.StatusOK {
+		t.Fatalf("200 attendu, obtenu %d", code)
+	}
+	// 24h par défaut : 5m est l'intervalle le plus fin avec au plus 300 points
+	if reponse.MoniteurID != id || reponse.Intervalle != "5m" || reponse.Agregats == nil {
+		t.Errorf("agrégats vides en 5m attendus, obtenu %+v", reponse)
+	}
+}
@@ -0,0 +1,201 @@
+/* Routes HTTP des fenêtres de maintenance
+ * Projet de session A25
+ * By : Leandre Kanmegne
+ *
+ * - GET    /api/maintenances      : liste des maintenances (avec "active" pour l'instant présent)
+ * - POST   /api/maintenances      : crée une maintenance
+ * - GET    /api/maintenances/{id} : détail d'une maintenance
+ * - PUT    /api/maintenances/{id} : remplace une maintenance
+ * - DELETE /api/maintenances/{id} : supprime une maintenance
+ * Ponctuelle : {"nom", "debut", "fin", "moniteurs": [1, 2]}
+ * Récurrente : {"nom", "cron": "0 2 * * 0", "duree_minutes": 60, "fuseau": "America/Toronto", "moniteurs": [1]}
+ * Les vérifications continuent pendant une maintenance, mais les statuts sont marqués et les alertes suspendues
+ */
+
+package routes
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"example.com/go-hello/src/internal/models"
+)
+
+// Représente une maintenance pour l'API
+type MaintenanceVue struct {
+	models.Maintenance
+	Active bool `json:"active"`
+}
+
+// Valide et normalise une maintenance
+func validerMaintenance(maintenance *models.Maintenance) error {
+	var problemes []string
+
+	maintenance.Nom = strings.TrimSpace(maintenance.Nom)
+	maintenance.Cron = strings.TrimSpace(maintenance.Cron)
+	if maintenance.Nom == "" {
+		problemes = append(problemes, "nom obligatoire")
+	} else if len(maintenance.Nom) > longueurMaxNom {
+		problemes = append(problemes, fmt.Sprintf("nom trop long (max %d caractères)", longueurMaxNom))
+	}
+
+	if maintenance.Cron == "" {
+		if maintenance.Debut == nil || maintenance.Fin == nil {
+			problemes = append(problemes, "debut et fin obligatoires pour une maintenance ponctuelle (ou cron)")
+		}
+		if maintenance.DureeMinutes != 0 {
+			problemes = append(problemes, "duree_minutes réservé aux maintenances récurrentes")
+		}
+	} else {
+		if _, err := models.ParserCron(maintenance.Cron); err != nil {
+			problemes = append(problemes, err.Error())
+		}
+		if maintenance.DureeMinutes <= 0 || maintenance.DureeMinutes > models.DureeMaxMaintenanceMinutes {
+			problemes = append(problemes, fmt.Sprintf("duree_minutes doit être entre 1 et %d", models.DureeMaxMaintenanceMinutes))
+		}
+	}
+	if maintenance.Debut != nil && maintenance.Fin != nil && !maintenance.Fin.After(*maintenance.Debut) {
+		problemes = append(problemes, "fin doit être après debut")
+	}
+
+	if maintenance.Fuseau == "" {
+		maintenance.Fuseau = "UTC"
+	}
+	if _, err := time.LoadLocation(maintenance.Fuseau); err != nil {
+		problemes = append(problemes, fmt.Sprintf("fuseau %q inconnu", maintenance.Fuseau))
+	}
+
+	if len(maintenance.MoniteurIDs) == 0 {
+		problemes = append(problemes, "moniteurs obligatoire (au moins un id)")
+	}
+	for _, id := range maintenance.MoniteurIDs {
+		if id <= 0 {
+			problemes = append(problemes, fmt.Sprintf("id de moniteur %d invalide", id))
+		}
+	}
+
+	if len(problemes) > 0 {
+		return errors.New(strings.Join(problemes, ", "))
+	}
+	return nil
+}
+
+// Lit et valide le body d'une maintenance
+func lireMaintenance(w http.ResponseWriter, req *http.Request) (models.Maintenance, error) {
+	var maintenance models.Maintenance
+	if err := lireJSON(w, req, &maintenance); err != nil {
+		return models.Maintenance{}, err
+	}
+	maintenance.ID = 0
+	maintenance.CreeA = time.Time{}
+	return maintenance, validerMaintenance(&maintenance)
+}
+
+// Ajoute l'indicateur "active" à une maintenance
+func maintenanceVue(maintenance models.Maintenance, maintenant time.Time) MaintenanceVue {
+	if maintenance.MoniteurIDs == nil {
+		maintenance.MoniteurIDs = []int{}
+	}
+	return MaintenanceVue{Maintenance: maintenance, Active: maintenance.ActiveA(maintenant)}
+}
+
+// Liste ou crée des maintenances
+func HandlerMaintenances(app ServicesApp) http.HandlerFunc {
+	return func(w http.ResponseWriter, req *http.Request) {
+		activerCORS(w)
+
+		switch req.Method {
+		case http.MethodOptions:
+			w.WriteHeader(http.StatusNoContent)
+
+		case http.MethodGet:
+			maintenances, err := app.Depot.ListerMaintenances(req.Context())
+			if err != nil {
+				ecrireErreurDepot(w, err)
+				return
+			}
+			maintenant := time.Now()
+			vues := make([]MaintenanceVue, 0, len(maintenances))
+			for _, maintenance := range maintenances {
+				vues = append(vues, maintenanceVue(maintenance, maintenant))
+			}
+			ecrireJSON(w, http.StatusOK, map[string]any{"maintenances": vues})
+
+		case http.MethodPost:
+			maintenance, err := lireMaintenance(w, req)
+			if err != nil {
+				ecrireErreur(w, http.StatusBadRequest, err.Error())
+				return
+			}
+			cree, err := app.Depot.AjouterMaintenance(req.Context(), maintenance)
+			if err != nil {
+				ecrireErreurDepot(w, err)
+				return
+			}
+			w.Header().Set("Location", "/api/maintenances/"+strconv.Itoa(cree.ID))
+			ecrireJSON(w, http.StatusCreated, maintenanceVue(cree, time.Now()))
+
+		default:
+			w.Header().Set("Allow", "GET, POST, OPTIONS")
+			ecrireErreur(w, http.StatusMethodNotAllowed, "méthode non autorisée")
+		}
+	}
+}
+
+// Lit, remplace ou supprime une maintenance
+func HandlerMaintenance(app ServicesApp) http.HandlerFunc {
+	return func(w http.ResponseWriter, req *http.Request) {
+		activerCORS(w)
+
+		if req.Method == http.MethodOptions {
+			w.WriteHeader(http.StatusNoContent)
+			return
+		}
+
+		id, err := strconv.Atoi(req.PathValue("id"))
+		if err != nil || id <= 0 {
+			ecrireErreur(w, http.StatusBadRequest, "id de maintenance invalide")
+			return
+		}
+
+		existante, err := app.Depot.ObtenirMaintenance(req.Context(), id)
+		if err != nil {
+			ecrireErreurDepot(w, err)
+			return
+		}
+
+		switch req.Method {
+		case http.MethodGet:
+			ecrireJSON(w, http.StatusOK, maintenanceVue(existante, time.Now()))
+
+		case http.MethodPut:
+			maintenance, err := lireMaintenance(w, req)
+			if err != nil {
+				ecrireErreur(w, http.StatusBadRequest, err.Error())
+				return
+			}
+			maintenance.ID = id
+			maintenance.CreeA = existante.CreeA
+			if err := app.Depot.MettreAJourMaintenance(req.Context(), maintenance); err != nil {
+				ecrireErreurDepot(w, err)
+				return
+			}
+			ecrireJSON(w, http.StatusOK, maintenanceVue(maintenance, time.Now()))
+
+		case http.MethodDelete:
+			if err := app.Depot.SupprimerMaintenance(req.Context(), id); err != nil {
+				ecrireErreurDepot(w, err)
+				return
+			}
+			w.WriteHeader(http.StatusNoContent)
+
+		default:
+			w.Header().Set("Allow", "GET, PUT, DELETE, OPTIONS")
+			ecrireErreur(w, http.StatusMethodNotAllowed, "méthode non autorisée")
+		}
+	}
+}
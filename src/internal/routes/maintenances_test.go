@@ -0,0 +1,85 @@
+/* Tests des routes des fenêtres de maintenance
+ * Projet de session A25
+ * By : Leandre Kanmegne
+ */
+package routes
+
+import (
+	"net/http"
+	"strconv"
+	"testing"
+	"time"
+
+	"example.com/go-hello/src/internal/models"
+)
+
+// test : maintenance ponctuelle active, remplacement par une récurrente, suppression
+func TestHandlerMaintenances_CRUD(t *testing.T) {
+	depot, routes := nouvelleAppTest(t)
+	premier := ajouterMoniteurTest(t, depot, models.Moniteur{Nom: "Premier", URL: "https://premier.test", Actif: true})
+	second := ajouterMoniteurTest(t, depot, models.Moniteur{Nom: "Second", URL: "https://second.test", Actif: true})
+	ids := strconv.Itoa(premier) + ", " + strconv.Itoa(second)
+	debut := time.Now().Add(-time.Hour).UTC().Format(time.RFC3339)
+	fin := time.Now().Add(time.Hour).UTC().Format(time.RFC3339)
+
+	var creee MaintenanceVue
+	corps := `{"nom": "Migration", "debut": "` + debut + `", "fin": "` + fin + `", "moniteurs": [` + ids + `]}`
+	if code := appeler(t, routes, http.MethodPost, "/api/maintenances", corps, &creee); code != http.StatusCreated {
+		t.Fatalf("201 attendu, obtenu %d", code)
+	}
+	if creee.ID == 0 || !creee.Active || creee.Fuseau != "UTC" || len(creee.MoniteurIDs) != 2 {
+		t.Errorf("maintenance active en UTC attendue, obtenu %+v", creee)
+	}
+	chemin := "/api/maintenances/" + strconv.Itoa(creee.ID)
+
+	var remplacee MaintenanceVue
+	corps = `{"nom": "Sauvegarde", "cron": "0 2 * * 0", "duree_minutes": 60, "fuseau": "America/Toronto", "moniteurs": [` + strconv.Itoa(premier) + `]}`
+	if code := appeler(t, routes, http.MethodPut, chemin, corps, &remplacee); code != http.StatusOK {
+		t.Fatalf("PUT : 200 attendu, obtenu %d", code)
+	}
+	if remplacee.ID != creee.ID || remplacee.Cron != "0 2 * * 0" || remplacee.Debut != nil {
+		t.Errorf("maintenance récurrente attendue, obtenu %+v", remplacee)
+	}
+
+	var liste struct {
+		Maintenances []MaintenanceVue `json:"maintenances"`
+	}
+	if code := appeler(t, routes, http.MethodGet, "/api/maintenances", "", &liste); code != http.StatusOK ||
+		len(liste.Maintenances) != 1 || liste.Maintenances[0].Nom != "Sauvegarde" {
+		t.Errorf("une maintenance listée attendue, obtenu %d %+v", code, liste.Maintenances)
+	}
+
+	if code := appeler(t, routes, http.MethodDelete, chemin, "", nil); code != http.StatusNoContent {
+		t.Errorf("DELETE : 204 attendu, obtenu %d", code)
+	}
+	if code := appeler(t, routes, http.MethodGet, chemin, "", nil); code != http.StatusNotFound {
+		t.Errorf("après suppression : 404 attendu, obtenu %d", code)
+	}
+}
+
+// test : maintenances invalides (400) et inconnues (404)
+func TestHandlerMaintenances_Erreurs(t *testing.T) {
+	_, routes := nouvelleAppTest(t)
+
+	cas := []struct {
+		methode, chemin, corps string
+		attendu                int
+	}{
+		{http.MethodPost, "/api/maintenances", `{"nom": "sans dates", "moniteurs": [1]}`, http.StatusBadRequest},
+		{http.MethodPost, "/api/maintenances", `{"nom": "inversée", "debut": "2025-10-02T00:00:00Z", "fin": "2025-10-01T00:00:00Z", "moniteurs": [1]}`, http.StatusBadRequest},
+		{http.MethodPost, "/api/maintenances", `{"nom": "cron", "cron": "0 25 * * *", "duree_minutes": 30, "moniteurs": [1]}`, http.StatusBadRequest},
+		{http.MethodPost, "/api/maintenances", `{"nom": "sans durée", "cron": "0 2 * * *", "moniteurs": [1]}`, http.StatusBadRequest},
+		{http.MethodPost, "/api/maintenances", `{"nom": "fuseau", "cron": "0 2 * * *", "duree_minutes": 30, "fuseau": "Mars/Olympus", "moniteurs": [1]}`, http.StatusBadRequest},
+		{http.MethodPost, "/api/maintenances", `{"nom": "sans moniteur", "cron": "0 2 * * *", "duree_minutes": 30}`, http.StatusBadRequest},
+		{http.MethodPost, "/api/maintenances", `{"nom": "moniteur inconnu", "cron": "0 2 * * *", "duree_minutes": 30, "moniteurs": [999]}`, http.StatusNotFound},
+		{http.MethodGet, "/api/maintenances/abc", "", http.StatusBadRequest},
+		{http.MethodGet, "/api/maintenances/999", "", http.StatusNotFound},
+		{http.MethodPut, "/api/maintenances/999", `{}`, http.StatusNotFound},
+		{http.MethodDelete, "/api/maintenances", "", http.StatusMethodNotAllowed},
+	}
+	for _, c := range cas {
+		if code := appeler(t, routes, c.methode, c.chemin, c.corps, nil); code != c.attendu {
+			t.Errorf("%s %s %s : %d attendu, obtenu %d", c.methode, c.chemin, c.corps, c.attendu, code)
+		}
+	}
+}
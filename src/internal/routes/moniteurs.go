@@ -0,0 +1,410 @@
+/* Routes HTTP de gestion des moniteurs
+ * Projet de session A25
+ * By : Leandre Kanmegne
+ *
+ * - GET    /api/moniteurs      : liste des moniteurs
+ * - POST   /api/moniteurs      : crée un moniteur
+ * - GET    /api/moniteurs/{id} : détail d'un moniteur
+ * - PUT    /api/moniteurs/{id} : remplace un moniteur
+ * - PATCH  /api/moniteurs/{id} : modifie certains champs (ex: {"actif": false})
+ * - DELETE /api/moniteurs/{id} : supprime un moniteur et son historique
+ * Utilise les motifs {id} du ServeMux de Go 1.22 (req.PathValue)
+ * Les secrets de la requête (jeton, mot de passe, en-têtes sensibles) sont masqués dans les réponses
+ * Retourne 400 si le JSON est invalide, 404 si le moniteur n'existe pas, 409 si l'URL est déjà surveillée
+ */
+
+package routes
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+
+	"example.com/go-hello/src/internal/models"
+	"example.com/go-hello/src/internal/services"
+	"example.com/go-hello/src/repos"
+)
+
+// Types de moniteurs acceptés par l'API
+var typesMoniteur = map[string]bool{
+	"http":  true,
+	"https": true,
+	"tcp":   true,
+	"dns":   true,
+}
+
+// Longueur max du nom d'un moniteur
+const longueurMaxNom = 200
+
+// Délai max entre deux tentatives d'une même vérification
+const delaiReessaiMaxMs = int(services.DelaiReessaiMax / time.Millisecond)
+
+// Représente le body pour créer ou modifier un moniteur
+// Les pointeurs permettent de distinguer un champ absent d'une valeur vide (PATCH)
+type RequeteMoniteur struct {
+	Nom                *string                    `json:"nom"`
+	URL                *string                    `json:"url"`
+	Type               *string                    `json:"type"`
+	Actif              *bool                      `json:"actif"`
+	IntervalleSecondes *int                       `json:"intervalle_secondes"`
+	Parametres         *models.ParametresMoniteur `json:"parametres"`
+	Requete            *models.RequeteHTTP        `json:"requete"`
+}
+
+// Applique les champs présents de la requête sur un moniteur
+func (r RequeteMoniteur) appliquer(moniteur *models.Moniteur) {
+	if r.Nom != nil {
+		moniteur.Nom = strings.TrimSpace(*r.Nom)
+	}
+	if r.URL != nil {
+		moniteur.URL = strings.TrimSpace(*r.URL)
+	}
+	if r.Type != nil {
+		moniteur.Type = strings.ToLower(strings.TrimSpace(*r.Type))
+	}
+	if r.Actif != nil {
+		moniteur.Actif = *r.Actif
+	}
+	if r.IntervalleSecondes != nil {
+		moniteur.IntervalleSecondes = *r.IntervalleSecondes
+	}
+	if r.Parametres != nil {
+		moniteur.Parametres = *r.Parametres
+	}
+	if r.Requete != nil {
+		moniteur.Requete = r.Requete
+	}
+}
+
+// Construit un moniteur complet (POST et PUT) avec les valeurs par défaut
+func (r RequeteMoniteur) nouveauMoniteur() models.Moniteur {
+	moniteur := models.Moniteur{Type: "http", Actif: true}
+	r.appliquer(&moniteur)
+	if moniteur.Nom == "" {
+		moniteur.Nom = moniteur.URL
+	}
+	return moniteur
+}
+
+// Valide et normalise un moniteur, retourne la liste des problèmes
+func validerMoniteur(moniteur *models.Moniteur) error {
+	var problemes []string
+
+	if !typesMoniteur[moniteur.Type] {
+		problemes = append(problemes, fmt.Sprintf("type %q non supporté", moniteur.Type))
+	}
+	if moniteur.URL == "" {
+		problemes = append(problemes, "url obligatoire")
+	} else if moniteur.Type == "http" || moniteur.Type == "https" {
+		// même normalisation que le vérificateur HTTP
+		moniteur.URL = services.NormaliserURLHTTP(moniteur.URL, moniteur.Type)
+		if lien, err := url.Parse(moniteur.URL); err != nil || lien.Host == "" {
+			problemes = append(problemes, "url invalide")
+		}
+	} else if moniteur.Type == "tcp" {
+		if _, err := services.AdresseTCP(moniteur.URL); err != nil {
+			problemes = append(problemes, err.Error())
+		}
+	}
+	if moniteur.Parametres.TCP != nil && moniteur.Type != "tcp" {
+		problemes = append(problemes, "parametres.tcp réservé aux moniteurs tcp")
+	}
+	if tls := moniteur.Parametres.TLS; tls != nil {
+		if moniteur.Type != "http" && moniteur.Type != "https" {
+			problemes = append(problemes, "parametres.tls réservé aux moniteurs http et https")
+		}
+		if tls.JoursAlerteExpiration < 0 {
+			problemes = append(problemes, "jours_alerte_expiration ne peut pas être négatif")
+		}
+	}
+	if moniteur.Requete != nil {
+		problemes = append(problemes, validerRequeteHTTP(moniteur.Type, moniteur.Requete)...)
+	}
+	if parametresHTTP := moniteur.Parametres.HTTP; parametresHTTP != nil {
+		if moniteur.Type != "http" && moniteur.Type != "https" {
+			problemes = append(problemes, "parametres.http réservé aux moniteurs http et https")
+		}
+		if _, err := services.ParserCodesAcceptes(parametresHTTP.CodesAcceptes); err != nil {
+			problemes = append(problemes, err.Error())
+		}
+		if parametresHTTP.MaxRedirections < 0 {
+			problemes = append(problemes, "max_redirections ne peut pas être négatif")
+		}
+	}
+	if contenu := moniteur.Parametres.Contenu; contenu != nil {
+		problemes = append(problemes, validerContenu(moniteur.Type, contenu)...)
+	}
+	if dns := moniteur.Parametres.DNS; dns != nil {
+		if moniteur.Type != "dns" {
+			problemes = append(problemes, "parametres.dns réservé aux moniteurs dns")
+		}
+		if dns.TypeEnregistrement != "" && !services.TypesEnregistrementDNS[strings.ToUpper(dns.TypeEnregistrement)] {
+			problemes = append(problemes, fmt.Sprintf("type_enregistrement %q non supporté (A, AAAA, CNAME, MX, TXT)", dns.TypeEnregistrement))
+		}
+	}
+	if moniteur.Nom == "" {
+		problemes = append(problemes, "nom obligatoire")
+	} else if len(moniteur.Nom) > longueurMaxNom {
+		problemes = append(problemes, fmt.Sprintf("nom trop long (max %d caractères)", longueurMaxNom))
+	}
+	if moniteur.Parametres.SeuilLatenceMs < 0 {
+		problemes = append(problemes, "seuil_latence_ms ne peut pas être négatif")
+	}
+	if confirmation := moniteur.Parametres.Confirmation; confirmation != nil {
+		if confirmation.Reessais < 0 || confirmation.Reessais > services.MaxReessais {
+			problemes = append(problemes, fmt.Sprintf("reessais doit être entre 0 et %d", services.MaxReessais))
+		}
+		if confirmation.DelaiReessaiMs < 0 || confirmation.DelaiReessaiMs > delaiReessaiMaxMs {
+			problemes = append(problemes, fmt.Sprintf("delai_reessai_ms doit être entre 0 et %d", delaiReessaiMaxMs))
+		}
+		if confirmation.EchecsAvantDown < 0 || confirmation.SuccesAvantUp < 0 {
+			problemes = append(problemes, "echecs_avant_down et succes_avant_up ne peuvent pas être négatifs")
+		}
+	}
+	if moniteur.IntervalleSecondes < 0 {
+		problemes = append(problemes, "intervalle_secondes ne peut pas être négatif")
+	}
+
+	if len(problemes) > 0 {
+		return errors.New(strings.Join(problemes, ", "))
+	}
+	return nil
+}
+
+// Méthodes acceptées pour la requête d'un moniteur
+var methodesRequete = map[string]bool{
+	http.MethodGet:     true,
+	http.MethodHead:    true,
+	http.MethodPost:    true,
+	http.MethodPut:     true,
+	http.MethodPatch:   true,
+	http.MethodDelete:  true,
+	http.MethodOptions: true,
+}
+
+// Valide et normalise la requête HTTP d'un moniteur
+func validerRequeteHTTP(typeMoniteur string, requete *models.RequeteHTTP) []string {
+	var problemes []string
+
+	if typeMoniteur != "http" && typeMoniteur != "https" {
+		problemes = append(problemes, "requete réservée aux moniteurs http et https")
+	}
+	requete.Methode = strings.ToUpper(strings.TrimSpace(requete.Methode))
+	if requete.Methode != "" && !methodesRequete[requete.Methode] {
+		problemes = append(problemes, fmt.Sprintf("methode %q non supportée", requete.Methode))
+	}
+	if requete.Methode == http.MethodHead && requete.Corps != "" {
+		problemes = append(problemes, "une requête HEAD ne peut pas avoir de corps")
+	}
+	for nom := range requete.EnTetes {
+		if nom == "" || strings.ContainsAny(nom, " :\r\n") {
+			problemes = append(problemes, fmt.Sprintf("nom d'en-tête %q invalide", nom))
+		}
+	}
+	if auth := requete.Auth; auth != nil {
+		auth.Type = strings.ToLower(strings.TrimSpace(auth.Type))
+		switch {
+		case auth.Type == "bearer" && auth.Jeton == "":
+			problemes = append(problemes, "auth bearer : jeton obligatoire")
+		case auth.Type == "basic" && auth.Utilisateur == "":
+			problemes = append(problemes, "auth basic : utilisateur obligatoire")
+		case auth.Type != "bearer" && auth.Type != "basic":
+			problemes = append(problemes, fmt.Sprintf("auth.type %q non supporté (bearer, basic)", auth.Type))
+		}
+	}
+	return problemes
+}
+
+// Copie du moniteur renvoyée par l'API, sans les secrets de la requête
+func moniteurVue(moniteur models.Moniteur) models.Moniteur {
+	moniteur.Requete = moniteur.Requete.Masquee()
+	return moniteur
+}
+
+// Valide les assertions de contenu d'un moniteur http
+func validerContenu(typeMoniteur string, contenu *models.ParametresContenu) []string {
+	var problemes []string
+
+	if typeMoniteur != "http" && typeMoniteur != "https" {
+		problemes = append(problemes, "parametres.contenu réservé aux moniteurs http et https")
+	}
+	if contenu.Regex != "" {
+		if _, err := regexp.Compile(contenu.Regex); err != nil {
+			problemes = append(problemes, fmt.Sprintf("regex invalide : %v", err))
+		}
+	}
+	for i, assertion := range contenu.JSON {
+		if strings.TrimSpace(assertion.Chemin) == "" {
+			problemes = append(problemes, fmt.Sprintf("json[%d].chemin obligatoire", i))
+		}
+	}
+	if contenu.TailleMax < 0 || contenu.TailleMax > services.TailleMaxCorps {
+		problemes = append(problemes, fmt.Sprintf("taille_max_octets doit être entre 0 et %d", services.TailleMaxCorps))
+	}
+	return problemes
+}
+
+// Décode le body JSON en refusant les champs inconnus
+func lireRequeteMoniteur(w http.ResponseWriter, req *http.Request) (RequeteMoniteur, error) {
+	var body RequeteMoniteur
+	if err := lireJSON(w, req, &body); err != nil {
+		return RequeteMoniteur{}, err
+	}
+	return body, nil
+}
+
+// Cherche un moniteur par URL
+func chercherMoniteurParURL(ctx context.Context, depot repos.Repo, url string) (models.Moniteur, bool, error) {
+	moniteurs, err := depot.ListerMoniteurs(ctx)
+	if err != nil {
+		return models.Moniteur{}, false, err
+	}
+	for _, moniteur := range moniteurs {
+		if moniteur.URL == url {
+			return moniteur, true, nil
+		}
+	}
+	return models.Moniteur{}, false, nil
+}
+
+// Traduit une erreur du dépôt en code HTTP
+func ecrireErreurDepot(w http.ResponseWriter, err error) {
+	switch {
+	case errors.Is(err, repos.ErrMoniteurIntrouvable), errors.Is(err, repos.ErrCanalIntrouvable),
+		errors.Is(err, repos.ErrMaintenanceIntrouvable), errors.Is(err, repos.ErrIncidentIntrouvable):
+		ecrireErreur(w, http.StatusNotFound, err.Error())
+	case errors.Is(err, repos.ErrMoniteurExistant), errors.Is(err, repos.ErrCanalExistant),
+		errors.Is(err, repos.ErrIncidentAcquitte):
+		ecrireErreur(w, http.StatusConflict, err.Error())
+	default:
+		ecrireErreur(w, http.StatusInternalServerError, err.Error())
+	}
+}
+
+// Liste ou crée des moniteurs
+func HandlerMoniteurs(app ServicesApp) http.HandlerFunc {
+	return func(w http.ResponseWriter, req *http.Request) {
+		activerCORS(w)
+
+		switch req.Method {
+		case http.MethodOptions:
+			w.WriteHeader(http.StatusNoContent)
+
+		case http.MethodGet:
+			moniteurs, err := app.Depot.ListerMoniteurs(req.Context())
+			if err != nil {
+				ecrireErreurDepot(w, err)
+				return
+			}
+			if moniteurs == nil {
+				moniteurs = []models.Moniteur{}
+			}
+			for i := range moniteurs {
+				moniteurs[i] = moniteurVue(moniteurs[i])
+			}
+			ecrireJSON(w, http.StatusOK, map[string]any{"moniteurs": moniteurs})
+
+		case http.MethodPost:
+			body, err := lireRequeteMoniteur(w, req)
+			if err != nil {
+				ecrireErreur(w, http.StatusBadRequest, err.Error())
+				return
+			}
+			moniteur := body.nouveauMoniteur()
+			if err := validerMoniteur(&moniteur); err != nil {
+				ecrireErreur(w, http.StatusBadRequest, err.Error())
+				return
+			}
+
+			// insertion simple : une URL déjà suivie donne ErrMoniteurExistant (409), même en cas de course
+			cree, err := app.Depot.CreerMoniteur(req.Context(), moniteur)
+			if err != nil {
+				ecrireErreurDepot(w, err)
+				return
+			}
+
+			w.Header().Set("Location", "/api/moniteurs/"+strconv.Itoa(cree.ID))
+			ecrireJSON(w, http.StatusCreated, moniteurVue(cree))
+
+		default:
+			w.Header().Set("Allow", "GET, POST, OPTIONS")
+			ecrireErreur(w, http.StatusMethodNotAllowed, "méthode non autorisée")
+		}
+	}
+}
+
+// Lit, remplace, modifie ou supprime un moniteur
+func HandlerMoniteur(app ServicesApp) http.HandlerFunc {
+	return func(w http.ResponseWriter, req *http.Request) {
+		activerCORS(w)
+
+		if req.Method == http.MethodOptions {
+			w.WriteHeader(http.StatusNoContent)
+			return
+		}
+
+		id, err := strconv.Atoi(req.PathValue("id"))
+		if err != nil || id <= 0 {
+			ecrireErreur(w, http.StatusBadRequest, "id de moniteur invalide")
+			return
+		}
+
+		existant, err := app.Depot.ObtenirMoniteur(req.Context(), id)
+		if err != nil {
+			ecrireErreurDepot(w, err)
+			return
+		}
+
+		switch req.Method {
+		case http.MethodGet:
+			ecrireJSON(w, http.StatusOK, moniteurVue(existant))
+
+		case http.MethodPut, http.MethodPatch:
+			body, err := lireRequeteMoniteur(w, req)
+			if err != nil {
+				ecrireErreur(w, http.StatusBadRequest, err.Error())
+				return
+			}
+
+			// PUT remplace tout, PATCH ne touche qu'aux champs fournis
+			moniteur := existant
+			if req.Method == http.MethodPut {
+				moniteur = body.nouveauMoniteur()
+			} else {
+				body.appliquer(&moniteur)
+			}
+			moniteur.ID = id
+			// le client renvoie les secrets masqués tels quels : on garde ceux en base
+			if moniteur.Requete != nil && moniteur.Requete != existant.Requete {
+				moniteur.Requete.RestaurerSecrets(existant.Requete)
+			}
+
+			if err := validerMoniteur(&moniteur); err != nil {
+				ecrireErreur(w, http.StatusBadRequest, err.Error())
+				return
+			}
+			if err := app.Depot.MettreAJourMoniteur(req.Context(), moniteur); err != nil {
+				ecrireErreurDepot(w, err)
+				return
+			}
+			ecrireJSON(w, http.StatusOK, moniteurVue(moniteur))
+
+		case http.MethodDelete:
+			if err := app.Depot.SupprimerMoniteur(req.Context(), existant.URL); err != nil {
+				ecrireErreurDepot(w, err)
+				return
+			}
+			w.WriteHeader(http.StatusNoContent)
+
+		default:
+			w.Header().Set("Allow", "GET, PUT, PATCH, DELETE, OPTIONS")
+			ecrireErreur(w, http.StatusMethodNotAllowed, "méthode non autorisée")
+		}
+	}
+}
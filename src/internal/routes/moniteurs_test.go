@@ -0,0 +1,97 @@
+/* Tests des routes CRUD des moniteurs
+ * Projet de session A25
+ * By : Leandre Kanmegne
+ */
+package routes
+
+import (
+	"net/http"
+	"strconv"
+	"testing"
+
+	"example.com/go-hello/src/internal/models"
+)
+
+// test : création, doublon, lecture, modification et suppression d'un moniteur
+func TestHandlerMoniteurs_CRUD(t *testing.T) {
+	_, routes := nouvelleAppTest(t)
+
+	var cree models.Moniteur
+	if code := appeler(t, routes, http.MethodPost, "/api/moniteurs", `{"nom": "Exemple", "url": "exemple.test"}`, &cree); code != http.StatusCreated {
+		t.Fatalf("201 attendu, obtenu %d", code)
+	}
+	if cree.ID == 0 || cree.URL != "http://exemple.test" || cree.Type != "http" || !cree.Actif {
+		t.Errorf("moniteur créé inattendu %+v", cree)
+	}
+	chemin := "/api/moniteurs/" + strconv.Itoa(cree.ID)
+
+	// même URL une fois normalisée : conflit
+	if code := appeler(t, routes, http.MethodPost, "/api/moniteurs", `{"url": "http://exemple.test"}`, nil); code != http.StatusConflict {
+		t.Errorf("doublon : 409 attendu, obtenu %d", code)
+	}
+
+	var liste struct {
+		Moniteurs []models.Moniteur `json:"moniteurs"`
+	}
+	if code := appeler(t, routes, http.MethodGet, "/api/moniteurs", "", &liste); code != http.StatusOK || len(liste.Moniteurs) != 1 {
+		t.Errorf("un moniteur listé attendu, obtenu %d %+v", code, liste.Moniteurs)
+	}
+
+	var modifie models.Moniteur
+	if code := appeler(t, routes, http.MethodPatch, chemin, `{"nom": "Renommé", "intervalle_secondes": 30}`, &modifie); code != http.StatusOK {
+		t.Fatalf("PATCH : 200 attendu, obtenu %d", code)
+	}
+	if modifie.Nom != "Renommé" || modifie.IntervalleSecondes != 30 || modifie.URL != cree.URL {
+		t.Errorf("PATCH ne devrait changer que les champs fournis, obtenu %+v", modifie)
+	}
+
+	var lu models.Moniteur
+	if code := appeler(t, routes, http.MethodGet, chemin, "", &lu); code != http.StatusOK || lu.Nom != "Renommé" {
+		t.Errorf("GET : moniteur modifié attendu, obtenu %d %+v", code, lu)
+	}
+
+	if code := appeler(t, routes, http.MethodDelete, chemin, "", nil); code != http.StatusNoContent {
+		t.Errorf("DELETE : 204 attendu, obtenu %d", code)
+	}
+	if code := appeler(t, routes, http.MethodGet, chemin, "", nil); code != http.StatusNotFound {
+		t.Errorf("après suppression : 404 attendu, obtenu %d", code)
+	}
+}
+
+// test : requêtes invalides (400), moniteur inconnu (404), méthode refusée (405)
+func TestHandlerMoniteurs_Erreurs(t *testing.T) {
+	depot, routes := nouvelleAppTest(t)
+	id := ajouterMoniteurTest(t, depot, models.Moniteur{Nom: "Existant", URL: "https://existant.test", Type: "https", Actif: true})
+	chemin := "/api/moniteurs/" + strconv.Itoa(id)
+
+	cas := []struct {
+		methode, chemin, corps string
+		attendu                int
+	}{
+		{http.MethodPost, "/api/moniteurs", `{"nom": "sans url"}`, http.StatusBadRequest},
+		{http.MethodPost, "/api/moniteurs", `{"url": "exemple.test", "type": "ftp"}`, http.StatusBadRequest},
+		{http.MethodPost, "/api/moniteurs", `{"url": "exemple.test", "inconnu": 1}`, http.StatusBadRequest},
+		{http.MethodPost, "/api/moniteurs", `{"url": "exemple.test", "parametres": {"http": {"codes_acceptes": "abc"}}}`, http.StatusBadRequest},
+		{http.MethodPost, "/api/moniteurs", `pas du json`, http.StatusBadRequest},
+		{http.MethodGet, "/api/moniteurs/abc", "", http.StatusBadRequest},
+		{http.MethodGet, "/api/moniteurs/0", "", http.StatusBadRequest},
+		{http.MethodGet, "/api/moniteurs/999", "", http.StatusNotFound},
+		{http.MethodPatch, "/api/moniteurs/999", `{"nom": "x"}`, http.StatusNotFound},
+		{http.MethodDelete, "/api/moniteurs/999", "", http.StatusNotFound},
+		{http.MethodPut, chemin, `{"nom": "sans url"}`, http.StatusBadRequest},
+		{http.MethodPatch, chemin, `{"intervalle_secondes": -1}`, http.StatusBadRequest},
+		{http.MethodDelete, "/api/moniteurs", "", http.StatusMethodNotAllowed},
+		{http.MethodPost, chemin, `{}`, http.StatusMethodNotAllowed},
+	}
+	for _, c := range cas {
+		if code := appeler(t, routes, c.methode, c.chemin, c.corps, nil); code != c.attendu {
+			t.Errorf("%s %s %s : %d attendu, obtenu %d", c.methode, c.chemin, c.corps, c.attendu, code)
+		}
+	}
+
+	// les requêtes refusées ne modifient pas le moniteur
+	var lu models.Moniteur
+	if appeler(t, routes, http.MethodGet, chemin, "", &lu); lu.Nom != "Existant" || lu.Type != "https" {
+		t.Errorf("moniteur modifié par une requête refusée : %+v", lu)
+	}
+}
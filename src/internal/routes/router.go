@@ -5,7 +5,15 @@
  * Définit les endpoints de l'API REST pour le monitoring
  * - /api/verifier : vérifie une URL donnée
  * - /api/resultats : récupère les derniers statuts des moniteurs
+ * - /api/moniteurs : CRUD des moniteurs (voir moniteurs.go)
+ * - /api/moniteurs/{id}/uptime : disponibilité, MTTR et MTBF (voir disponibilite.go)
+ * - /api/moniteurs/{id}/latences : percentiles de latence par intervalle (voir latences.go)
+ * - /api/canaux et /api/moniteurs/{id}/routes : canaux de notification et routage (voir canaux.go)
+ * - /api/maintenances : fenêtres de maintenance (voir maintenances.go)
+ * - /api/incidents : incidents ouverts et fermés, acquittement (voir incidents.go)
+ * - /api/admin/purge : rétention et purge manuelle de l'historique (voir admin.go)
  * - /api/etat : check de santé du serveur
+ * - /api/etat/verifications : profondeur de la file et vérifications en cours
  * Utilise le package net/http de Go pour gérer les routes et les handlers
  * Utilise le package context pour gérer les délais d'attente et annulations
  * Utilise le package encoding/json pour sérialiser/désérialiser les données JSON
@@ -35,34 +43,84 @@ import (
 
 // Regroupe les dépendances de l'app
 type ServicesApp struct {
-	Depot repos.Repo
+	Depot   repos.Repo
+	Pool    *services.PoolVerification // optionnel : borne les vérifications parallèles
+	Purgeur *services.Purgeur          // optionnel : purge manuelle de l'historique
 }
 
 // Représente le body pour vérifier une URL
 type RequeteVerification struct {
-	URL string `json:"url"`
+	URL  string `json:"url"`
+	Type string `json:"type"` // http par défaut
 }
 
 // Représente un statut pour l'API
 type StatutVue struct {
-	EstDisponible bool      `json:"est_disponible"`
-	CodeHTTP      int       `json:"code_http"`
-	LatenceMs     int64     `json:"latence_ms"`
-	MessageErreur string    `json:"message_erreur"`
-	VerifieA      time.Time `json:"verifie_a"`
-	URL           string    `json:"url"`
+	EstDisponible bool              `json:"est_disponible"`
+	Etat          string            `json:"etat"` // up, degrade ou down
+	CodeHTTP      int               `json:"code_http"`
+	LatenceMs     int64             `json:"latence_ms"`
+	MessageErreur string            `json:"message_erreur"`
+	VerifieA      time.Time         `json:"verifie_a"`
+	URL           string            `json:"url"`
+	Certificat    *CertificatVue    `json:"certificat,omitempty"`
+	URLFinale     string            `json:"url_finale,omitempty"`
+	Redirections  []string          `json:"redirections,omitempty"`
+	DetailLatence *DetailLatenceVue `json:"detail_latence,omitempty"`
+	EnMaintenance bool              `json:"en_maintenance"`
+}
+
+// Représente la latence par phase pour l'API (en millisecondes)
+type DetailLatenceVue struct {
+	DNSMs          int64 `json:"dns_ms"`
+	ConnexionMs    int64 `json:"connexion_ms"`
+	TLSMs          int64 `json:"tls_ms"`
+	PremierOctetMs int64 `json:"premier_octet_ms"`
+	TransfertMs    int64 `json:"transfert_ms"`
+}
+
+// Représente le certificat TLS pour l'API (avec les jours restants pour le dashboard)
+type CertificatVue struct {
+	Sujet           string    `json:"sujet"`
+	Emetteur        string    `json:"emetteur"`
+	SANs            []string  `json:"sans"`
+	ExpireA         time.Time `json:"expire_a"`
+	ExpireDansJours int       `json:"expire_dans_jours"`
 }
 
 // Convertit un StatutMoniteur en StatutVue
 func vueDepuisModele(statut models.StatutMoniteur) StatutVue {
-	return StatutVue{
+	vue := StatutVue{
 		EstDisponible: statut.EstDisponible,
+		Etat:          statut.EtatEffectif(),
 		CodeHTTP:      statut.CodeStatutHTTP,
 		LatenceMs:     statut.Latence.Milliseconds(),
 		MessageErreur: statut.MessageErreur,
 		VerifieA:      statut.VerifieA,
 		URL:           statut.URL,
+		URLFinale:     statut.URLFinale,
+		Redirections:  statut.Redirections,
+		EnMaintenance: statut.EnMaintenance,
+	}
+	if detail := statut.DetailLatence; detail != nil {
+		vue.DetailLatence = &DetailLatenceVue{
+			DNSMs:          detail.DNS.Milliseconds(),
+			ConnexionMs:    detail.Connexion.Milliseconds(),
+			TLSMs:          detail.TLS.Milliseconds(),
+			PremierOctetMs: detail.PremierOctet.Milliseconds(),
+			TransfertMs:    detail.Transfert.Milliseconds(),
+		}
 	}
+	if statut.Certificat != nil {
+		vue.Certificat = &CertificatVue{
+			Sujet:           statut.Certificat.Sujet,
+			Emetteur:        statut.Certificat.Emetteur,
+			SANs:            statut.Certificat.SANs,
+			ExpireA:         statut.Certificat.ExpireA,
+			ExpireDansJours: statut.Certificat.JoursAvantExpiration(time.Now()),
+		}
+	}
+	return vue
 }
 
 // Envoie une réponse JSON
@@ -72,29 +130,54 @@ func ecrireJSON(w http.ResponseWriter, code int, data any) {
 	json.NewEncoder(w).Encode(data)
 }
 
+// Envoie une erreur JSON (lue par appelAPI côté front)
+func ecrireErreur(w http.ResponseWriter, code int, message string) {
+	ecrireJSON(w, code, map[string]any{"error": message})
+}
+
 // Active CORS pour permettre les appels depuis n'importe quel client
 func activerCORS(w http.ResponseWriter) {
 	w.Header().Set("Access-Control-Allow-Origin", "*")
-	w.Header().Set("Access-Control-Allow-Methods", "GET, POST, DELETE, OPTIONS")
+	w.Header().Set("Access-Control-Allow-Methods", "GET, POST, PUT, PATCH, DELETE, OPTIONS")
 	w.Header().Set("Access-Control-Allow-Headers", "Content-Type")
 }
 
-// Récupère ou crée l'ID d'un moniteur
-func obtenirIDMoniteur(ctx context.Context, depot repos.Repo, url string) (int, error) {
-	depot.AjouterMoniteur(ctx, models.Moniteur{URL: url, Nom: url, Type: "http"})
-	
-	moniteurs, err := depot.ListerMoniteurs(ctx)
-	if err != nil {
-		return 0, err
-	}
-	
-	for _, moniteur := range moniteurs {
-		if moniteur.URL == url {
-			return moniteur.ID, nil
+// Crée un moniteur pour une URL vérifiée pour la première fois et retourne son ID
+func creerMoniteur(ctx context.Context, depot repos.Repo, url, typeMoniteur string) (int, error) {
+	moniteur, err := depot.CreerMoniteur(ctx, models.Moniteur{URL: url, Nom: url, Type: typeMoniteur, Actif: true})
+	if errors.Is(err, repos.ErrMoniteurExistant) {
+		// créé entre-temps par une autre requête : on garde le sien
+		existant, trouve, errRecherche := chercherMoniteurParURL(ctx, depot, url)
+		if errRecherche != nil {
+			return 0, errRecherche
+		}
+		if trouve {
+			return existant.ID, nil
 		}
 	}
-	
-	return 0, errors.New("moniteur introuvable après ajout")
+	return moniteur.ID, err
+}
+
+// Délais de /api/verifier : sans pool, et attente max d'une place dans la file du pool
+const (
+	delaiVerificationDirecte = 15 * time.Second
+	attenteFileVerification  = 10 * time.Second
+)
+
+// Délai accordé à la vérification d'un moniteur, réessais configurés compris
+func (app ServicesApp) delaiVerification(moniteur models.Moniteur) time.Duration {
+	if app.Pool == nil {
+		return delaiVerificationDirecte
+	}
+	return app.Pool.DureeVerification(moniteur) + attenteFileVerification
+}
+
+// Vérifie un moniteur via le pool s'il est configuré, sinon directement
+func (app ServicesApp) verifier(ctx context.Context, moniteur models.Moniteur) (models.StatutMoniteur, error) {
+	if app.Pool == nil {
+		return services.NouveauVerificateur(services.VerificateurHTTP{}).Verifier(ctx, moniteur), nil
+	}
+	return app.Pool.Verifier(ctx, moniteur)
 }
 
 // Check une URL et retourne le résultat
@@ -117,15 +200,53 @@ func HandlerVerification(app ServicesApp) http.HandlerFunc {
 			return
 		}
 
-		ctx, cancel := context.WithTimeout(req.Context(), 15*time.Second)
+		typeMoniteur := strings.ToLower(strings.TrimSpace(body.Type))
+		if typeMoniteur == "" {
+			typeMoniteur = "http"
+		}
+		if !typesMoniteur[typeMoniteur] {
+			http.Error(w, "Type de moniteur non supporté", http.StatusBadRequest)
+			return
+		}
+
+		url := strings.TrimSpace(body.URL)
+		if typeMoniteur == "http" || typeMoniteur == "https" {
+			// même normalisation que les moniteurs enregistrés
+			url = services.NormaliserURLHTTP(url, typeMoniteur)
+		}
+
+		// un moniteur existant est vérifié avec toute sa configuration (codes acceptés, requête, auth...)
+		moniteur, existe, errRecherche := chercherMoniteurParURL(req.Context(), app.Depot, url)
+		if !existe {
+			moniteur = models.Moniteur{URL: url, Type: typeMoniteur}
+		}
+
+		// le délai suit les réessais du moniteur ; la vérification s'arrête si le client part
+		delai := app.delaiVerification(moniteur)
+		ctx, cancel := context.WithTimeout(req.Context(), delai)
 		defer cancel()
 
-		statut := services.VerifierURL(ctx, body.URL)
+		statut, err := app.verifier(ctx, moniteur)
+		switch {
+		case errors.Is(err, services.ErrFilePleine), errors.Is(err, services.ErrPoolArrete):
+			http.Error(w, "Service surchargé, réessayez plus tard: "+err.Error(), http.StatusServiceUnavailable)
+			return
+		case err != nil:
+			http.Error(w, "Vérification non terminée dans le délai de "+delai.String()+": "+err.Error(), http.StatusGatewayTimeout)
+			return
+		}
 		
-		// enregistre dans la BD si possible
-		if id, err := obtenirIDMoniteur(ctx, app.Depot, statut.URL); err == nil {
-			statut.MoniteurID = id
-			app.Depot.EnregistrerStatutMoniteur(ctx, statut)
+		// enregistre dans la BD : sur le moniteur existant, ou sur un nouveau moniteur
+		// (jamais sur un moniteur dont la configuration n'a pas servi à la vérification)
+		switch {
+		case existe:
+			statut.MoniteurID = moniteur.ID
+			app.Depot.EnregistrerStatutMoniteur(req.Context(), statut)
+		case errRecherche == nil:
+			if id, err := creerMoniteur(req.Context(), app.Depot, url, typeMoniteur); err == nil {
+				statut.MoniteurID = id
+				app.Depot.EnregistrerStatutMoniteur(req.Context(), statut)
+			}
 		}
 
 		ecrireJSON(w, http.StatusOK, map[string]any{
@@ -211,13 +332,48 @@ func HandlerEtatApplication() http.HandlerFunc {
 	}
 }
 
+// Retourne la profondeur de la file et les vérifications en cours
+func HandlerEtatVerifications(app ServicesApp) http.HandlerFunc {
+	return func(w http.ResponseWriter, req *http.Request) {
+		activerCORS(w)
+
+		if req.Method == http.MethodOptions {
+			w.WriteHeader(http.StatusNoContent)
+			return
+		}
+
+		if app.Pool == nil {
+			ecrireJSON(w, http.StatusOK, map[string]any{"pool": nil})
+			return
+		}
+
+		ecrireJSON(w, http.StatusOK, map[string]any{
+			"pool": app.Pool.Statistiques(),
+		})
+	}
+}
+
 // Configure les routes HTTP
 func EnregistrerRoutes(app ServicesApp) http.Handler {
 	mux := http.NewServeMux()
 
 	mux.HandleFunc("/api/verifier", HandlerVerification(app))
 	mux.HandleFunc("/api/resultats", HandlerResultats(app))
+	mux.HandleFunc("/api/moniteurs", HandlerMoniteurs(app))
+	mux.HandleFunc("/api/moniteurs/{id}", HandlerMoniteur(app))
+	mux.HandleFunc("/api/moniteurs/{id}/routes", HandlerRoutesMoniteur(app))
+	mux.HandleFunc("/api/moniteurs/{id}/uptime", HandlerDisponibiliteMoniteur(app))
+	mux.HandleFunc("/api/moniteurs/{id}/latences", HandlerLatencesMoniteur(app))
+	mux.HandleFunc("/api/canaux", HandlerCanaux(app))
+	mux.HandleFunc("/api/canaux/{id}", HandlerCanal(app))
+	mux.HandleFunc("/api/maintenances", HandlerMaintenances(app))
+	mux.HandleFunc("/api/maintenances/{id}", HandlerMaintenance(app))
+	mux.HandleFunc("/api/incidents", HandlerIncidents(app))
+	mux.HandleFunc("/api/incidents/{id}", HandlerIncident(app))
+	mux.HandleFunc("/api/incidents/{id}/acquittement", HandlerAcquittementIncident(app))
+	mux.HandleFunc("/api/admin/purge", HandlerPurge(app))
 	mux.HandleFunc("/api/etat", HandlerEtatApplication())
+	mux.HandleFunc("/api/etat/verifications", HandlerEtatVerifications(app))
 	mux.Handle("/", http.FileServer(http.Dir("/web")))
 
 	return mux
@@ -0,0 +1,143 @@
+/* Tests des routes : helpers communs et vérification d'une URL
+ * Projet de session A25
+ * By : Leandre Kanmegne
+ *
+ * Les handlers sont testés avec httptest sur le dépôt en mémoire
+ */
+package routes
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"example.com/go-hello/src/internal/models"
+	"example.com/go-hello/src/internal/services"
+	"example.com/go-hello/src/repos"
+)
+
+// Routes de l'application sur un dépôt en mémoire vide (sans pool ni purgeur)
+func nouvelleAppTest(t *testing.T) (*repos.Memoire, http.Handler) {
+	t.Helper()
+	depot := repos.NouvelleMemoire(repos.Reglages{})
+	return depot, EnregistrerRoutes(ServicesApp{Depot: depot})
+}
+
+// Envoie une requête aux routes et décode la réponse JSON dans reponse (si non nil)
+// Retourne le code HTTP
+func appeler(t *testing.T, routes http.Handler, methode, chemin, corps string, reponse any) int {
+	t.Helper()
+	req := httptest.NewRequest(methode, chemin, strings.NewReader(corps))
+	if corps != "" {
+		req.Header.Set("Content-Type", "application/json")
+	}
+	enregistreur := httptest.NewRecorder()
+	routes.ServeHTTP(enregistreur, req)
+
+	if reponse != nil && enregistreur.Code < 300 {
+		if err := json.NewDecoder(enregistreur.Body).Decode(reponse); err != nil {
+			t.Fatalf("%s %s : réponse JSON invalide : %v", methode, chemin, err)
+		}
+	}
+	return enregistreur.Code
+}
+
+// Ajoute un moniteur dans le dépôt et retourne son ID
+func ajouterMoniteurTest(t *testing.T, depot *repos.Memoire, moniteur models.Moniteur) int {
+	t.Helper()
+	ctx := context.Background()
+	if err := depot.AjouterMoniteur(ctx, moniteur); err != nil {
+		t.Fatal(err)
+	}
+	moniteurs, err := depot.ListerMoniteurs(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, m := range moniteurs {
+		if m.URL == moniteur.URL {
+			return m.ID
+		}
+	}
+	t.Fatalf("moniteur %s introuvable après l'ajout", moniteur.URL)
+	return 0
+}
+
+// test : un moniteur existant est vérifié avec sa configuration, sans changer son type
+func TestHandlerVerification_MoniteurExistant(t *testing.T) {
+	cible := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusUnauthorized)
+	}))
+	defer cible.Close()
+
+	depot, routes := nouvelleAppTest(t)
+	id := ajouterMoniteurTest(t, depot, models.Moniteur{
+		Nom: "Protégé", URL: cible.URL, Type: "http", Actif: true,
+		Parametres: models.ParametresMoniteur{HTTP: &models.ParametresHTTP{CodesAcceptes: "401"}},
+	})
+
+	var reponse struct {
+		Statut StatutVue `json:"statut"`
+	}
+	if code := appeler(t, routes, http.MethodPost, "/api/verifier", `{"url": "`+cible.URL+`", "type": "https"}`, &reponse); code != http.StatusOK {
+		t.Fatalf("200 attendu, obtenu %d", code)
+	}
+	if !reponse.Statut.EstDisponible || reponse.Statut.CodeHTTP != http.StatusUnauthorized {
+		t.Errorf("401 accepté par le moniteur, obtenu %+v", reponse.Statut)
+	}
+
+	moniteur, err := depot.ObtenirMoniteur(context.Background(), id)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if moniteur.Type != "http" {
+		t.Errorf("le type du moniteur ne devrait pas changer, obtenu %q", moniteur.Type)
+	}
+	statuts, err := depot.DerniersStatutsMoniteur(context.Background(), id)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(statuts) != 1 || !statuts[0].EstDisponible {
+		t.Errorf("un statut disponible enregistré sur le moniteur attendu, obtenu %+v", statuts)
+	}
+}
+
+// test : une URL inconnue crée un moniteur du type demandé ; corps invalide refusé
+func TestHandlerVerification_NouvelleURL(t *testing.T) {
+	cible := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+	defer cible.Close()
+
+	depot, routes := nouvelleAppTest(t)
+	if code := appeler(t, routes, http.MethodPost, "/api/verifier", `{"url": "`+cible.URL+`"}`, nil); code != http.StatusOK {
+		t.Fatalf("200 attendu, obtenu %d", code)
+	}
+	moniteurs, _ := depot.ListerMoniteurs(context.Background())
+	if len(moniteurs) != 1 || moniteurs[0].URL != cible.URL || moniteurs[0].Type != "http" {
+		t.Errorf("un moniteur http créé attendu, obtenu %+v", moniteurs)
+	}
+
+	for _, corps := range []string{`{}`, `{"url": "` + cible.URL + `", "type": "ftp"}`, `pas du json`} {
+		if code := appeler(t, routes, http.MethodPost, "/api/verifier", corps, nil); code != http.StatusBadRequest {
+			t.Errorf("%s : 400 attendu, obtenu %d", corps, code)
+		}
+	}
+}
+
+// test : un pool arrêté refuse la vérification (503)
+func TestHandlerVerification_PoolArrete(t *testing.T) {
+	depot := repos.NouvelleMemoire(repos.Reglages{})
+	pool := services.NouveauPool(1, 1)
+	ctx, annuler := context.WithCancel(context.Background())
+	pool.Demarrer(ctx)
+	annuler()
+	pool.Attendre()
+	time.Sleep(10 * time.Millisecond) // fermeture des soumissions
+
+	routes := EnregistrerRoutes(ServicesApp{Depot: depot, Pool: pool})
+	if code := appeler(t, routes, http.MethodPost, "/api/verifier", `{"url": "https://arrete.test"}`, nil); code != http.StatusServiceUnavailable {
+		t.Errorf("503 attendu, obtenu %d", code)
+	}
+}
@@ -0,0 +1,134 @@
+/* Assertions sur le contenu des réponses HTTP
+ * Projet de session A25
+ * By : Leandre Kanmegne
+ *
+ * Vérifie le corps déjà limité par le vérificateur HTTP :
+ * textes présents ou interdits, expression régulière, valeurs JSON et taille max
+ * La première assertion en échec est retournée comme message d'erreur
+ *
+ * Source: https://pkg.go.dev/regexp/syntax
+ */
+package services
+
+import (
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"example.com/go-hello/src/internal/models"
+)
+
+// Taille max du corps lu par le vérificateur HTTP
+const TailleMaxCorps = 512 * 1024
+
+// Vérifie le corps selon les assertions, retourne "" si tout passe
+func evaluerContenu(parametres *models.ParametresContenu, corps []byte, tronque bool) string {
+	if parametres == nil {
+		return ""
+	}
+
+	if parametres.TailleMax > 0 && (len(corps) > parametres.TailleMax || tronque) {
+		return fmt.Sprintf("assertion échouée : corps de %d octets ou plus (max %d)", len(corps), parametres.TailleMax)
+	}
+
+	texte := string(corps)
+	for _, attendu := range parametres.Contient {
+		if !strings.Contains(texte, attendu) {
+			return fmt.Sprintf("assertion échouée : %q absent de la réponse", attendu)
+		}
+	}
+	for _, interdit := range parametres.NeContientPas {
+		if strings.Contains(texte, interdit) {
+			return fmt.Sprintf("assertion échouée : %q présent dans la réponse", interdit)
+		}
+	}
+
+	if parametres.Regex != "" {
+		expression, err := regexp.Compile(parametres.Regex)
+		if err != nil {
+			return fmt.Sprintf("assertion invalide : regex %q : %v", parametres.Regex, err)
+		}
+		if !expression.Match(corps) {
+			return fmt.Sprintf("assertion échouée : regex %q sans correspondance", parametres.Regex)
+		}
+	}
+
+	if len(parametres.JSON) > 0 {
+		var document any
+		if err := json.Unmarshal(corps, &document); err != nil {
+			return fmt.Sprintf("assertion échouée : réponse JSON invalide : %v", err)
+		}
+		for _, assertion := range parametres.JSON {
+			if message := verifierValeurJSON(document, assertion); message != "" {
+				return message
+			}
+		}
+	}
+
+	return ""
+}
+
+// Compare la valeur au chemin donné avec la valeur attendue
+func verifierValeurJSON(document any, assertion models.AssertionJSON) string {
+	trouvee, err := ValeurJSON(document, assertion.Chemin)
+	if err != nil {
+		return fmt.Sprintf("assertion échouée : %s : %v", assertion.Chemin, err)
+	}
+	if !reflect.DeepEqual(trouvee, normaliserJSON(assertion.Valeur)) {
+		return fmt.Sprintf("assertion échouée : %s vaut %s, attendu %s",
+			assertion.Chemin, encoderJSON(trouvee), encoderJSON(assertion.Valeur))
+	}
+	return ""
+}
+
+// ValeurJSON suit un chemin à points ("data.items.0.etat", "$." optionnel) dans un document décodé
+func ValeurJSON(document any, chemin string) (any, error) {
+	chemin = strings.TrimPrefix(strings.TrimPrefix(chemin, "$"), ".")
+	if chemin == "" {
+		return document, nil
+	}
+
+	courant := document
+	for _, segment := range strings.Split(chemin, ".") {
+		switch noeud := courant.(type) {
+		case map[string]any:
+			valeur, ok := noeud[segment]
+			if !ok {
+				return nil, fmt.Errorf("clé %q introuvable", segment)
+			}
+			courant = valeur
+		case []any:
+			index, err := strconv.Atoi(segment)
+			if err != nil || index < 0 || index >= len(noeud) {
+				return nil, fmt.Errorf("index %q hors du tableau (%d éléments)", segment, len(noeud))
+			}
+			courant = noeud[index]
+		default:
+			return nil, fmt.Errorf("impossible de descendre dans %q", segment)
+		}
+	}
+	return courant, nil
+}
+
+// Ramène une valeur Go aux types produits par encoding/json (float64, map[string]any...)
+func normaliserJSON(valeur any) any {
+	donnees, err := json.Marshal(valeur)
+	if err != nil {
+		return valeur
+	}
+	var normalisee any
+	json.Unmarshal(donnees, &normalisee)
+	return normalisee
+}
+
+// Encode une valeur pour un message d'erreur
+func encoderJSON(valeur any) string {
+	donnees, err := json.Marshal(valeur)
+	if err != nil {
+		return fmt.Sprint(valeur)
+	}
+	return string(donnees)
+}
@@ -0,0 +1,100 @@
+/* Tests pour les assertions sur le contenu des réponses HTTP
+ * Projet de session A25
+ * By : Leandre Kanmegne
+ *
+ * Sert un corps fixe avec httptest puis vérifie chaque type d'assertion
+ */
+package services
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"example.com/go-hello/src/internal/models"
+)
+
+// vérifie un serveur qui répond toujours 200 avec le corps donné
+func verifierContenu(t *testing.T, corps string, contenu models.ParametresContenu) models.StatutMoniteur {
+	t.Helper()
+	serveur := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(corps))
+	}))
+	t.Cleanup(serveur.Close)
+
+	ctx, annuler := context.WithTimeout(context.Background(), 2*time.Second)
+	defer annuler()
+	return VerificateurHTTP{}.Verifier(ctx, models.Moniteur{
+		Type:       "http",
+		URL:        serveur.URL,
+		Parametres: models.ParametresMoniteur{Contenu: &contenu},
+	})
+}
+
+// test : chaque assertion qui passe puis qui échoue
+func TestEvaluerContenu_Assertions(t *testing.T) {
+	page := "<html><body>Site en Maintenance</body></html>"
+	sante := `{"etat":"ok","services":[{"nom":"db","actif":true,"connexions":3}]}`
+
+	cas := []struct {
+		nom     string
+		corps   string
+		contenu models.ParametresContenu
+		erreur  string // "" = doit passer
+	}{
+		{"contient ok", page, models.ParametresContenu{Contient: []string{"Site"}}, ""},
+		{"contient absent", page, models.ParametresContenu{Contient: []string{"Bienvenue"}}, `"Bienvenue" absent`},
+		{"texte interdit", page, models.ParametresContenu{NeContientPas: []string{"Maintenance"}}, `"Maintenance" présent`},
+		{"regex ok", page, models.ParametresContenu{Regex: `(?i)<body>.*maintenance`}, ""},
+		{"regex sans correspondance", page, models.ParametresContenu{Regex: `^\{`}, "sans correspondance"},
+		{"json ok", sante, models.ParametresContenu{JSON: []models.AssertionJSON{
+			{Chemin: "$.etat", Valeur: "ok"},
+			{Chemin: "services.0.actif", Valeur: true},
+			{Chemin: "services.0.connexions", Valeur: 3},
+		}}, ""},
+		{"json valeur différente", sante, models.ParametresContenu{JSON: []models.AssertionJSON{
+			{Chemin: "etat", Valeur: "degrade"},
+		}}, `etat vaut "ok"`},
+		{"json chemin absent", sante, models.ParametresContenu{JSON: []models.AssertionJSON{
+			{Chemin: "services.3.nom", Valeur: "db"},
+		}}, "hors du tableau"},
+		{"json invalide", page, models.ParametresContenu{JSON: []models.AssertionJSON{
+			{Chemin: "etat", Valeur: "ok"},
+		}}, "JSON invalide"},
+		{"taille ok", page, models.ParametresContenu{TailleMax: 1024}, ""},
+		{"taille dépassée", page, models.ParametresContenu{TailleMax: 10}, "max 10"},
+	}
+
+	for _, c := range cas {
+		t.Run(c.nom, func(t *testing.T) {
+			resultat := verifierContenu(t, c.corps, c.contenu)
+			if c.erreur == "" {
+				if !resultat.EstDisponible {
+					t.Errorf("assertion devrait passer, erreur=%q", resultat.MessageErreur)
+				}
+				return
+			}
+			if resultat.EstDisponible || !strings.Contains(resultat.MessageErreur, c.erreur) {
+				t.Errorf("échec contenant %q attendu, reçu disponible=%v message=%q",
+					c.erreur, resultat.EstDisponible, resultat.MessageErreur)
+			}
+			if resultat.CodeStatutHTTP != http.StatusOK {
+				t.Errorf("le code HTTP devrait rester 200, reçu %d", resultat.CodeStatutHTTP)
+			}
+		})
+	}
+}
+
+// test : le corps tronqué à la limite de lecture dépasse toujours la taille max
+func TestEvaluerContenu_CorpsTronque(t *testing.T) {
+	contenu := &models.ParametresContenu{TailleMax: TailleMaxCorps}
+	if message := evaluerContenu(contenu, make([]byte, TailleMaxCorps), true); message == "" {
+		t.Error("un corps tronqué ne devrait pas respecter la taille max")
+	}
+	if message := evaluerContenu(contenu, make([]byte, TailleMaxCorps), false); message != "" {
+		t.Errorf("un corps à la limite devrait passer, reçu %q", message)
+	}
+}
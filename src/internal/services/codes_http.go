@@ -0,0 +1,99 @@
+/* Codes HTTP acceptés et politique de redirection
+ * Projet de session A25
+ * By : Leandre Kanmegne
+ *
+ * Lit une liste de codes acceptés ("200,204,401", "200-299", "2xx")
+ * Sans configuration, un code entre 200 et 399 est considéré disponible
+ * Prépare le client pour suivre ou non les redirections et noter la chaîne parcourue
+ *
+ * Source: https://pkg.go.dev/net/http#Client (CheckRedirect)
+ */
+package services
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+)
+
+// Nombre max de redirections suivies par défaut (même valeur que net/http)
+const MaxRedirectionsParDefaut = 10
+
+// Codes acceptés si le moniteur n'en précise pas
+const CodesAcceptesParDefaut = "200-399"
+
+// PlageCodes est un intervalle inclusif de codes HTTP
+type PlageCodes struct {
+	Min, Max int
+}
+
+// CodesAcceptes est une liste de plages de codes HTTP
+type CodesAcceptes []PlageCodes
+
+// ParserCodesAcceptes lit une liste séparée par des virgules de codes, plages (200-299) ou classes (2xx)
+func ParserCodesAcceptes(spec string) (CodesAcceptes, error) {
+	if strings.TrimSpace(spec) == "" {
+		spec = CodesAcceptesParDefaut
+	}
+
+	var codes CodesAcceptes
+	for _, element := range strings.Split(spec, ",") {
+		element = strings.ToLower(strings.TrimSpace(element))
+		var plage PlageCodes
+		var err error
+
+		switch {
+		case len(element) == 3 && strings.HasSuffix(element, "xx"):
+			var classe int
+			classe, err = strconv.Atoi(element[:1])
+			plage = PlageCodes{classe * 100, classe*100 + 99}
+		case strings.Contains(element, "-"):
+			debut, fin, _ := strings.Cut(element, "-")
+			plage.Min, err = strconv.Atoi(strings.TrimSpace(debut))
+			if err == nil {
+				plage.Max, err = strconv.Atoi(strings.TrimSpace(fin))
+			}
+		default:
+			plage.Min, err = strconv.Atoi(element)
+			plage.Max = plage.Min
+		}
+
+		if err != nil || plage.Min < 100 || plage.Max > 599 || plage.Min > plage.Max {
+			return nil, fmt.Errorf("code HTTP %q invalide (ex: 200,204,401 ou 200-299 ou 2xx)", element)
+		}
+		codes = append(codes, plage)
+	}
+	return codes, nil
+}
+
+// Accepte indique si le code fait partie d'une des plages
+func (c CodesAcceptes) Accepte(code int) bool {
+	for _, plage := range c {
+		if code >= plage.Min && code <= plage.Max {
+			return true
+		}
+	}
+	return false
+}
+
+// Erreur retournée quand le nombre max de redirections est dépassé
+var errTropDeRedirections = errors.New("trop de redirections")
+
+// Copie le client avec la politique de redirection du moniteur et note chaque URL suivie
+func clientAvecRedirections(client *http.Client, suivre bool, maxRedirections int, chaine *[]string) *http.Client {
+	copie := *client
+	copie.CheckRedirect = func(req *http.Request, via []*http.Request) error {
+		if !suivre {
+			// on garde la réponse 3xx telle quelle
+			return http.ErrUseLastResponse
+		}
+		if len(via) > maxRedirections {
+			return fmt.Errorf("%w (max %d)", errTropDeRedirections, maxRedirections)
+		}
+		*chaine = append(*chaine, req.URL.String())
+		return nil
+	}
+	return &copie
+}
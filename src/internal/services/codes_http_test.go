@@ -0,0 +1,116 @@
+/* Tests pour les codes HTTP acceptés et les redirections
+ * Projet de session A25
+ * By : Leandre Kanmegne
+ *
+ * Un serveur httptest redirige /depart -> /etape -> /connexion (401)
+ */
+package services
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"example.com/go-hello/src/internal/models"
+)
+
+// test : lecture des listes, plages et classes de codes
+func TestParserCodesAcceptes(t *testing.T) {
+	codes, err := ParserCodesAcceptes("200, 204,401, 300-302, 5xx")
+	if err != nil {
+		t.Fatalf("erreur inattendue : %v", err)
+	}
+	for _, code := range []int{200, 204, 401, 301, 503} {
+		if !codes.Accepte(code) {
+			t.Errorf("%d devrait être accepté", code)
+		}
+	}
+	for _, code := range []int{201, 303, 404} {
+		if codes.Accepte(code) {
+			t.Errorf("%d ne devrait pas être accepté", code)
+		}
+	}
+
+	defaut, _ := ParserCodesAcceptes("")
+	if !defaut.Accepte(302) || defaut.Accepte(404) {
+		t.Errorf("par défaut 200-399 attendu")
+	}
+
+	for _, invalide := range []string{"abc", "299-200", "700", "2x"} {
+		if _, err := ParserCodesAcceptes(invalide); err == nil {
+			t.Errorf("%q devrait être refusé", invalide)
+		}
+	}
+}
+
+// démarre un serveur avec une chaîne de redirections qui finit sur une page de connexion
+func creerServeurRedirections(t *testing.T) *httptest.Server {
+	t.Helper()
+	mux := http.NewServeMux()
+	mux.HandleFunc("/depart", func(w http.ResponseWriter, r *http.Request) {
+		http.Redirect(w, r, "/etape", http.StatusFound)
+	})
+	mux.HandleFunc("/etape", func(w http.ResponseWriter, r *http.Request) {
+		http.Redirect(w, r, "/connexion", http.StatusMovedPermanently)
+	})
+	mux.HandleFunc("/connexion", func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusUnauthorized)
+	})
+	serveur := httptest.NewServer(mux)
+	t.Cleanup(serveur.Close)
+	return serveur
+}
+
+// vérifie /depart avec les paramètres http donnés
+func verifierRedirections(serveur *httptest.Server, parametres models.ParametresHTTP) models.StatutMoniteur {
+	ctx, annuler := context.WithTimeout(context.Background(), 2*time.Second)
+	defer annuler()
+	return VerificateurHTTP{}.Verifier(ctx, models.Moniteur{
+		Type:       "http",
+		URL:        serveur.URL + "/depart",
+		Parametres: models.ParametresMoniteur{HTTP: &parametres},
+	})
+}
+
+// test : chaîne suivie, URL finale et codes acceptés
+func TestVerificateurHTTP_Redirections(t *testing.T) {
+	serveur := creerServeurRedirections(t)
+
+	suivie := verifierRedirections(serveur, models.ParametresHTTP{})
+	if suivie.EstDisponible || !strings.Contains(suivie.URLFinale, "/connexion") {
+		t.Errorf("401 sur /connexion attendu, reçu %+v", suivie)
+	}
+	if len(suivie.Redirections) != 2 || !strings.HasSuffix(suivie.Redirections[0], "/etape") {
+		t.Errorf("chaîne /etape -> /connexion attendue, reçu %v", suivie.Redirections)
+	}
+
+	acceptee := verifierRedirections(serveur, models.ParametresHTTP{CodesAcceptes: "200,204,401"})
+	if !acceptee.EstDisponible {
+		t.Errorf("401 devrait être accepté, erreur=%q", acceptee.MessageErreur)
+	}
+
+	trop := verifierRedirections(serveur, models.ParametresHTTP{MaxRedirections: 1})
+	if trop.EstDisponible || !strings.Contains(trop.MessageErreur, "trop de redirections") {
+		t.Errorf("dépassement du max de redirections attendu, reçu %q", trop.MessageErreur)
+	}
+}
+
+// test : sans suivre, le 302 est gardé et sa destination signalée
+func TestVerificateurHTTP_SansRedirection(t *testing.T) {
+	serveur := creerServeurRedirections(t)
+	non := false
+
+	resultat := verifierRedirections(serveur, models.ParametresHTTP{SuivreRedirections: &non, CodesAcceptes: "2xx"})
+	if resultat.EstDisponible || resultat.CodeStatutHTTP != http.StatusFound {
+		t.Fatalf("302 non accepté attendu, reçu %+v", resultat)
+	}
+	if !strings.Contains(resultat.MessageErreur, "vers /etape") || len(resultat.Redirections) != 0 {
+		t.Errorf("destination /etape attendue sans chaîne suivie, reçu %q %v", resultat.MessageErreur, resultat.Redirections)
+	}
+	if !strings.HasSuffix(resultat.URLFinale, "/depart") {
+		t.Errorf("l'URL finale devrait rester /depart, reçu %q", resultat.URLFinale)
+	}
+}
@@ -0,0 +1,68 @@
+/* Consolidation périodique de l'historique des statuts
+ * Projet de session A25
+ * By : Leandre Kanmegne
+ *
+ * Regroupe régulièrement les statuts bruts en agrégats horaires et quotidiens (voir repos/pg_consolidations.go)
+ * Seuls les intervalles terminés sont consolidés : une heure est consolidée peu après sa fin
+ * S'arrête proprement quand le contexte est annulé (signal.NotifyContext)
+ */
+package services
+
+import (
+	"context"
+	"log"
+	"time"
+)
+
+// Fréquence de consolidation par défaut
+const IntervalleConsolidationParDefaut = 5 * time.Minute
+
+// DepotConsolidation regroupe les opérations de persistance dont le consolidateur a besoin
+type DepotConsolidation interface {
+	ConsoliderStatuts(ctx context.Context, maintenant time.Time) (int, error)
+}
+
+// Consolidateur lance la consolidation de l'historique à intervalle régulier
+type Consolidateur struct {
+	Depot      DepotConsolidation
+	Intervalle time.Duration
+	Horloge    func() time.Time // time.Now par défaut (remplacée dans les tests)
+}
+
+// NouveauConsolidateur crée un consolidateur avec les valeurs par défaut
+func NouveauConsolidateur(depot DepotConsolidation, intervalle time.Duration) *Consolidateur {
+	if intervalle <= 0 {
+		intervalle = IntervalleConsolidationParDefaut
+	}
+	return &Consolidateur{Depot: depot, Intervalle: intervalle, Horloge: time.Now}
+}
+
+// Demarrer consolide immédiatement puis à chaque intervalle, jusqu'à l'annulation du contexte
+func (c *Consolidateur) Demarrer(ctx context.Context) {
+	ticker := time.NewTicker(c.Intervalle)
+	defer ticker.Stop()
+
+	for {
+		c.Consolider(ctx)
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// Consolider rattrape tous les intervalles terminés
+func (c *Consolidateur) Consolider(ctx context.Context) {
+	debut := time.Now()
+	lignes, err := c.Depot.ConsoliderStatuts(ctx, c.Horloge())
+	if err != nil {
+		if ctx.Err() == nil {
+			log.Printf("[CONSOLIDATION] Erreur : %v", err)
+		}
+		return
+	}
+	if lignes > 0 {
+		log.Printf("[CONSOLIDATION] %d agrégat(s) consolidé(s) en %s", lignes, time.Since(debut).Round(time.Millisecond))
+	}
+}
@@ -0,0 +1,66 @@
+/* Tests pour le consolidateur de l'historique
+ * Projet de session A25
+ * By : Leandre Kanmegne
+ */
+package services
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"testing"
+	"time"
+)
+
+// faux dépôt qui retient les instants de consolidation demandés
+type fauxDepotConsolidation struct {
+	mu     sync.Mutex
+	appels []time.Time
+	err    error
+}
+
+func (d *fauxDepotConsolidation) ConsoliderStatuts(ctx context.Context, maintenant time.Time) (int, error) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	d.appels = append(d.appels, maintenant)
+	return 1, d.err
+}
+
+func (d *fauxDepotConsolidation) nombreAppels() int {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	return len(d.appels)
+}
+
+// test : consolidation au démarrage puis à chaque intervalle, avec l'horloge du consolidateur
+func TestConsolidateur_Demarrer(t *testing.T) {
+	depot := &fauxDepotConsolidation{}
+	consolidateur := NouveauConsolidateur(depot, 20*time.Millisecond)
+	instant := time.Date(2025, time.October, 6, 14, 5, 0, 0, time.UTC)
+	consolidateur.Horloge = func() time.Time { return instant }
+
+	ctx, annuler := context.WithTimeout(context.Background(), 110*time.Millisecond)
+	defer annuler()
+	consolidateur.Demarrer(ctx)
+
+	if n := depot.nombreAppels(); n < 3 {
+		t.Fatalf("au moins 3 consolidations attendues, obtenu %d", n)
+	}
+	if !depot.appels[0].Equal(instant) {
+		t.Errorf("instant attendu %s, obtenu %s", instant, depot.appels[0])
+	}
+}
+
+// test : une erreur du dépôt n'arrête pas le consolidateur
+func TestConsolidateur_ErreurDepot(t *testing.T) {
+	depot := &fauxDepotConsolidation{err: errors.New("base indisponible")}
+	consolidateur := NouveauConsolidateur(depot, 10*time.Millisecond)
+
+	ctx, annuler := context.WithTimeout(context.Background(), 55*time.Millisecond)
+	defer annuler()
+	consolidateur.Demarrer(ctx)
+
+	if n := depot.nombreAppels(); n < 2 {
+		t.Errorf("le consolidateur devrait réessayer après une erreur, %d appel(s)", n)
+	}
+}
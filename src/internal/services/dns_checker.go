@@ -0,0 +1,183 @@
+/* Service de vérification DNS
+ * Projet de session A25
+ * By : Leandre Kanmegne
+ *
+ * Résout un nom (A, AAAA, CNAME, MX, TXT) et mesure la latence de résolution
+ * Peut interroger un résolveur précis (hôte:port) au lieu de celui du système
+ * Vérifie optionnellement que les valeurs attendues font partie de la réponse
+ * Donne un message clair pour NXDOMAIN, timeout ou serveur injoignable
+ *
+ * Source: https://pkg.go.dev/net#Resolver
+ */
+package services
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"strings"
+	"time"
+
+	"example.com/go-hello/src/internal/models"
+)
+
+// Types d'enregistrements DNS supportés
+var TypesEnregistrementDNS = map[string]bool{
+	"A":     true,
+	"AAAA":  true,
+	"CNAME": true,
+	"MX":    true,
+	"TXT":   true,
+}
+
+// VerificateurDNS implémente Verificateur pour les moniteurs dns
+type VerificateurDNS struct{}
+
+// Verifier résout le nom du moniteur et compare aux valeurs attendues
+func (VerificateurDNS) Verifier(ctx context.Context, moniteur models.Moniteur) models.StatutMoniteur {
+	statut := models.StatutMoniteur{
+		MoniteurID: moniteur.ID,
+		URL:        moniteur.URL,
+		VerifieA:   time.Now(),
+	}
+
+	var parametres models.ParametresDNS
+	if moniteur.Parametres.DNS != nil {
+		parametres = *moniteur.Parametres.DNS
+	}
+	typeEnregistrement := strings.ToUpper(parametres.TypeEnregistrement)
+	if typeEnregistrement == "" {
+		typeEnregistrement = "A"
+	}
+	if !TypesEnregistrementDNS[typeEnregistrement] {
+		statut.MessageErreur = fmt.Sprintf("type d'enregistrement DNS non supporté : %q", parametres.TypeEnregistrement)
+		return statut
+	}
+
+	nom := strings.TrimPrefix(moniteur.URL, "dns://")
+	resolveur := NouveauResolveur(parametres.Resolveur)
+
+	debut := time.Now()
+	valeurs, err := resoudre(ctx, resolveur, nom, typeEnregistrement)
+	statut.Latence = time.Since(debut)
+	if err != nil {
+		statut.MessageErreur = messageErreurDNS(err)
+		return statut
+	}
+
+	if manquantes := valeursManquantes(parametres.ValeursAttendues, valeurs); len(manquantes) > 0 {
+		statut.MessageErreur = fmt.Sprintf("%s %s : valeurs attendues absentes %v (reçu %v)",
+			typeEnregistrement, nom, manquantes, valeurs)
+		return statut
+	}
+
+	statut.EstDisponible = true
+	return statut
+}
+
+// NouveauResolveur retourne le résolveur du système ou un résolveur dirigé vers l'adresse donnée
+func NouveauResolveur(adresse string) *net.Resolver {
+	if adresse == "" {
+		return net.DefaultResolver
+	}
+	if _, _, err := net.SplitHostPort(adresse); err != nil {
+		adresse = net.JoinHostPort(adresse, "53")
+	}
+
+	return &net.Resolver{
+		PreferGo: true,
+		Dial: func(ctx context.Context, reseau, _ string) (net.Conn, error) {
+			var dialer net.Dialer
+			return dialer.DialContext(ctx, reseau, adresse)
+		},
+	}
+}
+
+// Résout le nom selon le type et retourne les valeurs normalisées
+func resoudre(ctx context.Context, resolveur *net.Resolver, nom, typeEnregistrement string) ([]string, error) {
+	var valeurs []string
+
+	switch typeEnregistrement {
+	case "A", "AAAA":
+		reseau := "ip4"
+		if typeEnregistrement == "AAAA" {
+			reseau = "ip6"
+		}
+		ips, err := resolveur.LookupIP(ctx, reseau, nom)
+		if err != nil {
+			return nil, err
+		}
+		for _, ip := range ips {
+			valeurs = append(valeurs, ip.String())
+		}
+
+	case "CNAME":
+		cname, err := resolveur.LookupCNAME(ctx, nom)
+		if err != nil {
+			return nil, err
+		}
+		valeurs = append(valeurs, normaliserNomDNS(cname))
+
+	case "MX":
+		enregistrements, err := resolveur.LookupMX(ctx, nom)
+		if err != nil {
+			return nil, err
+		}
+		for _, mx := range enregistrements {
+			valeurs = append(valeurs, normaliserNomDNS(mx.Host))
+		}
+
+	case "TXT":
+		textes, err := resolveur.LookupTXT(ctx, nom)
+		if err != nil {
+			return nil, err
+		}
+		valeurs = append(valeurs, textes...)
+	}
+
+	if len(valeurs) == 0 {
+		return nil, fmt.Errorf("aucun enregistrement %s pour %s", typeEnregistrement, nom)
+	}
+	return valeurs, nil
+}
+
+// Retourne les valeurs attendues absentes de la réponse
+func valeursManquantes(attendues, recues []string) []string {
+	presentes := make(map[string]bool, len(recues))
+	for _, valeur := range recues {
+		presentes[normaliserNomDNS(valeur)] = true
+	}
+
+	var manquantes []string
+	for _, valeur := range attendues {
+		if !presentes[normaliserNomDNS(valeur)] {
+			manquantes = append(manquantes, valeur)
+		}
+	}
+	return manquantes
+}
+
+// Met un nom DNS en minuscules sans le point final
+func normaliserNomDNS(nom string) string {
+	return strings.TrimSuffix(strings.ToLower(strings.TrimSpace(nom)), ".")
+}
+
+// Traduit une erreur de résolution en message lisible
+func messageErreurDNS(err error) string {
+	var erreurDNS *net.DNSError
+	if !errors.As(err, &erreurDNS) {
+		return err.Error()
+	}
+
+	switch {
+	case erreurDNS.IsNotFound:
+		return fmt.Sprintf("DNS : nom introuvable (NXDOMAIN) %s", erreurDNS.Name)
+	case erreurDNS.IsTimeout:
+		return fmt.Sprintf("DNS : délai de résolution dépassé pour %s (serveur %s)", erreurDNS.Name, erreurDNS.Server)
+	case erreurDNS.IsTemporary:
+		return fmt.Sprintf("DNS : échec temporaire pour %s : %s", erreurDNS.Name, erreurDNS.Err)
+	default:
+		return "DNS : " + erreurDNS.Error()
+	}
+}
@@ -0,0 +1,218 @@
+/* Tests pour le service de vérification DNS
+ * Projet de session A25
+ * By : Leandre Kanmegne
+ *
+ * Démarre un faux serveur DNS UDP en mémoire (127.0.0.1:0) qui répond
+ * à partir d'une table d'enregistrements, pour ne pas dépendre du réseau
+ * Encode les réponses à la main selon la RFC 1035 (en-tête, question, réponses)
+ *
+ * Source: https://datatracker.ietf.org/doc/html/rfc1035#section-4
+ */
+package services
+
+import (
+	"context"
+	"encoding/binary"
+	"net"
+	"strings"
+	"testing"
+	"time"
+
+	"example.com/go-hello/src/internal/models"
+)
+
+// Codes des types d'enregistrements (RFC 1035 et 3596)
+const (
+	dnsTypeA     = 1
+	dnsTypeCNAME = 5
+	dnsTypeMX    = 15
+	dnsTypeTXT   = 16
+	dnsTypeAAAA  = 28
+)
+
+// fauxServeurDNS répond aux requêtes à partir de zones[nom][type]
+type fauxServeurDNS struct {
+	zones map[string]map[uint16][][]byte // données brutes (RDATA) par nom et type
+}
+
+// encode un nom DNS en suite de labels
+func encoderNomDNS(nom string) []byte {
+	var sortie []byte
+	for _, label := range strings.Split(strings.TrimSuffix(nom, "."), ".") {
+		sortie = append(sortie, byte(len(label)))
+		sortie = append(sortie, label...)
+	}
+	return append(sortie, 0)
+}
+
+// démarre le serveur et retourne son adresse
+func (f *fauxServeurDNS) demarrer(t *testing.T) string {
+	t.Helper()
+	connexion, err := net.ListenPacket("udp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { connexion.Close() })
+
+	go func() {
+		tampon := make([]byte, 1500)
+		for {
+			n, adresse, err := connexion.ReadFrom(tampon)
+			if err != nil {
+				return
+			}
+			if reponse := f.repondre(tampon[:n]); reponse != nil {
+				connexion.WriteTo(reponse, adresse)
+			}
+		}
+	}()
+
+	return connexion.LocalAddr().String()
+}
+
+// construit la réponse à une requête
+func (f *fauxServeurDNS) repondre(requete []byte) []byte {
+	if len(requete) < 12 {
+		return nil
+	}
+
+	// lit le nom de la question
+	position := 12
+	var labels []string
+	for position < len(requete) && requete[position] != 0 {
+		longueur := int(requete[position])
+		if position+1+longueur > len(requete) {
+			return nil
+		}
+		labels = append(labels, string(requete[position+1:position+1+longueur]))
+		position += 1 + longueur
+	}
+	position++ // octet nul de fin
+	if position+4 > len(requete) {
+		return nil
+	}
+	typeQuestion := binary.BigEndian.Uint16(requete[position:])
+	finQuestion := position + 4
+	nom := strings.ToLower(strings.Join(labels, "."))
+
+	enregistrements, nomConnu := f.zones[nom]
+	donnees := enregistrements[typeQuestion]
+
+	// en-tête : même ID, QR + AA + RA, RD recopié, NXDOMAIN si nom inconnu
+	reponse := make([]byte, 12, 512)
+	copy(reponse[0:2], requete[0:2])
+	drapeaux := uint16(0x8000|0x0400|0x0080) | binary.BigEndian.Uint16(requete[2:4])&0x0100
+	if !nomConnu {
+		drapeaux |= 3
+	}
+	binary.BigEndian.PutUint16(reponse[2:], drapeaux)
+	binary.BigEndian.PutUint16(reponse[4:], 1)
+	binary.BigEndian.PutUint16(reponse[6:], uint16(len(donnees)))
+
+	// recopie la question
+	reponse = append(reponse, requete[12:finQuestion]...)
+
+	for _, rdata := range donnees {
+		reponse = append(reponse, 0xC0, 0x0C) // pointeur vers le nom de la question
+		reponse = binary.BigEndian.AppendUint16(reponse, typeQuestion)
+		reponse = binary.BigEndian.AppendUint16(reponse, 1) // classe IN
+		reponse = binary.BigEndian.AppendUint32(reponse, 60)
+		reponse = binary.BigEndian.AppendUint16(reponse, uint16(len(rdata)))
+		reponse = append(reponse, rdata...)
+	}
+
+	return reponse
+}
+
+// crée le faux serveur avec quelques enregistrements
+func creerServeurDNS(t *testing.T) string {
+	mx := append([]byte{0, 10}, encoderNomDNS("mail.exemple.test")...)
+	txt := append([]byte{byte(len("v=spf1 -all"))}, "v=spf1 -all"...)
+
+	serveur := &fauxServeurDNS{zones: map[string]map[uint16][][]byte{
+		"exemple.test": {
+			dnsTypeA:    {{192, 0, 2, 10}, {192, 0, 2, 11}},
+			dnsTypeAAAA: {net.ParseIP("2001:db8::1").To16()},
+			dnsTypeMX:   {mx},
+			dnsTypeTXT:  {txt},
+		},
+		"www.exemple.test": {
+			dnsTypeCNAME: {encoderNomDNS("exemple.test")},
+		},
+	}}
+	return serveur.demarrer(t)
+}
+
+// vérifie un moniteur dns contre le faux serveur
+func verifierDNS(resolveur, nom string, parametres models.ParametresDNS) models.StatutMoniteur {
+	parametres.Resolveur = resolveur
+	ctx, annuler := context.WithTimeout(context.Background(), 2*time.Second)
+	defer annuler()
+	return NouveauVerificateur(VerificateurHTTP{}).Verifier(ctx, models.Moniteur{
+		Type:       "dns",
+		URL:        nom,
+		Parametres: models.ParametresMoniteur{DNS: &parametres},
+	})
+}
+
+// test : chaque type d'enregistrement avec les valeurs attendues
+func TestVerificateurDNS_TypesEnregistrement(t *testing.T) {
+	resolveur := creerServeurDNS(t)
+
+	cas := []struct {
+		nom      string
+		typeEnr  string
+		attendue string
+	}{
+		{"exemple.test.", "A", "192.0.2.11"},
+		{"exemple.test.", "AAAA", "2001:db8::1"},
+		{"www.exemple.test.", "CNAME", "exemple.test."},
+		{"exemple.test.", "MX", "MAIL.exemple.test"},
+		{"exemple.test.", "TXT", "v=spf1 -all"},
+	}
+
+	for _, c := range cas {
+		t.Run(c.typeEnr, func(t *testing.T) {
+			resultat := verifierDNS(resolveur, c.nom, models.ParametresDNS{
+				TypeEnregistrement: c.typeEnr,
+				ValeursAttendues:   []string{c.attendue},
+			})
+			if !resultat.EstDisponible {
+				t.Errorf("résolution %s devrait réussir, erreur=%q", c.typeEnr, resultat.MessageErreur)
+			}
+			if resultat.Latence <= 0 {
+				t.Errorf("la latence de résolution devrait être mesurée")
+			}
+		})
+	}
+}
+
+// test : valeur attendue absente
+func TestVerificateurDNS_ValeurInattendue(t *testing.T) {
+	resolveur := creerServeurDNS(t)
+
+	resultat := verifierDNS(resolveur, "exemple.test.", models.ParametresDNS{
+		ValeursAttendues: []string{"203.0.113.5"},
+	})
+	if resultat.EstDisponible {
+		t.Fatal("une IP inattendue devrait rendre le moniteur indisponible")
+	}
+	if !strings.Contains(resultat.MessageErreur, "203.0.113.5") {
+		t.Errorf("le message devrait citer la valeur manquante, reçu %q", resultat.MessageErreur)
+	}
+}
+
+// test : nom inconnu et type non supporté
+func TestVerificateurDNS_Erreurs(t *testing.T) {
+	resolveur := creerServeurDNS(t)
+
+	inconnu := verifierDNS(resolveur, "absent.exemple.test.", models.ParametresDNS{})
+	if inconnu.EstDisponible || !strings.Contains(inconnu.MessageErreur, "NXDOMAIN") {
+		t.Errorf("NXDOMAIN attendu, reçu %q", inconnu.MessageErreur)
+	}
+
+	typeInvalide := verifierDNS(resolveur, "exemple.test.", models.ParametresDNS{TypeEnregistrement: "SRV"})
+	if typeInvalide.EstDisponible || !strings.Contains(typeInvalide.MessageErreur, "non supporté") {
+		t.Errorf("type SRV devrait être refusé, reçu %q", typeInvalide.MessageErreur)
+	}
+}
@@ -3,59 +3,236 @@
  * By : Leandre Kanmegne
  * 
  * Fait un GET sur une URL et retourne le statut
- * Gère les erreurs réseau et les codes HTTP
+ * La requête peut être personnalisée par moniteur (méthode, en-têtes, corps, auth)
+ * Gère les erreurs réseau et les codes HTTP (acceptés selon le moniteur)
+ * Note l'URL finale et la chaîne de redirections suivies
+ * Découpe la latence par phase avec httptrace (voir trace_http.go)
  * Limite la taille de la réponse luee pour éviter d'abuser de la mémoire
+ * Évalue les assertions de contenu du moniteur sur le corps lu
  */
 package services
 
 import (
 	"context"
+	"encoding/json"
+	"fmt"
 	"io"
 	"net/http"
+	"net/http/httptrace"
 	"strings"
 	"time"
 
 	"example.com/go-hello/src/internal/models"
 )
 
-// VerifierURL fait une requête GET et retourne le statut
+// User-Agent envoyé si le moniteur n'en définit pas
+const UserAgentParDefaut = "Mozilla/5.0 (Macintosh; Intel Mac OS X 13_3_1) AppleWebKit/537.36 (KHTML, like Gecko) Chrome/120.0.6099.199 Safari/537.36"
+
+// Timeout par défaut du client HTTP
+const TimeoutRequeteParDefaut = 10 * time.Second
+
+// client partagé par VerifierURL
+var clientParDefaut = NouveauClientHTTP(TimeoutRequeteParDefaut)
+
+// NouveauClientHTTP crée le client utilisé pour les vérifications
+func NouveauClientHTTP(timeout time.Duration) *http.Client {
+	if timeout <= 0 {
+		timeout = TimeoutRequeteParDefaut
+	}
+	return &http.Client{Timeout: timeout}
+}
+
+// VerificateurHTTP implémente Verificateur pour les moniteurs http et https
+type VerificateurHTTP struct {
+	Client                *http.Client
+	JoursAlerteCertificat int // seuil par défaut avant expiration du certificat (https)
+}
+
+// VerifierURL fait une requête GET avec le client par défaut et retourne le statut
 func VerifierURL(ctx context.Context, url string) models.StatutMoniteur {
-	// ajoute http:// si manquant
-	if !strings.HasPrefix(url, "http://") && !strings.HasPrefix(url, "https://") {
-		url = "http://" + url
+	return VerifierURLAvecClient(ctx, clientParDefaut, url)
+}
+
+// VerifierURLAvecClient fait une requête GET avec le client donné et retourne le statut
+func VerifierURLAvecClient(ctx context.Context, client *http.Client, url string) models.StatutMoniteur {
+	return VerificateurHTTP{Client: client}.Verifier(ctx, models.Moniteur{URL: url, Type: "http"})
+}
+
+// NormaliserURLHTTP ajoute http:// si le schéma manque (https:// pour un moniteur https)
+func NormaliserURLHTTP(url, typeMoniteur string) string {
+	if strings.HasPrefix(url, "http://") || strings.HasPrefix(url, "https://") {
+		return url
+	}
+	if typeMoniteur == "https" {
+		return "https://" + url
 	}
+	return "http://" + url
+}
 
-	client := &http.Client{Timeout: 10 * time.Second}
+// Verifier envoie la requête du moniteur (GET par défaut) sur son URL
+func (v VerificateurHTTP) Verifier(ctx context.Context, moniteur models.Moniteur) models.StatutMoniteur {
+	client := v.Client
+	if client == nil {
+		client = clientParDefaut
+	}
 
-	debut := time.Now()
-	req, _ := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
-	req.Header.Set("User-Agent", "Mozilla/5.0 (Macintosh; Intel Mac OS X 13_3_1) AppleWebKit/537.36 (KHTML, like Gecko) Chrome/120.0.6099.199 Safari/537.36")
+	url := NormaliserURLHTTP(moniteur.URL, moniteur.Type)
 
 	statut := models.StatutMoniteur{
-		URL:      url,
-		VerifieA: time.Now(),
+		MoniteurID: moniteur.ID,
+		URL:        url,
+		VerifieA:   time.Now(),
+	}
+
+	parametres := parametresHTTP(moniteur)
+	codesAcceptes, err := ParserCodesAcceptes(parametres.CodesAcceptes)
+	if err != nil {
+		statut.MessageErreur = err.Error()
+		return statut
+	}
+	suivre := parametres.SuivreRedirections == nil || *parametres.SuivreRedirections
+	client = clientAvecRedirections(client, suivre, parametres.MaxRedirections, &statut.Redirections)
+
+	var traceur traceurLatence
+	ctx = httptrace.WithClientTrace(ctx, traceur.trace())
+
+	debut := time.Now()
+	req, err := construireRequete(ctx, url, moniteur.Requete)
+	if err != nil {
+		statut.MessageErreur = "requête invalide : " + err.Error()
+		return statut
 	}
 
 	resp, err := client.Do(req)
 	if err != nil {
-		// erreur réseau
+		// erreur réseau, TLS (nom d'hôte, chaîne non approuvée...) ou trop de redirections
 		statut.EstDisponible = false
 		statut.MessageErreur = err.Error()
+		if message, certificat, ok := analyserErreurTLS(err); ok {
+			statut.MessageErreur = message
+			statut.Certificat = certificat
+		}
 		statut.CodeStatutHTTP = 0
 		statut.Latence = time.Since(debut)
+		statut.DetailLatence = traceur.terminer(time.Now())
 		return statut
 	}
 	defer resp.Body.Close()
+	statut.URLFinale = resp.Request.URL.String()
 
-	// limite la lecture pour pas abuser
-	io.Copy(io.Discard, io.LimitReader(resp.Body, 512*1024))
+	// limite la lecture pour pas abuser (un octet de plus pour savoir si le corps est tronqué)
+	corps, errLecture := io.ReadAll(io.LimitReader(resp.Body, TailleMaxCorps+1))
+	tronque := len(corps) > TailleMaxCorps
+	if tronque {
+		corps = corps[:TailleMaxCorps]
+	}
 
+	fin := time.Now()
 	statut.CodeStatutHTTP = resp.StatusCode
-	statut.Latence = time.Since(debut)
-	statut.EstDisponible = resp.StatusCode >= 200 && resp.StatusCode < 400
+	statut.Latence = fin.Sub(debut)
+	statut.DetailLatence = traceur.terminer(fin)
+	statut.EstDisponible = codesAcceptes.Accepte(resp.StatusCode)
 	if !statut.EstDisponible {
 		statut.MessageErreur = http.StatusText(resp.StatusCode)
+		if parametres.CodesAcceptes != "" {
+			statut.MessageErreur = fmt.Sprintf("code HTTP %d non accepté (attendu %s)", resp.StatusCode, parametres.CodesAcceptes)
+		}
+		// redirection non suivie : indique la destination (ex: page de connexion)
+		if destination := resp.Header.Get("Location"); destination != "" && !suivre {
+			statut.MessageErreur += " vers " + destination
+		}
+	}
+
+	// assertions sur le contenu (ex: page « Maintenance » servie avec un 200)
+	if statut.EstDisponible && moniteur.Parametres.Contenu != nil {
+		if errLecture != nil {
+			statut.EstDisponible = false
+			statut.MessageErreur = "lecture de la réponse : " + errLecture.Error()
+		} else if message := evaluerContenu(moniteur.Parametres.Contenu, corps, tronque); message != "" {
+			if moniteur.Parametres.Contenu.Souple {
+				statut.Degrader(message)
+			} else {
+				statut.EstDisponible = false
+				statut.MessageErreur = message
+			}
+		}
+	}
+
+	// métadonnées du certificat et alerte d'expiration
+	if resp.TLS != nil && len(resp.TLS.PeerCertificates) > 0 {
+		statut.Certificat = certificatDepuisX509(resp.TLS.PeerCertificates[0])
+		evaluerExpiration(&statut, v.seuilExpiration(moniteur))
 	}
 
 	return statut
-}
\ No newline at end of file
+}
+
+// Construit la requête du moniteur (GET par défaut) avec en-têtes, corps et authentification
+func construireRequete(ctx context.Context, url string, spec *models.RequeteHTTP) (*http.Request, error) {
+	if spec == nil {
+		spec = &models.RequeteHTTP{}
+	}
+
+	methode := strings.ToUpper(spec.Methode)
+	if methode == "" {
+		methode = http.MethodGet
+	}
+	var corps io.Reader
+	if spec.Corps != "" {
+		corps = strings.NewReader(spec.Corps)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, methode, url, corps)
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("User-Agent", UserAgentParDefaut)
+	if spec.Corps != "" {
+		if json.Valid([]byte(spec.Corps)) {
+			req.Header.Set("Content-Type", "application/json")
+		} else {
+			req.Header.Set("Content-Type", "text/plain; charset=utf-8")
+		}
+	}
+
+	// les en-têtes du moniteur remplacent ceux par défaut
+	for nom, valeur := range spec.EnTetes {
+		req.Header.Set(nom, valeur)
+	}
+
+	if auth := spec.Auth; auth != nil {
+		switch strings.ToLower(auth.Type) {
+		case "bearer":
+			req.Header.Set("Authorization", "Bearer "+auth.Jeton)
+		case "basic":
+			req.SetBasicAuth(auth.Utilisateur, auth.MotDePasse)
+		default:
+			return nil, fmt.Errorf("type d'authentification %q non supporté", auth.Type)
+		}
+	}
+
+	return req, nil
+}
+
+// Retourne les paramètres http du moniteur avec le max de redirections par défaut
+func parametresHTTP(moniteur models.Moniteur) models.ParametresHTTP {
+	var parametres models.ParametresHTTP
+	if moniteur.Parametres.HTTP != nil {
+		parametres = *moniteur.Parametres.HTTP
+	}
+	if parametres.MaxRedirections <= 0 {
+		parametres.MaxRedirections = MaxRedirectionsParDefaut
+	}
+	return parametres
+}
+
+// Retourne le seuil d'alerte du moniteur, sinon celui du vérificateur
+func (v VerificateurHTTP) seuilExpiration(moniteur models.Moniteur) int {
+	if moniteur.Parametres.TLS != nil && moniteur.Parametres.TLS.JoursAlerteExpiration > 0 {
+		return moniteur.Parametres.TLS.JoursAlerteExpiration
+	}
+	if v.JoursAlerteCertificat > 0 {
+		return v.JoursAlerteCertificat
+	}
+	return JoursAlerteCertificatParDefaut
+}
@@ -16,10 +16,14 @@ package services
 
 import (
 	"context"
+	"io"
 	"net/http"
 	"net/http/httptest"
+	"strings"
 	"testing"
 	"time"
+
+	"example.com/go-hello/src/internal/models"
 )
 
 // démarre un faux serveur HTTP généré aleatoirement pour les tests
@@ -106,6 +110,21 @@ func TestVerifierURL_AjouteHTTP(t *testing.T) {
 	}
 }
 
+// test : le schéma ajouté dépend du type du moniteur, un schéma présent est gardé
+func TestNormaliserURLHTTP(t *testing.T) {
+	cas := []struct{ url, typeMoniteur, attendu string }{
+		{"example.com", "http", "http://example.com"},
+		{"example.com", "https", "https://example.com"},
+		{"http://example.com", "https", "http://example.com"},
+		{"https://example.com", "http", "https://example.com"},
+	}
+	for _, c := range cas {
+		if recu := NormaliserURLHTTP(c.url, c.typeMoniteur); recu != c.attendu {
+			t.Errorf("NormaliserURLHTTP(%q, %q) = %q, attendu %q", c.url, c.typeMoniteur, recu, c.attendu)
+		}
+	}
+}
+
 // test avec un timeout (site trop lent)
 func TestVerifierURL_Timeout(t *testing.T) {
 	// serveur qui attend 3 secondes avant de répondre
@@ -158,4 +177,75 @@ func TestVerifierURL_Concurrent(t *testing.T) {
 	for i := 0; i < nbGoroutines; i++ {
 		<-termine
 	}
-}
\ No newline at end of file
+}
+
+// test : méthode, en-têtes, corps et authentification du moniteur sont envoyés
+func TestVerificateurHTTP_RequetePersonnalisee(t *testing.T) {
+	var recue *http.Request
+	var corpsRecu string
+	serveur := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		donnees, _ := io.ReadAll(r.Body)
+		recue, corpsRecu = r, string(donnees)
+	}))
+	defer serveur.Close()
+
+	verifier := func(requete *models.RequeteHTTP) models.StatutMoniteur {
+		ctx, annuler := context.WithTimeout(context.Background(), 2*time.Second)
+		defer annuler()
+		return VerificateurHTTP{}.Verifier(ctx, models.Moniteur{Type: "http", URL: serveur.URL, Requete: requete})
+	}
+
+	resultat := verifier(&models.RequeteHTTP{
+		Methode: "post",
+		EnTetes: map[string]string{"X-Sonde": "monitoring", "User-Agent": "sonde/1.0"},
+		Corps:   `{"ping":true}`,
+		Auth:    &models.AuthHTTP{Type: "bearer", Jeton: "abc123"},
+	})
+	if !resultat.EstDisponible {
+		t.Fatalf("requête POST devrait réussir, erreur=%q", resultat.MessageErreur)
+	}
+	if recue.Method != http.MethodPost || corpsRecu != `{"ping":true}` {
+		t.Errorf("POST avec corps attendu, reçu %s %q", recue.Method, corpsRecu)
+	}
+	if recue.Header.Get("Content-Type") != "application/json" || recue.Header.Get("X-Sonde") != "monitoring" {
+		t.Errorf("en-têtes inattendus : %v", recue.Header)
+	}
+	if recue.UserAgent() != "sonde/1.0" || recue.Header.Get("Authorization") != "Bearer abc123" {
+		t.Errorf("User-Agent ou jeton bearer incorrect : %v", recue.Header)
+	}
+
+	verifier(&models.RequeteHTTP{Methode: "HEAD", Auth: &models.AuthHTTP{Type: "basic", Utilisateur: "admin", MotDePasse: "secret"}})
+	if utilisateur, motDePasse, ok := recue.BasicAuth(); recue.Method != http.MethodHead || !ok || utilisateur != "admin" || motDePasse != "secret" {
+		t.Errorf("HEAD avec auth basic attendu, reçu %s %v", recue.Method, recue.Header)
+	}
+
+	invalide := verifier(&models.RequeteHTTP{Auth: &models.AuthHTTP{Type: "digest"}})
+	if invalide.EstDisponible || !strings.Contains(invalide.MessageErreur, "requête invalide") {
+		t.Errorf("auth digest devrait être refusée, reçu %q", invalide.MessageErreur)
+	}
+}
+
+// test : les secrets sont masqués dans la copie puis restaurés depuis l'ancienne requête
+func TestRequeteHTTP_MasquerEtRestaurer(t *testing.T) {
+	originale := &models.RequeteHTTP{
+		EnTetes: map[string]string{"X-Api-Key": "cle", "Accept": "text/html"},
+		Auth:    &models.AuthHTTP{Type: "basic", Utilisateur: "admin", MotDePasse: "secret"},
+		Corps:   `{"client_secret":"s3cret"}`,
+	}
+
+	masquee := originale.Masquee()
+	if masquee.EnTetes["X-Api-Key"] != models.SecretMasque || masquee.Auth.MotDePasse != models.SecretMasque || masquee.Corps != models.SecretMasque {
+		t.Errorf("les secrets devraient être masqués : %+v", masquee)
+	}
+	if masquee.EnTetes["Accept"] != "text/html" || masquee.Auth.Utilisateur != "admin" {
+		t.Errorf("les valeurs non sensibles devraient rester visibles : %+v", masquee)
+	}
+	if originale.Auth.MotDePasse != "secret" {
+		t.Fatal("l'originale ne devrait pas être modifiée")
+	}
+
+	masquee.RestaurerSecrets(originale)
+	if masquee.EnTetes["X-Api-Key"] != "cle" || masquee.Auth.MotDePasse != "secret" || masquee.Corps != originale.Corps {
+		t.Errorf("les secrets devraient être restaurés : %+v", masquee)
+	}
+}
@@ -0,0 +1,174 @@
+/* Planificateur des vérifications en arrière-plan
+ * Projet de session A25
+ * By : Leandre Kanmegne
+ *
+ * Vérifie chaque moniteur actif à son propre intervalle, même sans onglet ouvert
+ * Recharge périodiquement la liste des moniteurs depuis la base
+ * Évite de lancer deux vérifications en même temps pour le même moniteur
+ * Délègue l'exécution au pool borné : si la file est pleine, le moniteur est retenté au prochain tick
+ * S'arrête proprement quand le contexte est annulé (signal.NotifyContext)
+ */
+package services
+
+import (
+	"context"
+	"errors"
+	"log"
+	"sync"
+	"time"
+
+	"example.com/go-hello/src/internal/models"
+)
+
+// Valeurs par défaut du planificateur
+const (
+	IntervalleParDefaut       = 60 * time.Second
+	rafraichissementParDefaut = 30 * time.Second
+	resolutionParDefaut       = time.Second
+	timeoutVerificationDefaut = 15 * time.Second
+	delaiReessaiFilePleine    = 5 * time.Second
+)
+
+// DepotPlanificateur regroupe les opérations de persistance dont le planificateur a besoin
+type DepotPlanificateur interface {
+	ListerMoniteurs(ctx context.Context) ([]models.Moniteur, error)
+	EnregistrerStatutMoniteur(ctx context.Context, statut models.StatutMoniteur) error
+}
+
+// Planificateur lance les vérifications des moniteurs actifs
+type Planificateur struct {
+	Depot            DepotPlanificateur
+	Pool             *PoolVerification
+	IntervalleDefaut time.Duration // utilisé si le moniteur n'a pas d'intervalle
+	Rafraichissement time.Duration // fréquence de rechargement de la liste
+	Resolution       time.Duration // fréquence de vérification des échéances
+
+	mu        sync.Mutex
+	moniteurs []models.Moniteur
+	echeances map[int]time.Time
+	enCours   map[int]bool
+}
+
+// NouveauPlanificateur crée un planificateur avec les valeurs par défaut
+func NouveauPlanificateur(depot DepotPlanificateur, pool *PoolVerification, intervalleDefaut time.Duration) *Planificateur {
+	if intervalleDefaut <= 0 {
+		intervalleDefaut = IntervalleParDefaut
+	}
+	return &Planificateur{
+		Depot:            depot,
+		Pool:             pool,
+		IntervalleDefaut: intervalleDefaut,
+		Rafraichissement: rafraichissementParDefaut,
+		Resolution:       resolutionParDefaut,
+	}
+}
+
+// Demarrer bloque jusqu'à l'annulation du contexte
+// Les vérifications en cours sont attendues via Pool.Attendre
+func (p *Planificateur) Demarrer(ctx context.Context) {
+	p.mu.Lock()
+	p.echeances = make(map[int]time.Time)
+	p.enCours = make(map[int]bool)
+	p.mu.Unlock()
+
+	p.recharger(ctx)
+	p.lancerEcheances(time.Now())
+
+	tickRecharge := time.NewTicker(p.Rafraichissement)
+	defer tickRecharge.Stop()
+	tickEcheances := time.NewTicker(p.Resolution)
+	defer tickEcheances.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-tickRecharge.C:
+			p.recharger(ctx)
+		case maintenant := <-tickEcheances.C:
+			p.lancerEcheances(maintenant)
+		}
+	}
+}
+
+// Recharge la liste des moniteurs depuis la base
+func (p *Planificateur) recharger(ctx context.Context) {
+	moniteurs, err := p.Depot.ListerMoniteurs(ctx)
+	if err != nil {
+		if ctx.Err() == nil {
+			log.Printf("[PLANIFICATEUR] Erreur chargement moniteurs : %v", err)
+		}
+		return
+	}
+
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	p.moniteurs = moniteurs
+
+	// oublie les échéances des moniteurs supprimés
+	presents := make(map[int]bool, len(moniteurs))
+	for _, moniteur := range moniteurs {
+		presents[moniteur.ID] = true
+	}
+	for id := range p.echeances {
+		if !presents[id] {
+			delete(p.echeances, id)
+		}
+	}
+}
+
+// Lance la vérification des moniteurs arrivés à échéance
+func (p *Planificateur) lancerEcheances(maintenant time.Time) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	for _, moniteur := range p.moniteurs {
+		if !moniteur.Actif || p.enCours[moniteur.ID] {
+			continue
+		}
+		if echeance, ok := p.echeances[moniteur.ID]; ok && maintenant.Before(echeance) {
+			continue
+		}
+
+		tache := TacheVerification{Moniteur: moniteur, Terminer: p.enregistrer}
+		if err := p.Pool.EssayerSoumettre(tache); err != nil {
+			// contre-pression : on réessaie un peu plus tard
+			if errors.Is(err, ErrFilePleine) {
+				log.Printf("[PLANIFICATEUR] File pleine, vérification de %s reportée", moniteur.URL)
+				p.echeances[moniteur.ID] = maintenant.Add(delaiReessaiFilePleine)
+			}
+			continue
+		}
+
+		p.echeances[moniteur.ID] = maintenant.Add(p.intervalle(moniteur))
+		p.enCours[moniteur.ID] = true
+	}
+}
+
+// Retourne l'intervalle propre au moniteur ou celui par défaut
+func (p *Planificateur) intervalle(moniteur models.Moniteur) time.Duration {
+	if moniteur.IntervalleSecondes > 0 {
+		return time.Duration(moniteur.IntervalleSecondes) * time.Second
+	}
+	return p.IntervalleDefaut
+}
+
+// Enregistre le résultat d'une vérification (appelé par le pool)
+func (p *Planificateur) enregistrer(ctx context.Context, statut models.StatutMoniteur) {
+	moniteurID := statut.MoniteurID
+	defer func() {
+		p.mu.Lock()
+		delete(p.enCours, moniteurID)
+		p.mu.Unlock()
+	}()
+
+	// arrêt en cours : le résultat est faussé par l'annulation, on ne l'enregistre pas
+	if ctx.Err() != nil {
+		return
+	}
+
+	if err := p.Depot.EnregistrerStatutMoniteur(ctx, statut); err != nil {
+		log.Printf("[PLANIFICATEUR] Erreur enregistrement statut %s : %v", statut.URL, err)
+	}
+}
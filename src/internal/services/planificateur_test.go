@@ -0,0 +1,130 @@
+/* Tests pour le planificateur des vérifications
+ * Projet de session A25
+ * By : Leandre Kanmegne
+ *
+ * Utilise un faux dépôt en mémoire et une fausse fonction de vérification
+ * pour ne pas dépendre du réseau ni de PostgreSQL
+ */
+package services
+
+import (
+	"context"
+	"sync"
+	"testing"
+	"time"
+
+	"example.com/go-hello/src/internal/models"
+)
+
+// faux dépôt qui garde les statuts en mémoire
+type fauxDepot struct {
+	mu        sync.Mutex
+	moniteurs []models.Moniteur
+	statuts   []models.StatutMoniteur
+}
+
+func (d *fauxDepot) ListerMoniteurs(ctx context.Context) ([]models.Moniteur, error) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	return append([]models.Moniteur(nil), d.moniteurs...), nil
+}
+
+func (d *fauxDepot) EnregistrerStatutMoniteur(ctx context.Context, statut models.StatutMoniteur) error {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	d.statuts = append(d.statuts, statut)
+	return nil
+}
+
+// compte les statuts enregistrés par moniteur
+func (d *fauxDepot) compter() map[int]int {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	compte := make(map[int]int)
+	for _, statut := range d.statuts {
+		compte[statut.MoniteurID]++
+	}
+	return compte
+}
+
+// crée un planificateur rapide avec une vérification factice
+func creerPlanificateurTest(depot *fauxDepot) *Planificateur {
+	pool := NouveauPool(2, 10)
+	pool.Verificateur = VerificateurFunc(func(ctx context.Context, moniteur models.Moniteur) models.StatutMoniteur {
+		return models.StatutMoniteur{URL: moniteur.URL, EstDisponible: true}
+	})
+	p := NouveauPlanificateur(depot, pool, time.Hour)
+	p.Resolution = 10 * time.Millisecond
+	p.Rafraichissement = 50 * time.Millisecond
+	return p
+}
+
+// démarre le pool et le planificateur, puis attend l'arrêt complet
+func executerPlanificateur(ctx context.Context, p *Planificateur) {
+	p.Pool.Demarrer(ctx)
+	p.Demarrer(ctx)
+	p.Pool.Attendre()
+}
+
+// test : seuls les moniteurs actifs sont vérifiés, chacun à son intervalle
+func TestPlanificateur_IntervalleParMoniteur(t *testing.T) {
+	depot := &fauxDepot{moniteurs: []models.Moniteur{
+		{ID: 1, URL: "http://rapide.test", Actif: true, IntervalleSecondes: 1},
+		{ID: 2, URL: "http://lent.test", Actif: true}, // intervalle par défaut (1h)
+		{ID: 3, URL: "http://pause.test", Actif: false, IntervalleSecondes: 1},
+	}}
+	p := creerPlanificateurTest(depot)
+
+	ctx, annuler := context.WithTimeout(context.Background(), 2500*time.Millisecond)
+	defer annuler()
+	executerPlanificateur(ctx, p)
+
+	compte := depot.compter()
+
+	// vérifié au démarrage puis chaque seconde
+	if compte[1] < 2 {
+		t.Errorf("moniteur 1 devrait être vérifié au moins 2 fois, reçu %d", compte[1])
+	}
+	// vérifié une seule fois au démarrage
+	if compte[2] != 1 {
+		t.Errorf("moniteur 2 devrait être vérifié 1 fois, reçu %d", compte[2])
+	}
+	// inactif : jamais vérifié
+	if compte[3] != 0 {
+		t.Errorf("moniteur inactif ne devrait pas être vérifié, reçu %d", compte[3])
+	}
+}
+
+// test : un résultat obtenu pendant l'arrêt n'est pas enregistré
+func TestPlanificateur_ArretPropre(t *testing.T) {
+	depot := &fauxDepot{moniteurs: []models.Moniteur{
+		{ID: 1, URL: "http://bloque.test", Actif: true},
+	}}
+	p := creerPlanificateurTest(depot)
+	demarree := make(chan struct{})
+	p.Pool.Verificateur = VerificateurFunc(func(ctx context.Context, moniteur models.Moniteur) models.StatutMoniteur {
+		close(demarree)
+		<-ctx.Done()
+		return models.StatutMoniteur{URL: moniteur.URL, MessageErreur: ctx.Err().Error()}
+	})
+
+	ctx, annuler := context.WithCancel(context.Background())
+	fini := make(chan struct{})
+	go func() {
+		executerPlanificateur(ctx, p)
+		close(fini)
+	}()
+
+	<-demarree
+	annuler()
+
+	select {
+	case <-fini:
+	case <-time.After(2 * time.Second):
+		t.Fatal("le planificateur ne s'est pas arrêté")
+	}
+
+	if n := len(depot.compter()); n != 0 {
+		t.Errorf("aucun statut ne devrait être enregistré à l'arrêt, reçu %d", n)
+	}
+}
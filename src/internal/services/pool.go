@@ -0,0 +1,235 @@
+/* Pool borné de vérifications concurrentes
+ * Projet de session A25
+ * By : Leandre Kanmegne
+ *
+ * Reçoit les tâches de vérification dans une file (channel) de taille fixe
+ * Lance au plus N vérifications en parallèle (WORKERS_MAX_PARALLELES)
+ * Applique une contre-pression : Soumettre bloque quand la file est pleine,
+ * EssayerSoumettre refuse la tâche immédiatement
+ * Expose la profondeur de la file et le nombre de vérifications en cours
+ * Une tâche peut porter le contexte de son demandeur : la vérification s'arrête s'il abandonne
+ *
+ * Source: https://gobyexample.com/worker-pools
+ */
+package services
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"example.com/go-hello/src/internal/models"
+)
+
+// Erreurs retournées à la soumission
+var (
+	ErrFilePleine = errors.New("file de vérifications pleine")
+	ErrPoolArrete = errors.New("pool de vérifications arrêté")
+)
+
+// Valeurs par défaut du pool
+const (
+	WorkersParDefaut = 5
+	facteurFile      = 20 // taille de la file = workers * facteurFile
+)
+
+// TacheVerification représente un moniteur à vérifier et quoi faire du résultat
+type TacheVerification struct {
+	Moniteur models.Moniteur
+	// contexte du demandeur (ex: requête HTTP), nil = seulement celui du pool
+	Contexte context.Context
+	// appelé par le worker avec le contexte du pool et le résultat
+	Terminer func(ctx context.Context, statut models.StatutMoniteur)
+}
+
+// StatistiquesPool donne l'état courant du pool
+type StatistiquesPool struct {
+	Workers    int   `json:"workers"`
+	TailleFile int   `json:"taille_file"`
+	Profondeur int   `json:"profondeur_file"`
+	EnCours    int64 `json:"en_cours"`
+	Traitees   int64 `json:"traitees"`
+	Rejetees   int64 `json:"rejetees"`
+}
+
+// PoolVerification exécute les vérifications avec un nombre borné de workers
+type PoolVerification struct {
+	Verificateur        Verificateur // choisit la vérification selon le type du moniteur
+	TimeoutVerification time.Duration
+
+	workers int
+	file    chan TacheVerification
+	arret   chan struct{}
+
+	enCours  atomic.Int64
+	traitees atomic.Int64
+	rejetees atomic.Int64
+	wg       sync.WaitGroup
+}
+
+// NouveauPool crée un pool avec N workers et une file de taille donnée
+func NouveauPool(workers, tailleFile int) *PoolVerification {
+	if workers <= 0 {
+		workers = WorkersParDefaut
+	}
+	if tailleFile <= 0 {
+		tailleFile = workers * facteurFile
+	}
+	return &PoolVerification{
+		Verificateur:        NouveauVerificateur(VerificateurHTTP{Client: clientParDefaut}),
+		TimeoutVerification: timeoutVerificationDefaut,
+		workers:             workers,
+		file:                make(chan TacheVerification, tailleFile),
+		arret:               make(chan struct{}),
+	}
+}
+
+// Demarrer lance les workers, qui s'arrêtent quand le contexte est annulé
+func (p *PoolVerification) Demarrer(ctx context.Context) {
+	for i := 0; i < p.workers; i++ {
+		p.wg.Add(1)
+		go p.travailler(ctx)
+	}
+
+	// ferme les soumissions à l'arrêt
+	go func() {
+		<-ctx.Done()
+		close(p.arret)
+	}()
+}
+
+// Attendre bloque jusqu'à la fin de tous les workers
+func (p *PoolVerification) Attendre() {
+	p.wg.Wait()
+}
+
+// Boucle d'un worker
+func (p *PoolVerification) travailler(ctx context.Context) {
+	defer p.wg.Done()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case tache := <-p.file:
+			p.executer(ctx, tache)
+		}
+	}
+}
+
+// DureeMaxVerification retourne la durée max d'une vérification avec toutes ses tentatives
+func DureeMaxVerification(timeoutVerification time.Duration) time.Duration {
+	return timeoutVerification*(MaxReessais+1) + DelaiReessaiMax*MaxReessais
+}
+
+// DureeVerification retourne la durée max d'une vérification du moniteur, selon ses propres réessais
+func (p *PoolVerification) DureeVerification(moniteur models.Moniteur) time.Duration {
+	// chaque tentative a droit au timeout complet, plus les délais entre tentatives
+	reessais, delai := ParametresReessai(moniteur)
+	return p.TimeoutVerification*time.Duration(reessais+1) + delai*time.Duration(reessais)
+}
+
+// Exécute une tâche et appelle son callback
+func (p *PoolVerification) executer(ctx context.Context, tache TacheVerification) {
+	if tache.Contexte != nil && tache.Contexte.Err() != nil {
+		// le demandeur est parti pendant l'attente dans la file
+		return
+	}
+	p.enCours.Add(1)
+	defer p.enCours.Add(-1)
+	defer p.traitees.Add(1)
+
+	ctxVerification, cancel := context.WithTimeout(ctx, p.DureeVerification(tache.Moniteur))
+	defer cancel()
+	if tache.Contexte != nil {
+		arreter := context.AfterFunc(tache.Contexte, cancel)
+		defer arreter()
+	}
+
+	statut := p.Verificateur.Verifier(ctxVerification, tache.Moniteur)
+	statut.MoniteurID = tache.Moniteur.ID
+	if tache.Terminer != nil {
+		tache.Terminer(ctx, statut)
+	}
+}
+
+// Soumettre ajoute une tâche en attendant une place dans la file
+func (p *PoolVerification) Soumettre(ctx context.Context, tache TacheVerification) error {
+	select {
+	case <-p.arret:
+		return ErrPoolArrete
+	default:
+	}
+
+	select {
+	case p.file <- tache:
+		return nil
+	case <-p.arret:
+		return ErrPoolArrete
+	case <-ctx.Done():
+		p.rejetees.Add(1)
+		return ctx.Err()
+	}
+}
+
+// EssayerSoumettre ajoute une tâche seulement s'il reste de la place
+func (p *PoolVerification) EssayerSoumettre(tache TacheVerification) error {
+	select {
+	case <-p.arret:
+		return ErrPoolArrete
+	default:
+	}
+
+	select {
+	case p.file <- tache:
+		return nil
+	default:
+		p.rejetees.Add(1)
+		return ErrFilePleine
+	}
+}
+
+// Verifier soumet un moniteur et attend son résultat ; la vérification est annulée avec ctx
+// Un refus du pool (file pleine jusqu'à la fin de ctx, arrêt) donne ErrFilePleine ou ErrPoolArrete,
+// une vérification acceptée mais pas terminée à temps donne l'erreur de ctx
+func (p *PoolVerification) Verifier(ctx context.Context, moniteur models.Moniteur) (models.StatutMoniteur, error) {
+	resultat := make(chan models.StatutMoniteur, 1)
+	tache := TacheVerification{
+		Moniteur: moniteur,
+		Contexte: ctx,
+		Terminer: func(_ context.Context, statut models.StatutMoniteur) {
+			resultat <- statut
+		},
+	}
+
+	if err := p.Soumettre(ctx, tache); err != nil {
+		if !errors.Is(err, ErrPoolArrete) {
+			err = fmt.Errorf("%w : %w", ErrFilePleine, err)
+		}
+		return models.StatutMoniteur{}, err
+	}
+
+	select {
+	case statut := <-resultat:
+		return statut, nil
+	case <-ctx.Done():
+		return models.StatutMoniteur{}, ctx.Err()
+	case <-p.arret:
+		return models.StatutMoniteur{}, ErrPoolArrete
+	}
+}
+
+// Statistiques retourne la profondeur de la file et les compteurs
+func (p *PoolVerification) Statistiques() StatistiquesPool {
+	return StatistiquesPool{
+		Workers:    p.workers,
+		TailleFile: cap(p.file),
+		Profondeur: len(p.file),
+		EnCours:    p.enCours.Load(),
+		Traitees:   p.traitees.Load(),
+		Rejetees:   p.rejetees.Load(),
+	}
+}
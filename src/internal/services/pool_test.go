@@ -0,0 +1,181 @@
+/* Tests pour le pool borné de vérifications
+ * Projet de session A25
+ * By : Leandre Kanmegne
+ *
+ * Vérifie la limite de parallélisme, la contre-pression et les statistiques
+ */
+package services
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"example.com/go-hello/src/internal/models"
+)
+
+// test : jamais plus de N vérifications en même temps
+func TestPool_LimiteParallelisme(t *testing.T) {
+	const workers = 3
+	pool := NouveauPool(workers, 50)
+
+	var enCours, maximum atomic.Int64
+	pool.Verificateur = VerificateurFunc(func(ctx context.Context, moniteur models.Moniteur) models.StatutMoniteur {
+		n := enCours.Add(1)
+		for {
+			m := maximum.Load()
+			if n <= m || maximum.CompareAndSwap(m, n) {
+				break
+			}
+		}
+		time.Sleep(20 * time.Millisecond)
+		enCours.Add(-1)
+		return models.StatutMoniteur{EstDisponible: true}
+	})
+
+	ctx, annuler := context.WithCancel(context.Background())
+	defer annuler()
+	pool.Demarrer(ctx)
+
+	var wg sync.WaitGroup
+	for i := 1; i <= 20; i++ {
+		wg.Add(1)
+		go func(id int) {
+			defer wg.Done()
+			statut, err := pool.Verifier(ctx, models.Moniteur{ID: id})
+			if err != nil {
+				t.Errorf("vérification %d échouée : %v", id, err)
+				return
+			}
+			if statut.MoniteurID != id {
+				t.Errorf("MoniteurID attendu %d, reçu %d", id, statut.MoniteurID)
+			}
+		}(i)
+	}
+	wg.Wait()
+
+	if maximum.Load() > workers {
+		t.Errorf("au plus %d vérifications parallèles attendues, reçu %d", workers, maximum.Load())
+	}
+	if traitees := pool.Statistiques().Traitees; traitees != 20 {
+		t.Errorf("20 tâches traitées attendues, reçu %d", traitees)
+	}
+}
+
+// test : une file pleine refuse les nouvelles tâches
+func TestPool_FilePleine(t *testing.T) {
+	pool := NouveauPool(1, 2)
+	debloquer := make(chan struct{})
+	demarree := make(chan struct{}, 1)
+	pool.Verificateur = VerificateurFunc(func(ctx context.Context, moniteur models.Moniteur) models.StatutMoniteur {
+		demarree <- struct{}{}
+		<-debloquer
+		return models.StatutMoniteur{}
+	})
+
+	ctx, annuler := context.WithCancel(context.Background())
+	defer annuler()
+	pool.Demarrer(ctx)
+
+	// une tâche occupe le worker, deux remplissent la file
+	if err := pool.EssayerSoumettre(TacheVerification{}); err != nil {
+		t.Fatalf("première soumission refusée : %v", err)
+	}
+	<-demarree
+	for i := 0; i < 2; i++ {
+		if err := pool.EssayerSoumettre(TacheVerification{}); err != nil {
+			t.Fatalf("soumission %d refusée : %v", i, err)
+		}
+	}
+
+	if err := pool.EssayerSoumettre(TacheVerification{}); !errors.Is(err, ErrFilePleine) {
+		t.Errorf("ErrFilePleine attendue, reçu %v", err)
+	}
+
+	// Soumettre bloque jusqu'à l'expiration du contexte
+	ctxCourt, annulerCourt := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer annulerCourt()
+	if err := pool.Soumettre(ctxCourt, TacheVerification{}); !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("DeadlineExceeded attendue, reçu %v", err)
+	}
+
+	stats := pool.Statistiques()
+	if stats.Profondeur != 2 || stats.EnCours != 1 || stats.Rejetees != 2 {
+		t.Errorf("statistiques inattendues : %+v", stats)
+	}
+
+	close(debloquer)
+}
+
+// test : plus de soumissions après l'arrêt
+func TestPool_Arrete(t *testing.T) {
+	pool := NouveauPool(1, 1)
+	ctx, annuler := context.WithCancel(context.Background())
+	pool.Demarrer(ctx)
+	annuler()
+	pool.Attendre()
+
+	// laisse le temps à la goroutine d'arrêt de fermer les soumissions
+	time.Sleep(10 * time.Millisecond)
+	if err := pool.EssayerSoumettre(TacheVerification{}); !errors.Is(err, ErrPoolArrete) {
+		t.Errorf("ErrPoolArrete attendue, reçu %v", err)
+	}
+}
+
+// test : le contexte du demandeur annule la vérification en cours, sans être pris pour un refus du pool
+func TestPool_VerifierAnnule(t *testing.T) {
+	pool := NouveauPool(1, 1)
+	pool.TimeoutVerification = time.Minute
+	annulee := make(chan struct{})
+	pool.Verificateur = VerificateurFunc(func(ctx context.Context, moniteur models.Moniteur) models.StatutMoniteur {
+		<-ctx.Done()
+		close(annulee)
+		return models.StatutMoniteur{}
+	})
+
+	ctx, annuler := context.WithCancel(context.Background())
+	defer annuler()
+	pool.Demarrer(ctx)
+
+	ctxCourt, annulerCourt := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer annulerCourt()
+	_, err := pool.Verifier(ctxCourt, models.Moniteur{ID: 1})
+	if !errors.Is(err, context.DeadlineExceeded) || errors.Is(err, ErrFilePleine) {
+		t.Errorf("DeadlineExceeded sans ErrFilePleine attendue, reçu %v", err)
+	}
+	select {
+	case <-annulee:
+	case <-time.After(time.Second):
+		t.Fatal("la vérification devrait être annulée avec le contexte du demandeur")
+	}
+}
+
+// test : une file pleine jusqu'à la fin du contexte est un refus du pool
+func TestPool_VerifierFilePleine(t *testing.T) {
+	pool := NouveauPool(1, 1)
+	debloquer := make(chan struct{})
+	pool.Verificateur = VerificateurFunc(func(ctx context.Context, moniteur models.Moniteur) models.StatutMoniteur {
+		<-debloquer
+		return models.StatutMoniteur{}
+	})
+	defer close(debloquer)
+
+	ctx, annuler := context.WithCancel(context.Background())
+	defer annuler()
+	pool.Demarrer(ctx)
+
+	// une tâche occupe le worker, l'autre remplit la file
+	for i := 0; i < 2; i++ {
+		if err := pool.Soumettre(ctx, TacheVerification{}); err != nil {
+			t.Fatal(err)
+		}
+	}
+	ctxCourt, annulerCourt := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer annulerCourt()
+	if _, err := pool.Verifier(ctxCourt, models.Moniteur{ID: 1}); !errors.Is(err, ErrFilePleine) {
+		t.Errorf("ErrFilePleine attendue, reçu %v", err)
+	}
+}
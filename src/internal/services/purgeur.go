@@ -0,0 +1,143 @@
+/* Purge périodique de l'historique selon la politique de rétention
+ * Projet de session A25
+ * By : Leandre Kanmegne
+ *
+ * Supprime par lots les lignes plus vieilles que la rétention de chaque cible (statuts, alertes, agrégats)
+ * Fait une courte pause entre les lots pour laisser passer les autres requêtes
+ * Peut aussi être lancée à la main (POST /api/admin/purge) ; une seule purge à la fois
+ * S'arrête proprement quand le contexte est annulé (signal.NotifyContext)
+ */
+package services
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"sync"
+	"time"
+
+	"example.com/go-hello/src/internal/models"
+)
+
+// Valeurs par défaut du purgeur
+const (
+	IntervallePurgeParDefaut = time.Hour
+	TailleLotPurgeParDefaut  = 5000
+	pauseEntreLotsParDefaut  = 100 * time.Millisecond
+)
+
+// ErrPurgeEnCours est retournée si une purge est déjà en cours
+var ErrPurgeEnCours = errors.New("une purge est déjà en cours")
+
+// DepotPurge regroupe les opérations de persistance dont le purgeur a besoin
+type DepotPurge interface {
+	PurgerLot(ctx context.Context, cible models.CiblePurge, avant time.Time, tailleLot int) (int64, error)
+}
+
+// Purgeur applique la politique de rétention à intervalle régulier
+type Purgeur struct {
+	Depot          DepotPurge
+	Politique      models.PolitiqueRetention
+	Intervalle     time.Duration
+	TailleLot      int
+	PauseEntreLots time.Duration
+	Horloge        func() time.Time // time.Now par défaut (remplacée dans les tests)
+
+	enCours sync.Mutex
+	mu      sync.Mutex
+	dernier *models.RapportPurge
+}
+
+// NouveauPurgeur crée un purgeur avec les valeurs par défaut
+func NouveauPurgeur(depot DepotPurge, politique models.PolitiqueRetention) *Purgeur {
+	return &Purgeur{
+		Depot:          depot,
+		Politique:      politique,
+		Intervalle:     IntervallePurgeParDefaut,
+		TailleLot:      TailleLotPurgeParDefaut,
+		PauseEntreLots: pauseEntreLotsParDefaut,
+		Horloge:        time.Now,
+	}
+}
+
+// Demarrer purge à chaque intervalle jusqu'à l'annulation du contexte
+func (p *Purgeur) Demarrer(ctx context.Context) {
+	ticker := time.NewTicker(p.Intervalle)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			if _, err := p.Purger(ctx); err != nil && !errors.Is(err, ErrPurgeEnCours) && ctx.Err() == nil {
+				log.Printf("[PURGE] Erreur : %v", err)
+			}
+		}
+	}
+}
+
+// Purger supprime tout ce qui dépasse la rétention et retourne le rapport
+// Le rapport contient les lignes déjà supprimées même en cas d'erreur
+func (p *Purgeur) Purger(ctx context.Context) (models.RapportPurge, error) {
+	if !p.enCours.TryLock() {
+		return models.RapportPurge{}, ErrPurgeEnCours
+	}
+	defer p.enCours.Unlock()
+
+	debut := time.Now()
+	maintenant := p.Horloge()
+	rapport := models.RapportPurge{DebutA: maintenant, Lignes: map[models.CiblePurge]int64{}}
+	var err error
+	for _, cible := range models.CiblesPurge {
+		retention := p.Politique[cible]
+		if retention <= 0 {
+			continue
+		}
+		var lignes int64
+		lignes, err = p.purgerCible(ctx, cible, maintenant.Add(-retention))
+		rapport.Lignes[cible] = lignes
+		rapport.Total += lignes
+		if err != nil {
+			err = fmt.Errorf("purge %s : %w", cible, err)
+			rapport.Erreur = err.Error()
+			break
+		}
+	}
+	rapport.DureeMs = time.Since(debut).Milliseconds()
+
+	p.mu.Lock()
+	p.dernier = &rapport
+	p.mu.Unlock()
+
+	if rapport.Total > 0 {
+		log.Printf("[PURGE] %d ligne(s) supprimée(s) en %d ms %v", rapport.Total, rapport.DureeMs, rapport.Lignes)
+	}
+	return rapport, err
+}
+
+// Supprime les lignes d'une cible par lots jusqu'à ce qu'il n'en reste plus
+func (p *Purgeur) purgerCible(ctx context.Context, cible models.CiblePurge, avant time.Time) (int64, error) {
+	var total int64
+	for {
+		lignes, err := p.Depot.PurgerLot(ctx, cible, avant, p.TailleLot)
+		total += lignes
+		if err != nil || lignes < int64(p.TailleLot) {
+			return total, err
+		}
+
+		select {
+		case <-ctx.Done():
+			return total, ctx.Err()
+		case <-time.After(p.PauseEntreLots):
+		}
+	}
+}
+
+// DernierRapport retourne le rapport de la dernière purge (nil si aucune)
+func (p *Purgeur) DernierRapport() *models.RapportPurge {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	return p.dernier
+}
@@ -0,0 +1,120 @@
+/* Tests pour le purgeur de l'historique
+ * Projet de session A25
+ * By : Leandre Kanmegne
+ */
+package services
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"example.com/go-hello/src/internal/models"
+)
+
+// faux dépôt : un nombre de lignes à purger par cible, et les dates limites demandées
+type fauxDepotPurge struct {
+	restantes map[models.CiblePurge]int64
+	limites   map[models.CiblePurge]time.Time
+	lots      int
+	err       error
+	entree    chan struct{} // si non nil, PurgerLot signale son entrée
+	bloquer   chan struct{} // si non nil, PurgerLot attend sa fermeture
+}
+
+func (d *fauxDepotPurge) PurgerLot(ctx context.Context, cible models.CiblePurge, avant time.Time, tailleLot int) (int64, error) {
+	if d.entree != nil {
+		d.entree <- struct{}{}
+	}
+	if d.bloquer != nil {
+		<-d.bloquer
+	}
+	if d.err != nil {
+		return 0, d.err
+	}
+	d.lots++
+	d.limites[cible] = avant
+	lignes := min(d.restantes[cible], int64(tailleLot))
+	d.restantes[cible] -= lignes
+	return lignes, nil
+}
+
+// crée un purgeur rapide avec une horloge fixe
+func creerPurgeurTest(depot *fauxDepotPurge, politique models.PolitiqueRetention, maintenant time.Time) *Purgeur {
+	purgeur := NouveauPurgeur(depot, politique)
+	purgeur.TailleLot = 10
+	purgeur.PauseEntreLots = 0
+	purgeur.Horloge = func() time.Time { return maintenant }
+	return purgeur
+}
+
+// test : suppression par lots, cibles sans rétention ignorées, dates limites calculées
+func TestPurgeur_Purger(t *testing.T) {
+	maintenant := time.Date(2025, time.October, 6, 12, 0, 0, 0, time.UTC)
+	depot := &fauxDepotPurge{
+		restantes: map[models.CiblePurge]int64{models.CibleStatuts: 25, models.CibleAlertes: 3, models.CibleAgregatsQuotidiens: 7},
+		limites:   map[models.CiblePurge]time.Time{},
+	}
+	purgeur := creerPurgeurTest(depot, models.PolitiqueRetention{
+		models.CibleStatuts: 30 * 24 * time.Hour,
+		models.CibleAlertes: 365 * 24 * time.Hour,
+	}, maintenant)
+
+	rapport, err := purgeur.Purger(context.Background())
+	if err != nil {
+		t.Fatalf("erreur inattendue : %v", err)
+	}
+	if rapport.Total != 28 || rapport.Lignes[models.CibleStatuts] != 25 || rapport.Lignes[models.CibleAlertes] != 3 {
+		t.Errorf("rapport inattendu : %+v", rapport)
+	}
+	if depot.lots != 4 { // 10 + 10 + 5 statuts, puis 3 alertes
+		t.Errorf("4 lots attendus, obtenu %d", depot.lots)
+	}
+	if depot.restantes[models.CibleAgregatsQuotidiens] != 7 {
+		t.Error("les agrégats quotidiens sans rétention ne devraient pas être purgés")
+	}
+	if attendu := maintenant.AddDate(0, 0, -30); !depot.limites[models.CibleStatuts].Equal(attendu) {
+		t.Errorf("limite des statuts attendue %s, obtenue %s", attendu, depot.limites[models.CibleStatuts])
+	}
+	if purgeur.DernierRapport() == nil || purgeur.DernierRapport().Total != 28 {
+		t.Error("le dernier rapport devrait être conservé")
+	}
+}
+
+// test : une erreur arrête la purge et est reportée dans le rapport
+func TestPurgeur_Erreur(t *testing.T) {
+	depot := &fauxDepotPurge{limites: map[models.CiblePurge]time.Time{}, err: errors.New("verrou expiré")}
+	purgeur := creerPurgeurTest(depot, models.PolitiqueRetention{models.CibleStatuts: time.Hour}, time.Now())
+
+	rapport, err := purgeur.Purger(context.Background())
+	if err == nil || rapport.Erreur == "" {
+		t.Fatalf("erreur attendue dans le retour et le rapport, obtenu %v / %+v", err, rapport)
+	}
+}
+
+// test : une seule purge à la fois
+func TestPurgeur_UneSeuleALaFois(t *testing.T) {
+	depot := &fauxDepotPurge{
+		restantes: map[models.CiblePurge]int64{},
+		limites:   map[models.CiblePurge]time.Time{},
+		entree:    make(chan struct{}, 1),
+		bloquer:   make(chan struct{}),
+	}
+	purgeur := creerPurgeurTest(depot, models.PolitiqueRetention{models.CibleStatuts: time.Hour}, time.Now())
+
+	termine := make(chan struct{})
+	go func() {
+		defer close(termine)
+		purgeur.Purger(context.Background())
+	}()
+
+	// attend que la première purge soit dans le dépôt
+	<-depot.entree
+	if _, err := purgeur.Purger(context.Background()); !errors.Is(err, ErrPurgeEnCours) {
+		t.Errorf("ErrPurgeEnCours attendue, obtenu %v", err)
+	}
+
+	close(depot.bloquer)
+	<-termine
+}
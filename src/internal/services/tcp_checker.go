@@ -0,0 +1,132 @@
+/* Service de vérification TCP
+ * Projet de session A25
+ * By : Leandre Kanmegne
+ *
+ * Ouvre une connexion TCP sur hôte:port et mesure la latence de connexion
+ * Peut envoyer des données puis vérifier que la réponse contient une bannière attendue
+ * Limite la lecture de la bannière pour éviter d'abuser de la mémoire
+ *
+ * Source: https://pkg.go.dev/net#Dialer.DialContext
+ */
+package services
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"net"
+	"strings"
+	"time"
+
+	"example.com/go-hello/src/internal/models"
+)
+
+// Taille max lue pour chercher la bannière
+const tailleMaxBanniere = 4 * 1024
+
+// Délai max d'attente de la bannière si le contexte n'en impose pas
+const delaiBanniereParDefaut = 5 * time.Second
+
+// VerificateurTCP implémente Verificateur pour les moniteurs tcp
+type VerificateurTCP struct{}
+
+// AdresseTCP extrait hôte:port d'une URL de moniteur tcp (avec ou sans tcp://)
+func AdresseTCP(url string) (string, error) {
+	adresse := strings.TrimPrefix(url, "tcp://")
+	adresse = strings.TrimSuffix(adresse, "/")
+	if _, port, err := net.SplitHostPort(adresse); err != nil || port == "" {
+		return "", fmt.Errorf("adresse tcp invalide %q : format hôte:port attendu", url)
+	}
+	return adresse, nil
+}
+
+// Verifier se connecte au port et vérifie la bannière si demandé
+func (VerificateurTCP) Verifier(ctx context.Context, moniteur models.Moniteur) models.StatutMoniteur {
+	statut := models.StatutMoniteur{
+		MoniteurID: moniteur.ID,
+		URL:        moniteur.URL,
+		VerifieA:   time.Now(),
+	}
+
+	adresse, err := AdresseTCP(moniteur.URL)
+	if err != nil {
+		statut.MessageErreur = err.Error()
+		return statut
+	}
+
+	var dialer net.Dialer
+	debut := time.Now()
+	connexion, err := dialer.DialContext(ctx, "tcp", adresse)
+	statut.Latence = time.Since(debut)
+	if err != nil {
+		// erreur réseau (refusé, timeout, DNS...)
+		statut.MessageErreur = err.Error()
+		return statut
+	}
+	defer connexion.Close()
+
+	parametres := moniteur.Parametres.TCP
+	if parametres == nil || (parametres.Envoi == "" && parametres.BanniereAttendue == "") {
+		statut.EstDisponible = true
+		return statut
+	}
+
+	// borne les échanges par le contexte
+	echeance, ok := ctx.Deadline()
+	if !ok {
+		echeance = time.Now().Add(delaiBanniereParDefaut)
+	}
+	connexion.SetDeadline(echeance)
+
+	if parametres.Envoi != "" {
+		if _, err := io.WriteString(connexion, parametres.Envoi); err != nil {
+			statut.MessageErreur = "envoi impossible : " + err.Error()
+			return statut
+		}
+	}
+
+	if parametres.BanniereAttendue == "" {
+		statut.EstDisponible = true
+		return statut
+	}
+
+	if err := attendreBanniere(connexion, parametres.BanniereAttendue); err != nil {
+		statut.MessageErreur = err.Error()
+		return statut
+	}
+
+	statut.EstDisponible = true
+	return statut
+}
+
+// Lit la réponse jusqu'à trouver la bannière, la fin du flux ou la limite de taille
+func attendreBanniere(connexion net.Conn, banniere string) error {
+	recu := make([]byte, 0, 512)
+	tampon := make([]byte, 512)
+	attendu := []byte(banniere)
+
+	for len(recu) < tailleMaxBanniere {
+		n, err := connexion.Read(tampon)
+		recu = append(recu, tampon[:n]...)
+		if bytes.Contains(recu, attendu) {
+			return nil
+		}
+		if err != nil {
+			if err == io.EOF {
+				break
+			}
+			return fmt.Errorf("bannière %q non reçue : %v", banniere, err)
+		}
+	}
+
+	return fmt.Errorf("bannière %q non trouvée dans la réponse %q", banniere, tronquer(string(recu), 120))
+}
+
+// Coupe un texte trop long pour le message d'erreur
+func tronquer(texte string, longueur int) string {
+	if len(texte) <= longueur {
+		return texte
+	}
+	return texte[:longueur] + "…"
+}
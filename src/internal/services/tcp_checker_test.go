@@ -0,0 +1,133 @@
+/* Tests pour le service de vérification TCP
+ * Projet de session A25
+ * By : Leandre Kanmegne
+ *
+ * Démarre un petit serveur TCP local (net.Listen sur 127.0.0.1:0)
+ * qui envoie une bannière ou répond en écho
+ */
+package services
+
+import (
+	"bufio"
+	"context"
+	"net"
+	"strings"
+	"testing"
+	"time"
+
+	"example.com/go-hello/src/internal/models"
+)
+
+// démarre un serveur TCP qui envoie la bannière puis renvoie la première ligne reçue
+func creerServeurTCP(t *testing.T, banniere string) string {
+	t.Helper()
+	ecouteur, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { ecouteur.Close() })
+
+	go func() {
+		for {
+			connexion, err := ecouteur.Accept()
+			if err != nil {
+				return
+			}
+			go func(c net.Conn) {
+				defer c.Close()
+				c.SetDeadline(time.Now().Add(2 * time.Second))
+				if banniere != "" {
+					c.Write([]byte(banniere))
+				}
+				ligne, err := bufio.NewReader(c).ReadString('\n')
+				if err == nil {
+					c.Write([]byte("ECHO " + ligne))
+				}
+			}(connexion)
+		}
+	}()
+
+	return ecouteur.Addr().String()
+}
+
+// vérifie un moniteur tcp avec un timeout court
+func verifierTCP(moniteur models.Moniteur) models.StatutMoniteur {
+	ctx, annuler := context.WithTimeout(context.Background(), 2*time.Second)
+	defer annuler()
+	return NouveauVerificateur(VerificateurHTTP{}).Verifier(ctx, moniteur)
+}
+
+// test : port ouvert sans bannière attendue
+func TestVerificateurTCP_PortOuvert(t *testing.T) {
+	adresse := creerServeurTCP(t, "")
+
+	resultat := verifierTCP(models.Moniteur{Type: "tcp", URL: "tcp://" + adresse})
+
+	if !resultat.EstDisponible {
+		t.Errorf("port devrait être disponible, erreur=%q", resultat.MessageErreur)
+	}
+	if resultat.Latence <= 0 {
+		t.Errorf("la latence de connexion devrait être mesurée")
+	}
+}
+
+// test : bannière attendue reçue ou non
+func TestVerificateurTCP_Banniere(t *testing.T) {
+	adresse := creerServeurTCP(t, "SSH-2.0-OpenSSH_9.6\r\n")
+
+	ok := verifierTCP(models.Moniteur{Type: "tcp", URL: adresse, Parametres: models.ParametresMoniteur{
+		TCP: &models.ParametresTCP{BanniereAttendue: "SSH-2.0"},
+	}})
+	if !ok.EstDisponible {
+		t.Errorf("bannière SSH devrait être trouvée, erreur=%q", ok.MessageErreur)
+	}
+
+	ko := verifierTCP(models.Moniteur{Type: "tcp", URL: adresse, Parametres: models.ParametresMoniteur{
+		TCP: &models.ParametresTCP{BanniereAttendue: "220 smtp"},
+	}})
+	if ko.EstDisponible || !strings.Contains(ko.MessageErreur, "bannière") {
+		t.Errorf("bannière absente devrait rendre le moniteur indisponible, reçu %+v", ko)
+	}
+}
+
+// test : envoi de données puis lecture de la réponse
+func TestVerificateurTCP_EnvoiEtReponse(t *testing.T) {
+	adresse := creerServeurTCP(t, "")
+
+	resultat := verifierTCP(models.Moniteur{Type: "tcp", URL: adresse, Parametres: models.ParametresMoniteur{
+		TCP: &models.ParametresTCP{Envoi: "PING\n", BanniereAttendue: "ECHO PING"},
+	}})
+	if !resultat.EstDisponible {
+		t.Errorf("la réponse à l'envoi devrait être trouvée, erreur=%q", resultat.MessageErreur)
+	}
+}
+
+// test : port fermé et adresse invalide
+func TestVerificateurTCP_Erreurs(t *testing.T) {
+	// réserve un port puis le libère pour avoir un port fermé
+	ecouteur, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	adresse := ecouteur.Addr().String()
+	ecouteur.Close()
+
+	if resultat := verifierTCP(models.Moniteur{Type: "tcp", URL: adresse}); resultat.EstDisponible {
+		t.Errorf("port fermé devrait être indisponible")
+	}
+	if resultat := verifierTCP(models.Moniteur{Type: "tcp", URL: "sans-port"}); resultat.EstDisponible || resultat.MessageErreur == "" {
+		t.Errorf("adresse sans port devrait être refusée, reçu %+v", resultat)
+	}
+}
+
+// test : un type inconnu n'est pas vérifié
+func TestVerificateurParType_TypeInconnu(t *testing.T) {
+	resultat := verifierTCP(models.Moniteur{ID: 7, Type: "gopher", URL: "gopher://exemple"})
+
+	if resultat.EstDisponible || !strings.Contains(resultat.MessageErreur, "non supporté") {
+		t.Errorf("type inconnu devrait être signalé, reçu %+v", resultat)
+	}
+	if resultat.MoniteurID != 7 {
+		t.Errorf("MoniteurID attendu 7, reçu %d", resultat.MoniteurID)
+	}
+}
@@ -0,0 +1,84 @@
+/* Analyse des certificats TLS des moniteurs https
+ * Projet de session A25
+ * By : Leandre Kanmegne
+ *
+ * Extrait le sujet, l'émetteur, les SANs et la date d'expiration du certificat du serveur
+ * Marque le moniteur comme dégradé quand l'expiration approche
+ * Distingue les erreurs TLS : nom d'hôte, chaîne non approuvée, certificat expiré
+ * Utilise tls.CertificateVerificationError (Go 1.20+) pour garder le certificat même en cas d'échec
+ *
+ * Source: https://pkg.go.dev/crypto/x509
+ */
+package services
+
+import (
+	"crypto/sha256"
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"time"
+
+	"example.com/go-hello/src/internal/models"
+)
+
+// Nombre de jours avant expiration à partir duquel le moniteur est dégradé
+const JoursAlerteCertificatParDefaut = 14
+
+// Convertit un certificat x509 en métadonnées à stocker
+func certificatDepuisX509(certificat *x509.Certificate) *models.CertificatTLS {
+	sans := append([]string{}, certificat.DNSNames...)
+	for _, ip := range certificat.IPAddresses {
+		sans = append(sans, ip.String())
+	}
+
+	empreinte := sha256.Sum256(certificat.Raw)
+	return &models.CertificatTLS{
+		Sujet:     certificat.Subject.String(),
+		Emetteur:  certificat.Issuer.String(),
+		SANs:      sans,
+		ExpireA:   certificat.NotAfter,
+		Empreinte: hex.EncodeToString(empreinte[:]),
+	}
+}
+
+// Marque le statut comme dégradé si le certificat expire bientôt
+func evaluerExpiration(statut *models.StatutMoniteur, seuilJours int) {
+	jours := statut.Certificat.JoursAvantExpiration(time.Now())
+	if !statut.EstDisponible || jours > seuilJours {
+		return
+	}
+
+	statut.Degrader(fmt.Sprintf("certificat TLS expire dans %d jour(s) (%s)",
+		jours, statut.Certificat.ExpireA.Format("2006-01-02")))
+}
+
+// Reconnaît une erreur de vérification TLS et retourne un message distinct par cas
+func analyserErreurTLS(err error) (string, *models.CertificatTLS, bool) {
+	var certificat *models.CertificatTLS
+	var erreurVerification *tls.CertificateVerificationError
+	if errors.As(err, &erreurVerification) && len(erreurVerification.UnverifiedCertificates) > 0 {
+		certificat = certificatDepuisX509(erreurVerification.UnverifiedCertificates[0])
+	}
+
+	var erreurNom x509.HostnameError
+	var erreurAutorite x509.UnknownAuthorityError
+	var erreurInvalide x509.CertificateInvalidError
+
+	switch {
+	case errors.As(err, &erreurNom):
+		return fmt.Sprintf("TLS : le certificat ne correspond pas au nom d'hôte %q", erreurNom.Host), certificat, true
+	case errors.As(err, &erreurAutorite):
+		return "TLS : chaîne de certificats non approuvée (autorité inconnue)", certificat, true
+	case errors.As(err, &erreurInvalide):
+		if erreurInvalide.Reason == x509.Expired {
+			return "TLS : certificat expiré ou pas encore valide", certificat, true
+		}
+		return "TLS : certificat invalide : " + erreurInvalide.Error(), certificat, true
+	case erreurVerification != nil:
+		return "TLS : vérification du certificat échouée : " + erreurVerification.Err.Error(), certificat, true
+	}
+
+	return "", nil, false
+}
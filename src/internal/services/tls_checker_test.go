@@ -0,0 +1,84 @@
+/* Tests pour l'analyse des certificats TLS
+ * Projet de session A25
+ * By : Leandre Kanmegne
+ *
+ * Utilise httptest.NewTLSServer : son certificat couvre example.com, 127.0.0.1 et ::1
+ * Le client de serveur.Client() fait confiance à ce certificat, pas le client par défaut
+ */
+package services
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"example.com/go-hello/src/internal/models"
+)
+
+// vérifie un moniteur https avec le client donné
+func verifierHTTPS(client *http.Client, moniteur models.Moniteur) models.StatutMoniteur {
+	ctx, annuler := context.WithTimeout(context.Background(), 5*time.Second)
+	defer annuler()
+	return VerificateurHTTP{Client: client}.Verifier(ctx, moniteur)
+}
+
+// test : métadonnées du certificat et état dégradé près de l'expiration
+func TestVerificateurHTTP_Certificat(t *testing.T) {
+	serveur := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+	defer serveur.Close()
+
+	resultat := verifierHTTPS(serveur.Client(), models.Moniteur{Type: "https", URL: serveur.URL})
+	if !resultat.EstDisponible || resultat.Etat == models.EtatDegrade {
+		t.Fatalf("site https valide attendu, reçu %+v", resultat)
+	}
+	if resultat.Certificat == nil {
+		t.Fatal("les métadonnées du certificat devraient être capturées")
+	}
+	if !strings.Contains(strings.Join(resultat.Certificat.SANs, ","), "127.0.0.1") {
+		t.Errorf("les SANs devraient contenir 127.0.0.1, reçu %v", resultat.Certificat.SANs)
+	}
+	if resultat.Certificat.ExpireA.IsZero() {
+		t.Errorf("la date d'expiration devrait être renseignée")
+	}
+	if len(resultat.Certificat.Empreinte) != 64 {
+		t.Errorf("empreinte SHA-256 en hexadécimal attendue, reçu %q", resultat.Certificat.Empreinte)
+	}
+
+	// seuil plus grand que la durée de vie restante : dégradé mais disponible
+	jours := resultat.Certificat.JoursAvantExpiration(time.Now()) + 1
+	proche := verifierHTTPS(serveur.Client(), models.Moniteur{
+		Type:       "https",
+		URL:        serveur.URL,
+		Parametres: models.ParametresMoniteur{TLS: &models.ParametresTLS{JoursAlerteExpiration: jours}},
+	})
+	if !proche.EstDisponible || proche.Etat != models.EtatDegrade {
+		t.Errorf("certificat proche de l'expiration : disponible et dégradé attendus, reçu %+v", proche)
+	}
+	if !strings.Contains(proche.MessageErreur, "expire dans") {
+		t.Errorf("message d'expiration attendu, reçu %q", proche.MessageErreur)
+	}
+}
+
+// test : chaîne non approuvée et nom d'hôte incorrect donnent des erreurs distinctes
+func TestVerificateurHTTP_ErreursTLS(t *testing.T) {
+	serveur := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+	defer serveur.Close()
+
+	nonApprouve := verifierHTTPS(NouveauClientHTTP(2*time.Second), models.Moniteur{Type: "https", URL: serveur.URL})
+	if nonApprouve.EstDisponible || !strings.Contains(nonApprouve.MessageErreur, "non approuvée") {
+		t.Errorf("chaîne non approuvée attendue, reçu %q", nonApprouve.MessageErreur)
+	}
+	if nonApprouve.Certificat == nil {
+		t.Errorf("le certificat devrait être capturé même si la chaîne est refusée")
+	}
+
+	// localhost ne fait pas partie des SANs du certificat de test
+	urlLocalhost := strings.Replace(serveur.URL, "127.0.0.1", "localhost", 1)
+	mauvaisNom := verifierHTTPS(serveur.Client(), models.Moniteur{Type: "https", URL: urlLocalhost})
+	if mauvaisNom.EstDisponible || !strings.Contains(mauvaisNom.MessageErreur, "nom d'hôte") {
+		t.Errorf("erreur de nom d'hôte attendue, reçu %q", mauvaisNom.MessageErreur)
+	}
+}
@@ -0,0 +1,91 @@
+/* Décomposition de la latence HTTP
+ * Projet de session A25
+ * By : Leandre Kanmegne
+ *
+ * Utilise net/http/httptrace pour mesurer chaque phase d'une requête :
+ * résolution DNS, connexion TCP, poignée de main TLS, attente du premier octet et transfert du corps
+ * Les durées s'additionnent sur toutes les requêtes d'une chaîne de redirections
+ * Une connexion réutilisée n'a pas de phase DNS, connexion ni TLS
+ *
+ * Source: https://pkg.go.dev/net/http/httptrace
+ */
+package services
+
+import (
+	"crypto/tls"
+	"net/http/httptrace"
+	"sync"
+	"time"
+
+	"example.com/go-hello/src/internal/models"
+)
+
+// traceurLatence accumule les durées de chaque phase
+type traceurLatence struct {
+	mu             sync.Mutex // les tentatives de connexion (IPv4/IPv6) peuvent être parallèles
+	debutDNS       time.Time
+	debutConnexion time.Time
+	debutTLS       time.Time
+	requeteEcrite  time.Time
+	premierOctet   time.Time
+	detail         models.DetailLatence
+}
+
+// Retourne les fonctions appelées par net/http à chaque étape
+func (t *traceurLatence) trace() *httptrace.ClientTrace {
+	return &httptrace.ClientTrace{
+		DNSStart: func(httptrace.DNSStartInfo) {
+			t.noter(func() { t.debutDNS = time.Now() })
+		},
+		DNSDone: func(httptrace.DNSDoneInfo) {
+			t.noter(func() { t.detail.DNS += depuis(t.debutDNS) })
+		},
+		ConnectStart: func(string, string) {
+			t.noter(func() { t.debutConnexion = time.Now() })
+		},
+		ConnectDone: func(string, string, error) {
+			t.noter(func() { t.detail.Connexion += depuis(t.debutConnexion) })
+		},
+		TLSHandshakeStart: func() {
+			t.noter(func() { t.debutTLS = time.Now() })
+		},
+		TLSHandshakeDone: func(tls.ConnectionState, error) {
+			t.noter(func() { t.detail.TLS += depuis(t.debutTLS) })
+		},
+		WroteRequest: func(httptrace.WroteRequestInfo) {
+			t.noter(func() { t.requeteEcrite = time.Now() })
+		},
+		GotFirstResponseByte: func() {
+			t.noter(func() {
+				t.premierOctet = time.Now()
+				t.detail.PremierOctet += depuis(t.requeteEcrite)
+			})
+		},
+	}
+}
+
+// Exécute une mise à jour sous verrou
+func (t *traceurLatence) noter(miseAJour func()) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	miseAJour()
+}
+
+// Termine la mesure à la fin de la lecture du corps et retourne le détail
+func (t *traceurLatence) terminer(fin time.Time) *models.DetailLatence {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	detail := t.detail
+	if !t.premierOctet.IsZero() {
+		detail.Transfert = fin.Sub(t.premierOctet)
+	}
+	return &detail
+}
+
+// Durée écoulée depuis debut, 0 si la phase n'a pas commencé
+func depuis(debut time.Time) time.Duration {
+	if debut.IsZero() {
+		return 0
+	}
+	return time.Since(debut)
+}
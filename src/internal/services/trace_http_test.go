@@ -0,0 +1,61 @@
+/* Tests pour la décomposition de la latence HTTP
+ * Projet de session A25
+ * By : Leandre Kanmegne
+ *
+ * Le serveur attend avant d'envoyer les en-têtes puis entre deux morceaux du corps,
+ * pour que l'attente du premier octet et le transfert soient mesurables
+ */
+package services
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"example.com/go-hello/src/internal/models"
+)
+
+// test : chaque phase est mesurée et une connexion réutilisée n'a plus de handshake
+func TestVerificateurHTTP_DetailLatence(t *testing.T) {
+	serveur := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		time.Sleep(40 * time.Millisecond)
+		w.Write([]byte("debut "))
+		w.(http.Flusher).Flush()
+		time.Sleep(30 * time.Millisecond)
+		w.Write([]byte("fin"))
+	}))
+	defer serveur.Close()
+
+	verificateur := VerificateurHTTP{Client: serveur.Client()}
+	verifier := func() models.StatutMoniteur {
+		ctx, annuler := context.WithTimeout(context.Background(), 5*time.Second)
+		defer annuler()
+		return verificateur.Verifier(ctx, models.Moniteur{Type: "https", URL: serveur.URL})
+	}
+
+	premier := verifier()
+	detail := premier.DetailLatence
+	if !premier.EstDisponible || detail == nil {
+		t.Fatalf("vérification avec détail attendue, reçu %+v", premier)
+	}
+	if detail.Connexion <= 0 || detail.TLS <= 0 {
+		t.Errorf("connexion et TLS devraient être mesurés : %+v", detail)
+	}
+	if detail.DNS != 0 {
+		t.Errorf("pas de résolution DNS pour une IP, reçu %s", detail.DNS)
+	}
+	if detail.PremierOctet < 40*time.Millisecond || detail.Transfert < 30*time.Millisecond {
+		t.Errorf("attente >= 40ms et transfert >= 30ms attendus : %+v", detail)
+	}
+	if somme := detail.Connexion + detail.TLS + detail.PremierOctet + detail.Transfert; somme > premier.Latence {
+		t.Errorf("la somme des phases (%s) dépasse la latence totale (%s)", somme, premier.Latence)
+	}
+
+	// le client garde la connexion : ni connexion ni handshake au deuxième appel
+	second := verifier()
+	if second.DetailLatence.Connexion != 0 || second.DetailLatence.TLS != 0 {
+		t.Errorf("connexion réutilisée attendue, reçu %+v", second.DetailLatence)
+	}
+}
@@ -0,0 +1,124 @@
+/* Interface commune des vérifications
+ * Projet de session A25
+ * By : Leandre Kanmegne
+ *
+ * Chaque type de moniteur (http, https, tcp, dns) a son propre vérificateur
+ * VerificateurParType choisit le bon selon Moniteur.Type
+ * Refait la vérification en cas d'échec si le moniteur prévoit des tentatives
+ * puis classe le résultat en up, degrade (lent ou avertissement) ou down
+ * Utilisé par le pool (donc le planificateur) et par /api/verifier
+ */
+package services
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"example.com/go-hello/src/internal/models"
+)
+
+// Verificateur vérifie un moniteur et retourne le statut obtenu
+type Verificateur interface {
+	Verifier(ctx context.Context, moniteur models.Moniteur) models.StatutMoniteur
+}
+
+// VerificateurFunc permet d'utiliser une simple fonction comme Verificateur
+type VerificateurFunc func(ctx context.Context, moniteur models.Moniteur) models.StatutMoniteur
+
+// Verifier appelle la fonction
+func (f VerificateurFunc) Verifier(ctx context.Context, moniteur models.Moniteur) models.StatutMoniteur {
+	return f(ctx, moniteur)
+}
+
+// Nombre max de nouvelles tentatives par vérification
+const MaxReessais = 5
+
+// Délai max entre deux tentatives d'une même vérification
+const DelaiReessaiMax = time.Minute
+
+// Seuil de latence par défaut au-delà duquel un moniteur disponible est dégradé
+const SeuilLatenceLenteParDefaut = 800 * time.Millisecond
+
+// VerificateurParType répartit les vérifications selon le type du moniteur
+// et calcule l'état final (up, degrade, down)
+type VerificateurParType struct {
+	Verificateurs     map[string]Verificateur
+	SeuilLatenceLente time.Duration // seuil global, remplacé par Parametres.SeuilLatenceMs
+}
+
+// NouveauVerificateur crée le répartiteur avec tous les types supportés
+func NouveauVerificateur(verificateurHTTP VerificateurHTTP) *VerificateurParType {
+	return &VerificateurParType{
+		Verificateurs: map[string]Verificateur{
+			"http":  verificateurHTTP,
+			"https": verificateurHTTP,
+			"tcp":   VerificateurTCP{},
+			"dns":   VerificateurDNS{},
+		},
+		SeuilLatenceLente: SeuilLatenceLenteParDefaut,
+	}
+}
+
+// Verifier délègue au vérificateur du type (http si le type est vide)
+func (v *VerificateurParType) Verifier(ctx context.Context, moniteur models.Moniteur) models.StatutMoniteur {
+	typeMoniteur := moniteur.Type
+	if typeMoniteur == "" {
+		typeMoniteur = "http"
+	}
+
+	verificateur, ok := v.Verificateurs[typeMoniteur]
+	if !ok {
+		return models.StatutMoniteur{
+			MoniteurID:    moniteur.ID,
+			URL:           moniteur.URL,
+			VerifieA:      time.Now(),
+			MessageErreur: fmt.Sprintf("type de moniteur non supporté : %q", moniteur.Type),
+			Etat:          models.EtatDown,
+		}
+	}
+
+	statut := verificateur.Verifier(ctx, moniteur)
+
+	// nouvelles tentatives avant de déclarer l'échec (paquet perdu, réseau instable)
+	reessais, delai := ParametresReessai(moniteur)
+	tentatives := 1
+reessayer:
+	for !statut.EstDisponible && tentatives <= reessais {
+		select {
+		case <-ctx.Done():
+			break reessayer
+		case <-time.After(delai):
+		}
+		statut = verificateur.Verifier(ctx, moniteur)
+		tentatives++
+	}
+	if !statut.EstDisponible && tentatives > 1 {
+		statut.MessageErreur += fmt.Sprintf(" (%d tentatives)", tentatives)
+	}
+
+	v.determinerEtat(&statut, moniteur)
+	return statut
+}
+
+// ParametresReessai retourne le nombre de nouvelles tentatives (borné) et le délai entre elles
+func ParametresReessai(moniteur models.Moniteur) (int, time.Duration) {
+	confirmation := moniteur.Parametres.Confirmation
+	if confirmation == nil || confirmation.Reessais <= 0 {
+		return 0, 0
+	}
+	return min(confirmation.Reessais, MaxReessais), time.Duration(confirmation.DelaiReessaiMs) * time.Millisecond
+}
+
+// Dégrade un moniteur trop lent et fixe l'état final du statut
+func (v *VerificateurParType) determinerEtat(statut *models.StatutMoniteur, moniteur models.Moniteur) {
+	seuil := v.SeuilLatenceLente
+	if moniteur.Parametres.SeuilLatenceMs > 0 {
+		seuil = time.Duration(moniteur.Parametres.SeuilLatenceMs) * time.Millisecond
+	}
+	if seuil > 0 && statut.Latence > seuil {
+		statut.Degrader(fmt.Sprintf("latence de %d ms au-dessus du seuil de %d ms",
+			statut.Latence.Milliseconds(), seuil.Milliseconds()))
+	}
+	statut.Etat = statut.EtatEffectif()
+}
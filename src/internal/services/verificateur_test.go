@@ -0,0 +1,112 @@
+/* Tests pour le calcul de l'état up / degrade / down
+ * Projet de session A25
+ * By : Leandre Kanmegne
+ *
+ * Remplace le vérificateur http par une fonction qui retourne une latence fixe
+ */
+package services
+
+import (
+	"context"
+	"strings"
+	"testing"
+	"time"
+
+	"example.com/go-hello/src/internal/models"
+)
+
+// crée un répartiteur dont le vérificateur http retourne le statut donné
+func creerVerificateurEtat(statut models.StatutMoniteur) *VerificateurParType {
+	return &VerificateurParType{
+		Verificateurs: map[string]Verificateur{
+			"http": VerificateurFunc(func(ctx context.Context, moniteur models.Moniteur) models.StatutMoniteur {
+				return statut
+			}),
+		},
+		SeuilLatenceLente: 500 * time.Millisecond,
+	}
+}
+
+// test : seuil global, seuil du moniteur et statut indisponible
+func TestVerificateurParType_Etat(t *testing.T) {
+	cas := []struct {
+		nom      string
+		statut   models.StatutMoniteur
+		seuilMs  int
+		attendu  string
+		contient string
+	}{
+		{"rapide", models.StatutMoniteur{EstDisponible: true, Latence: 100 * time.Millisecond}, 0, models.EtatUp, ""},
+		{"lent selon le seuil global", models.StatutMoniteur{EstDisponible: true, Latence: 600 * time.Millisecond}, 0, models.EtatDegrade, "seuil de 500 ms"},
+		{"seuil du moniteur plus large", models.StatutMoniteur{EstDisponible: true, Latence: 600 * time.Millisecond}, 1000, models.EtatUp, ""},
+		{"seuil du moniteur plus strict", models.StatutMoniteur{EstDisponible: true, Latence: 60 * time.Millisecond}, 50, models.EtatDegrade, "latence de 60 ms"},
+		{"indisponible reste down", models.StatutMoniteur{MessageErreur: "Bad Gateway", Latence: 2 * time.Second}, 0, models.EtatDown, "Bad Gateway"},
+	}
+
+	for _, c := range cas {
+		t.Run(c.nom, func(t *testing.T) {
+			moniteur := models.Moniteur{Type: "http", Parametres: models.ParametresMoniteur{SeuilLatenceMs: c.seuilMs}}
+			resultat := creerVerificateurEtat(c.statut).Verifier(context.Background(), moniteur)
+
+			if resultat.Etat != c.attendu {
+				t.Errorf("état %q attendu, reçu %q (%q)", c.attendu, resultat.Etat, resultat.MessageErreur)
+			}
+			if !strings.Contains(resultat.MessageErreur, c.contient) {
+				t.Errorf("message contenant %q attendu, reçu %q", c.contient, resultat.MessageErreur)
+			}
+		})
+	}
+}
+
+// test : une assertion souple dégrade au lieu de rendre indisponible
+func TestVerificateurParType_AssertionSouple(t *testing.T) {
+	souple := models.ParametresContenu{Contient: []string{"Bienvenue"}, Souple: true}
+	resultat := verifierContenu(t, "Site en Maintenance", souple)
+	NouveauVerificateur(VerificateurHTTP{}).determinerEtat(&resultat, models.Moniteur{})
+
+	if !resultat.EstDisponible || resultat.Etat != models.EtatDegrade {
+		t.Errorf("assertion souple : disponible et dégradé attendus, reçu %+v", resultat)
+	}
+	if !strings.Contains(resultat.MessageErreur, `"Bienvenue" absent`) {
+		t.Errorf("le message devrait citer l'assertion, reçu %q", resultat.MessageErreur)
+	}
+}
+
+// test : les nouvelles tentatives s'arrêtent au premier succès
+func TestVerificateurParType_Reessais(t *testing.T) {
+	appels := 0
+	verificateur := &VerificateurParType{Verificateurs: map[string]Verificateur{
+		"http": VerificateurFunc(func(ctx context.Context, moniteur models.Moniteur) models.StatutMoniteur {
+			appels++
+			// échoue deux fois puis répond
+			return models.StatutMoniteur{EstDisponible: appels == 3, MessageErreur: "paquet perdu"}
+		}),
+	}}
+	moniteur := func(reessais int) models.Moniteur {
+		return models.Moniteur{Type: "http", Parametres: models.ParametresMoniteur{
+			Confirmation: &models.ParametresConfirmation{Reessais: reessais, DelaiReessaiMs: 1},
+		}}
+	}
+
+	if resultat := verificateur.Verifier(context.Background(), moniteur(5)); resultat.Etat != models.EtatUp || appels != 3 {
+		t.Errorf("succès à la 3e tentative attendu, reçu %q après %d appels", resultat.Etat, appels)
+	}
+
+	appels = 0
+	resultat := verificateur.Verifier(context.Background(), moniteur(1))
+	if resultat.Etat != models.EtatDown || appels != 2 {
+		t.Errorf("échec après 2 tentatives attendu, reçu %q après %d appels", resultat.Etat, appels)
+	}
+	if !strings.Contains(resultat.MessageErreur, "(2 tentatives)") {
+		t.Errorf("le message devrait indiquer le nombre de tentatives, reçu %q", resultat.MessageErreur)
+	}
+
+	// contexte annulé : pas de nouvelle tentative
+	appels = 0
+	ctx, annuler := context.WithCancel(context.Background())
+	annuler()
+	verificateur.Verifier(ctx, moniteur(5))
+	if appels != 1 {
+		t.Errorf("une seule tentative attendue avec un contexte annulé, reçu %d", appels)
+	}
+}
@@ -0,0 +1,644 @@
+/* Contrat commun aux implémentations de Repo
+ * Projet de session A25
+ * By : Leandre Kanmegne
+ *
+ * Les mêmes tests passent sur la mémoire (memoire_test.go), sur SQLite (sqlite_test.go) et sur PostgreSQL (pg_test.go)
+ * Chaque sous-test reçoit un dépôt vide
+ */
+package repos
+
+import (
+	"context"
+	"errors"
+	"math"
+	"slices"
+	"testing"
+	"time"
+
+	"example.com/go-hello/src/internal/models"
+)
+
+// Ce que le contrat vérifie : le Repo et les opérations du répartiteur de notifications
+type depotContrat interface {
+	Repo
+	AlertesNonTraitees(ctx context.Context, depuis time.Time, limite int) ([]models.Alerte, error)
+	MarquerAlerteTraitee(ctx context.Context, id int) error
+	EnregistrerLivraison(ctx context.Context, livraison models.Livraison) error
+	CanauxLivres(ctx context.Context, alerteID int) ([]string, error)
+	CanauxPourAlerte(ctx context.Context, moniteurID int, typeAlerte string) ([]models.CanalNotification, error)
+}
+
+// Début des données de test (une heure pleine, loin de minuit)
+var origineContrat = time.Date(2025, time.March, 10, 10, 0, 0, 0, time.UTC)
+
+// Lance tous les tests du contrat ; nouveau retourne un dépôt vide avec ces réglages
+func testerContrat(t *testing.T, nouveau func(t *testing.T, reglages Reglages) depotContrat) {
+	tests := []struct {
+		nom string
+		fn  func(t *testing.T, depot depotContrat)
+	}{
+		{"Moniteurs", contratMoniteurs},
+		{"Statuts", contratStatuts},
+		{"Certificats", contratCertificats},
+		{"Transitions", contratTransitions},
+		{"Degradation", contratDegradation},
+		{"Maintenances", contratMaintenances},
+		{"Canaux", contratCanaux},
+		{"Historique", contratHistorique},
+		{"StatutTardif", contratStatutTardif},
+		{"ViderTout", contratViderTout},
+	}
+	for _, test := range tests {
+		t.Run(test.nom, func(t *testing.T) {
+			test.fn(t, nouveau(t, Reglages{}))
+		})
+	}
+
+	// moniteur sans intervalle : l'intervalle par défaut vient des réglages
+	t.Run("IntervalleParDefaut", func(t *testing.T) {
+		contratIntervalleParDefaut(t, nouveau(t, Reglages{IntervalleParDefaut: 20 * time.Second}))
+	})
+}
+
+// Ajoute un moniteur et retourne son ID
+func ajouterMoniteurContrat(t *testing.T, depot depotContrat, moniteur models.Moniteur) int {
+	t.Helper()
+	ctx := context.Background()
+	if err := depot.AjouterMoniteur(ctx, moniteur); err != nil {
+		t.Fatalf("ajout du moniteur %s : %v", moniteur.URL, err)
+	}
+	moniteurs, err := depot.ListerMoniteurs(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, m := range moniteurs {
+		if m.URL == moniteur.URL {
+			return m.ID
+		}
+	}
+	t.Fatalf("moniteur %s introuvable après l'ajout", moniteur.URL)
+	return 0
+}
+
+// Enregistre un statut à origineContrat + decalage
+func enregistrerStatutContrat(t *testing.T, depot depotContrat, moniteurID int, decalage time.Duration, etat string, latence time.Duration) {
+	t.Helper()
+	statut := models.StatutMoniteur{
+		MoniteurID:     moniteurID,
+		URL:            "https://contrat.test",
+		EstDisponible:  etat != models.EtatDown,
+		Etat:           etat,
+		CodeStatutHTTP: 200,
+		Latence:        latence,
+		VerifieA:       origineContrat.Add(decalage),
+	}
+	if etat == models.EtatDown {
+		statut.CodeStatutHTTP = 503
+		statut.MessageErreur = "service indisponible"
+	}
+	if err := depot.EnregistrerStatutMoniteur(context.Background(), statut); err != nil {
+		t.Fatalf("enregistrement du statut : %v", err)
+	}
+}
+
+// moniteurs : upsert par URL, tri par id, erreurs
+func contratMoniteurs(t *testing.T, depot depotContrat) {
+	ctx := context.Background()
+
+	if err := depot.AjouterMoniteur(ctx, models.Moniteur{Nom: "sans url"}); err == nil {
+		t.Error("l'URL devrait être obligatoire")
+	}
+
+	premier := ajouterMoniteurContrat(t, depot, models.Moniteur{
+		Nom: "Exemple", URL: "https://exemple.test", Actif: true,
+		Parametres: models.ParametresMoniteur{SeuilLatenceMs: 500},
+	})
+	second := ajouterMoniteurContrat(t, depot, models.Moniteur{Nom: "Autre", URL: "https://autre.test", Type: "https", Actif: true})
+	if second <= premier {
+		t.Errorf("ids croissants attendus, obtenu %d puis %d", premier, second)
+	}
+
+	// même URL : nom et intervalle mis à jour, paramètres conservés
+	if err := depot.AjouterMoniteur(ctx, models.Moniteur{Nom: "Renommé", URL: "https://exemple.test", IntervalleSecondes: 30}); err != nil {
+		t.Fatal(err)
+	}
+	moniteurs, err := depot.ListerMoniteurs(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(moniteurs) != 2 || moniteurs[0].ID != premier || moniteurs[1].ID != second {
+		t.Fatalf("deux moniteurs triés par id attendus, obtenu %+v", moniteurs)
+	}
+	if m := moniteurs[0]; m.Nom != "Renommé" || m.Type != "http" || m.IntervalleSecondes != 30 || m.Parametres.SeuilLatenceMs != 500 {
+		t.Errorf("upsert inattendu : %+v", m)
+	}
+
+	// création sans upsert : une URL déjà suivie est refusée et le moniteur existant n'est pas touché
+	if _, err := depot.CreerMoniteur(ctx, models.Moniteur{Nom: "Doublon", URL: "https://exemple.test"}); !errors.Is(err, ErrMoniteurExistant) {
+		t.Errorf("ErrMoniteurExistant attendue, obtenu %v", err)
+	}
+	if m, _ := depot.ObtenirMoniteur(ctx, premier); m.Nom != "Renommé" {
+		t.Errorf("le moniteur existant ne devrait pas changer, obtenu %+v", m)
+	}
+	cree, err := depot.CreerMoniteur(ctx, models.Moniteur{Nom: "Créé", URL: "https://cree.test", Actif: true, IntervalleSecondes: 45})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if cree.ID <= second || cree.Type != "http" || cree.IntervalleSecondes != 45 || !cree.Actif || cree.EtatConfirme != "" {
+		t.Errorf("moniteur créé inattendu : %+v", cree)
+	}
+
+	if _, err := depot.ObtenirMoniteur(ctx, second+100); !errors.Is(err, ErrMoniteurIntrouvable) {
+		t.Errorf("ErrMoniteurIntrouvable attendue, obtenu %v", err)
+	}
+
+	modifie := moniteurs[1]
+	modifie.URL = "https://exemple.test"
+	if err := depot.MettreAJourMoniteur(ctx, modifie); !errors.Is(err, ErrMoniteurExistant) {
+		t.Errorf("ErrMoniteurExistant attendue, obtenu %v", err)
+	}
+	modifie.ID = second + 100
+	modifie.URL = "https://nouveau.test"
+	if err := depot.MettreAJourMoniteur(ctx, modifie); !errors.Is(err, ErrMoniteurIntrouvable) {
+		t.Errorf("ErrMoniteurIntrouvable attendue, obtenu %v", err)
+	}
+
+	modifie.ID = second
+	modifie.Actif = false
+	modifie.Requete = &models.RequeteHTTP{Methode: "HEAD"}
+	if err := depot.MettreAJourMoniteur(ctx, modifie); err != nil {
+		t.Fatal(err)
+	}
+	lu, err := depot.ObtenirMoniteur(ctx, second)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if lu.URL != "https://nouveau.test" || lu.Actif || lu.Requete == nil || lu.Requete.Methode != "HEAD" {
+		t.Errorf("mise à jour inattendue : %+v", lu)
+	}
+
+	if err := depot.SupprimerMoniteur(ctx, "https://inconnu.test"); !errors.Is(err, ErrMoniteurIntrouvable) {
+		t.Errorf("ErrMoniteurIntrouvable attendue, obtenu %v", err)
+	}
+	enregistrerStatutContrat(t, depot, premier, 0, models.EtatUp, 0)
+	if err := depot.SupprimerMoniteur(ctx, "https://exemple.test"); err != nil {
+		t.Fatal(err)
+	}
+	if statuts, _ := depot.DerniersStatutsMoniteur(ctx, premier); len(statuts) != 0 {
+		t.Errorf("les statuts devraient être supprimés avec le moniteur, reste %d", len(statuts))
+	}
+}
+
+// statuts : tri, précision, état et certificat
+func contratStatuts(t *testing.T, depot depotContrat) {
+	ctx := context.Background()
+	id := ajouterMoniteurContrat(t, depot, models.Moniteur{Nom: "Statuts", URL: "https://statuts.test", Actif: true})
+
+	if err := depot.EnregistrerStatutMoniteur(ctx, models.StatutMoniteur{MoniteurID: id}); err == nil {
+		t.Error("l'URL du statut devrait être obligatoire")
+	}
+
+	expire := origineContrat.AddDate(0, 2, 0)
+	err := depot.EnregistrerStatutMoniteur(ctx, models.StatutMoniteur{
+		MoniteurID: id, URL: "https://statuts.test", EstDisponible: true, CodeStatutHTTP: 200,
+		Latence:       123*time.Millisecond + 456*time.Microsecond,
+		VerifieA:      origineContrat.Add(time.Minute),
+		DetailLatence: &models.DetailLatence{DNS: 5 * time.Millisecond, Transfert: 7*time.Millisecond + time.Microsecond},
+		Certificat:    &models.CertificatTLS{Sujet: "statuts.test", Emetteur: "AC test", SANs: []string{"statuts.test"}, ExpireA: expire},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	// l'état est déduit de EstDisponible
+	err = depot.EnregistrerStatutMoniteur(ctx, models.StatutMoniteur{
+		MoniteurID: id, URL: "https://statuts.test", EstDisponible: false, Etat: models.EtatUp, VerifieA: origineContrat,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	statuts, err := depot.DerniersStatutsMoniteur(ctx, id)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(statuts) != 2 || !statuts[0].VerifieA.Equal(origineContrat.Add(time.Minute)) {
+		t.Fatalf("deux statuts du plus récent au plus ancien attendus, obtenu %+v", statuts)
+	}
+	recent, ancien := statuts[0], statuts[1]
+	if recent.Latence != 123*time.Millisecond || recent.Etat != models.EtatUp {
+		t.Errorf("latence en millisecondes et état up attendus, obtenu %s / %s", recent.Latence, recent.Etat)
+	}
+	if recent.DetailLatence == nil || recent.DetailLatence.DNS != 5*time.Millisecond || recent.DetailLatence.Transfert != 7*time.Millisecond {
+		t.Errorf("détail de latence inattendu : %+v", recent.DetailLatence)
+	}
+	if c := recent.Certificat; c == nil || c.Sujet != "statuts.test" || len(c.SANs) != 1 || !c.ExpireA.Equal(expire) {
+		t.Errorf("certificat inattendu : %+v", recent.Certificat)
+	}
+	if ancien.Etat != models.EtatDown || ancien.Certificat != nil {
+		t.Errorf("statut down sans certificat attendu, obtenu %+v", ancien)
+	}
+}
+
+// transitions : confirmation, alertes et incidents (trigger de transition)
+func contratTransitions(t *testing.T, depot depotContrat) {
+	ctx := context.Background()
+	id := ajouterMoniteurContrat(t, depot, models.Moniteur{
+		Nom: "Transitions", URL: "https://transitions.test", Actif: true,
+		Parametres: models.ParametresMoniteur{Confirmation: &models.ParametresConfirmation{EchecsAvantDown: 2}},
+	})
+
+	enregistrerStatutContrat(t, depot, id, 0, models.EtatUp, 0)
+	enregistrerStatutContrat(t, depot, id, time.Minute, models.EtatDown, 0)
+	if m, _ := depot.ObtenirMoniteur(ctx, id); m.EtatConfirme != models.EtatUp {
+		t.Errorf("un seul échec ne devrait pas confirmer la panne, état %q", m.EtatConfirme)
+	}
+	enregistrerStatutContrat(t, depot, id, 2*time.Minute, models.EtatDown, 0)
+	if m, _ := depot.ObtenirMoniteur(ctx, id); m.EtatConfirme != models.EtatDown {
+		t.Errorf("panne confirmée attendue, état %q", m.EtatConfirme)
+	}
+
+	ouverts := true
+	incidents, err := depot.ListerIncidents(ctx, models.FiltreIncidents{MoniteurID: id, Ouverts: &ouverts})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(incidents) != 1 || !incidents[0].OuvertA.Equal(origineContrat.Add(2*time.Minute)) ||
+		incidents[0].CodeHTTP != 503 || incidents[0].URL != "https://transitions.test" {
+		t.Fatalf("un incident ouvert attendu, obtenu %+v", incidents)
+	}
+
+	enregistrerStatutContrat(t, depot, id, 3*time.Minute, models.EtatUp, 0)
+	incident, err := depot.ObtenirIncident(ctx, incidents[0].ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if incident.FermeA == nil || !incident.FermeA.Equal(origineContrat.Add(3*time.Minute)) {
+		t.Errorf("incident fermé par le retour attendu, obtenu %+v", incident)
+	}
+
+	alertes, err := depot.AlertesNonTraitees(ctx, time.Time{}, 10)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(alertes) != 2 || alertes[0].Type != models.AlerteDown || alertes[1].Type != models.AlerteUp ||
+		alertes[0].Details != "Indisponible - HTTP 503" || alertes[1].NomMoniteur != "Transitions" {
+		t.Fatalf("alertes DOWN puis UP attendues, obtenu %+v", alertes)
+	}
+	if err := depot.MarquerAlerteTraitee(ctx, alertes[0].ID); err != nil {
+		t.Fatal(err)
+	}
+	if restantes, _ := depot.AlertesNonTraitees(ctx, time.Time{}, 10); len(restantes) != 1 || restantes[0].ID != alertes[1].ID {
+		t.Errorf("seule l'alerte UP devrait rester, obtenu %+v", restantes)
+	}
+
+	// seuls les canaux avec une livraison réussie comptent comme livrés
+	for _, livraison := range []models.Livraison{
+		{AlerteID: alertes[1].ID, Canal: "webhook:a", Tentative: 1, Succes: false, Erreur: "timeout", CreeA: origineContrat},
+		{AlerteID: alertes[1].ID, Canal: "webhook:a", Tentative: 2, Succes: true, CreeA: origineContrat},
+		{AlerteID: alertes[1].ID, Canal: "email:b", Tentative: 1, Succes: false, CodeHTTP: 503, CreeA: origineContrat},
+	} {
+		if err := depot.EnregistrerLivraison(ctx, livraison); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if livres, err := depot.CanauxLivres(ctx, alertes[1].ID); err != nil || !slices.Equal(livres, []string{"webhook:a"}) {
+		t.Errorf("canal livré webhook:a attendu, obtenu %v (%v)", livres, err)
+	}
+	if livres, _ := depot.CanauxLivres(ctx, alertes[0].ID); len(livres) != 0 {
+		t.Errorf("aucun canal livré attendu, obtenu %v", livres)
+	}
+
+	acquitte, err := depot.AcquitterIncident(ctx, incident.ID, "alice", "redémarré")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if acquitte.AcquitteA == nil || acquitte.AcquittePar != "alice" || acquitte.NoteAcquittement != "redémarré" {
+		t.Errorf("acquittement inattendu : %+v", acquitte)
+	}
+	if _, err := depot.AcquitterIncident(ctx, incident.ID, "bob", ""); !errors.Is(err, ErrIncidentAcquitte) {
+		t.Errorf("ErrIncidentAcquitte attendue, obtenu %v", err)
+	}
+	if _, err := depot.AcquitterIncident(ctx, incident.ID+100, "bob", ""); !errors.Is(err, ErrIncidentIntrouvable) {
+		t.Errorf("ErrIncidentIntrouvable attendue, obtenu %v", err)
+	}
+}
+
+// certificats : chaque statut relit le certificat observé, même quand la ligne est partagée
+func contratCertificats(t *testing.T, depot depotContrat) {
+	ctx := context.Background()
+	id := ajouterMoniteurContrat(t, depot, models.Moniteur{Nom: "Certificats", URL: "https://certificats.test", Actif: true})
+
+	ancien := models.CertificatTLS{Sujet: "CN=certificats.test", Emetteur: "CN=AC test", SANs: []string{"certificats.test"},
+		ExpireA: origineContrat.AddDate(0, 1, 0), Empreinte: "aa"}
+	renouvele := ancien
+	renouvele.ExpireA, renouvele.Empreinte = origineContrat.AddDate(0, 4, 0), "bb"
+
+	for i, certificat := range []models.CertificatTLS{ancien, ancien, renouvele} {
+		err := depot.EnregistrerStatutMoniteur(ctx, models.StatutMoniteur{
+			MoniteurID: id, URL: "https://certificats.test", EstDisponible: true, CodeStatutHTTP: 200,
+			VerifieA: origineContrat.Add(time.Duration(i) * time.Minute), Certificat: &certificat,
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	statuts, err := depot.DerniersStatutsMoniteur(ctx, id)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(statuts) != 3 {
+		t.Fatalf("3 statuts attendus, obtenu %d", len(statuts))
+	}
+	// du plus récent au plus ancien
+	for i, attendu := range []models.CertificatTLS{renouvele, ancien, ancien} {
+		c := statuts[i].Certificat
+		if c == nil || c.Empreinte != attendu.Empreinte || !c.ExpireA.Equal(attendu.ExpireA) || !slices.Equal(c.SANs, attendu.SANs) {
+			t.Errorf("statut %d : certificat %+v attendu, obtenu %+v", i, attendu, c)
+		}
+	}
+}
+
+// dégradation : le retour à up sans panne donne DEGRADE_FIN, seul un retour après down donne UP
+func contratDegradation(t *testing.T, depot depotContrat) {
+	ctx := context.Background()
+	id := ajouterMoniteurContrat(t, depot, models.Moniteur{Nom: "Lent", URL: "https://lent.test", Actif: true})
+
+	enregistrerStatutContrat(t, depot, id, 0, models.EtatUp, 100*time.Millisecond)
+	enregistrerStatutContrat(t, depot, id, time.Minute, models.EtatDegrade, 3*time.Second)
+	enregistrerStatutContrat(t, depot, id, 2*time.Minute, models.EtatUp, 150*time.Millisecond)
+	enregistrerStatutContrat(t, depot, id, 3*time.Minute, models.EtatDegrade, 3*time.Second)
+	enregistrerStatutContrat(t, depot, id, 4*time.Minute, models.EtatDown, 0)
+	enregistrerStatutContrat(t, depot, id, 5*time.Minute, models.EtatUp, 100*time.Millisecond)
+
+	alertes, err := depot.AlertesNonTraitees(ctx, time.Time{}, 10)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var types []string
+	for _, alerte := range alertes {
+		types = append(types, alerte.Type)
+	}
+	attendus := []string{models.AlerteDegrade, models.AlerteDegradeFin, models.AlerteDegrade, models.AlerteDown, models.AlerteUp}
+	if !slices.Equal(types, attendus) {
+		t.Fatalf("alertes %v attendues, obtenu %v", attendus, types)
+	}
+	if alertes[1].Details != "Performances rétablies - latence 150 ms" {
+		t.Errorf("détails de la fin de dégradation inattendus : %q", alertes[1].Details)
+	}
+}
+
+// maintenances : liens vers les moniteurs, statuts en maintenance sans alerte
+func contratMaintenances(t *testing.T, depot depotContrat) {
+	ctx := context.Background()
+	id := ajouterMoniteurContrat(t, depot, models.Moniteur{Nom: "Maintenu", URL: "https://maintenu.test", Actif: true})
+
+	debut, fin := origineContrat, origineContrat.Add(time.Hour)
+	if _, err := depot.AjouterMaintenance(ctx, models.Maintenance{Nom: "x", Debut: &debut, Fin: &fin, Fuseau: "UTC", MoniteurIDs: []int{id + 100}}); !errors.Is(err, ErrMoniteurIntrouvable) {
+		t.Errorf("ErrMoniteurIntrouvable attendue, obtenu %v", err)
+	}
+	maintenance, err := depot.AjouterMaintenance(ctx, models.Maintenance{Nom: "Mise à jour", Debut: &debut, Fin: &fin, Fuseau: "UTC", MoniteurIDs: []int{id, id}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	lue, err := depot.ObtenirMaintenance(ctx, maintenance.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(lue.MoniteurIDs) != 1 || lue.MoniteurIDs[0] != id || !lue.Fin.Equal(fin) {
+		t.Errorf("maintenance inattendue : %+v", lue)
+	}
+
+	enregistrerStatutContrat(t, depot, id, -time.Minute, models.EtatUp, 0)
+	enregistrerStatutContrat(t, depot, id, time.Minute, models.EtatDown, 0)
+	statuts, _ := depot.DerniersStatutsMoniteur(ctx, id)
+	if len(statuts) != 2 || !statuts[0].EnMaintenance || statuts[1].EnMaintenance {
+		t.Errorf("seul le statut pendant la maintenance devrait être marqué, obtenu %+v", statuts)
+	}
+	if incidents, _ := depot.ListerIncidents(ctx, models.FiltreIncidents{}); len(incidents) != 0 {
+		t.Errorf("aucun incident attendu pendant une maintenance, obtenu %+v", incidents)
+	}
+
+	if err := depot.SupprimerMaintenance(ctx, maintenance.ID); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := depot.ObtenirMaintenance(ctx, maintenance.ID); !errors.Is(err, ErrMaintenanceIntrouvable) {
+		t.Errorf("ErrMaintenanceIntrouvable attendue, obtenu %v", err)
+	}
+	if err := depot.MettreAJourMaintenance(ctx, maintenance); !errors.Is(err, ErrMaintenanceIntrouvable) {
+		t.Errorf("ErrMaintenanceIntrouvable attendue, obtenu %v", err)
+	}
+}
+
+// canaux et routes : nom unique (création et mise à jour), routage par type, cascade
+func contratCanaux(t *testing.T, depot depotContrat) {
+	ctx := context.Background()
+	id := ajouterMoniteurContrat(t, depot, models.Moniteur{Nom: "Routé", URL: "https://route.test", Actif: true})
+
+	slack, err := depot.AjouterCanal(ctx, models.CanalNotification{Nom: "slack", Type: models.CanalSlack, Actif: true,
+		Config: models.ConfigCanal{URL: "https://hooks.slack.test/x"}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if slack.ID == 0 || slack.CreeA.IsZero() || slack.Config.URL != "https://hooks.slack.test/x" {
+		t.Errorf("canal créé inattendu : %+v", slack)
+	}
+	if _, err := depot.AjouterCanal(ctx, models.CanalNotification{Nom: "slack", Type: models.CanalWebhook}); !errors.Is(err, ErrCanalExistant) {
+		t.Errorf("ErrCanalExistant attendue, obtenu %v", err)
+	}
+	webhook, err := depot.AjouterCanal(ctx, models.CanalNotification{Nom: "webhook", Type: models.CanalWebhook, Actif: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	modifie := webhook
+	modifie.Nom = "webhook-ops"
+	modifie.Config = models.ConfigCanal{URL: "https://ops.test/alertes", Secret: "s3cret"}
+	if err := depot.MettreAJourCanal(ctx, modifie); err != nil {
+		t.Fatal(err)
+	}
+	lu, err := depot.ObtenirCanal(ctx, webhook.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if lu.Nom != "webhook-ops" || lu.Type != models.CanalWebhook || !lu.Actif || lu.Config.URL != "https://ops.test/alertes" ||
+		lu.Config.Secret != "s3cret" || !lu.CreeA.Equal(webhook.CreeA) {
+		t.Errorf("canal mis à jour inattendu : %+v", lu)
+	}
+	modifie.Nom = "slack"
+	if err := depot.MettreAJourCanal(ctx, modifie); !errors.Is(err, ErrCanalExistant) {
+		t.Errorf("ErrCanalExistant attendue, obtenu %v", err)
+	}
+	modifie.ID, modifie.Nom = slack.ID+100, "inconnu"
+	if err := depot.MettreAJourCanal(ctx, modifie); !errors.Is(err, ErrCanalIntrouvable) {
+		t.Errorf("ErrCanalIntrouvable attendue, obtenu %v", err)
+	}
+
+	routes := []models.RouteNotification{
+		{CanalID: webhook.ID, Types: []string{models.AlerteDown}},
+		{CanalID: slack.ID, Types: []string{models.AlerteDown, models.AlerteUp}},
+	}
+	if err := depot.DefinirRoutesMoniteur(ctx, id, append(routes, models.RouteNotification{CanalID: webhook.ID + 100})); !errors.Is(err, ErrCanalIntrouvable) {
+		t.Errorf("ErrCanalIntrouvable attendue, obtenu %v", err)
+	}
+	if err := depot.DefinirRoutesMoniteur(ctx, id, routes); err != nil {
+		t.Fatal(err)
+	}
+	lues, err := depot.RoutesMoniteur(ctx, id)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(lues) != 2 || lues[0].CanalID != slack.ID || lues[0].MoniteurID != id || len(lues[0].Types) != 2 {
+		t.Errorf("routes triées par canal attendues, obtenu %+v", lues)
+	}
+	if canaux, _ := depot.CanauxPourAlerte(ctx, id, models.AlerteUp); len(canaux) != 1 || canaux[0].ID != slack.ID {
+		t.Errorf("seul slack reçoit les alertes UP, obtenu %+v", canaux)
+	}
+
+	if err := depot.SupprimerCanal(ctx, slack.ID); err != nil {
+		t.Fatal(err)
+	}
+	if lues, _ := depot.RoutesMoniteur(ctx, id); len(lues) != 1 || lues[0].CanalID != webhook.ID {
+		t.Errorf("la route vers le canal supprimé devrait disparaître, obtenu %+v", lues)
+	}
+	if err := depot.SupprimerCanal(ctx, slack.ID); !errors.Is(err, ErrCanalIntrouvable) {
+		t.Errorf("ErrCanalIntrouvable attendue, obtenu %v", err)
+	}
+}
+
+// historique : disponibilité et latences, identiques avant et après consolidation et purge
+func contratHistorique(t *testing.T, depot depotContrat) {
+	ctx := context.Background()
+	id := ajouterMoniteurContrat(t, depot, models.Moniteur{Nom: "Historique", URL: "https://historique.test", Actif: true, IntervalleSecondes: 60})
+
+	enregistrerStatutContrat(t, depot, id, 0, models.EtatUp, 100*time.Millisecond)
+	enregistrerStatutContrat(t, depot, id, time.Minute, models.EtatDown, 200*time.Millisecond)
+	enregistrerStatutContrat(t, depot, id, 2*time.Minute, models.EtatUp, 300*time.Millisecond)
+
+	jour := origineContrat.Truncate(24 * time.Hour)
+	verifier := func(etape string) {
+		t.Helper()
+		// up 60 s, down 60 s, puis up jusqu'à 3 intervalles (180 s)
+		rapport, err := depot.DisponibiliteMoniteur(ctx, id, jour, jour.Add(24*time.Hour))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if rapport.Verifications != 3 || rapport.SecondesObservees != 300 || rapport.SecondesIndisponibles != 60 || rapport.Incidents != 1 {
+			t.Errorf("%s : rapport inattendu %+v", etape, rapport)
+		}
+		if rapport.DisponibilitePct == nil || math.Abs(*rapport.DisponibilitePct-80) > 0.001 {
+			t.Errorf("%s : 80 %% de disponibilité attendu, obtenu %v", etape, rapport.DisponibilitePct)
+		}
+
+		agregats, err := depot.AgregatsLatence(ctx, id, jour, jour.Add(24*time.Hour), time.Hour)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(agregats) != 1 || !agregats[0].Debut.Equal(origineContrat) || agregats[0].Verifications != 3 {
+			t.Fatalf("%s : un agrégat horaire attendu, obtenu %+v", etape, agregats)
+		}
+		a := agregats[0]
+		if math.Abs(a.TauxSucces-2.0/3) > 0.001 || *a.MinMs != 100 || *a.MaxMs != 300 || *a.P50Ms != 200 || math.Abs(*a.P90Ms-280) > 0.001 {
+			t.Errorf("%s : agrégat inattendu %+v", etape, a)
+		}
+	}
+
+	verifier("statuts bruts")
+
+	lignes, err := depot.ConsoliderStatuts(ctx, jour.Add(48*time.Hour))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if lignes != 2 { // une heure et un jour
+		t.Errorf("2 lignes consolidées attendues, obtenu %d", lignes)
+	}
+	verifier("après consolidation")
+
+	// purge par lots de 2 : 2 puis 1
+	for _, attendu := range []int64{2, 1, 0} {
+		supprimees, err := depot.PurgerLot(ctx, models.CibleStatuts, jour.Add(24*time.Hour), 2)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if supprimees != attendu {
+			t.Errorf("%d statut(s) supprimé(s) attendu(s), obtenu %d", attendu, supprimees)
+		}
+	}
+	verifier("après purge des statuts")
+
+	if _, err := depot.PurgerLot(ctx, models.CiblePurge("inconnue"), jour, 10); err == nil {
+		t.Error("une cible inconnue devrait être refusée")
+	}
+}
+
+// statut tardif : enregistré après la fin de son heure, il est compté tant que le délai de grâce n'est pas écoulé
+func contratStatutTardif(t *testing.T, depot depotContrat) {
+	ctx := context.Background()
+	id := ajouterMoniteurContrat(t, depot, models.Moniteur{Nom: "Tardif", URL: "https://tardif.test", Actif: true, IntervalleSecondes: 60})
+	finHeure := origineContrat.Add(time.Hour)
+
+	enregistrerStatutContrat(t, depot, id, 0, models.EtatUp, 100*time.Millisecond)
+	// l'heure est terminée mais le délai de grâce ne l'est pas : rien n'est consolidé
+	if lignes, err := depot.ConsoliderStatuts(ctx, finHeure.Add(DelaiGraceParDefaut/2)); err != nil || lignes != 0 {
+		t.Fatalf("aucune ligne consolidée attendue pendant le délai de grâce, obtenu %d (%v)", lignes, err)
+	}
+
+	// statut d'une vérification commencée avant la fin de l'heure (tentatives), enregistré après
+	enregistrerStatutContrat(t, depot, id, 59*time.Minute, models.EtatDown, 300*time.Millisecond)
+	if lignes, err := depot.ConsoliderStatuts(ctx, finHeure.Add(DelaiGraceParDefaut)); err != nil || lignes != 1 {
+		t.Fatalf("une ligne horaire consolidée attendue, obtenu %d (%v)", lignes, err)
+	}
+
+	// l'heure consolidée est lue dans l'agrégat horaire
+	agregats, err := depot.AgregatsLatence(ctx, id, origineContrat, finHeure, time.Hour)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(agregats) != 1 || agregats[0].Verifications != 2 || math.Abs(agregats[0].TauxSucces-0.5) > 0.001 {
+		t.Errorf("l'agrégat horaire devrait compter le statut tardif, obtenu %+v", agregats)
+	}
+}
+
+// intervalle par défaut de 20 s : un statut couvre au plus 60 s, en brut comme après consolidation
+func contratIntervalleParDefaut(t *testing.T, depot depotContrat) {
+	ctx := context.Background()
+	id := ajouterMoniteurContrat(t, depot, models.Moniteur{Nom: "Sans intervalle", URL: "https://sans-intervalle.test", Actif: true})
+
+	enregistrerStatutContrat(t, depot, id, 0, models.EtatUp, 100*time.Millisecond)
+	enregistrerStatutContrat(t, depot, id, 10*time.Minute, models.EtatDown, 100*time.Millisecond)
+
+	verifier := func(etape string) {
+		t.Helper()
+		rapport, err := depot.DisponibiliteMoniteur(ctx, id, origineContrat, origineContrat.Add(time.Hour))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if rapport.SecondesObservees != 120 || rapport.SecondesIndisponibles != 60 {
+			t.Errorf("%s : 120 s observées dont 60 s indisponibles attendues, obtenu %+v", etape, rapport)
+		}
+	}
+
+	verifier("statuts bruts")
+	if _, err := depot.ConsoliderStatuts(ctx, origineContrat.Add(48*time.Hour)); err != nil {
+		t.Fatal(err)
+	}
+	verifier("après consolidation")
+}
+
+// ViderTout : moniteurs et statuts supprimés, compteurs remis à zéro
+func contratViderTout(t *testing.T, depot depotContrat) {
+	ctx := context.Background()
+	id := ajouterMoniteurContrat(t, depot, models.Moniteur{Nom: "A", URL: "https://a.test", Actif: true})
+	ajouterMoniteurContrat(t, depot, models.Moniteur{Nom: "B", URL: "https://b.test", Actif: true})
+	enregistrerStatutContrat(t, depot, id, 0, models.EtatUp, 0)
+
+	if err := depot.ViderTout(ctx); err != nil {
+		t.Fatal(err)
+	}
+	if moniteurs, _ := depot.ListerMoniteurs(ctx); len(moniteurs) != 0 {
+		t.Errorf("aucun moniteur attendu, obtenu %+v", moniteurs)
+	}
+	if nouveau := ajouterMoniteurContrat(t, depot, models.Moniteur{Nom: "C", URL: "https://c.test", Actif: true}); nouveau != 1 {
+		t.Errorf("id 1 attendu après ViderTout, obtenu %d", nouveau)
+	}
+}
@@ -0,0 +1,988 @@
+/* Implémentation en mémoire du repo
+ * Projet de session A25
+ * By : Leandre Kanmegne
+ *
+ * Même comportement que l'implémentation PostgreSQL (upsert par URL, erreurs, tris, cascades)
+ * sans base de données : pour les tests et le mode démo (STOCKAGE=memoire)
+ * Le trigger de transition (confirmation, alertes, incidents) est refait en Go dans detecterTransition
+ * Les valeurs sont copiées à l'entrée et à la sortie, comme si elles passaient par la base
+ * Un seul mutex protège tout : les opérations sont courtes et restent atomiques
+ * Les données sont perdues à l'arrêt du serveur
+ */
+package repos
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"slices"
+	"sort"
+	"sync"
+	"time"
+
+	"example.com/go-hello/src/internal/models"
+)
+
+// Un statut enregistré avec son id (ordre d'insertion)
+type statutMemoire struct {
+	id int
+	models.StatutMoniteur
+}
+
+// Une alerte enregistrée (monitoring.alertes)
+type alerteMemoire struct {
+	id         int
+	moniteurID int
+	typeAlerte string
+	details    string
+	codeHTTP   int
+	creeA      time.Time
+	traiteeA   *time.Time
+}
+
+// Une tentative d'envoi enregistrée (monitoring.livraisons)
+type livraisonMemoire struct {
+	id int
+	models.Livraison
+}
+
+// Memoire implémente Repo en mémoire
+type Memoire struct {
+	mu sync.Mutex
+
+	moniteurs    []models.Moniteur // triés par id
+	statuts      []statutMemoire   // triés par id
+	alertes      []alerteMemoire   // triées par id
+	livraisons   []livraisonMemoire
+	incidents    []models.Incident // sans NomMoniteur ni URL (lus dans le moniteur)
+	canaux       []models.CanalNotification
+	routes       []models.RouteNotification
+	maintenances []models.Maintenance
+
+	agregats   map[string]map[cleAgregat]agregatMemoire // par granularité (voir memoire_historique.go)
+	filigranes map[string]time.Time                     // par granularité
+
+	sequences map[string]int // dernier id attribué par table
+
+	reglages Reglages
+}
+
+// Crée un repo en mémoire vide
+func NouvelleMemoire(reglages Reglages) *Memoire {
+	return &Memoire{
+		reglages:   reglages.completer(),
+		agregats:   map[string]map[cleAgregat]agregatMemoire{},
+		filigranes: map[string]time.Time{},
+		sequences:  map[string]int{},
+	}
+}
+
+// Ne fait rien (même signature que Postgres.Fermer)
+func (m *Memoire) Fermer() error {
+	return nil
+}
+
+// Attribue le prochain id d'une table (comme BIGSERIAL)
+func (m *Memoire) prochainID(table string) int {
+	m.sequences[table]++
+	return m.sequences[table]
+}
+
+// Heure courante à la précision de PostgreSQL (microseconde)
+func maintenantMemoire() time.Time {
+	return arrondirTemps(time.Now())
+}
+
+// Arrondit à la microseconde comme TIMESTAMPTZ
+func arrondirTemps(t time.Time) time.Time {
+	return t.Round(time.Microsecond)
+}
+
+// Copie une valeur en passant par JSON, comme une colonne JSONB
+func copierJSON[T any](valeur T) (T, error) {
+	var copie T
+	donnees, err := json.Marshal(valeur)
+	if err != nil {
+		return copie, err
+	}
+	err = json.Unmarshal(donnees, &copie)
+	return copie, err
+}
+
+// Retourne l'index d'un moniteur par son ID (-1 si introuvable)
+func (m *Memoire) indexMoniteur(id int) int {
+	for i, moniteur := range m.moniteurs {
+		if moniteur.ID == id {
+			return i
+		}
+	}
+	return -1
+}
+
+// Normalise les colonnes JSONB d'un moniteur
+func normaliserMoniteur(moniteur models.Moniteur) (models.Moniteur, error) {
+	parametres, err := copierJSON(moniteur.Parametres)
+	if err != nil {
+		return models.Moniteur{}, err
+	}
+	moniteur.Parametres = parametres
+	if moniteur.Requete != nil {
+		requete, err := copierJSON(*moniteur.Requete)
+		if err != nil {
+			return models.Moniteur{}, err
+		}
+		moniteur.Requete = &requete
+	}
+	return moniteur, nil
+}
+
+// Retourne une copie d'un moniteur (les pointeurs ne sont pas partagés)
+func copierMoniteur(moniteur models.Moniteur) models.Moniteur {
+	copie, err := normaliserMoniteur(moniteur)
+	if err != nil {
+		// déjà encodé une fois à l'enregistrement
+		panic(err)
+	}
+	return copie
+}
+
+// Ajoute un moniteur (ou met à jour le nom, le type et l'intervalle si l'URL existe déjà)
+func (m *Memoire) AjouterMoniteur(ctx context.Context, moniteur models.Moniteur) error {
+	if moniteur.URL == "" {
+		return errors.New("l'URL du moniteur est obligatoire")
+	}
+	if moniteur.Type == "" {
+		moniteur.Type = "http"
+	}
+	moniteur, err := normaliserMoniteur(moniteur)
+	if err != nil {
+		return err
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	for i := range m.moniteurs {
+		existant := &m.moniteurs[i]
+		if existant.URL != moniteur.URL {
+			continue
+		}
+		if moniteur.Nom != "" {
+			existant.Nom = moniteur.Nom
+		}
+		existant.Type = moniteur.Type
+		if moniteur.IntervalleSecondes != 0 {
+			existant.IntervalleSecondes = moniteur.IntervalleSecondes
+		}
+		return nil
+	}
+
+	moniteur.ID = m.prochainID("moniteurs")
+	moniteur.EtatConfirme = ""
+	m.moniteurs = append(m.moniteurs, moniteur)
+	return nil
+}
+
+// Crée un moniteur et le retourne avec son id (ErrMoniteurExistant si l'URL existe, comme la contrainte UNIQUE)
+func (m *Memoire) CreerMoniteur(ctx context.Context, moniteur models.Moniteur) (models.Moniteur, error) {
+	if moniteur.URL == "" {
+		return models.Moniteur{}, errors.New("l'URL du moniteur est obligatoire")
+	}
+	if moniteur.Type == "" {
+		moniteur.Type = "http"
+	}
+	moniteur, err := normaliserMoniteur(moniteur)
+	if err != nil {
+		return models.Moniteur{}, err
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	for _, existant := range m.moniteurs {
+		if existant.URL == moniteur.URL {
+			return models.Moniteur{}, ErrMoniteurExistant
+		}
+	}
+	moniteur.ID = m.prochainID("moniteurs")
+	moniteur.EtatConfirme = ""
+	m.moniteurs = append(m.moniteurs, moniteur)
+	return copierMoniteur(moniteur), nil
+}
+
+// Supprime un moniteur par URL (et tout ce qui en dépend)
+func (m *Memoire) SupprimerMoniteur(ctx context.Context, url string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	for i, moniteur := range m.moniteurs {
+		if moniteur.URL == url {
+			m.moniteurs = slices.Delete(m.moniteurs, i, i+1)
+			m.supprimerDependances(moniteur.ID)
+			return nil
+		}
+	}
+	return ErrMoniteurIntrouvable
+}
+
+// Supprime les lignes liées à un moniteur (ON DELETE CASCADE)
+func (m *Memoire) supprimerDependances(moniteurID int) {
+	m.statuts = slices.DeleteFunc(m.statuts, func(s statutMemoire) bool { return s.MoniteurID == moniteurID })
+	m.incidents = slices.DeleteFunc(m.incidents, func(i models.Incident) bool { return i.MoniteurID == moniteurID })
+	m.routes = slices.DeleteFunc(m.routes, func(r models.RouteNotification) bool { return r.MoniteurID == moniteurID })
+	m.alertes = slices.DeleteFunc(m.alertes, func(a alerteMemoire) bool { return a.moniteurID == moniteurID })
+	m.supprimerLivraisonsOrphelines()
+	for i := range m.maintenances {
+		m.maintenances[i].MoniteurIDs = slices.DeleteFunc(m.maintenances[i].MoniteurIDs, func(id int) bool { return id == moniteurID })
+	}
+	for _, agregats := range m.agregats {
+		for cle := range agregats {
+			if cle.moniteurID == moniteurID {
+				delete(agregats, cle)
+			}
+		}
+	}
+}
+
+// Supprime les livraisons dont l'alerte n'existe plus
+func (m *Memoire) supprimerLivraisonsOrphelines() {
+	ids := map[int]bool{}
+	for _, alerte := range m.alertes {
+		ids[alerte.id] = true
+	}
+	m.livraisons = slices.DeleteFunc(m.livraisons, func(l livraisonMemoire) bool { return !ids[l.AlerteID] })
+}
+
+// Retourne tous les moniteurs
+func (m *Memoire) ListerMoniteurs(ctx context.Context) ([]models.Moniteur, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	var moniteurs []models.Moniteur
+	for _, moniteur := range m.moniteurs {
+		moniteurs = append(moniteurs, copierMoniteur(moniteur))
+	}
+	return moniteurs, nil
+}
+
+// Retourne un moniteur par son ID
+func (m *Memoire) ObtenirMoniteur(ctx context.Context, id int) (models.Moniteur, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	i := m.indexMoniteur(id)
+	if i < 0 {
+		return models.Moniteur{}, ErrMoniteurIntrouvable
+	}
+	return copierMoniteur(m.moniteurs[i]), nil
+}
+
+// Met à jour tous les champs modifiables d'un moniteur
+func (m *Memoire) MettreAJourMoniteur(ctx context.Context, moniteur models.Moniteur) error {
+	if moniteur.URL == "" {
+		return errors.New("l'URL du moniteur est obligatoire")
+	}
+	if moniteur.Type == "" {
+		moniteur.Type = "http"
+	}
+	moniteur, err := normaliserMoniteur(moniteur)
+	if err != nil {
+		return err
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	i := m.indexMoniteur(moniteur.ID)
+	if i < 0 {
+		return ErrMoniteurIntrouvable
+	}
+	for _, autre := range m.moniteurs {
+		if autre.ID != moniteur.ID && autre.URL == moniteur.URL {
+			return ErrMoniteurExistant
+		}
+	}
+
+	// l'état confirmé est tenu par le trigger
+	moniteur.EtatConfirme = m.moniteurs[i].EtatConfirme
+	m.moniteurs[i] = moniteur
+	return nil
+}
+
+// Enregistre un statut (et son certificat) puis applique le trigger de transition
+func (m *Memoire) EnregistrerStatutMoniteur(ctx context.Context, statut models.StatutMoniteur) error {
+	if statut.URL == "" {
+		return errors.New("l'URL est obligatoire pour un statut")
+	}
+	if statut.VerifieA.IsZero() {
+		statut.VerifieA = time.Now()
+	}
+	statut = normaliserStatut(statut)
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if statut.MoniteurID != 0 {
+		if m.indexMoniteur(statut.MoniteurID) < 0 {
+			return ErrMoniteurIntrouvable
+		}
+		// statut vérifié pendant une maintenance : pas d'alerte
+		if !statut.EnMaintenance {
+			statut.EnMaintenance = m.enMaintenance(statut.MoniteurID, statut.VerifieA)
+		}
+	}
+
+	enregistre := statutMemoire{id: m.prochainID("statuts"), StatutMoniteur: statut}
+	m.statuts = append(m.statuts, enregistre)
+	m.detecterTransition(enregistre)
+	return nil
+}
+
+// Ramène un statut à ce que la base conserve (millisecondes, microsecondes, état calculé, copies)
+func normaliserStatut(statut models.StatutMoniteur) models.StatutMoniteur {
+	statut.VerifieA = arrondirTemps(statut.VerifieA)
+	statut.Latence = statut.Latence.Truncate(time.Millisecond)
+	statut.Etat = statut.EtatEffectif()
+	if len(statut.Redirections) > 0 {
+		statut.Redirections = slices.Clone(statut.Redirections)
+	} else {
+		statut.Redirections = nil
+	}
+	if detail := statut.DetailLatence; detail != nil {
+		statut.DetailLatence = &models.DetailLatence{
+			DNS:          detail.DNS.Truncate(time.Millisecond),
+			Connexion:    detail.Connexion.Truncate(time.Millisecond),
+			TLS:          detail.TLS.Truncate(time.Millisecond),
+			PremierOctet: detail.PremierOctet.Truncate(time.Millisecond),
+			Transfert:    detail.Transfert.Truncate(time.Millisecond),
+		}
+	}
+	if certificat := statut.Certificat; certificat != nil {
+		statut.Certificat = &models.CertificatTLS{
+			Sujet:     certificat.Sujet,
+			Emetteur:  certificat.Emetteur,
+			SANs:      slices.Clone(certificat.SANs),
+			ExpireA:   arrondirTemps(certificat.ExpireA),
+			Empreinte: certificat.Empreinte,
+		}
+	}
+	return statut
+}
+
+// DerniersStatutsMoniteur récupère les statuts d'un moniteur, du plus récent au plus ancien
+func (m *Memoire) DerniersStatutsMoniteur(ctx context.Context, moniteurID int) ([]models.StatutMoniteur, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	var trouves []statutMemoire
+	for _, statut := range m.statuts {
+		if statut.MoniteurID == moniteurID {
+			trouves = append(trouves, statut)
+		}
+	}
+	sort.SliceStable(trouves, func(i, j int) bool { return trouves[i].VerifieA.After(trouves[j].VerifieA) })
+
+	var statuts []models.StatutMoniteur
+	for _, statut := range trouves {
+		statuts = append(statuts, normaliserStatut(statut.StatutMoniteur))
+	}
+	return statuts, nil
+}
+
+// Refait le trigger monitoring.detecter_transition pour un statut qui vient d'être ajouté
+// Voir migrations/0003_alertes_et_notifications.up.sql pour les règles
+func (m *Memoire) detecterTransition(nouveau statutMemoire) {
+	if nouveau.MoniteurID == 0 || nouveau.EnMaintenance {
+		return
+	}
+	moniteur := &m.moniteurs[m.indexMoniteur(nouveau.MoniteurID)]
+
+	echecsRequis, succesRequis := 1, 1
+	if confirmation := moniteur.Parametres.Confirmation; confirmation != nil {
+		echecsRequis = max(confirmation.EchecsAvantDown, 1)
+		succesRequis = max(confirmation.SuccesAvantUp, 1)
+	}
+
+	// premier statut : l'état est confirmé sans alerte
+	ancienEtat := moniteur.EtatConfirme
+	if ancienEtat == "" {
+		moniteur.EtatConfirme = nouveau.Etat
+		return
+	}
+	if ancienEtat == nouveau.Etat {
+		return
+	}
+
+	// nombre de vérifications consécutives nécessaires pour confirmer le changement
+	requis := succesRequis
+	if nouveau.Etat != models.EtatUp && ancienEtat != models.EtatDown {
+		requis = echecsRequis
+	}
+
+	// compte les derniers statuts (le nouveau compris) qui vont dans le sens du changement
+	var derniers []statutMemoire
+	for _, statut := range m.statuts {
+		if statut.MoniteurID == nouveau.MoniteurID && !statut.EnMaintenance {
+			derniers = append(derniers, statut)
+		}
+	}
+	sort.Slice(derniers, func(i, j int) bool {
+		if !derniers[i].VerifieA.Equal(derniers[j].VerifieA) {
+			return derniers[i].VerifieA.After(derniers[j].VerifieA)
+		}
+		return derniers[i].id > derniers[j].id
+	})
+	confirmes := 0
+	for _, statut := range derniers[:min(requis, len(derniers))] {
+		switch {
+		case nouveau.Etat == models.EtatDown:
+			if statut.Etat == models.EtatDown {
+				confirmes++
+			}
+		case ancienEtat == models.EtatDown:
+			if statut.Etat != models.EtatDown {
+				confirmes++
+			}
+		case statut.Etat == nouveau.Etat:
+			confirmes++
+		}
+	}
+	if confirmes < requis {
+		return
+	}
+	moniteur.EtatConfirme = nouveau.Etat
+
+	switch {
+	// si passage à indisponible : alerte DOWN et ouverture d'un incident
+	case nouveau.Etat == models.EtatDown:
+		m.ajouterAlerte(nouveau, models.AlerteDown, fmt.Sprintf("Indisponible - HTTP %d", nouveau.CodeStatutHTTP))
+		for _, incident := range m.incidents {
+			if incident.MoniteurID == nouveau.MoniteurID && incident.FermeA == nil {
+				return
+			}
+		}
+		m.incidents = append(m.incidents, models.Incident{
+			ID:            m.prochainID("incidents"),
+			MoniteurID:    nouveau.MoniteurID,
+			OuvertA:       nouveau.VerifieA,
+			MessageErreur: nouveau.MessageErreur,
+			CodeHTTP:      nouveau.CodeStatutHTTP,
+		})
+
+	// si passage de indisponible à disponible (même dégradé) : alerte UP et fermeture de l'incident
+	case ancienEtat == models.EtatDown:
+		details := fmt.Sprintf("Rétabli - HTTP %d", nouveau.CodeStatutHTTP)
+		if nouveau.Etat == models.EtatDegrade {
+			details += " (dégradé : " + valeurOu(nouveau.MessageErreur, "lent") + ")"
+		}
+		m.ajouterAlerte(nouveau, models.AlerteUp, details)
+		for i := range m.incidents {
+			if m.incidents[i].MoniteurID == nouveau.MoniteurID && m.incidents[i].FermeA == nil {
+				fermeA := nouveau.VerifieA
+				m.incidents[i].FermeA = &fermeA
+			}
+		}
+
+	// si passage de up à dégradé
+	case nouveau.Etat == models.EtatDegrade:
+		m.ajouterAlerte(nouveau, models.AlerteDegrade,
+			"Dégradé - "+valeurOu(nouveau.MessageErreur, fmt.Sprintf("latence %d ms", nouveau.Latence.Milliseconds())))
+
+	// si passage de dégradé à up : fin de la dégradation, pas de panne à clore
+	default:
+		m.ajouterAlerte(nouveau, models.AlerteDegradeFin, fmt.Sprintf("Performances rétablies - latence %d ms", nouveau.Latence.Milliseconds()))
+	}
+}
+
+// Retourne la valeur, ou la valeur par défaut si elle est vide (COALESCE sur une colonne NULL)
+func valeurOu(valeur, defaut string) string {
+	if valeur == "" {
+		return defaut
+	}
+	return valeur
+}
+
+// Ajoute une alerte pour le statut
+func (m *Memoire) ajouterAlerte(statut statutMemoire, typeAlerte, details string) {
+	m.alertes = append(m.alertes, alerteMemoire{
+		id:         m.prochainID("alertes"),
+		moniteurID: statut.MoniteurID,
+		typeAlerte: typeAlerte,
+		details:    details,
+		codeHTTP:   statut.CodeStatutHTTP,
+		creeA:      maintenantMemoire(),
+	})
+}
+
+// AlertesNonTraitees retourne les alertes créées depuis la date donnée et pas encore envoyées
+func (m *Memoire) AlertesNonTraitees(ctx context.Context, depuis time.Time, limite int) ([]models.Alerte, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	var alertes []models.Alerte
+	for i, alerte := range m.alertes {
+		if len(alertes) >= limite {
+			break
+		}
+		if alerte.traiteeA != nil || alerte.creeA.Before(depuis) {
+			continue
+		}
+		moniteur := m.moniteurs[m.indexMoniteur(alerte.moniteurID)]
+		resultat := models.Alerte{
+			ID:          alerte.id,
+			MoniteurID:  alerte.moniteurID,
+			NomMoniteur: moniteur.Nom,
+			URL:         moniteur.URL,
+			Type:        alerte.typeAlerte,
+			Details:     alerte.details,
+			CodeHTTP:    alerte.codeHTTP,
+			CreeA:       alerte.creeA,
+		}
+
+		// pour une alerte UP, la panne commence au DOWN précédent du même moniteur (sans UP entre les deux)
+		if alerte.typeAlerte == models.AlerteUp {
+			var debutPanne time.Time
+			for _, precedente := range slices.Backward(m.alertes[:i]) {
+				if precedente.moniteurID != alerte.moniteurID {
+					continue
+				}
+				if precedente.typeAlerte == models.AlerteUp {
+					break
+				}
+				if precedente.typeAlerte == models.AlerteDown && precedente.creeA.After(debutPanne) {
+					debutPanne = precedente.creeA
+				}
+			}
+			if !debutPanne.IsZero() {
+				resultat.DureePanne = alerte.creeA.Sub(debutPanne)
+			}
+		}
+		alertes = append(alertes, resultat)
+	}
+	return alertes, nil
+}
+
+// MarquerAlerteTraitee note que l'alerte a été envoyée sur tous les canaux (ou abandonnée car expirée)
+func (m *Memoire) MarquerAlerteTraitee(ctx context.Context, id int) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	for i := range m.alertes {
+		if m.alertes[i].id == id {
+			traiteeA := maintenantMemoire()
+			m.alertes[i].traiteeA = &traiteeA
+		}
+	}
+	return nil
+}
+
+// EnregistrerLivraison garde la trace d'une tentative d'envoi
+func (m *Memoire) EnregistrerLivraison(ctx context.Context, livraison models.Livraison) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if !slices.ContainsFunc(m.alertes, func(a alerteMemoire) bool { return a.id == livraison.AlerteID }) {
+		return fmt.Errorf("alerte %d introuvable", livraison.AlerteID)
+	}
+	livraison.Duree = livraison.Duree.Truncate(time.Millisecond)
+	livraison.CreeA = arrondirTemps(livraison.CreeA)
+	m.livraisons = append(m.livraisons, livraisonMemoire{id: m.prochainID("livraisons"), Livraison: livraison})
+	return nil
+}
+
+// CanauxLivres retourne les canaux qui ont déjà reçu l'alerte (au moins une livraison réussie)
+func (m *Memoire) CanauxLivres(ctx context.Context, alerteID int) ([]string, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	var canaux []string
+	for _, livraison := range m.livraisons {
+		if livraison.AlerteID == alerteID && livraison.Succes && !slices.Contains(canaux, livraison.Canal) {
+			canaux = append(canaux, livraison.Canal)
+		}
+	}
+	slices.Sort(canaux)
+	return canaux, nil
+}
+
+// Retourne l'index d'un canal par son ID (-1 si introuvable)
+func (m *Memoire) indexCanal(id int) int {
+	for i, canal := range m.canaux {
+		if canal.ID == id {
+			return i
+		}
+	}
+	return -1
+}
+
+// Retourne une copie d'un canal (config comprise)
+func copierCanal(canal models.CanalNotification) (models.CanalNotification, error) {
+	config, err := copierJSON(canal.Config)
+	canal.Config = config
+	return canal, err
+}
+
+// Ajoute un canal et le retourne avec son id
+func (m *Memoire) AjouterCanal(ctx context.Context, canal models.CanalNotification) (models.CanalNotification, error) {
+	canal, err := copierCanal(canal)
+	if err != nil {
+		return models.CanalNotification{}, err
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	for _, autre := range m.canaux {
+		if autre.Nom == canal.Nom {
+			return models.CanalNotification{}, ErrCanalExistant
+		}
+	}
+	canal.ID = m.prochainID("canaux")
+	canal.CreeA = maintenantMemoire()
+	m.canaux = append(m.canaux, canal)
+	return copierCanal(canal)
+}
+
+// Retourne tous les canaux
+func (m *Memoire) ListerCanaux(ctx context.Context) ([]models.CanalNotification, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	var canaux []models.CanalNotification
+	for _, canal := range m.canaux {
+		copie, err := copierCanal(canal)
+		if err != nil {
+			return nil, err
+		}
+		canaux = append(canaux, copie)
+	}
+	return canaux, nil
+}
+
+// Retourne un canal par son ID
+func (m *Memoire) ObtenirCanal(ctx context.Context, id int) (models.CanalNotification, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	i := m.indexCanal(id)
+	if i < 0 {
+		return models.CanalNotification{}, ErrCanalIntrouvable
+	}
+	return copierCanal(m.canaux[i])
+}
+
+// Met à jour le nom, le type, l'activation et la config d'un canal
+func (m *Memoire) MettreAJourCanal(ctx context.Context, canal models.CanalNotification) error {
+	canal, err := copierCanal(canal)
+	if err != nil {
+		return err
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	i := m.indexCanal(canal.ID)
+	if i < 0 {
+		return ErrCanalIntrouvable
+	}
+	for _, autre := range m.canaux {
+		if autre.ID != canal.ID && autre.Nom == canal.Nom {
+			return ErrCanalExistant
+		}
+	}
+	canal.CreeA = m.canaux[i].CreeA
+	m.canaux[i] = canal
+	return nil
+}
+
+// Supprime un canal (ses routes sont supprimées en cascade)
+func (m *Memoire) SupprimerCanal(ctx context.Context, id int) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	i := m.indexCanal(id)
+	if i < 0 {
+		return ErrCanalIntrouvable
+	}
+	m.canaux = slices.Delete(m.canaux, i, i+1)
+	m.routes = slices.DeleteFunc(m.routes, func(r models.RouteNotification) bool { return r.CanalID == id })
+	return nil
+}
+
+// Retourne les routes d'un moniteur
+func (m *Memoire) RoutesMoniteur(ctx context.Context, moniteurID int) ([]models.RouteNotification, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	var routes []models.RouteNotification
+	for _, route := range m.routes {
+		if route.MoniteurID == moniteurID {
+			route.Types = slices.Clone(route.Types)
+			routes = append(routes, route)
+		}
+	}
+	sort.Slice(routes, func(i, j int) bool { return routes[i].CanalID < routes[j].CanalID })
+	return routes, nil
+}
+
+// Remplace toutes les routes d'un moniteur
+func (m *Memoire) DefinirRoutesMoniteur(ctx context.Context, moniteurID int, routes []models.RouteNotification) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	// tout est vérifié avant de modifier quoi que ce soit (comme la transaction)
+	if len(routes) > 0 && m.indexMoniteur(moniteurID) < 0 {
+		return ErrMoniteurIntrouvable
+	}
+	canaux := map[int]bool{}
+	for _, route := range routes {
+		if m.indexCanal(route.CanalID) < 0 {
+			return ErrCanalIntrouvable
+		}
+		if canaux[route.CanalID] {
+			return fmt.Errorf("route en double vers le canal %d", route.CanalID)
+		}
+		canaux[route.CanalID] = true
+	}
+
+	m.routes = slices.DeleteFunc(m.routes, func(r models.RouteNotification) bool { return r.MoniteurID == moniteurID })
+	for _, route := range routes {
+		m.routes = append(m.routes, models.RouteNotification{MoniteurID: moniteurID, CanalID: route.CanalID, Types: slices.Clone(route.Types)})
+	}
+	return nil
+}
+
+// CanauxPourAlerte retourne les canaux actifs routés pour ce moniteur et ce type d'alerte
+func (m *Memoire) CanauxPourAlerte(ctx context.Context, moniteurID int, typeAlerte string) ([]models.CanalNotification, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	var canaux []models.CanalNotification
+	for _, canal := range m.canaux {
+		if !canal.Actif {
+			continue
+		}
+		for _, route := range m.routes {
+			if route.MoniteurID == moniteurID && route.CanalID == canal.ID && slices.Contains(route.Types, typeAlerte) {
+				copie, err := copierCanal(canal)
+				if err != nil {
+					return nil, err
+				}
+				canaux = append(canaux, copie)
+			}
+		}
+	}
+	return canaux, nil
+}
+
+// Retourne l'index d'une maintenance par son ID (-1 si introuvable)
+func (m *Memoire) indexMaintenance(id int) int {
+	for i, maintenance := range m.maintenances {
+		if maintenance.ID == id {
+			return i
+		}
+	}
+	return -1
+}
+
+// Retourne une copie d'une maintenance avec ses moniteurs triés et sans doublon
+func copierMaintenance(maintenance models.Maintenance) models.Maintenance {
+	if maintenance.Debut != nil {
+		debut := arrondirTemps(*maintenance.Debut)
+		maintenance.Debut = &debut
+	}
+	if maintenance.Fin != nil {
+		fin := arrondirTemps(*maintenance.Fin)
+		maintenance.Fin = &fin
+	}
+	moniteurIDs := append([]int{}, maintenance.MoniteurIDs...)
+	slices.Sort(moniteurIDs)
+	maintenance.MoniteurIDs = slices.Compact(moniteurIDs)
+	return maintenance
+}
+
+// Vérifie que les moniteurs liés à une maintenance existent
+func (m *Memoire) verifierMoniteurs(moniteurIDs []int) error {
+	for _, id := range moniteurIDs {
+		if m.indexMoniteur(id) < 0 {
+			return ErrMoniteurIntrouvable
+		}
+	}
+	return nil
+}
+
+// Ajoute une maintenance et la retourne avec son id
+func (m *Memoire) AjouterMaintenance(ctx context.Context, maintenance models.Maintenance) (models.Maintenance, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if err := m.verifierMoniteurs(maintenance.MoniteurIDs); err != nil {
+		return models.Maintenance{}, err
+	}
+	maintenance.ID = m.prochainID("maintenances")
+	maintenance.CreeA = maintenantMemoire()
+	m.maintenances = append(m.maintenances, copierMaintenance(maintenance))
+	return maintenance, nil
+}
+
+// Retourne toutes les maintenances
+func (m *Memoire) ListerMaintenances(ctx context.Context) ([]models.Maintenance, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	var maintenances []models.Maintenance
+	for _, maintenance := range m.maintenances {
+		maintenances = append(maintenances, copierMaintenance(maintenance))
+	}
+	return maintenances, nil
+}
+
+// Retourne une maintenance par son ID
+func (m *Memoire) ObtenirMaintenance(ctx context.Context, id int) (models.Maintenance, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	i := m.indexMaintenance(id)
+	if i < 0 {
+		return models.Maintenance{}, ErrMaintenanceIntrouvable
+	}
+	return copierMaintenance(m.maintenances[i]), nil
+}
+
+// Met à jour une maintenance et ses moniteurs
+func (m *Memoire) MettreAJourMaintenance(ctx context.Context, maintenance models.Maintenance) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	i := m.indexMaintenance(maintenance.ID)
+	if i < 0 {
+		return ErrMaintenanceIntrouvable
+	}
+	if err := m.verifierMoniteurs(maintenance.MoniteurIDs); err != nil {
+		return err
+	}
+	maintenance.CreeA = m.maintenances[i].CreeA
+	m.maintenances[i] = copierMaintenance(maintenance)
+	return nil
+}
+
+// Supprime une maintenance
+func (m *Memoire) SupprimerMaintenance(ctx context.Context, id int) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	i := m.indexMaintenance(id)
+	if i < 0 {
+		return ErrMaintenanceIntrouvable
+	}
+	m.maintenances = slices.Delete(m.maintenances, i, i+1)
+	return nil
+}
+
+// Indique si une maintenance du moniteur couvre l'instant donné
+func (m *Memoire) enMaintenance(moniteurID int, instant time.Time) bool {
+	for _, maintenance := range m.maintenances {
+		if slices.Contains(maintenance.MoniteurIDs, moniteurID) && maintenance.ActiveA(instant) {
+			return true
+		}
+	}
+	return false
+}
+
+// Retourne un incident avec le nom et l'URL de son moniteur
+func (m *Memoire) incidentComplet(incident models.Incident) models.Incident {
+	moniteur := m.moniteurs[m.indexMoniteur(incident.MoniteurID)]
+	incident.NomMoniteur = moniteur.Nom
+	incident.URL = moniteur.URL
+	if incident.FermeA != nil {
+		fermeA := *incident.FermeA
+		incident.FermeA = &fermeA
+	}
+	if incident.AcquitteA != nil {
+		acquitteA := *incident.AcquitteA
+		incident.AcquitteA = &acquitteA
+	}
+	return incident
+}
+
+// Retourne les incidents correspondant au filtre, du plus récent au plus ancien
+func (m *Memoire) ListerIncidents(ctx context.Context, filtre models.FiltreIncidents) ([]models.Incident, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	var incidents []models.Incident
+	for _, incident := range m.incidents {
+		switch {
+		case filtre.MoniteurID != 0 && incident.MoniteurID != filtre.MoniteurID:
+		case !filtre.Depuis.IsZero() && incident.FermeA != nil && incident.FermeA.Before(filtre.Depuis):
+		case !filtre.Jusqua.IsZero() && !incident.OuvertA.Before(filtre.Jusqua):
+		case filtre.Ouverts != nil && *filtre.Ouverts != (incident.FermeA == nil):
+		default:
+			incidents = append(incidents, m.incidentComplet(incident))
+		}
+	}
+	sort.Slice(incidents, func(i, j int) bool {
+		if !incidents[i].OuvertA.Equal(incidents[j].OuvertA) {
+			return incidents[i].OuvertA.After(incidents[j].OuvertA)
+		}
+		return incidents[i].ID > incidents[j].ID
+	})
+
+	limite := filtre.Limite
+	if limite <= 0 {
+		limite = limiteIncidentsParDefaut
+	}
+	return incidents[:min(limite, len(incidents))], nil
+}
+
+// Retourne un incident par son ID
+func (m *Memoire) ObtenirIncident(ctx context.Context, id int) (models.Incident, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	for _, incident := range m.incidents {
+		if incident.ID == id {
+			return m.incidentComplet(incident), nil
+		}
+	}
+	return models.Incident{}, ErrIncidentIntrouvable
+}
+
+// Acquitte un incident (une seule fois) et le retourne à jour
+func (m *Memoire) AcquitterIncident(ctx context.Context, id int, par, note string) (models.Incident, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	for i := range m.incidents {
+		incident := &m.incidents[i]
+		if incident.ID != id {
+			continue
+		}
+		if incident.AcquitteA != nil {
+			return models.Incident{}, ErrIncidentAcquitte
+		}
+		acquitteA := maintenantMemoire()
+		incident.AcquitteA = &acquitteA
+		incident.AcquittePar = par
+		incident.NoteAcquittement = note
+		return m.incidentComplet(*incident), nil
+	}
+	return models.Incident{}, ErrIncidentIntrouvable
+}
+
+// Supprime tous les moniteurs et statuts (et ce qui en dépend) et remet les compteurs à zéro
+func (m *Memoire) ViderTout(ctx context.Context) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	m.moniteurs, m.statuts, m.alertes, m.livraisons, m.incidents, m.routes = nil, nil, nil, nil, nil, nil
+	for i := range m.maintenances {
+		m.maintenances[i].MoniteurIDs = nil
+	}
+	m.agregats = map[string]map[cleAgregat]agregatMemoire{}
+	for _, table := range []string{"moniteurs", "statuts", "alertes", "livraisons", "incidents"} {
+		delete(m.sequences, table)
+	}
+	return nil
+}
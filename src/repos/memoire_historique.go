@@ -0,0 +1,451 @@
+/* Historique en mémoire : disponibilité, latences, consolidation et purge
+ * Projet de session A25
+ * By : Leandre Kanmegne
+ *
+ * Refait en Go les calculs SQL de pg_disponibilite.go, pg_latences.go, pg_consolidations.go et pg_retention.go
+ * Mêmes règles : durées coupées au statut suivant, à 3 intervalles de vérification et aux bornes,
+ * percentiles continus (interpolation linéaire), intervalles consolidés lus quand ils sont entiers
+ */
+package repos
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"slices"
+	"sort"
+	"time"
+
+	"example.com/go-hello/src/internal/models"
+)
+
+// Clé d'un agrégat consolidé : un moniteur et le début de son intervalle (en nanosecondes Unix)
+type cleAgregat struct {
+	moniteurID int
+	debut      int64
+}
+
+// Un agrégat consolidé (une ligne de statuts_horaires ou statuts_quotidiens)
+type agregatMemoire struct {
+	debut                 time.Time
+	verifications         int
+	succes                int
+	echecs                int
+	secondesObservees     int64
+	secondesIndisponibles int64
+	secondesMaintenance   int64
+	latences              statistiquesLatence
+}
+
+// Min, max, moyenne et percentiles d'une série de latences (nil si la série est vide)
+type statistiquesLatence struct {
+	min, max, moyenne, p50, p90, p95, p99 *float64
+}
+
+// Calcule les statistiques de latence comme MIN, MAX, AVG et percentile_cont
+func calculerStatistiques(latences []float64) statistiquesLatence {
+	if len(latences) == 0 {
+		return statistiquesLatence{}
+	}
+	triees := slices.Clone(latences)
+	slices.Sort(triees)
+
+	somme := 0.0
+	for _, latence := range triees {
+		somme += latence
+	}
+	valeur := func(v float64) *float64 { return &v }
+	percentile := func(p float64) *float64 {
+		position := p * float64(len(triees)-1)
+		bas := int(math.Floor(position))
+		haut := int(math.Ceil(position))
+		return valeur(triees[bas] + (triees[haut]-triees[bas])*(position-float64(bas)))
+	}
+	return statistiquesLatence{
+		min:     valeur(triees[0]),
+		max:     valeur(triees[len(triees)-1]),
+		moyenne: valeur(somme / float64(len(triees))),
+		p50:     percentile(0.50),
+		p90:     percentile(0.90),
+		p95:     percentile(0.95),
+		p99:     percentile(0.99),
+	}
+}
+
+// Retourne le début de l'intervalle qui contient t (comme date_bin avec origineIntervalles)
+func debutIntervalle(t time.Time, intervalle time.Duration) time.Time {
+	origine, _ := time.Parse(time.RFC3339, origineIntervalles)
+	ecart := t.Sub(origine)
+	n := ecart / intervalle
+	if ecart%intervalle < 0 {
+		n--
+	}
+	return origine.Add(n * intervalle).In(t.Location())
+}
+
+// Écart maximal couvert par un statut : 3 intervalles de vérification (intervalle par défaut des réglages)
+func (m *Memoire) ecartMax(moniteur models.Moniteur) time.Duration {
+	intervalle := m.reglages.IntervalleParDefaut
+	if moniteur.IntervalleSecondes != 0 {
+		intervalle = time.Duration(moniteur.IntervalleSecondes) * time.Second
+	}
+	return 3 * intervalle
+}
+
+// Retourne les statuts d'un moniteur dans [depuis, jusqua[, par date puis id
+func (m *Memoire) statutsEntre(moniteurID int, depuis, jusqua time.Time) []statutMemoire {
+	var statuts []statutMemoire
+	for _, statut := range m.statuts {
+		if statut.MoniteurID == moniteurID && !statut.VerifieA.Before(depuis) && statut.VerifieA.Before(jusqua) {
+			statuts = append(statuts, statut)
+		}
+	}
+	trierStatuts(statuts)
+	return statuts
+}
+
+// Trie les statuts par date puis par id (ORDER BY verifie_a, id)
+func trierStatuts(statuts []statutMemoire) {
+	sort.Slice(statuts, func(i, j int) bool {
+		if !statuts[i].VerifieA.Equal(statuts[j].VerifieA) {
+			return statuts[i].VerifieA.Before(statuts[j].VerifieA)
+		}
+		return statuts[i].id < statuts[j].id
+	})
+}
+
+// Retourne la plus petite des dates
+func premiereDate(dates ...time.Time) time.Time {
+	plusPetite := dates[0]
+	for _, date := range dates[1:] {
+		if date.Before(plusPetite) {
+			plusPetite = date
+		}
+	}
+	return plusPetite
+}
+
+// Calcule le rapport de disponibilité d'un moniteur sur [depuis, jusqua[
+func (m *Memoire) DisponibiliteMoniteur(ctx context.Context, moniteurID int, depuis, jusqua time.Time) (models.RapportDisponibilite, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	rapport := m.durees(consolidations, moniteurID, depuis, jusqua)
+	rapport.Depuis, rapport.Jusqua = depuis, jusqua
+	for _, incident := range m.incidents {
+		if incident.MoniteurID == moniteurID && incident.OuvertA.Before(jusqua) && (incident.FermeA == nil || incident.FermeA.After(depuis)) {
+			rapport.Incidents++
+		}
+	}
+	rapport.Calculer()
+	return rapport, nil
+}
+
+// Calcule les durées d'un moniteur sur [depuis, jusqua[ (voir Postgres.durees)
+func (m *Memoire) durees(niveaux []consolidation, moniteurID int, depuis, jusqua time.Time) models.RapportDisponibilite {
+	if len(niveaux) == 0 {
+		return m.dureesBrutes(moniteurID, depuis, jusqua)
+	}
+	c, plusFins := niveaux[len(niveaux)-1], niveaux[:len(niveaux)-1]
+
+	debut, fin, ok := decouper(depuis, jusqua, m.filigranes[c.granularite], c.duree)
+	if !ok {
+		return m.durees(plusFins, moniteurID, depuis, jusqua)
+	}
+
+	var total models.RapportDisponibilite
+	for _, rapport := range []models.RapportDisponibilite{
+		m.durees(plusFins, moniteurID, depuis, debut),
+		m.dureesConsolidees(c, moniteurID, debut, fin),
+		m.durees(plusFins, moniteurID, fin, jusqua),
+	} {
+		total.Verifications += rapport.Verifications
+		total.SecondesObservees += rapport.SecondesObservees
+		total.SecondesIndisponibles += rapport.SecondesIndisponibles
+		total.SecondesMaintenance += rapport.SecondesMaintenance
+	}
+	return total
+}
+
+// Calcule les durées d'un moniteur sur [depuis, jusqua[ à partir des statuts bruts
+func (m *Memoire) dureesBrutes(moniteurID int, depuis, jusqua time.Time) models.RapportDisponibilite {
+	var rapport models.RapportDisponibilite
+	i := m.indexMoniteur(moniteurID)
+	if !depuis.Before(jusqua) || i < 0 {
+		return rapport
+	}
+	ecart := m.ecartMax(m.moniteurs[i])
+
+	var observees, indisponibles, maintenance float64
+	statuts := m.statutsEntre(moniteurID, depuis.Add(-ecart), jusqua)
+	for j, statut := range statuts {
+		if !statut.VerifieA.Before(depuis) {
+			rapport.Verifications++
+		}
+		debut := statut.VerifieA
+		if debut.Before(depuis) {
+			debut = depuis
+		}
+		fin := premiereDate(statut.VerifieA.Add(ecart), jusqua)
+		if j+1 < len(statuts) {
+			fin = premiereDate(fin, statuts[j+1].VerifieA)
+		}
+		if !fin.After(debut) {
+			continue
+		}
+		secondes := fin.Sub(debut).Seconds()
+		switch {
+		case statut.EnMaintenance:
+			maintenance += secondes
+		case statut.Etat == models.EtatDown:
+			observees += secondes
+			indisponibles += secondes
+		default:
+			observees += secondes
+		}
+	}
+	rapport.SecondesObservees = int64(math.Round(observees))
+	rapport.SecondesIndisponibles = int64(math.Round(indisponibles))
+	rapport.SecondesMaintenance = int64(math.Round(maintenance))
+	return rapport
+}
+
+// Retourne les durées consolidées d'un moniteur sur [depuis, jusqua[
+func (m *Memoire) dureesConsolidees(c consolidation, moniteurID int, depuis, jusqua time.Time) models.RapportDisponibilite {
+	var rapport models.RapportDisponibilite
+	for cle, agregat := range m.agregats[c.granularite] {
+		if cle.moniteurID == moniteurID && !agregat.debut.Before(depuis) && agregat.debut.Before(jusqua) {
+			rapport.Verifications += agregat.verifications
+			rapport.SecondesObservees += agregat.secondesObservees
+			rapport.SecondesIndisponibles += agregat.secondesIndisponibles
+			rapport.SecondesMaintenance += agregat.secondesMaintenance
+		}
+	}
+	return rapport
+}
+
+// Retourne les agrégats de latence d'un moniteur sur [depuis, jusqua[, du plus ancien au plus récent
+func (m *Memoire) AgregatsLatence(ctx context.Context, moniteurID int, depuis, jusqua time.Time, intervalle time.Duration) ([]models.AgregatLatence, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	c, ok := consolidationPour(intervalle)
+	if !ok {
+		return m.agregatsBruts(moniteurID, depuis, jusqua, intervalle), nil
+	}
+	debut, fin, ok := decouper(depuis, jusqua, m.filigranes[c.granularite], c.duree)
+	if !ok {
+		return m.agregatsBruts(moniteurID, depuis, jusqua, intervalle), nil
+	}
+
+	agregats := m.agregatsBruts(moniteurID, depuis, debut, intervalle)
+	agregats = append(agregats, m.agregatsConsolides(c, moniteurID, debut, fin)...)
+	return append(agregats, m.agregatsBruts(moniteurID, fin, jusqua, intervalle)...), nil
+}
+
+// Calcule les agrégats de latence à partir des statuts bruts
+func (m *Memoire) agregatsBruts(moniteurID int, depuis, jusqua time.Time, intervalle time.Duration) []models.AgregatLatence {
+	if !depuis.Before(jusqua) {
+		return nil
+	}
+
+	// les statuts sont triés : ceux d'un même intervalle se suivent
+	var agregats []models.AgregatLatence
+	var latences []float64
+	succes := 0
+	terminer := func() {
+		if len(latences) == 0 {
+			return
+		}
+		dernier := &agregats[len(agregats)-1]
+		dernier.Verifications = len(latences)
+		dernier.TauxSucces = float64(succes) / float64(len(latences))
+		remplirLatences(dernier, calculerStatistiques(latences))
+		latences, succes = nil, 0
+	}
+	for _, statut := range m.statutsEntre(moniteurID, depuis, jusqua) {
+		debut := debutIntervalle(statut.VerifieA, intervalle)
+		if len(agregats) == 0 || !agregats[len(agregats)-1].Debut.Equal(debut) {
+			terminer()
+			agregats = append(agregats, models.AgregatLatence{Debut: debut})
+		}
+		latences = append(latences, float64(statut.Latence.Milliseconds()))
+		if statut.EstDisponible {
+			succes++
+		}
+	}
+	terminer()
+	return agregats
+}
+
+// Copie les statistiques dans un agrégat de l'API
+func remplirLatences(agregat *models.AgregatLatence, stats statistiquesLatence) {
+	agregat.MinMs, agregat.MaxMs, agregat.MoyenneMs = stats.min, stats.max, stats.moyenne
+	agregat.P50Ms, agregat.P90Ms, agregat.P95Ms, agregat.P99Ms = stats.p50, stats.p90, stats.p95, stats.p99
+}
+
+// Retourne les agrégats de latence consolidés d'un moniteur sur [depuis, jusqua[
+func (m *Memoire) agregatsConsolides(c consolidation, moniteurID int, depuis, jusqua time.Time) []models.AgregatLatence {
+	var agregats []models.AgregatLatence
+	for cle, consolide := range m.agregats[c.granularite] {
+		if cle.moniteurID != moniteurID || consolide.debut.Before(depuis) || !consolide.debut.Before(jusqua) || consolide.verifications == 0 {
+			continue
+		}
+		agregat := models.AgregatLatence{
+			Debut:         consolide.debut,
+			Verifications: consolide.verifications,
+			TauxSucces:    float64(consolide.succes) / float64(consolide.verifications),
+		}
+		remplirLatences(&agregat, consolide.latences)
+		agregats = append(agregats, agregat)
+	}
+	sort.Slice(agregats, func(i, j int) bool { return agregats[i].Debut.Before(agregats[j].Debut) })
+	return agregats
+}
+
+// ConsoliderStatuts consolide les intervalles terminés avant maintenant - délai de grâce pour chaque granularité
+// Retourne le nombre de lignes consolidées (insérées ou mises à jour)
+func (m *Memoire) ConsoliderStatuts(ctx context.Context, maintenant time.Time) (int, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	total := 0
+	for _, c := range consolidations {
+		// même délai de grâce que pg_consolidations.go
+		limite := maintenant.Add(-m.reglages.DelaiGrace).Truncate(c.duree)
+		debut, ok := m.filigranes[c.granularite]
+		if !ok {
+			// première consolidation : depuis le plus vieux statut
+			if len(m.statuts) == 0 {
+				continue
+			}
+			debut = m.statuts[0].VerifieA
+			for _, statut := range m.statuts[1:] {
+				debut = premiereDate(debut, statut.VerifieA)
+			}
+			debut = debut.Truncate(c.duree)
+		}
+		if !debut.Before(limite) {
+			continue
+		}
+		total += m.consolider(c, debut, limite)
+		m.filigranes[c.granularite] = limite
+	}
+	return total, nil
+}
+
+// Consolide les statuts de [debut, fin[ dans les agrégats d'une granularité et retourne le nombre d'agrégats écrits
+func (m *Memoire) consolider(c consolidation, debut, fin time.Time) int {
+	if m.agregats[c.granularite] == nil {
+		m.agregats[c.granularite] = map[cleAgregat]agregatMemoire{}
+	}
+
+	lignes := 0
+	for _, moniteur := range m.moniteurs {
+		ecart := m.ecartMax(moniteur)
+		statuts := m.statutsEntre(moniteur.ID, debut, fin)
+
+		// regroupe les statuts par intervalle (ils sont triés)
+		for j := 0; j < len(statuts); {
+			debutIntervalleCourant := debutIntervalle(statuts[j].VerifieA, c.duree)
+			finIntervalle := debutIntervalleCourant.Add(c.duree)
+			agregat := agregatMemoire{debut: debutIntervalleCourant}
+			var latences, observees, indisponibles, maintenance []float64
+			for ; j < len(statuts) && statuts[j].VerifieA.Before(finIntervalle); j++ {
+				statut := statuts[j]
+				agregat.verifications++
+				if statut.EstDisponible {
+					agregat.succes++
+				}
+				if statut.Etat == models.EtatDown {
+					agregat.echecs++
+				}
+				latences = append(latences, float64(statut.Latence.Milliseconds()))
+
+				// le statut couvre le temps jusqu'au suivant, au plus 3 intervalles, coupé à la fin de l'intervalle
+				finStatut := premiereDate(statut.VerifieA.Add(ecart), finIntervalle)
+				if j+1 < len(statuts) {
+					finStatut = premiereDate(finStatut, statuts[j+1].VerifieA)
+				}
+				secondes := finStatut.Sub(statut.VerifieA).Seconds()
+				switch {
+				case statut.EnMaintenance:
+					maintenance = append(maintenance, secondes)
+				case statut.Etat == models.EtatDown:
+					observees = append(observees, secondes)
+					indisponibles = append(indisponibles, secondes)
+				default:
+					observees = append(observees, secondes)
+				}
+			}
+			agregat.secondesObservees = sommeArrondie(observees)
+			agregat.secondesIndisponibles = sommeArrondie(indisponibles)
+			agregat.secondesMaintenance = sommeArrondie(maintenance)
+			agregat.latences = calculerStatistiques(latences)
+
+			m.agregats[c.granularite][cleAgregat{moniteur.ID, debutIntervalleCourant.UnixNano()}] = agregat
+			lignes++
+		}
+	}
+	return lignes
+}
+
+// Additionne des secondes et arrondit à l'entier (SUM(...)::BIGINT)
+func sommeArrondie(secondes []float64) int64 {
+	total := 0.0
+	for _, s := range secondes {
+		total += s
+	}
+	return int64(math.Round(total))
+}
+
+// PurgerLot supprime au plus tailleLot lignes de la cible antérieures à avant
+// Retourne le nombre de lignes supprimées (inférieur à tailleLot quand il ne reste plus rien à purger)
+func (m *Memoire) PurgerLot(ctx context.Context, cible models.CiblePurge, avant time.Time, tailleLot int) (int64, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	supprimees := 0
+	restantes := func() bool { return supprimees < tailleLot }
+
+	switch cible {
+	case models.CibleStatuts:
+		// ne jamais supprimer des statuts pas encore consolidés
+		for _, c := range consolidations {
+			avant = premiereDate(avant, m.filigranes[c.granularite])
+		}
+		m.statuts = slices.DeleteFunc(m.statuts, func(s statutMemoire) bool {
+			if restantes() && s.VerifieA.Before(avant) {
+				supprimees++
+				return true
+			}
+			return false
+		})
+
+	case models.CibleAlertes:
+		m.alertes = slices.DeleteFunc(m.alertes, func(a alerteMemoire) bool {
+			if restantes() && a.creeA.Before(avant) {
+				supprimees++
+				return true
+			}
+			return false
+		})
+		m.supprimerLivraisonsOrphelines()
+
+	case models.CibleAgregatsHoraires, models.CibleAgregatsQuotidiens:
+		granularite := consolidationHoraire.granularite
+		if cible == models.CibleAgregatsQuotidiens {
+			granularite = consolidationQuotidienne.granularite
+		}
+		for cle, agregat := range m.agregats[granularite] {
+			if restantes() && agregat.debut.Before(avant) {
+				delete(m.agregats[granularite], cle)
+				supprimees++
+			}
+		}
+
+	default:
+		return 0, fmt.Errorf("cible de purge inconnue : %q", cible)
+	}
+	return int64(supprimees), nil
+}
@@ -0,0 +1,66 @@
+/* Tests du dépôt en mémoire
+ * Projet de session A25
+ * By : Leandre Kanmegne
+ */
+package repos
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"testing"
+	"time"
+
+	"example.com/go-hello/src/internal/models"
+)
+
+var _ Repo = (*Memoire)(nil)
+
+func TestMemoire_Contrat(t *testing.T) {
+	testerContrat(t, func(t *testing.T, reglages Reglages) depotContrat {
+		return NouvelleMemoire(reglages)
+	})
+}
+
+// Écritures et lectures concurrentes (à lancer avec -race)
+func TestMemoire_Concurrence(t *testing.T) {
+	ctx := context.Background()
+	memoire := NouvelleMemoire(Reglages{})
+
+	var wg sync.WaitGroup
+	for i := 0; i < 8; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			url := fmt.Sprintf("https://concurrent-%d.test", i)
+			if err := memoire.AjouterMoniteur(ctx, models.Moniteur{Nom: url, URL: url, Actif: true}); err != nil {
+				t.Error(err)
+				return
+			}
+			moniteurs, _ := memoire.ListerMoniteurs(ctx)
+			for _, m := range moniteurs {
+				if m.URL != url {
+					continue
+				}
+				for j := 0; j < 20; j++ {
+					statut := models.StatutMoniteur{MoniteurID: m.ID, URL: url, EstDisponible: j%2 == 0, VerifieA: origineContrat.Add(time.Duration(j) * time.Minute)}
+					if err := memoire.EnregistrerStatutMoniteur(ctx, statut); err != nil {
+						t.Error(err)
+					}
+					_, _ = memoire.DerniersStatutsMoniteur(ctx, m.ID)
+				}
+			}
+		}(i)
+	}
+	wg.Wait()
+
+	moniteurs, _ := memoire.ListerMoniteurs(ctx)
+	if len(moniteurs) != 8 {
+		t.Fatalf("8 moniteurs attendus, obtenu %d", len(moniteurs))
+	}
+	for i, m := range moniteurs {
+		if m.ID != i+1 {
+			t.Errorf("ids consécutifs attendus, obtenu %d en position %d", m.ID, i)
+		}
+	}
+}
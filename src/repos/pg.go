@@ -19,31 +19,38 @@ package repos
 import (
 	"context"
 	"database/sql"
+	"encoding/json"
 	"errors"
 	"time"
 
+	"example.com/go-hello/src/internal/config"
 	"example.com/go-hello/src/internal/models"
+	"github.com/jackc/pgx/v5/pgconn"
 	_ "github.com/jackc/pgx/v5/stdlib"
 )
 
+// Code SQLSTATE d'une violation de contrainte UNIQUE
+const codeViolationUnicite = "23505"
+
 // Postgres implémente Repo avec PostgreSQL
 type Postgres struct {
-	db *sql.DB
+	db       *sql.DB
+	reglages Reglages
 }
 
 // Crée une connexion PostgreSQL
-func NouvelleConnexion(dsn string) (*Postgres, error) {
-	db, err := sql.Open("pgx", dsn)
+func NouvelleConnexion(cfg config.ConfigBaseDeDonnees, reglages Reglages) (*Postgres, error) {
+	db, err := sql.Open("pgx", cfg.URL)
 	if err != nil {
 		return nil, err
 	}
 
 	// config du pool de connexions
-	db.SetMaxOpenConns(15) // nombre max de connexions ouvertes
-	db.SetMaxIdleConns(15)  // nombre max de connexions inactives
-	db.SetConnMaxLifetime(30 * time.Minute) // durée max de vie d'une connexion
+	db.SetMaxOpenConns(cfg.MaxConnexionsOuvertes) // nombre max de connexions ouvertes
+	db.SetMaxIdleConns(cfg.MaxConnexionsIdle)     // nombre max de connexions inactives
+	db.SetConnMaxLifetime(cfg.DureeVieConnexion)  // durée max de vie d'une connexion
 
-	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	ctx, cancel := context.WithTimeout(context.Background(), cfg.TimeoutConnexion)
 	defer cancel()
 
 	// check si la connexion marche
@@ -52,8 +59,8 @@ func NouvelleConnexion(dsn string) (*Postgres, error) {
 		return nil, err
 	}
 
-    // retourne l'instance du repo
-	return &Postgres{db: db}, nil
+	// retourne l'instance du repo
+	return &Postgres{db: db, reglages: reglages.completer()}, nil
 }
 
 // Ferme la connexion à la base
@@ -61,6 +68,11 @@ func (p *Postgres) Fermer() error {
 	return p.db.Close()
 }
 
+// Retourne le pool de connexions (pour les migrations du schéma)
+func (p *Postgres) DB() *sql.DB {
+	return p.db
+}
+
 // Ajoute un moniteur
 func (p *Postgres) AjouterMoniteur(ctx context.Context, moniteur models.Moniteur) error {
 	if moniteur.URL == "" {
@@ -70,19 +82,65 @@ func (p *Postgres) AjouterMoniteur(ctx context.Context, moniteur models.Moniteur
 		moniteur.Type = "http"
 	}
 
-    // Requete preparée pour insertion des données et gestion des doublons sur l'URL
+	// Requete preparée pour insertion des données et gestion des doublons sur l'URL
 	requete := `
-		INSERT INTO monitoring.moniteurs (nom, url, type)
-		VALUES ($1, $2, $3)
+		INSERT INTO monitoring.moniteurs (nom, url, type, actif, intervalle_secondes, parametres, requete)
+		VALUES ($1, $2, $3, $4, $5, $6, $7)
 		ON CONFLICT (url) 
 		DO UPDATE SET
 			nom = COALESCE(NULLIF(EXCLUDED.nom, ''), monitoring.moniteurs.nom),
-			type = COALESCE(NULLIF(EXCLUDED.type, ''), monitoring.moniteurs.type)
+			type = COALESCE(NULLIF(EXCLUDED.type, ''), monitoring.moniteurs.type),
+			intervalle_secondes = COALESCE(EXCLUDED.intervalle_secondes, monitoring.moniteurs.intervalle_secondes)
 	`
-	_, err := p.db.ExecContext(ctx, requete, moniteur.Nom, moniteur.URL, moniteur.Type) 
+	parametres, err := json.Marshal(moniteur.Parametres)
+	if err != nil {
+		return err
+	}
+	requeteHTTP, err := valeurRequeteHTTP(moniteur.Requete)
+	if err != nil {
+		return err
+	}
+
+	_, err = p.db.ExecContext(ctx, requete, moniteur.Nom, moniteur.URL, moniteur.Type, moniteur.Actif, valeurNullInt(moniteur.IntervalleSecondes), parametres, requeteHTTP) 
 	return err
 }
 
+// Crée un moniteur et le retourne avec son id, sans toucher à un moniteur existant pour la même URL
+func (p *Postgres) CreerMoniteur(ctx context.Context, moniteur models.Moniteur) (models.Moniteur, error) {
+	if moniteur.URL == "" {
+		return models.Moniteur{}, errors.New("l'URL du moniteur est obligatoire")
+	}
+	if moniteur.Type == "" {
+		moniteur.Type = "http"
+	}
+
+	parametres, err := json.Marshal(moniteur.Parametres)
+	if err != nil {
+		return models.Moniteur{}, err
+	}
+	requeteHTTP, err := valeurRequeteHTTP(moniteur.Requete)
+	if err != nil {
+		return models.Moniteur{}, err
+	}
+
+	ligne := p.db.QueryRowContext(ctx, `
+		INSERT INTO monitoring.moniteurs (nom, url, type, actif, intervalle_secondes, parametres, requete)
+		VALUES ($1, $2, $3, $4, $5, $6, $7)
+		RETURNING `+colonnesMoniteur,
+		moniteur.Nom, moniteur.URL, moniteur.Type, moniteur.Actif, valeurNullInt(moniteur.IntervalleSecondes), parametres, requeteHTTP,
+	)
+	cree, err := scannerMoniteur(ligne)
+	if err != nil {
+		// violation de la contrainte UNIQUE sur l'URL (deux créations simultanées comprises)
+		var erreurPg *pgconn.PgError
+		if errors.As(err, &erreurPg) && erreurPg.Code == codeViolationUnicite {
+			return models.Moniteur{}, ErrMoniteurExistant
+		}
+		return models.Moniteur{}, err
+	}
+	return cree, nil
+}
+
 // Supprime un moniteur par URL
 func (p *Postgres) SupprimerMoniteur(ctx context.Context, url string) error {
 	resultat, err := p.db.ExecContext(ctx, `DELETE FROM monitoring.moniteurs WHERE url=$1`, url)
@@ -92,15 +150,50 @@ func (p *Postgres) SupprimerMoniteur(ctx context.Context, url string) error {
 	
 	lignesAffectees, _ := resultat.RowsAffected()
 	if lignesAffectees == 0 {
-		return errors.New("moniteur introuvable")
+		return ErrMoniteurIntrouvable
 	}
 	
 	return nil
 }
 
+// Colonnes lues pour un moniteur, dans l'ordre attendu par scannerMoniteur
+const colonnesMoniteur = `id, nom, url, type, actif, intervalle_secondes, parametres, requete, etat_confirme`
+
+// Interface commune à *sql.Row et *sql.Rows
+type scanneur interface {
+	Scan(dest ...any) error
+}
+
+// Lit un moniteur depuis une ligne de résultat
+func scannerMoniteur(ligne scanneur) (models.Moniteur, error) {
+	var moniteur models.Moniteur
+	var intervalleNull sql.NullInt64
+	var parametres, requeteHTTP []byte
+	var etatConfirmeNull sql.NullString
+	if err := ligne.Scan(&moniteur.ID, &moniteur.Nom, &moniteur.URL, &moniteur.Type, &moniteur.Actif, &intervalleNull, &parametres, &requeteHTTP, &etatConfirmeNull); err != nil {
+		return models.Moniteur{}, err
+	}
+	moniteur.EtatConfirme = etatConfirmeNull.String
+	if intervalleNull.Valid {
+		moniteur.IntervalleSecondes = int(intervalleNull.Int64)
+	}
+	if len(parametres) > 0 {
+		if err := json.Unmarshal(parametres, &moniteur.Parametres); err != nil {
+			return models.Moniteur{}, err
+		}
+	}
+	if len(requeteHTTP) > 0 {
+		moniteur.Requete = &models.RequeteHTTP{}
+		if err := json.Unmarshal(requeteHTTP, moniteur.Requete); err != nil {
+			return models.Moniteur{}, err
+		}
+	}
+	return moniteur, nil
+}
+
 // Retourne tous les moniteurs
 func (p *Postgres) ListerMoniteurs(ctx context.Context) ([]models.Moniteur, error) {
-	rows, err := p.db.QueryContext(ctx, `SELECT id, nom, url, type FROM monitoring.moniteurs ORDER BY id ASC`)
+	rows, err := p.db.QueryContext(ctx, `SELECT `+colonnesMoniteur+` FROM monitoring.moniteurs ORDER BY id ASC`)
 	if err != nil {
 		return nil, err
 	}
@@ -108,8 +201,8 @@ func (p *Postgres) ListerMoniteurs(ctx context.Context) ([]models.Moniteur, erro
 
 	var moniteurs []models.Moniteur
 	for rows.Next() {
-		var moniteur models.Moniteur
-		if err := rows.Scan(&moniteur.ID, &moniteur.Nom, &moniteur.URL, &moniteur.Type); err != nil {
+		moniteur, err := scannerMoniteur(rows)
+		if err != nil {
 			return nil, err
 		}
 		moniteurs = append(moniteurs, moniteur)
@@ -118,7 +211,60 @@ func (p *Postgres) ListerMoniteurs(ctx context.Context) ([]models.Moniteur, erro
 	return moniteurs, rows.Err()
 }
 
-// Enregistre un statut dans la BD
+// Retourne un moniteur par son ID
+func (p *Postgres) ObtenirMoniteur(ctx context.Context, id int) (models.Moniteur, error) {
+	ligne := p.db.QueryRowContext(ctx, `SELECT `+colonnesMoniteur+` FROM monitoring.moniteurs WHERE id = $1`, id)
+	moniteur, err := scannerMoniteur(ligne)
+	if errors.Is(err, sql.ErrNoRows) {
+		return models.Moniteur{}, ErrMoniteurIntrouvable
+	}
+	return moniteur, err
+}
+
+// Met à jour tous les champs modifiables d'un moniteur
+func (p *Postgres) MettreAJourMoniteur(ctx context.Context, moniteur models.Moniteur) error {
+	if moniteur.URL == "" {
+		return errors.New("l'URL du moniteur est obligatoire")
+	}
+	if moniteur.Type == "" {
+		moniteur.Type = "http"
+	}
+
+	parametres, err := json.Marshal(moniteur.Parametres)
+	if err != nil {
+		return err
+	}
+	requeteHTTP, err := valeurRequeteHTTP(moniteur.Requete)
+	if err != nil {
+		return err
+	}
+
+	requete := `
+		UPDATE monitoring.moniteurs
+		SET nom = $2, url = $3, type = $4, actif = $5, intervalle_secondes = $6, parametres = $7, requete = $8
+		WHERE id = $1
+	`
+	resultat, err := p.db.ExecContext(ctx, requete,
+		moniteur.ID, moniteur.Nom, moniteur.URL, moniteur.Type, moniteur.Actif, valeurNullInt(moniteur.IntervalleSecondes), parametres, requeteHTTP,
+	)
+	if err != nil {
+		// violation de la contrainte UNIQUE sur l'URL
+		var erreurPg *pgconn.PgError
+		if errors.As(err, &erreurPg) && erreurPg.Code == codeViolationUnicite {
+			return ErrMoniteurExistant
+		}
+		return err
+	}
+
+	lignesAffectees, _ := resultat.RowsAffected()
+	if lignesAffectees == 0 {
+		return ErrMoniteurIntrouvable
+	}
+
+	return nil
+}
+
+// Enregistre un statut dans la BD (et le certificat TLS s'il y en a un)
 func (p *Postgres) EnregistrerStatutMoniteur(ctx context.Context, statut models.StatutMoniteur) error {
 	if statut.URL == "" {
 		return errors.New("l'URL est obligatoire pour un statut")
@@ -128,8 +274,9 @@ func (p *Postgres) EnregistrerStatutMoniteur(ctx context.Context, statut models.
 	}
 
 	requete := `
-		INSERT INTO monitoring.statuts (moniteur_id, url, est_disponible, code_http, message_erreur, latence_ms, verifie_a)
-		VALUES ($1, $2, $3, $4, $5, $6, $7)
+		INSERT INTO monitoring.statuts (moniteur_id, url, est_disponible, code_http, message_erreur, latence_ms, verifie_a, etat, url_finale, redirections,
+			dns_ms, connexion_ms, tls_ms, premier_octet_ms, transfert_ms, en_maintenance, certificat_id)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12, $13, $14, $15, $16, $17)
 	`
 
 	// convertit MoniteurID en int64 ou NULL si 0
@@ -140,20 +287,175 @@ func (p *Postgres) EnregistrerStatutMoniteur(ctx context.Context, statut models.
 		moniteurID = int64(statut.MoniteurID)
 	}
 
-	_, err := p.db.ExecContext(ctx, requete,
+	// chaîne de redirections en JSONB, NULL s'il n'y en a pas
+	var redirections any
+	if len(statut.Redirections) > 0 {
+		donnees, err := json.Marshal(statut.Redirections)
+		if err != nil {
+			return err
+		}
+		redirections = donnees
+	}
+
+	// détail de la latence en millisecondes, NULL pour les moniteurs tcp et dns
+	phases := make([]any, 5)
+	if detail := statut.DetailLatence; detail != nil {
+		for i, duree := range []time.Duration{detail.DNS, detail.Connexion, detail.TLS, detail.PremierOctet, detail.Transfert} {
+			phases[i] = duree.Milliseconds()
+		}
+	}
+
+	// transaction : le statut et son certificat sont enregistrés ensemble
+	tx, err := p.db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	// statut vérifié pendant une maintenance : le trigger ne créera pas d'alerte
+	if statut.MoniteurID != 0 && !statut.EnMaintenance {
+		statut.EnMaintenance, err = enMaintenance(ctx, tx, statut.MoniteurID, statut.VerifieA)
+		if err != nil {
+			return err
+		}
+	}
+
+	var certificatID any
+	if statut.Certificat != nil {
+		certificatID, err = certificatObserve(ctx, tx, moniteurID, statut.Certificat, statut.VerifieA)
+		if err != nil {
+			return err
+		}
+	}
+
+	_, err = tx.ExecContext(ctx, requete,
 		moniteurID, statut.URL, statut.EstDisponible, statut.CodeStatutHTTP,
 		valeurNullString(statut.MessageErreur), statut.Latence.Milliseconds(), statut.VerifieA,
+		statut.EtatEffectif(), valeurNullString(statut.URLFinale), redirections,
+		phases[0], phases[1], phases[2], phases[3], phases[4], statut.EnMaintenance, certificatID,
 	)
-	return err
+	if err != nil {
+		return err
+	}
+
+	return tx.Commit()
+}
+
+// Retourne l'id du certificat d'un statut : le dernier certificat du moniteur s'il a la même empreinte
+// et la même expiration (vu_a avance), sinon une nouvelle ligne
+func certificatObserve(ctx context.Context, tx *sql.Tx, moniteurID any, certificat *models.CertificatTLS, vuA time.Time) (int64, error) {
+	var id int64
+	if moniteurID != nil {
+		err := tx.QueryRowContext(ctx, `
+			UPDATE monitoring.certificats
+			SET vu_a = GREATEST(vu_a, $2)
+			WHERE id = (SELECT MAX(id) FROM monitoring.certificats WHERE moniteur_id = $1)
+				AND empreinte IS NOT DISTINCT FROM $3 AND expire_a = $4 AND sujet = $5 AND emetteur = $6
+			RETURNING id
+		`, moniteurID, vuA, valeurNullString(certificat.Empreinte), certificat.ExpireA, certificat.Sujet, certificat.Emetteur).Scan(&id)
+		if err == nil {
+			return id, nil
+		}
+		if !errors.Is(err, sql.ErrNoRows) {
+			return 0, err
+		}
+	}
+
+	sans, err := json.Marshal(certificat.SANs)
+	if err != nil {
+		return 0, err
+	}
+	err = tx.QueryRowContext(ctx, `
+		INSERT INTO monitoring.certificats (moniteur_id, empreinte, sujet, emetteur, sans, expire_a, vu_a)
+		VALUES ($1, $2, $3, $4, $5, $6, $7)
+		RETURNING id
+	`, moniteurID, valeurNullString(certificat.Empreinte), certificat.Sujet, certificat.Emetteur, sans, certificat.ExpireA, vuA).Scan(&id)
+	return id, err
+}
+
+// Colonnes lues pour un statut (alias s = statuts, c = certificats), dans l'ordre attendu par scannerStatut
+const colonnesStatut = `
+	s.moniteur_id, s.url, s.est_disponible, s.code_http, s.message_erreur, s.latence_ms, s.verifie_a,
+	s.etat, s.url_finale, s.redirections, s.dns_ms, s.connexion_ms, s.tls_ms, s.premier_octet_ms, s.transfert_ms, s.en_maintenance,
+	c.sujet, c.emetteur, c.sans, c.expire_a, c.empreinte
+`
+
+// Jointure des statuts avec leur certificat éventuel
+const sourceStatuts = `
+	monitoring.statuts AS s
+	LEFT JOIN monitoring.certificats AS c ON c.id = s.certificat_id
+`
+
+// Lit un statut depuis une ligne de résultat, avec gestion des valeurs NULL
+func scannerStatut(ligne scanneur) (models.StatutMoniteur, error) {
+	var statut models.StatutMoniteur
+	var moniteurIDNull sql.NullInt64
+	var messageNull sql.NullString
+	var latenceMs sql.NullInt64
+	var sujetNull, emetteurNull, empreinteNull sql.NullString
+	var sans []byte
+	var expireNull sql.NullTime
+	var urlFinaleNull sql.NullString
+	var redirections []byte
+	var phases [5]sql.NullInt64
+
+	if err := ligne.Scan(&moniteurIDNull, &statut.URL, &statut.EstDisponible, &statut.CodeStatutHTTP, &messageNull, &latenceMs, &statut.VerifieA,
+		&statut.Etat, &urlFinaleNull, &redirections, &phases[0], &phases[1], &phases[2], &phases[3], &phases[4], &statut.EnMaintenance,
+		&sujetNull, &emetteurNull, &sans, &expireNull, &empreinteNull); err != nil {
+		return models.StatutMoniteur{}, err
+	}
+
+	if phases[0].Valid {
+		ms := func(valeur sql.NullInt64) time.Duration { return time.Duration(valeur.Int64) * time.Millisecond }
+		statut.DetailLatence = &models.DetailLatence{
+			DNS:          ms(phases[0]),
+			Connexion:    ms(phases[1]),
+			TLS:          ms(phases[2]),
+			PremierOctet: ms(phases[3]),
+			Transfert:    ms(phases[4]),
+		}
+	}
+
+	statut.URLFinale = urlFinaleNull.String
+	if len(redirections) > 0 {
+		if err := json.Unmarshal(redirections, &statut.Redirections); err != nil {
+			return models.StatutMoniteur{}, err
+		}
+	}
+
+	if moniteurIDNull.Valid {
+		statut.MoniteurID = int(moniteurIDNull.Int64)
+	}
+	if messageNull.Valid {
+		statut.MessageErreur = messageNull.String
+	}
+	if latenceMs.Valid {
+		statut.Latence = time.Duration(latenceMs.Int64) * time.Millisecond
+	}
+	if expireNull.Valid {
+		statut.Certificat = &models.CertificatTLS{
+			Sujet:     sujetNull.String,
+			Emetteur:  emetteurNull.String,
+			ExpireA:   expireNull.Time,
+			Empreinte: empreinteNull.String,
+		}
+		if len(sans) > 0 {
+			if err := json.Unmarshal(sans, &statut.Certificat.SANs); err != nil {
+				return models.StatutMoniteur{}, err
+			}
+		}
+	}
+
+	return statut, nil
 }
 
 // DerniersStatutsMoniteur récupère les derniers statuts d'un moniteur
 func (p *Postgres) DerniersStatutsMoniteur(ctx context.Context, moniteurID int) ([]models.StatutMoniteur, error) {
 	requete := `
-		SELECT moniteur_id, url, est_disponible, code_http, message_erreur, latence_ms, verifie_a
-		FROM monitoring.statuts
-		WHERE moniteur_id = $1
-		ORDER BY verifie_a DESC
+		SELECT ` + colonnesStatut + `
+		FROM ` + sourceStatuts + `
+		WHERE s.moniteur_id = $1
+		ORDER BY s.verifie_a DESC
 	`
 	rows, err := p.db.QueryContext(ctx, requete, moniteurID)
 	if err != nil {
@@ -163,26 +465,10 @@ func (p *Postgres) DerniersStatutsMoniteur(ctx context.Context, moniteurID int)
 
 	var statuts []models.StatutMoniteur
 	for rows.Next() {
-		var statut models.StatutMoniteur
-		var moniteurIDNull sql.NullInt64
-		var messageNull sql.NullString
-		var latenceMs sql.NullInt64
-
-        // Scan des valeurs de la ligne courante, avec gestion des valeurs NULL
-		if err := rows.Scan(&moniteurIDNull, &statut.URL, &statut.EstDisponible, &statut.CodeStatutHTTP, &messageNull, &latenceMs, &statut.VerifieA); err != nil {
+		statut, err := scannerStatut(rows)
+		if err != nil {
 			return nil, err
 		}
-
-		if moniteurIDNull.Valid {
-			statut.MoniteurID = int(moniteurIDNull.Int64)
-		}
-		if messageNull.Valid {
-			statut.MessageErreur = messageNull.String
-		}
-		if latenceMs.Valid {
-			statut.Latence = time.Duration(latenceMs.Int64) * time.Millisecond
-		}
-
 		statuts = append(statuts, statut)
 	}
 
@@ -197,10 +483,26 @@ func valeurNullString(valeur string) any {
 	return valeur
 }
 
+// Retourne nil si l'entier est nul (valeur par défaut côté base)
+func valeurNullInt(valeur int) any {
+	if valeur == 0 {
+		return nil
+	}
+	return int64(valeur)
+}
+
+// Encode la requête HTTP d'un moniteur en JSONB (NULL si absente)
+func valeurRequeteHTTP(requete *models.RequeteHTTP) (any, error) {
+	if requete == nil {
+		return nil, nil
+	}
+	return json.Marshal(requete)
+}
+
 // Supprime toutes les données et reset les séquences
 func (p *Postgres) ViderTout(ctx context.Context) error {
 	_, err := p.db.ExecContext(ctx, `
-		TRUNCATE TABLE monitoring.statuts, monitoring.moniteurs RESTART IDENTITY CASCADE
+		TRUNCATE TABLE monitoring.certificats, monitoring.statuts, monitoring.moniteurs RESTART IDENTITY CASCADE
 	`)
 	return err
 }
\ No newline at end of file
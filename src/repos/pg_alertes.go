@@ -0,0 +1,101 @@
+/* Accès PostgreSQL aux alertes et aux livraisons de notifications
+ * Projet de session A25
+ * By : Leandre Kanmegne
+ *
+ * Les alertes sont insérées par le trigger de transition (migrations/0003_alertes_et_notifications.up.sql)
+ * Le répartiteur de notifications les lit, les envoie puis les marque traitées
+ */
+package repos
+
+import (
+	"context"
+	"database/sql"
+	"time"
+
+	"example.com/go-hello/src/internal/models"
+)
+
+// AlertesNonTraitees retourne les alertes créées depuis la date donnée et pas encore envoyées
+func (p *Postgres) AlertesNonTraitees(ctx context.Context, depuis time.Time, limite int) ([]models.Alerte, error) {
+	// pour une alerte UP, la panne commence au DOWN précédent du même moniteur (sans UP entre les deux)
+	requete := `
+		SELECT a.id, a.moniteur_id, m.nom, m.url, a.type, COALESCE(a.details, ''), COALESCE(a.code_http, 0), a.cree_a,
+			CASE WHEN a.type = 'UP' THEN (
+				SELECT EXTRACT(EPOCH FROM a.cree_a - MAX(d.cree_a))
+				FROM monitoring.alertes AS d
+				WHERE d.moniteur_id = a.moniteur_id AND d.type = 'DOWN' AND d.id < a.id
+					AND NOT EXISTS (
+						SELECT 1 FROM monitoring.alertes AS u
+						WHERE u.moniteur_id = a.moniteur_id AND u.type = 'UP' AND u.id > d.id AND u.id < a.id
+					)
+			) END
+		FROM monitoring.alertes AS a
+		JOIN monitoring.moniteurs AS m ON m.id = a.moniteur_id
+		WHERE a.traitee_a IS NULL AND a.cree_a >= $1
+		ORDER BY a.id ASC
+		LIMIT $2
+	`
+	rows, err := p.db.QueryContext(ctx, requete, depuis, limite)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var alertes []models.Alerte
+	for rows.Next() {
+		var alerte models.Alerte
+		var dureePanne sql.NullFloat64
+		if err := rows.Scan(&alerte.ID, &alerte.MoniteurID, &alerte.NomMoniteur, &alerte.URL, &alerte.Type,
+			&alerte.Details, &alerte.CodeHTTP, &alerte.CreeA, &dureePanne); err != nil {
+			return nil, err
+		}
+		if dureePanne.Valid {
+			alerte.DureePanne = time.Duration(dureePanne.Float64 * float64(time.Second))
+		}
+		alertes = append(alertes, alerte)
+	}
+
+	return alertes, rows.Err()
+}
+
+// MarquerAlerteTraitee note que l'alerte a été envoyée sur tous les canaux (ou abandonnée car expirée)
+func (p *Postgres) MarquerAlerteTraitee(ctx context.Context, id int) error {
+	_, err := p.db.ExecContext(ctx, `UPDATE monitoring.alertes SET traitee_a = NOW() WHERE id = $1`, id)
+	return err
+}
+
+// CanauxLivres retourne les canaux qui ont déjà reçu l'alerte (au moins une livraison réussie)
+func (p *Postgres) CanauxLivres(ctx context.Context, alerteID int) ([]string, error) {
+	rows, err := p.db.QueryContext(ctx, `
+		SELECT DISTINCT canal FROM monitoring.livraisons
+		WHERE alerte_id = $1 AND succes
+		ORDER BY canal
+	`, alerteID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var canaux []string
+	for rows.Next() {
+		var canal string
+		if err := rows.Scan(&canal); err != nil {
+			return nil, err
+		}
+		canaux = append(canaux, canal)
+	}
+	return canaux, rows.Err()
+}
+
+// EnregistrerLivraison garde la trace d'une tentative d'envoi
+func (p *Postgres) EnregistrerLivraison(ctx context.Context, livraison models.Livraison) error {
+	requete := `
+		INSERT INTO monitoring.livraisons (alerte_id, canal, tentative, succes, code_http, erreur, duree_ms, cree_a)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8)
+	`
+	_, err := p.db.ExecContext(ctx, requete,
+		livraison.AlerteID, livraison.Canal, livraison.Tentative, livraison.Succes,
+		valeurNullInt(livraison.CodeHTTP), valeurNullString(livraison.Erreur), livraison.Duree.Milliseconds(), livraison.CreeA,
+	)
+	return err
+}
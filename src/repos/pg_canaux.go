@@ -0,0 +1,217 @@
+/* Accès PostgreSQL aux canaux de notification et au routage des alertes
+ * Projet de session A25
+ * By : Leandre Kanmegne
+ *
+ * Un canal (monitoring.canaux) garde sa configuration en JSONB
+ * Une route (monitoring.routes_notification) relie un moniteur à un canal avec les types d'alertes à envoyer
+ * Les routes d'un moniteur sont remplacées d'un bloc dans une transaction
+ */
+package repos
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+
+	"example.com/go-hello/src/internal/models"
+	"github.com/jackc/pgx/v5/pgconn"
+)
+
+// Code SQLSTATE d'une violation de clé étrangère
+const codeViolationCleEtrangere = "23503"
+
+// Colonnes lues pour un canal, dans l'ordre attendu par scannerCanal
+const colonnesCanal = `id, nom, type, actif, config, cree_a`
+
+// Lit un canal depuis une ligne de résultat
+func scannerCanal(ligne scanneur) (models.CanalNotification, error) {
+	var canal models.CanalNotification
+	var config []byte
+	if err := ligne.Scan(&canal.ID, &canal.Nom, &canal.Type, &canal.Actif, &config, &canal.CreeA); err != nil {
+		return models.CanalNotification{}, err
+	}
+	if len(config) > 0 {
+		if err := json.Unmarshal(config, &canal.Config); err != nil {
+			return models.CanalNotification{}, err
+		}
+	}
+	return canal, nil
+}
+
+// Traduit une violation d'unicité sur le nom en ErrCanalExistant
+func erreurCanal(err error) error {
+	var erreurPg *pgconn.PgError
+	if errors.As(err, &erreurPg) && erreurPg.Code == codeViolationUnicite {
+		return ErrCanalExistant
+	}
+	return err
+}
+
+// Ajoute un canal et le retourne avec son id
+func (p *Postgres) AjouterCanal(ctx context.Context, canal models.CanalNotification) (models.CanalNotification, error) {
+	config, err := json.Marshal(canal.Config)
+	if err != nil {
+		return models.CanalNotification{}, err
+	}
+	ligne := p.db.QueryRowContext(ctx, `
+		INSERT INTO monitoring.canaux (nom, type, actif, config)
+		VALUES ($1, $2, $3, $4)
+		RETURNING `+colonnesCanal,
+		canal.Nom, canal.Type, canal.Actif, config,
+	)
+	cree, err := scannerCanal(ligne)
+	return cree, erreurCanal(err)
+}
+
+// Retourne tous les canaux
+func (p *Postgres) ListerCanaux(ctx context.Context) ([]models.CanalNotification, error) {
+	rows, err := p.db.QueryContext(ctx, `SELECT `+colonnesCanal+` FROM monitoring.canaux ORDER BY id ASC`)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var canaux []models.CanalNotification
+	for rows.Next() {
+		canal, err := scannerCanal(rows)
+		if err != nil {
+			return nil, err
+		}
+		canaux = append(canaux, canal)
+	}
+
+	return canaux, rows.Err()
+}
+
+// Retourne un canal par son ID
+func (p *Postgres) ObtenirCanal(ctx context.Context, id int) (models.CanalNotification, error) {
+	canal, err := scannerCanal(p.db.QueryRowContext(ctx, `SELECT `+colonnesCanal+` FROM monitoring.canaux WHERE id = $1`, id))
+	if errors.Is(err, sql.ErrNoRows) {
+		return models.CanalNotification{}, ErrCanalIntrouvable
+	}
+	return canal, err
+}
+
+// Met à jour le nom, le type, l'activation et la config d'un canal
+func (p *Postgres) MettreAJourCanal(ctx context.Context, canal models.CanalNotification) error {
+	config, err := json.Marshal(canal.Config)
+	if err != nil {
+		return err
+	}
+	resultat, err := p.db.ExecContext(ctx, `
+		UPDATE monitoring.canaux SET nom = $2, type = $3, actif = $4, config = $5 WHERE id = $1
+	`, canal.ID, canal.Nom, canal.Type, canal.Actif, config)
+	if err != nil {
+		return erreurCanal(err)
+	}
+
+	lignesAffectees, _ := resultat.RowsAffected()
+	if lignesAffectees == 0 {
+		return ErrCanalIntrouvable
+	}
+	return nil
+}
+
+// Supprime un canal (ses routes sont supprimées en cascade)
+func (p *Postgres) SupprimerCanal(ctx context.Context, id int) error {
+	resultat, err := p.db.ExecContext(ctx, `DELETE FROM monitoring.canaux WHERE id = $1`, id)
+	if err != nil {
+		return err
+	}
+
+	lignesAffectees, _ := resultat.RowsAffected()
+	if lignesAffectees == 0 {
+		return ErrCanalIntrouvable
+	}
+	return nil
+}
+
+// Retourne les routes d'un moniteur
+func (p *Postgres) RoutesMoniteur(ctx context.Context, moniteurID int) ([]models.RouteNotification, error) {
+	rows, err := p.db.QueryContext(ctx, `
+		SELECT moniteur_id, canal_id, types FROM monitoring.routes_notification
+		WHERE moniteur_id = $1
+		ORDER BY canal_id ASC
+	`, moniteurID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var routes []models.RouteNotification
+	for rows.Next() {
+		var route models.RouteNotification
+		var types []byte
+		if err := rows.Scan(&route.MoniteurID, &route.CanalID, &types); err != nil {
+			return nil, err
+		}
+		if err := json.Unmarshal(types, &route.Types); err != nil {
+			return nil, err
+		}
+		routes = append(routes, route)
+	}
+
+	return routes, rows.Err()
+}
+
+// Remplace toutes les routes d'un moniteur
+func (p *Postgres) DefinirRoutesMoniteur(ctx context.Context, moniteurID int, routes []models.RouteNotification) error {
+	tx, err := p.db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	if _, err := tx.ExecContext(ctx, `DELETE FROM monitoring.routes_notification WHERE moniteur_id = $1`, moniteurID); err != nil {
+		return err
+	}
+	for _, route := range routes {
+		types, err := json.Marshal(route.Types)
+		if err != nil {
+			return err
+		}
+		_, err = tx.ExecContext(ctx, `
+			INSERT INTO monitoring.routes_notification (moniteur_id, canal_id, types) VALUES ($1, $2, $3)
+		`, moniteurID, route.CanalID, types)
+		if err != nil {
+			// moniteur ou canal supprimé entre la validation et l'insertion
+			var erreurPg *pgconn.PgError
+			if errors.As(err, &erreurPg) && erreurPg.Code == codeViolationCleEtrangere {
+				if erreurPg.ConstraintName == "routes_notification_moniteur_id_fkey" {
+					return ErrMoniteurIntrouvable
+				}
+				return ErrCanalIntrouvable
+			}
+			return err
+		}
+	}
+
+	return tx.Commit()
+}
+
+// CanauxPourAlerte retourne les canaux actifs routés pour ce moniteur et ce type d'alerte
+func (p *Postgres) CanauxPourAlerte(ctx context.Context, moniteurID int, typeAlerte string) ([]models.CanalNotification, error) {
+	rows, err := p.db.QueryContext(ctx, `
+		SELECT c.id, c.nom, c.type, c.actif, c.config, c.cree_a
+		FROM monitoring.routes_notification AS r
+		JOIN monitoring.canaux AS c ON c.id = r.canal_id
+		WHERE r.moniteur_id = $1 AND c.actif AND r.types ? $2
+		ORDER BY c.id ASC
+	`, moniteurID, typeAlerte)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var canaux []models.CanalNotification
+	for rows.Next() {
+		canal, err := scannerCanal(rows)
+		if err != nil {
+			return nil, err
+		}
+		canaux = append(canaux, canal)
+	}
+
+	return canaux, rows.Err()
+}
@@ -0,0 +1,240 @@
+/* Consolidation PostgreSQL de l'historique des statuts
+ * Projet de session A25
+ * By : Leandre Kanmegne
+ *
+ * Regroupe les statuts bruts par heure (monitoring.statuts_horaires) et par jour (monitoring.statuts_quotidiens)
+ * monitoring.consolidations retient jusqu'où chaque granularité est consolidée : seuls les intervalles terminés le sont
+ * Un intervalle n'est consolidé qu'après le délai de grâce (Reglages.DelaiGrace), le temps que les statuts en retard arrivent
+ * Les lectures longues combinent : statuts bruts au début, intervalles consolidés au milieu, statuts bruts à la fin
+ * Les durées d'un statut sont coupées à la fin de son intervalle (le temps avant le premier statut n'est pas observé)
+ *
+ * Source: https://www.postgresql.org/docs/current/sql-insert.html#SQL-ON-CONFLICT
+ */
+package repos
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"time"
+
+	"example.com/go-hello/src/internal/models"
+)
+
+// Nombre max d'intervalles consolidés par transaction (rattrapage par lots)
+const intervallesParLot = 168
+
+// consolidation décrit une granularité de l'historique
+type consolidation struct {
+	granularite string
+	table       string
+	duree       time.Duration
+}
+
+// Granularités, de la plus fine à la plus grossière
+var (
+	consolidationHoraire     = consolidation{"horaire", "monitoring.statuts_horaires", time.Hour}
+	consolidationQuotidienne = consolidation{"quotidienne", "monitoring.statuts_quotidiens", 24 * time.Hour}
+	consolidations           = []consolidation{consolidationHoraire, consolidationQuotidienne}
+)
+
+// Retourne la consolidation dont les intervalles ont exactement cette durée
+func consolidationPour(duree time.Duration) (consolidation, bool) {
+	for _, c := range consolidations {
+		if c.duree == duree {
+			return c, true
+		}
+	}
+	return consolidation{}, false
+}
+
+// Retourne la partie de [depuis, jusqua[ couverte par des intervalles consolidés entiers
+// ok = false si aucun intervalle entier n'est consolidé dans la période
+func decouper(depuis, jusqua, filigrane time.Time, duree time.Duration) (debut, fin time.Time, ok bool) {
+	debut = depuis.Truncate(duree)
+	if debut.Before(depuis) {
+		debut = debut.Add(duree)
+	}
+	fin = jusqua.Truncate(duree)
+	if filigrane.Before(fin) {
+		fin = filigrane
+	}
+	return debut, fin, debut.Before(fin)
+}
+
+// Retourne la date jusqu'à laquelle une granularité est consolidée (zéro si jamais consolidée)
+func (p *Postgres) filigrane(ctx context.Context, c consolidation) (time.Time, error) {
+	var jusqua time.Time
+	err := p.db.QueryRowContext(ctx, `SELECT jusqua FROM monitoring.consolidations WHERE granularite = $1`, c.granularite).Scan(&jusqua)
+	if errors.Is(err, sql.ErrNoRows) {
+		return time.Time{}, nil
+	}
+	return jusqua, err
+}
+
+// ConsoliderStatuts consolide les intervalles terminés avant maintenant - délai de grâce pour chaque granularité
+// Retourne le nombre de lignes consolidées (insérées ou mises à jour)
+func (p *Postgres) ConsoliderStatuts(ctx context.Context, maintenant time.Time) (int, error) {
+	total := 0
+	for _, c := range consolidations {
+		for {
+			lignes, termine, err := p.consoliderLot(ctx, c, maintenant)
+			total += lignes
+			if err != nil {
+				return total, fmt.Errorf("consolidation %s : %w", c.granularite, err)
+			}
+			if termine {
+				break
+			}
+		}
+	}
+	return total, nil
+}
+
+// Consolide un lot d'intervalles et avance le filigrane dans la même transaction
+// termine = true quand tous les intervalles terminés sont consolidés
+func (p *Postgres) consoliderLot(ctx context.Context, c consolidation, maintenant time.Time) (int, bool, error) {
+	// les statuts en retard (tentatives, timeouts) arrivent avant la fin du délai de grâce
+	limite := maintenant.Add(-p.reglages.DelaiGrace).Truncate(c.duree)
+
+	tx, err := p.db.BeginTx(ctx, nil)
+	if err != nil {
+		return 0, false, err
+	}
+	defer tx.Rollback()
+
+	// verrou : un seul consolidateur à la fois par granularité
+	var debut sql.NullTime
+	err = tx.QueryRowContext(ctx, `SELECT jusqua FROM monitoring.consolidations WHERE granularite = $1 FOR UPDATE`, c.granularite).Scan(&debut)
+	if errors.Is(err, sql.ErrNoRows) {
+		// première consolidation : depuis le plus vieux statut
+		err = tx.QueryRowContext(ctx, `SELECT MIN(verifie_a) FROM monitoring.statuts`).Scan(&debut)
+		if err == nil && debut.Valid {
+			debut.Time = debut.Time.Truncate(c.duree)
+		}
+	}
+	if err != nil {
+		return 0, false, err
+	}
+	if !debut.Valid || !debut.Time.Before(limite) {
+		return 0, true, nil
+	}
+
+	fin := debut.Time.Add(intervallesParLot * c.duree)
+	if fin.After(limite) {
+		fin = limite
+	}
+
+	// $5 = intervalle de vérification par défaut (INTERVALLE_VERIFICATION_SECONDES), comme DisponibiliteMoniteur
+	resultat, err := tx.ExecContext(ctx, `
+		WITH periodes AS (
+			SELECT
+				s.moniteur_id,
+				s.est_disponible,
+				s.etat,
+				s.en_maintenance,
+				s.latence_ms,
+				date_bin(make_interval(secs => $3), s.verifie_a, $4::TIMESTAMPTZ) AS debut,
+				EXTRACT(EPOCH FROM LEAST(
+					LEAD(s.verifie_a) OVER (PARTITION BY s.moniteur_id ORDER BY s.verifie_a, s.id),
+					s.verifie_a + make_interval(secs => 3 * COALESCE(m.intervalle_secondes, $5::INTEGER)),
+					date_bin(make_interval(secs => $3), s.verifie_a, $4::TIMESTAMPTZ) + make_interval(secs => $3)
+				) - s.verifie_a) AS secondes
+			FROM monitoring.statuts AS s
+			JOIN monitoring.moniteurs AS m ON m.id = s.moniteur_id
+			WHERE s.verifie_a >= $1 AND s.verifie_a < $2
+		)
+		INSERT INTO `+c.table+` (
+			moniteur_id, debut, verifications, succes, echecs,
+			secondes_observees, secondes_indisponibles, secondes_maintenance,
+			latence_min_ms, latence_max_ms, latence_moyenne_ms,
+			latence_p50_ms, latence_p90_ms, latence_p95_ms, latence_p99_ms
+		)
+		SELECT
+			moniteur_id,
+			debut,
+			COUNT(*),
+			COUNT(*) FILTER (WHERE est_disponible),
+			COUNT(*) FILTER (WHERE etat = 'down'),
+			COALESCE(SUM(secondes) FILTER (WHERE NOT en_maintenance), 0)::BIGINT,
+			COALESCE(SUM(secondes) FILTER (WHERE NOT en_maintenance AND etat = 'down'), 0)::BIGINT,
+			COALESCE(SUM(secondes) FILTER (WHERE en_maintenance), 0)::BIGINT,
+			MIN(latence_ms),
+			MAX(latence_ms),
+			AVG(latence_ms),
+			percentile_cont(0.50) WITHIN GROUP (ORDER BY latence_ms),
+			percentile_cont(0.90) WITHIN GROUP (ORDER BY latence_ms),
+			percentile_cont(0.95) WITHIN GROUP (ORDER BY latence_ms),
+			percentile_cont(0.99) WITHIN GROUP (ORDER BY latence_ms)
+		FROM periodes
+		GROUP BY moniteur_id, debut
+		ON CONFLICT (moniteur_id, debut) DO UPDATE SET
+			verifications = EXCLUDED.verifications,
+			succes = EXCLUDED.succes,
+			echecs = EXCLUDED.echecs,
+			secondes_observees = EXCLUDED.secondes_observees,
+			secondes_indisponibles = EXCLUDED.secondes_indisponibles,
+			secondes_maintenance = EXCLUDED.secondes_maintenance,
+			latence_min_ms = EXCLUDED.latence_min_ms,
+			latence_max_ms = EXCLUDED.latence_max_ms,
+			latence_moyenne_ms = EXCLUDED.latence_moyenne_ms,
+			latence_p50_ms = EXCLUDED.latence_p50_ms,
+			latence_p90_ms = EXCLUDED.latence_p90_ms,
+			latence_p95_ms = EXCLUDED.latence_p95_ms,
+			latence_p99_ms = EXCLUDED.latence_p99_ms
+	`, debut.Time, fin, c.duree.Seconds(), origineIntervalles, p.reglages.intervalleSecondes())
+	if err != nil {
+		return 0, false, err
+	}
+	lignes, _ := resultat.RowsAffected()
+
+	if _, err := tx.ExecContext(ctx, `
+		INSERT INTO monitoring.consolidations (granularite, jusqua) VALUES ($1, $2)
+		ON CONFLICT (granularite) DO UPDATE SET jusqua = EXCLUDED.jusqua
+	`, c.granularite, fin); err != nil {
+		return 0, false, err
+	}
+
+	return int(lignes), !fin.Before(limite), tx.Commit()
+}
+
+// Retourne les agrégats de latence consolidés d'un moniteur sur [depuis, jusqua[
+func (p *Postgres) agregatsConsolides(ctx context.Context, c consolidation, moniteurID int, depuis, jusqua time.Time) ([]models.AgregatLatence, error) {
+	rows, err := p.db.QueryContext(ctx, `
+		SELECT debut, verifications, succes::FLOAT8 / verifications,
+			latence_min_ms, latence_max_ms, latence_moyenne_ms,
+			latence_p50_ms, latence_p90_ms, latence_p95_ms, latence_p99_ms
+		FROM `+c.table+`
+		WHERE moniteur_id = $1 AND debut >= $2 AND debut < $3 AND verifications > 0
+		ORDER BY debut
+	`, moniteurID, depuis, jusqua)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var agregats []models.AgregatLatence
+	for rows.Next() {
+		agregat, err := scannerAgregat(rows)
+		if err != nil {
+			return nil, err
+		}
+		agregats = append(agregats, agregat)
+	}
+
+	return agregats, rows.Err()
+}
+
+// Retourne les durées consolidées d'un moniteur sur [depuis, jusqua[ (sans les incidents)
+func (p *Postgres) dureesConsolidees(ctx context.Context, c consolidation, moniteurID int, depuis, jusqua time.Time) (models.RapportDisponibilite, error) {
+	var rapport models.RapportDisponibilite
+	err := p.db.QueryRowContext(ctx, `
+		SELECT COALESCE(SUM(verifications), 0), COALESCE(SUM(secondes_observees), 0),
+			COALESCE(SUM(secondes_indisponibles), 0), COALESCE(SUM(secondes_maintenance), 0)
+		FROM `+c.table+`
+		WHERE moniteur_id = $1 AND debut >= $2 AND debut < $3
+	`, moniteurID, depuis, jusqua).Scan(&rapport.Verifications, &rapport.SecondesObservees,
+		&rapport.SecondesIndisponibles, &rapport.SecondesMaintenance)
+	return rapport, err
+}
@@ -0,0 +1,116 @@
+/* Calcul PostgreSQL de la disponibilité d'un moniteur
+ * Projet de session A25
+ * By : Leandre Kanmegne
+ *
+ * Chaque statut couvre le temps jusqu'au statut suivant (LEAD), au plus 3 intervalles de vérification
+ * pour ne pas compter un moniteur en pause, et coupé aux bornes de la période
+ * Les jours et heures déjà consolidés sont lus dans les tables consolidées (voir pg_consolidations.go),
+ * ce qui garde les rapports possibles après la purge des statuts bruts
+ * Les incidents comptés sont ceux de monitoring.incidents qui chevauchent la période
+ *
+ * Source: https://www.postgresql.org/docs/current/functions-window.html
+ */
+package repos
+
+import (
+	"context"
+	"time"
+
+	"example.com/go-hello/src/internal/models"
+)
+
+// Calcule le rapport de disponibilité d'un moniteur sur [depuis, jusqua[
+func (p *Postgres) DisponibiliteMoniteur(ctx context.Context, moniteurID int, depuis, jusqua time.Time) (models.RapportDisponibilite, error) {
+	rapport, err := p.durees(ctx, consolidations, moniteurID, depuis, jusqua)
+	if err != nil {
+		return models.RapportDisponibilite{}, err
+	}
+	rapport.Depuis, rapport.Jusqua = depuis, jusqua
+
+	err = p.db.QueryRowContext(ctx, `
+		SELECT COUNT(*)
+		FROM monitoring.incidents
+		WHERE moniteur_id = $1 AND ouvert_a < $3 AND (ferme_a IS NULL OR ferme_a > $2)
+	`, moniteurID, depuis, jusqua).Scan(&rapport.Incidents)
+	if err != nil {
+		return models.RapportDisponibilite{}, err
+	}
+
+	rapport.Calculer()
+	return rapport, nil
+}
+
+// Calcule les durées d'un moniteur sur [depuis, jusqua[ (sans les incidents)
+// Utilise la granularité la plus grossière pour les intervalles consolidés entiers, puis les plus fines aux bords
+func (p *Postgres) durees(ctx context.Context, niveaux []consolidation, moniteurID int, depuis, jusqua time.Time) (models.RapportDisponibilite, error) {
+	if len(niveaux) == 0 {
+		return p.dureesBrutes(ctx, moniteurID, depuis, jusqua)
+	}
+	c, plusFins := niveaux[len(niveaux)-1], niveaux[:len(niveaux)-1]
+
+	filigrane, err := p.filigrane(ctx, c)
+	if err != nil {
+		return models.RapportDisponibilite{}, err
+	}
+	debut, fin, ok := decouper(depuis, jusqua, filigrane, c.duree)
+	if !ok {
+		return p.durees(ctx, plusFins, moniteurID, depuis, jusqua)
+	}
+
+	var total models.RapportDisponibilite
+	for _, partie := range []func() (models.RapportDisponibilite, error){
+		func() (models.RapportDisponibilite, error) { return p.durees(ctx, plusFins, moniteurID, depuis, debut) },
+		func() (models.RapportDisponibilite, error) { return p.dureesConsolidees(ctx, c, moniteurID, debut, fin) },
+		func() (models.RapportDisponibilite, error) { return p.durees(ctx, plusFins, moniteurID, fin, jusqua) },
+	} {
+		rapport, err := partie()
+		if err != nil {
+			return models.RapportDisponibilite{}, err
+		}
+		total.Verifications += rapport.Verifications
+		total.SecondesObservees += rapport.SecondesObservees
+		total.SecondesIndisponibles += rapport.SecondesIndisponibles
+		total.SecondesMaintenance += rapport.SecondesMaintenance
+	}
+	return total, nil
+}
+
+// Calcule les durées d'un moniteur sur [depuis, jusqua[ à partir des statuts bruts (sans les incidents)
+func (p *Postgres) dureesBrutes(ctx context.Context, moniteurID int, depuis, jusqua time.Time) (models.RapportDisponibilite, error) {
+	var rapport models.RapportDisponibilite
+	if !depuis.Before(jusqua) {
+		return rapport, nil
+	}
+
+	// $4 = intervalle de vérification par défaut (INTERVALLE_VERIFICATION_SECONDES)
+	err := p.db.QueryRowContext(ctx, `
+		WITH moniteur AS (
+			SELECT id, make_interval(secs => 3 * COALESCE(intervalle_secondes, $4::INTEGER)) AS ecart_max
+			FROM monitoring.moniteurs
+			WHERE id = $1
+		),
+		periodes AS (
+			SELECT
+				s.verifie_a,
+				s.etat,
+				s.en_maintenance,
+				GREATEST(s.verifie_a, $2::TIMESTAMPTZ) AS debut,
+				LEAST(
+					LEAD(s.verifie_a) OVER (ORDER BY s.verifie_a, s.id),
+					s.verifie_a + moniteur.ecart_max,
+					$3::TIMESTAMPTZ
+				) AS fin
+			FROM monitoring.statuts AS s
+			JOIN moniteur ON moniteur.id = s.moniteur_id
+			WHERE s.verifie_a >= $2::TIMESTAMPTZ - moniteur.ecart_max AND s.verifie_a < $3::TIMESTAMPTZ
+		)
+		SELECT
+			COUNT(*) FILTER (WHERE verifie_a >= $2::TIMESTAMPTZ),
+			COALESCE(SUM(EXTRACT(EPOCH FROM fin - debut)) FILTER (WHERE fin > debut AND NOT en_maintenance), 0)::BIGINT,
+			COALESCE(SUM(EXTRACT(EPOCH FROM fin - debut)) FILTER (WHERE fin > debut AND NOT en_maintenance AND etat = 'down'), 0)::BIGINT,
+			COALESCE(SUM(EXTRACT(EPOCH FROM fin - debut)) FILTER (WHERE fin > debut AND en_maintenance), 0)::BIGINT
+		FROM periodes
+	`, moniteurID, depuis, jusqua, p.reglages.intervalleSecondes()).Scan(&rapport.Verifications, &rapport.SecondesObservees,
+		&rapport.SecondesIndisponibles, &rapport.SecondesMaintenance)
+	return rapport, err
+}
@@ -0,0 +1,132 @@
+/* Accès PostgreSQL aux incidents
+ * Projet de session A25
+ * By : Leandre Kanmegne
+ *
+ * Les incidents sont ouverts (DOWN) et fermés (UP) par le trigger de transition (src/database/migrations)
+ * Ici on les lit avec des filtres et on enregistre l'acquittement (qui, quand, note)
+ */
+package repos
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"strconv"
+	"strings"
+
+	"example.com/go-hello/src/internal/models"
+)
+
+// Nombre d'incidents retournés si aucune limite n'est donnée
+const limiteIncidentsParDefaut = 100
+
+// Sélection d'un incident avec son moniteur, dans l'ordre attendu par scannerIncident
+const selectionIncident = `
+	SELECT i.id, i.moniteur_id, m.nom, m.url, i.ouvert_a, i.ferme_a, COALESCE(i.message_erreur, ''), COALESCE(i.code_http, 0),
+		i.acquitte_a, COALESCE(i.acquitte_par, ''), COALESCE(i.note_acquittement, '')
+	FROM monitoring.incidents AS i
+	JOIN monitoring.moniteurs AS m ON m.id = i.moniteur_id
+`
+
+// Lit un incident depuis une ligne de résultat
+func scannerIncident(ligne scanneur) (models.Incident, error) {
+	var incident models.Incident
+	var fermeA, acquitteA sql.NullTime
+	if err := ligne.Scan(&incident.ID, &incident.MoniteurID, &incident.NomMoniteur, &incident.URL, &incident.OuvertA, &fermeA,
+		&incident.MessageErreur, &incident.CodeHTTP, &acquitteA, &incident.AcquittePar, &incident.NoteAcquittement); err != nil {
+		return models.Incident{}, err
+	}
+	if fermeA.Valid {
+		incident.FermeA = &fermeA.Time
+	}
+	if acquitteA.Valid {
+		incident.AcquitteA = &acquitteA.Time
+	}
+	return incident, nil
+}
+
+// Retourne les incidents correspondant au filtre, du plus récent au plus ancien
+func (p *Postgres) ListerIncidents(ctx context.Context, filtre models.FiltreIncidents) ([]models.Incident, error) {
+	var conditions []string
+	var arguments []any
+	ajouter := func(condition string, valeur any) {
+		arguments = append(arguments, valeur)
+		conditions = append(conditions, strings.ReplaceAll(condition, "?", "$"+strconv.Itoa(len(arguments))))
+	}
+
+	if filtre.MoniteurID != 0 {
+		ajouter("i.moniteur_id = ?", filtre.MoniteurID)
+	}
+	if !filtre.Depuis.IsZero() {
+		ajouter("(i.ferme_a IS NULL OR i.ferme_a >= ?)", filtre.Depuis)
+	}
+	if !filtre.Jusqua.IsZero() {
+		ajouter("i.ouvert_a < ?", filtre.Jusqua)
+	}
+	if filtre.Ouverts != nil {
+		if *filtre.Ouverts {
+			conditions = append(conditions, "i.ferme_a IS NULL")
+		} else {
+			conditions = append(conditions, "i.ferme_a IS NOT NULL")
+		}
+	}
+
+	requete := selectionIncident
+	if len(conditions) > 0 {
+		requete += " WHERE " + strings.Join(conditions, " AND ")
+	}
+	limite := filtre.Limite
+	if limite <= 0 {
+		limite = limiteIncidentsParDefaut
+	}
+	arguments = append(arguments, limite)
+	requete += " ORDER BY i.ouvert_a DESC, i.id DESC LIMIT $" + strconv.Itoa(len(arguments))
+
+	rows, err := p.db.QueryContext(ctx, requete, arguments...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var incidents []models.Incident
+	for rows.Next() {
+		incident, err := scannerIncident(rows)
+		if err != nil {
+			return nil, err
+		}
+		incidents = append(incidents, incident)
+	}
+
+	return incidents, rows.Err()
+}
+
+// Retourne un incident par son ID
+func (p *Postgres) ObtenirIncident(ctx context.Context, id int) (models.Incident, error) {
+	incident, err := scannerIncident(p.db.QueryRowContext(ctx, selectionIncident+` WHERE i.id = $1`, id))
+	if errors.Is(err, sql.ErrNoRows) {
+		return models.Incident{}, ErrIncidentIntrouvable
+	}
+	return incident, err
+}
+
+// Acquitte un incident (une seule fois) et le retourne à jour
+func (p *Postgres) AcquitterIncident(ctx context.Context, id int, par, note string) (models.Incident, error) {
+	resultat, err := p.db.ExecContext(ctx, `
+		UPDATE monitoring.incidents
+		SET acquitte_a = NOW(), acquitte_par = $2, note_acquittement = $3
+		WHERE id = $1 AND acquitte_a IS NULL
+	`, id, par, valeurNullString(note))
+	if err != nil {
+		return models.Incident{}, err
+	}
+
+	lignesAffectees, _ := resultat.RowsAffected()
+	if lignesAffectees == 0 {
+		// introuvable ou déjà acquitté
+		if _, err := p.ObtenirIncident(ctx, id); err != nil {
+			return models.Incident{}, err
+		}
+		return models.Incident{}, ErrIncidentAcquitte
+	}
+	return p.ObtenirIncident(ctx, id)
+}
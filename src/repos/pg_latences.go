@@ -0,0 +1,118 @@
+/* Agrégats PostgreSQL de latence
+ * Projet de session A25
+ * By : Leandre Kanmegne
+ *
+ * Regroupe les statuts par intervalle avec date_bin (PostgreSQL 14+)
+ * et calcule les percentiles avec percentile_cont (les latences NULL sont ignorées)
+ * Les intervalles de 1h et 1d déjà consolidés sont lus dans les tables consolidées (voir pg_consolidations.go)
+ *
+ * Source: https://www.postgresql.org/docs/current/functions-datetime.html#FUNCTIONS-DATETIME-BIN
+ */
+package repos
+
+import (
+	"context"
+	"database/sql"
+	"time"
+
+	"example.com/go-hello/src/internal/models"
+)
+
+// Origine des intervalles : les intervalles d'un jour commencent à minuit UTC
+const origineIntervalles = "2000-01-01T00:00:00Z"
+
+// Retourne les agrégats de latence d'un moniteur sur [depuis, jusqua[, du plus ancien au plus récent
+// Les intervalles sans statut ne sont pas retournés
+func (p *Postgres) AgregatsLatence(ctx context.Context, moniteurID int, depuis, jusqua time.Time, intervalle time.Duration) ([]models.AgregatLatence, error) {
+	c, ok := consolidationPour(intervalle)
+	if !ok {
+		return p.agregatsBruts(ctx, moniteurID, depuis, jusqua, intervalle)
+	}
+	filigrane, err := p.filigrane(ctx, c)
+	if err != nil {
+		return nil, err
+	}
+	debut, fin, ok := decouper(depuis, jusqua, filigrane, c.duree)
+	if !ok {
+		return p.agregatsBruts(ctx, moniteurID, depuis, jusqua, intervalle)
+	}
+
+	avant, err := p.agregatsBruts(ctx, moniteurID, depuis, debut, intervalle)
+	if err != nil {
+		return nil, err
+	}
+	milieu, err := p.agregatsConsolides(ctx, c, moniteurID, debut, fin)
+	if err != nil {
+		return nil, err
+	}
+	apres, err := p.agregatsBruts(ctx, moniteurID, fin, jusqua, intervalle)
+	if err != nil {
+		return nil, err
+	}
+	return append(append(avant, milieu...), apres...), nil
+}
+
+// Calcule les agrégats de latence à partir des statuts bruts
+func (p *Postgres) agregatsBruts(ctx context.Context, moniteurID int, depuis, jusqua time.Time, intervalle time.Duration) ([]models.AgregatLatence, error) {
+	if !depuis.Before(jusqua) {
+		return nil, nil
+	}
+	rows, err := p.db.QueryContext(ctx, `
+		SELECT
+			date_bin(make_interval(secs => $4), verifie_a, $5::TIMESTAMPTZ) AS debut,
+			COUNT(*),
+			COUNT(*) FILTER (WHERE est_disponible)::FLOAT8 / COUNT(*),
+			MIN(latence_ms)::FLOAT8,
+			MAX(latence_ms)::FLOAT8,
+			AVG(latence_ms)::FLOAT8,
+			percentile_cont(0.50) WITHIN GROUP (ORDER BY latence_ms),
+			percentile_cont(0.90) WITHIN GROUP (ORDER BY latence_ms),
+			percentile_cont(0.95) WITHIN GROUP (ORDER BY latence_ms),
+			percentile_cont(0.99) WITHIN GROUP (ORDER BY latence_ms)
+		FROM monitoring.statuts
+		WHERE moniteur_id = $1 AND verifie_a >= $2 AND verifie_a < $3
+		GROUP BY debut
+		ORDER BY debut
+	`, moniteurID, depuis, jusqua, intervalle.Seconds(), origineIntervalles)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var agregats []models.AgregatLatence
+	for rows.Next() {
+		agregat, err := scannerAgregat(rows)
+		if err != nil {
+			return nil, err
+		}
+		agregats = append(agregats, agregat)
+	}
+
+	return agregats, rows.Err()
+}
+
+// Lit un agrégat (début, vérifications, taux de succès, min, max, moyenne, p50, p90, p95, p99)
+func scannerAgregat(ligne scanneur) (models.AgregatLatence, error) {
+	var agregat models.AgregatLatence
+	var minimum, maximum, moyenne, p50, p90, p95, p99 sql.NullFloat64
+	if err := ligne.Scan(&agregat.Debut, &agregat.Verifications, &agregat.TauxSucces,
+		&minimum, &maximum, &moyenne, &p50, &p90, &p95, &p99); err != nil {
+		return models.AgregatLatence{}, err
+	}
+	agregat.MinMs = valeurFloat(minimum)
+	agregat.MaxMs = valeurFloat(maximum)
+	agregat.MoyenneMs = valeurFloat(moyenne)
+	agregat.P50Ms = valeurFloat(p50)
+	agregat.P90Ms = valeurFloat(p90)
+	agregat.P95Ms = valeurFloat(p95)
+	agregat.P99Ms = valeurFloat(p99)
+	return agregat, nil
+}
+
+// Retourne nil si la valeur est NULL
+func valeurFloat(valeur sql.NullFloat64) *float64 {
+	if !valeur.Valid {
+		return nil
+	}
+	return &valeur.Float64
+}
@@ -0,0 +1,206 @@
+/* Accès PostgreSQL aux fenêtres de maintenance
+ * Projet de session A25
+ * By : Leandre Kanmegne
+ *
+ * Une maintenance (monitoring.maintenances) est liée à ses moniteurs par monitoring.maintenances_moniteurs
+ * Les liens sont remplacés d'un bloc dans la même transaction que la maintenance
+ * EnregistrerStatutMoniteur s'en sert pour marquer les statuts en_maintenance
+ */
+package repos
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"time"
+
+	"example.com/go-hello/src/internal/models"
+	"github.com/jackc/pgx/v5/pgconn"
+)
+
+// Sélection d'une maintenance avec la liste de ses moniteurs, dans l'ordre attendu par scannerMaintenance
+const selectionMaintenance = `
+	SELECT m.id, m.nom, m.debut, m.fin, m.cron, m.duree_minutes, m.fuseau, m.cree_a,
+		COALESCE((
+			SELECT jsonb_agg(mm.moniteur_id ORDER BY mm.moniteur_id)
+			FROM monitoring.maintenances_moniteurs AS mm
+			WHERE mm.maintenance_id = m.id
+		), '[]')
+	FROM monitoring.maintenances AS m
+`
+
+// Lit une maintenance depuis une ligne de résultat
+func scannerMaintenance(ligne scanneur) (models.Maintenance, error) {
+	var maintenance models.Maintenance
+	var debut, fin sql.NullTime
+	var cron sql.NullString
+	var duree sql.NullInt64
+	var moniteurs []byte
+	if err := ligne.Scan(&maintenance.ID, &maintenance.Nom, &debut, &fin, &cron, &duree, &maintenance.Fuseau, &maintenance.CreeA, &moniteurs); err != nil {
+		return models.Maintenance{}, err
+	}
+	if debut.Valid {
+		maintenance.Debut = &debut.Time
+	}
+	if fin.Valid {
+		maintenance.Fin = &fin.Time
+	}
+	maintenance.Cron = cron.String
+	maintenance.DureeMinutes = int(duree.Int64)
+	if err := json.Unmarshal(moniteurs, &maintenance.MoniteurIDs); err != nil {
+		return models.Maintenance{}, err
+	}
+	return maintenance, nil
+}
+
+// Retourne nil si la date est absente
+func valeurNullTemps(valeur *time.Time) any {
+	if valeur == nil {
+		return nil
+	}
+	return *valeur
+}
+
+// Remplace les moniteurs liés à une maintenance
+func lierMoniteursMaintenance(ctx context.Context, tx *sql.Tx, maintenanceID int, moniteurIDs []int) error {
+	if _, err := tx.ExecContext(ctx, `DELETE FROM monitoring.maintenances_moniteurs WHERE maintenance_id = $1`, maintenanceID); err != nil {
+		return err
+	}
+	for _, moniteurID := range moniteurIDs {
+		_, err := tx.ExecContext(ctx, `
+			INSERT INTO monitoring.maintenances_moniteurs (maintenance_id, moniteur_id) VALUES ($1, $2)
+			ON CONFLICT DO NOTHING
+		`, maintenanceID, moniteurID)
+		if err != nil {
+			var erreurPg *pgconn.PgError
+			if errors.As(err, &erreurPg) && erreurPg.Code == codeViolationCleEtrangere {
+				return ErrMoniteurIntrouvable
+			}
+			return err
+		}
+	}
+	return nil
+}
+
+// Ajoute une maintenance et la retourne avec son id
+func (p *Postgres) AjouterMaintenance(ctx context.Context, maintenance models.Maintenance) (models.Maintenance, error) {
+	tx, err := p.db.BeginTx(ctx, nil)
+	if err != nil {
+		return models.Maintenance{}, err
+	}
+	defer tx.Rollback()
+
+	err = tx.QueryRowContext(ctx, `
+		INSERT INTO monitoring.maintenances (nom, debut, fin, cron, duree_minutes, fuseau)
+		VALUES ($1, $2, $3, $4, $5, $6)
+		RETURNING id, cree_a
+	`, maintenance.Nom, valeurNullTemps(maintenance.Debut), valeurNullTemps(maintenance.Fin),
+		valeurNullString(maintenance.Cron), valeurNullInt(maintenance.DureeMinutes), maintenance.Fuseau,
+	).Scan(&maintenance.ID, &maintenance.CreeA)
+	if err != nil {
+		return models.Maintenance{}, err
+	}
+	if err := lierMoniteursMaintenance(ctx, tx, maintenance.ID, maintenance.MoniteurIDs); err != nil {
+		return models.Maintenance{}, err
+	}
+
+	return maintenance, tx.Commit()
+}
+
+// Retourne toutes les maintenances
+func (p *Postgres) ListerMaintenances(ctx context.Context) ([]models.Maintenance, error) {
+	rows, err := p.db.QueryContext(ctx, selectionMaintenance+` ORDER BY m.id ASC`)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var maintenances []models.Maintenance
+	for rows.Next() {
+		maintenance, err := scannerMaintenance(rows)
+		if err != nil {
+			return nil, err
+		}
+		maintenances = append(maintenances, maintenance)
+	}
+
+	return maintenances, rows.Err()
+}
+
+// Retourne une maintenance par son ID
+func (p *Postgres) ObtenirMaintenance(ctx context.Context, id int) (models.Maintenance, error) {
+	maintenance, err := scannerMaintenance(p.db.QueryRowContext(ctx, selectionMaintenance+` WHERE m.id = $1`, id))
+	if errors.Is(err, sql.ErrNoRows) {
+		return models.Maintenance{}, ErrMaintenanceIntrouvable
+	}
+	return maintenance, err
+}
+
+// Met à jour une maintenance et ses moniteurs
+func (p *Postgres) MettreAJourMaintenance(ctx context.Context, maintenance models.Maintenance) error {
+	tx, err := p.db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	resultat, err := tx.ExecContext(ctx, `
+		UPDATE monitoring.maintenances
+		SET nom = $2, debut = $3, fin = $4, cron = $5, duree_minutes = $6, fuseau = $7
+		WHERE id = $1
+	`, maintenance.ID, maintenance.Nom, valeurNullTemps(maintenance.Debut), valeurNullTemps(maintenance.Fin),
+		valeurNullString(maintenance.Cron), valeurNullInt(maintenance.DureeMinutes), maintenance.Fuseau,
+	)
+	if err != nil {
+		return err
+	}
+	lignesAffectees, _ := resultat.RowsAffected()
+	if lignesAffectees == 0 {
+		return ErrMaintenanceIntrouvable
+	}
+	if err := lierMoniteursMaintenance(ctx, tx, maintenance.ID, maintenance.MoniteurIDs); err != nil {
+		return err
+	}
+
+	return tx.Commit()
+}
+
+// Supprime une maintenance
+func (p *Postgres) SupprimerMaintenance(ctx context.Context, id int) error {
+	resultat, err := p.db.ExecContext(ctx, `DELETE FROM monitoring.maintenances WHERE id = $1`, id)
+	if err != nil {
+		return err
+	}
+
+	lignesAffectees, _ := resultat.RowsAffected()
+	if lignesAffectees == 0 {
+		return ErrMaintenanceIntrouvable
+	}
+	return nil
+}
+
+// Indique si une maintenance du moniteur couvre l'instant donné
+func enMaintenance(ctx context.Context, tx *sql.Tx, moniteurID int, instant time.Time) (bool, error) {
+	rows, err := tx.QueryContext(ctx, selectionMaintenance+`
+		JOIN monitoring.maintenances_moniteurs AS lien ON lien.maintenance_id = m.id
+		WHERE lien.moniteur_id = $1
+			AND (m.debut IS NULL OR m.debut <= $2)
+			AND (m.fin IS NULL OR m.fin > $2)
+	`, moniteurID, instant)
+	if err != nil {
+		return false, err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		maintenance, err := scannerMaintenance(rows)
+		if err != nil {
+			return false, err
+		}
+		if maintenance.ActiveA(instant) {
+			return true, nil
+		}
+	}
+	return false, rows.Err()
+}
@@ -0,0 +1,62 @@
+/* Purge PostgreSQL de l'historique selon la politique de rétention
+ * Projet de session A25
+ * By : Leandre Kanmegne
+ *
+ * Supprime par lots (DELETE ... WHERE ctid IN (SELECT ... LIMIT n)) pour garder des transactions et des verrous courts
+ * Les statuts bruts ne sont supprimés qu'une fois consolidés par heure et par jour (voir pg_consolidations.go)
+ * La suppression d'un statut supprime son certificat, celle d'une alerte ses livraisons (ON DELETE CASCADE)
+ *
+ * Source: https://www.postgresql.org/docs/current/ddl-system-columns.html
+ */
+package repos
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"example.com/go-hello/src/internal/models"
+)
+
+// Table et colonne de date de chaque cible
+var tablesPurge = map[models.CiblePurge]struct{ table, colonne string }{
+	models.CibleStatuts:            {"monitoring.statuts", "verifie_a"},
+	models.CibleAlertes:            {"monitoring.alertes", "cree_a"},
+	models.CibleAgregatsHoraires:   {consolidationHoraire.table, "debut"},
+	models.CibleAgregatsQuotidiens: {consolidationQuotidienne.table, "debut"},
+}
+
+// PurgerLot supprime au plus tailleLot lignes de la cible antérieures à avant
+// Retourne le nombre de lignes supprimées (inférieur à tailleLot quand il ne reste plus rien à purger)
+func (p *Postgres) PurgerLot(ctx context.Context, cible models.CiblePurge, avant time.Time, tailleLot int) (int64, error) {
+	source, ok := tablesPurge[cible]
+	if !ok {
+		return 0, fmt.Errorf("cible de purge inconnue : %q", cible)
+	}
+
+	if cible == models.CibleStatuts {
+		// ne jamais supprimer des statuts pas encore consolidés
+		for _, c := range consolidations {
+			filigrane, err := p.filigrane(ctx, c)
+			if err != nil {
+				return 0, err
+			}
+			if filigrane.Before(avant) {
+				avant = filigrane
+			}
+		}
+	}
+
+	resultat, err := p.db.ExecContext(ctx, `
+		DELETE FROM `+source.table+`
+		WHERE ctid IN (
+			SELECT ctid FROM `+source.table+`
+			WHERE `+source.colonne+` < $1
+			LIMIT $2
+		)
+	`, avant, tailleLot)
+	if err != nil {
+		return 0, err
+	}
+	return resultat.RowsAffected()
+}
@@ -0,0 +1,60 @@
+/* Contrat du dépôt PostgreSQL
+ * Projet de session A25
+ * By : Leandre Kanmegne
+ *
+ * Ignoré sans DATABASE_URL_TEST : la base pointée est vidée entre chaque test
+ */
+package repos
+
+import (
+	"context"
+	"os"
+	"testing"
+	"time"
+
+	"example.com/go-hello/src/database"
+	"example.com/go-hello/src/internal/config"
+)
+
+var _ Repo = (*Postgres)(nil)
+
+// Tables vidées avant chaque sous-test
+const viderTablesTest = `TRUNCATE monitoring.moniteurs, monitoring.statuts, monitoring.alertes,
+	monitoring.livraisons, monitoring.incidents, monitoring.canaux, monitoring.routes_notification,
+	monitoring.maintenances, monitoring.maintenances_moniteurs, monitoring.statuts_horaires,
+	monitoring.statuts_quotidiens, monitoring.consolidations, monitoring.certificats RESTART IDENTITY CASCADE`
+
+func TestPostgres_Contrat(t *testing.T) {
+	url := os.Getenv("DATABASE_URL_TEST")
+	if url == "" {
+		t.Skip("DATABASE_URL_TEST non définie")
+	}
+
+	p, err := NouvelleConnexion(config.ConfigBaseDeDonnees{
+		URL:                   url,
+		MaxConnexionsOuvertes: 4,
+		MaxConnexionsIdle:     2,
+		DureeVieConnexion:     time.Minute,
+		TimeoutConnexion:      5 * time.Second,
+	}, Reglages{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { p.Fermer() })
+
+	migrations, err := database.Embarquees()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := database.NouveauMigrateur(p.DB(), migrations).Monter(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+
+	testerContrat(t, func(t *testing.T, reglages Reglages) depotContrat {
+		if _, err := p.DB().ExecContext(context.Background(), viderTablesTest); err != nil {
+			t.Fatal(err)
+		}
+		p.reglages = reglages.completer()
+		return p
+	})
+}
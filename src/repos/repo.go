@@ -4,26 +4,100 @@
  * Date : 01-10-2025
  *
  * Utilise le contexte d'exécution pour les opérations de la base de données
- * Définit les opérations pour accéder aux données (moniteurs et statuts)
+ * Définit les opérations pour accéder aux données (moniteurs, statuts, incidents, canaux, maintenances)
  */
 package repos
 
 import (
 	"context"
+	"errors"
+	"time"
 
 	"example.com/go-hello/src/internal/models"
 )
 
+// Erreurs communes à toutes les implémentations
+var (
+	ErrMoniteurIntrouvable    = errors.New("moniteur introuvable")
+	ErrMoniteurExistant       = errors.New("un moniteur existe déjà pour cette URL")
+	ErrCanalIntrouvable       = errors.New("canal introuvable")
+	ErrCanalExistant          = errors.New("un canal existe déjà avec ce nom")
+	ErrMaintenanceIntrouvable = errors.New("maintenance introuvable")
+	ErrIncidentIntrouvable    = errors.New("incident introuvable")
+	ErrIncidentAcquitte       = errors.New("incident déjà acquitté")
+)
+
+// Valeurs par défaut des réglages
+const (
+	IntervalleParDefaut = time.Minute      // comme INTERVALLE_VERIFICATION_SECONDES
+	DelaiGraceParDefaut = 10 * time.Minute // plus long qu'une vérification avec toutes ses tentatives
+)
+
+// Reglages contient les paramètres de surveillance utilisés par les dépôts (zéro = valeur par défaut)
+type Reglages struct {
+	// intervalle de vérification des moniteurs sans intervalle_secondes (INTERVALLE_VERIFICATION_SECONDES) :
+	// un statut couvre au plus 3 intervalles dans les calculs de disponibilité
+	IntervalleParDefaut time.Duration
+
+	// un statut peut être enregistré jusqu'à ce délai après sa date de vérification (tentatives, timeouts) :
+	// la consolidation attend ce délai avant de figer un intervalle
+	DelaiGrace time.Duration
+}
+
+// Complète les réglages non renseignés avec les valeurs par défaut
+func (r Reglages) completer() Reglages {
+	if r.IntervalleParDefaut <= 0 {
+		r.IntervalleParDefaut = IntervalleParDefaut
+	}
+	if r.DelaiGrace <= 0 {
+		r.DelaiGrace = DelaiGraceParDefaut
+	}
+	return r
+}
+
+// Retourne l'intervalle par défaut en secondes (paramètre des requêtes SQL)
+func (r Reglages) intervalleSecondes() int64 {
+	return int64(r.IntervalleParDefaut / time.Second)
+}
+
 // Définit les opérations de base pour la persistance
 type Repo interface {
 	// gestion des moniteurs
 	AjouterMoniteur(ctx context.Context, moniteur models.Moniteur) error
+	CreerMoniteur(ctx context.Context, moniteur models.Moniteur) (models.Moniteur, error) // ErrMoniteurExistant si l'URL est déjà suivie
 	ListerMoniteurs(ctx context.Context) ([]models.Moniteur, error)
+	ObtenirMoniteur(ctx context.Context, id int) (models.Moniteur, error)
+	MettreAJourMoniteur(ctx context.Context, moniteur models.Moniteur) error
 	SupprimerMoniteur(ctx context.Context, url string) error
 
 	// gestion des statuts
 	EnregistrerStatutMoniteur(ctx context.Context, statut models.StatutMoniteur) error
 	DerniersStatutsMoniteur(ctx context.Context, moniteurID int) ([]models.StatutMoniteur, error)
+	DisponibiliteMoniteur(ctx context.Context, moniteurID int, depuis, jusqua time.Time) (models.RapportDisponibilite, error)
+	AgregatsLatence(ctx context.Context, moniteurID int, depuis, jusqua time.Time, intervalle time.Duration) ([]models.AgregatLatence, error)
+	ConsoliderStatuts(ctx context.Context, maintenant time.Time) (int, error) // agrégats horaires et quotidiens
+	PurgerLot(ctx context.Context, cible models.CiblePurge, avant time.Time, tailleLot int) (int64, error)
+
+	// canaux de notification et routage des alertes par moniteur
+	AjouterCanal(ctx context.Context, canal models.CanalNotification) (models.CanalNotification, error)
+	ListerCanaux(ctx context.Context) ([]models.CanalNotification, error)
+	ObtenirCanal(ctx context.Context, id int) (models.CanalNotification, error)
+	MettreAJourCanal(ctx context.Context, canal models.CanalNotification) error
+	SupprimerCanal(ctx context.Context, id int) error
+	RoutesMoniteur(ctx context.Context, moniteurID int) ([]models.RouteNotification, error)
+	DefinirRoutesMoniteur(ctx context.Context, moniteurID int, routes []models.RouteNotification) error
+
+	// fenêtres de maintenance (les statuts vérifiés pendant une maintenance sont marqués en_maintenance)
+	AjouterMaintenance(ctx context.Context, maintenance models.Maintenance) (models.Maintenance, error)
+	ListerMaintenances(ctx context.Context) ([]models.Maintenance, error)
+	ObtenirMaintenance(ctx context.Context, id int) (models.Maintenance, error)
+	MettreAJourMaintenance(ctx context.Context, maintenance models.Maintenance) error
+	SupprimerMaintenance(ctx context.Context, id int) error
+
+	// incidents (ouverts et fermés par le trigger de transition)
+	ListerIncidents(ctx context.Context, filtre models.FiltreIncidents) ([]models.Incident, error)
+	ObtenirIncident(ctx context.Context, id int) (models.Incident, error)
+	AcquitterIncident(ctx context.Context, id int, par, note string) (models.Incident, error)
 
 	// utilitaire admin
 	ViderTout(ctx context.Context) error // supprime tous les moniteurs et statuts
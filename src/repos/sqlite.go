@@ -0,0 +1,455 @@
+/* Implémentation SQLite du repo
+ * Projet de session A25
+ * By : Leandre Kanmegne
+ *
+ * Stockage embarqué dans un seul fichier, pour lancer le moniteur sans PostgreSQL (DATABASE_URL=sqlite://chemin)
+ * Utilise modernc.org/sqlite, un pilote en Go pur (pas de cgo : le binaire reste statique)
+ * Le schéma vient des migrations SQLite (src/database/migrations/sqlite), triggers de transition compris
+ * Les dates sont stockées en microsecondes Unix (UTC), le JSON en texte
+ * Mode WAL + busy_timeout : les lectures ne bloquent pas l'écriture et les écritures s'attendent
+ *
+ * Sources:
+ * https://pkg.go.dev/modernc.org/sqlite
+ * https://www.sqlite.org/wal.html
+ */
+
+package repos
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"time"
+
+	"example.com/go-hello/src/internal/config"
+	"example.com/go-hello/src/internal/models"
+	"modernc.org/sqlite"
+	sqlite3 "modernc.org/sqlite/lib"
+)
+
+// Options de connexion : clés étrangères (ON DELETE CASCADE), attente des verrous, WAL,
+// et BEGIN IMMEDIATE pour que deux transactions d'écriture s'attendent au lieu d'échouer
+const optionsSQLite = "?_pragma=foreign_keys(1)&_pragma=busy_timeout(5000)&_pragma=journal_mode(WAL)&_txlock=immediate"
+
+// SQLite implémente Repo avec un fichier SQLite
+type SQLite struct {
+	db       *sql.DB
+	reglages Reglages
+}
+
+// Ouvre (ou crée) la base SQLite de DATABASE_URL
+func NouvelleSQLite(cfg config.ConfigBaseDeDonnees, reglages Reglages) (*SQLite, error) {
+	chemin, ok := cfg.CheminSQLite()
+	if !ok || chemin == "" {
+		return nil, fmt.Errorf("DATABASE_URL SQLite attendue (%schemin/vers/fichier.db)", config.PrefixeSQLite)
+	}
+	if err := os.MkdirAll(filepath.Dir(chemin), 0o755); err != nil {
+		return nil, err
+	}
+
+	db, err := sql.Open("sqlite", chemin+optionsSQLite)
+	if err != nil {
+		return nil, err
+	}
+	db.SetMaxOpenConns(cfg.MaxConnexionsOuvertes)
+	db.SetMaxIdleConns(cfg.MaxConnexionsIdle)
+	db.SetConnMaxLifetime(cfg.DureeVieConnexion)
+
+	ctx, cancel := context.WithTimeout(context.Background(), cfg.TimeoutConnexion)
+	defer cancel()
+	if err := db.PingContext(ctx); err != nil {
+		db.Close()
+		return nil, err
+	}
+	return &SQLite{db: db, reglages: reglages.completer()}, nil
+}
+
+// Ferme la base
+func (s *SQLite) Fermer() error {
+	return s.db.Close()
+}
+
+// Retourne le pool de connexions (pour les migrations du schéma)
+func (s *SQLite) DB() *sql.DB {
+	return s.db
+}
+
+// Convertit une date en microsecondes Unix
+func versMicro(t time.Time) int64 {
+	return t.UnixMicro()
+}
+
+// Convertit des microsecondes Unix en date UTC
+func depuisMicro(micro int64) time.Time {
+	return time.UnixMicro(micro).UTC()
+}
+
+// Retourne nil si la date est NULL
+func depuisMicroNull(micro sql.NullInt64) *time.Time {
+	if !micro.Valid {
+		return nil
+	}
+	t := depuisMicro(micro.Int64)
+	return &t
+}
+
+// Retourne nil si la date est absente, sinon ses microsecondes
+func microNull(t *time.Time) any {
+	if t == nil {
+		return nil
+	}
+	return versMicro(*t)
+}
+
+// Encode une valeur en texte JSON (json_extract ne lit pas les BLOB comme du JSON texte)
+func texteJSON(valeur any) (string, error) {
+	donnees, err := json.Marshal(valeur)
+	return string(donnees), err
+}
+
+// Indique si err est une violation de contrainte SQLite du code donné (sqlite3.SQLITE_CONSTRAINT_*)
+func violationSQLite(err error, code int) bool {
+	var erreur *sqlite.Error
+	return errors.As(err, &erreur) && erreur.Code() == code
+}
+
+// Ajoute un moniteur (mise à jour du nom, du type et de l'intervalle si l'URL existe déjà)
+func (s *SQLite) AjouterMoniteur(ctx context.Context, moniteur models.Moniteur) error {
+	if moniteur.URL == "" {
+		return errors.New("l'URL du moniteur est obligatoire")
+	}
+	if moniteur.Type == "" {
+		moniteur.Type = "http"
+	}
+
+	parametres, err := texteJSON(moniteur.Parametres)
+	if err != nil {
+		return err
+	}
+	requeteHTTP, err := texteRequeteHTTP(moniteur.Requete)
+	if err != nil {
+		return err
+	}
+
+	_, err = s.db.ExecContext(ctx, `
+		INSERT INTO moniteurs (nom, url, type, actif, intervalle_secondes, parametres, requete)
+		VALUES (?, ?, ?, ?, ?, ?, ?)
+		ON CONFLICT (url)
+		DO UPDATE SET
+			nom = COALESCE(NULLIF(excluded.nom, ''), moniteurs.nom),
+			type = COALESCE(NULLIF(excluded.type, ''), moniteurs.type),
+			intervalle_secondes = COALESCE(excluded.intervalle_secondes, moniteurs.intervalle_secondes)
+	`, moniteur.Nom, moniteur.URL, moniteur.Type, moniteur.Actif, valeurNullInt(moniteur.IntervalleSecondes), parametres, requeteHTTP)
+	return err
+}
+
+// Crée un moniteur et le retourne avec son id ; ErrMoniteurExistant si l'URL est déjà suivie
+func (s *SQLite) CreerMoniteur(ctx context.Context, moniteur models.Moniteur) (models.Moniteur, error) {
+	if moniteur.URL == "" {
+		return models.Moniteur{}, errors.New("l'URL du moniteur est obligatoire")
+	}
+	if moniteur.Type == "" {
+		moniteur.Type = "http"
+	}
+
+	parametres, err := texteJSON(moniteur.Parametres)
+	if err != nil {
+		return models.Moniteur{}, err
+	}
+	requeteHTTP, err := texteRequeteHTTP(moniteur.Requete)
+	if err != nil {
+		return models.Moniteur{}, err
+	}
+
+	cree, err := scannerMoniteur(s.db.QueryRowContext(ctx, `
+		INSERT INTO moniteurs (nom, url, type, actif, intervalle_secondes, parametres, requete)
+		VALUES (?, ?, ?, ?, ?, ?, ?)
+		RETURNING `+colonnesMoniteur,
+		moniteur.Nom, moniteur.URL, moniteur.Type, moniteur.Actif, valeurNullInt(moniteur.IntervalleSecondes), parametres, requeteHTTP,
+	))
+	if violationSQLite(err, sqlite3.SQLITE_CONSTRAINT_UNIQUE) {
+		return models.Moniteur{}, ErrMoniteurExistant
+	}
+	return cree, err
+}
+
+// Encode la requête HTTP d'un moniteur en texte JSON (NULL si absente)
+func texteRequeteHTTP(requete *models.RequeteHTTP) (any, error) {
+	if requete == nil {
+		return nil, nil
+	}
+	return texteJSON(requete)
+}
+
+// Supprime un moniteur par URL (ses statuts, alertes, incidents et routes suivent en cascade)
+func (s *SQLite) SupprimerMoniteur(ctx context.Context, url string) error {
+	resultat, err := s.db.ExecContext(ctx, `DELETE FROM moniteurs WHERE url = ?`, url)
+	if err != nil {
+		return err
+	}
+	lignesAffectees, _ := resultat.RowsAffected()
+	if lignesAffectees == 0 {
+		return ErrMoniteurIntrouvable
+	}
+	return nil
+}
+
+// Retourne tous les moniteurs
+func (s *SQLite) ListerMoniteurs(ctx context.Context) ([]models.Moniteur, error) {
+	rows, err := s.db.QueryContext(ctx, `SELECT `+colonnesMoniteur+` FROM moniteurs ORDER BY id ASC`)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var moniteurs []models.Moniteur
+	for rows.Next() {
+		moniteur, err := scannerMoniteur(rows)
+		if err != nil {
+			return nil, err
+		}
+		moniteurs = append(moniteurs, moniteur)
+	}
+	return moniteurs, rows.Err()
+}
+
+// Retourne un moniteur par son ID
+func (s *SQLite) ObtenirMoniteur(ctx context.Context, id int) (models.Moniteur, error) {
+	moniteur, err := scannerMoniteur(s.db.QueryRowContext(ctx, `SELECT `+colonnesMoniteur+` FROM moniteurs WHERE id = ?`, id))
+	if errors.Is(err, sql.ErrNoRows) {
+		return models.Moniteur{}, ErrMoniteurIntrouvable
+	}
+	return moniteur, err
+}
+
+// Met à jour tous les champs modifiables d'un moniteur
+func (s *SQLite) MettreAJourMoniteur(ctx context.Context, moniteur models.Moniteur) error {
+	if moniteur.URL == "" {
+		return errors.New("l'URL du moniteur est obligatoire")
+	}
+	if moniteur.Type == "" {
+		moniteur.Type = "http"
+	}
+
+	parametres, err := texteJSON(moniteur.Parametres)
+	if err != nil {
+		return err
+	}
+	requeteHTTP, err := texteRequeteHTTP(moniteur.Requete)
+	if err != nil {
+		return err
+	}
+
+	resultat, err := s.db.ExecContext(ctx, `
+		UPDATE moniteurs
+		SET nom = ?, url = ?, type = ?, actif = ?, intervalle_secondes = ?, parametres = ?, requete = ?
+		WHERE id = ?
+	`, moniteur.Nom, moniteur.URL, moniteur.Type, moniteur.Actif, valeurNullInt(moniteur.IntervalleSecondes), parametres, requeteHTTP, moniteur.ID)
+	if err != nil {
+		if violationSQLite(err, sqlite3.SQLITE_CONSTRAINT_UNIQUE) {
+			return ErrMoniteurExistant
+		}
+		return err
+	}
+
+	lignesAffectees, _ := resultat.RowsAffected()
+	if lignesAffectees == 0 {
+		return ErrMoniteurIntrouvable
+	}
+	return nil
+}
+
+// Enregistre un statut (et le certificat TLS s'il y en a un)
+// Les triggers de transition créent les alertes et les incidents dans la même transaction
+func (s *SQLite) EnregistrerStatutMoniteur(ctx context.Context, statut models.StatutMoniteur) error {
+	if statut.URL == "" {
+		return errors.New("l'URL est obligatoire pour un statut")
+	}
+	if statut.VerifieA.IsZero() {
+		statut.VerifieA = time.Now()
+	}
+
+	var moniteurID any
+	if statut.MoniteurID != 0 {
+		moniteurID = int64(statut.MoniteurID)
+	}
+
+	var redirections any
+	if len(statut.Redirections) > 0 {
+		texte, err := texteJSON(statut.Redirections)
+		if err != nil {
+			return err
+		}
+		redirections = texte
+	}
+
+	// détail de la latence en millisecondes, NULL pour les moniteurs tcp et dns
+	phases := make([]any, 5)
+	if detail := statut.DetailLatence; detail != nil {
+		for i, duree := range []time.Duration{detail.DNS, detail.Connexion, detail.TLS, detail.PremierOctet, detail.Transfert} {
+			phases[i] = duree.Milliseconds()
+		}
+	}
+
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	// statut vérifié pendant une maintenance : les triggers ne créeront pas d'alerte
+	if statut.MoniteurID != 0 && !statut.EnMaintenance {
+		statut.EnMaintenance, err = enMaintenanceSQLite(ctx, tx, statut.MoniteurID, statut.VerifieA)
+		if err != nil {
+			return err
+		}
+	}
+
+	var certificatID any
+	if statut.Certificat != nil {
+		certificatID, err = certificatObserveSQLite(ctx, tx, moniteurID, statut.Certificat, statut.VerifieA)
+		if err != nil {
+			return err
+		}
+	}
+
+	_, err = tx.ExecContext(ctx, `
+		INSERT INTO statuts (moniteur_id, url, est_disponible, code_http, message_erreur, latence_ms, verifie_a, etat, url_finale, redirections,
+			dns_ms, connexion_ms, tls_ms, premier_octet_ms, transfert_ms, en_maintenance, certificat_id)
+		VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)
+	`, moniteurID, statut.URL, statut.EstDisponible, statut.CodeStatutHTTP,
+		valeurNullString(statut.MessageErreur), statut.Latence.Milliseconds(), versMicro(statut.VerifieA),
+		statut.EtatEffectif(), valeurNullString(statut.URLFinale), redirections,
+		phases[0], phases[1], phases[2], phases[3], phases[4], statut.EnMaintenance, certificatID,
+	)
+	if err != nil {
+		if violationSQLite(err, sqlite3.SQLITE_CONSTRAINT_FOREIGNKEY) {
+			return ErrMoniteurIntrouvable
+		}
+		return err
+	}
+
+	return tx.Commit()
+}
+
+// Même logique que certificatObserve (PostgreSQL) : la ligne du dernier certificat du moniteur est réutilisée
+// tant que l'empreinte, l'expiration, le sujet et l'émetteur sont identiques
+func certificatObserveSQLite(ctx context.Context, tx *sql.Tx, moniteurID any, certificat *models.CertificatTLS, vuA time.Time) (int64, error) {
+	var id int64
+	if moniteurID != nil {
+		err := tx.QueryRowContext(ctx, `
+			UPDATE certificats
+			SET vu_a = MAX(COALESCE(vu_a, 0), ?)
+			WHERE id = (SELECT MAX(id) FROM certificats WHERE moniteur_id = ?)
+				AND empreinte IS ? AND expire_a = ? AND sujet = ? AND emetteur = ?
+			RETURNING id
+		`, versMicro(vuA), moniteurID, valeurNullString(certificat.Empreinte), versMicro(certificat.ExpireA),
+			certificat.Sujet, certificat.Emetteur).Scan(&id)
+		if err == nil {
+			return id, nil
+		}
+		if !errors.Is(err, sql.ErrNoRows) {
+			return 0, err
+		}
+	}
+
+	sans, err := texteJSON(certificat.SANs)
+	if err != nil {
+		return 0, err
+	}
+	err = tx.QueryRowContext(ctx, `
+		INSERT INTO certificats (moniteur_id, empreinte, sujet, emetteur, sans, expire_a, vu_a)
+		VALUES (?, ?, ?, ?, ?, ?, ?)
+		RETURNING id
+	`, moniteurID, valeurNullString(certificat.Empreinte), certificat.Sujet, certificat.Emetteur, sans,
+		versMicro(certificat.ExpireA), versMicro(vuA)).Scan(&id)
+	if violationSQLite(err, sqlite3.SQLITE_CONSTRAINT_FOREIGNKEY) {
+		return 0, ErrMoniteurIntrouvable
+	}
+	return id, err
+}
+
+// Retourne les statuts d'un moniteur, du plus récent au plus ancien
+func (s *SQLite) DerniersStatutsMoniteur(ctx context.Context, moniteurID int) ([]models.StatutMoniteur, error) {
+	// mêmes colonnes que colonnesStatut (dates en microsecondes)
+	rows, err := s.db.QueryContext(ctx, `
+		SELECT `+colonnesStatut+`
+		FROM statuts AS s
+		LEFT JOIN certificats AS c ON c.id = s.certificat_id
+		WHERE s.moniteur_id = ?
+		ORDER BY s.verifie_a DESC, s.id DESC
+	`, moniteurID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var statuts []models.StatutMoniteur
+	for rows.Next() {
+		statut, err := scannerStatut(lecteurDatesSQLite{rows})
+		if err != nil {
+			return nil, err
+		}
+		statuts = append(statuts, statut)
+	}
+	return statuts, rows.Err()
+}
+
+// lecteurDatesSQLite convertit les dates en microsecondes pour les scanneurs partagés avec PostgreSQL :
+// une destination *time.Time ou *sql.NullTime reçoit la colonne entière convertie en date
+type lecteurDatesSQLite struct {
+	ligne scanneur
+}
+
+func (l lecteurDatesSQLite) Scan(dest ...any) error {
+	micros := make([]sql.NullInt64, len(dest))
+	converties := make([]any, len(dest))
+	for i, d := range dest {
+		switch d.(type) {
+		case *time.Time, *sql.NullTime:
+			converties[i] = &micros[i]
+		default:
+			converties[i] = d
+		}
+	}
+	if err := l.ligne.Scan(converties...); err != nil {
+		return err
+	}
+	for i, d := range dest {
+		switch d := d.(type) {
+		case *time.Time:
+			if micros[i].Valid {
+				*d = depuisMicro(micros[i].Int64)
+			}
+		case *sql.NullTime:
+			*d = sql.NullTime{}
+			if micros[i].Valid {
+				*d = sql.NullTime{Time: depuisMicro(micros[i].Int64), Valid: true}
+			}
+		}
+	}
+	return nil
+}
+
+// Supprime tous les moniteurs et statuts et remet les compteurs d'id à zéro (comme TRUNCATE ... RESTART IDENTITY CASCADE)
+func (s *SQLite) ViderTout(ctx context.Context) error {
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	_, err = tx.ExecContext(ctx, `
+		DELETE FROM statuts;
+		DELETE FROM moniteurs;
+		DELETE FROM sqlite_sequence WHERE name IN ('moniteurs', 'statuts', 'certificats', 'alertes', 'livraisons', 'incidents');
+	`)
+	if err != nil {
+		return err
+	}
+	return tx.Commit()
+}
@@ -0,0 +1,104 @@
+/* Accès SQLite aux alertes et aux livraisons de notifications
+ * Projet de session A25
+ * By : Leandre Kanmegne
+ *
+ * Les alertes sont insérées par les triggers de transition (migrations/sqlite/0001_schema_initial.up.sql)
+ * Mêmes requêtes que pg_alertes.go, avec les dates en microsecondes
+ */
+package repos
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"time"
+
+	"example.com/go-hello/src/internal/models"
+	sqlite3 "modernc.org/sqlite/lib"
+)
+
+// AlertesNonTraitees retourne les alertes créées depuis la date donnée et pas encore envoyées
+func (s *SQLite) AlertesNonTraitees(ctx context.Context, depuis time.Time, limite int) ([]models.Alerte, error) {
+	// pour une alerte UP, la panne commence au DOWN précédent du même moniteur (sans UP entre les deux)
+	rows, err := s.db.QueryContext(ctx, `
+		SELECT a.id, a.moniteur_id, m.nom, m.url, a.type, COALESCE(a.details, ''), COALESCE(a.code_http, 0), a.cree_a,
+			CASE WHEN a.type = 'UP' THEN (
+				SELECT a.cree_a - MAX(d.cree_a)
+				FROM alertes AS d
+				WHERE d.moniteur_id = a.moniteur_id AND d.type = 'DOWN' AND d.id < a.id
+					AND NOT EXISTS (
+						SELECT 1 FROM alertes AS u
+						WHERE u.moniteur_id = a.moniteur_id AND u.type = 'UP' AND u.id > d.id AND u.id < a.id
+					)
+			) END
+		FROM alertes AS a
+		JOIN moniteurs AS m ON m.id = a.moniteur_id
+		WHERE a.traitee_a IS NULL AND a.cree_a >= ?
+		ORDER BY a.id ASC
+		LIMIT ?
+	`, versMicro(depuis), limite)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var alertes []models.Alerte
+	for rows.Next() {
+		var alerte models.Alerte
+		var creeA int64
+		var dureePanne sql.NullInt64
+		if err := rows.Scan(&alerte.ID, &alerte.MoniteurID, &alerte.NomMoniteur, &alerte.URL, &alerte.Type,
+			&alerte.Details, &alerte.CodeHTTP, &creeA, &dureePanne); err != nil {
+			return nil, err
+		}
+		alerte.CreeA = depuisMicro(creeA)
+		if dureePanne.Valid {
+			alerte.DureePanne = time.Duration(dureePanne.Int64) * time.Microsecond
+		}
+		alertes = append(alertes, alerte)
+	}
+	return alertes, rows.Err()
+}
+
+// MarquerAlerteTraitee note que l'alerte a été envoyée sur tous les canaux (ou abandonnée car expirée)
+func (s *SQLite) MarquerAlerteTraitee(ctx context.Context, id int) error {
+	_, err := s.db.ExecContext(ctx, `UPDATE alertes SET traitee_a = ? WHERE id = ?`, versMicro(time.Now()), id)
+	return err
+}
+
+// CanauxLivres retourne les canaux qui ont déjà reçu l'alerte (au moins une livraison réussie)
+func (s *SQLite) CanauxLivres(ctx context.Context, alerteID int) ([]string, error) {
+	rows, err := s.db.QueryContext(ctx, `
+		SELECT DISTINCT canal FROM livraisons
+		WHERE alerte_id = ? AND succes
+		ORDER BY canal
+	`, alerteID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var canaux []string
+	for rows.Next() {
+		var canal string
+		if err := rows.Scan(&canal); err != nil {
+			return nil, err
+		}
+		canaux = append(canaux, canal)
+	}
+	return canaux, rows.Err()
+}
+
+// EnregistrerLivraison garde la trace d'une tentative d'envoi
+func (s *SQLite) EnregistrerLivraison(ctx context.Context, livraison models.Livraison) error {
+	_, err := s.db.ExecContext(ctx, `
+		INSERT INTO livraisons (alerte_id, canal, tentative, succes, code_http, erreur, duree_ms, cree_a)
+		VALUES (?, ?, ?, ?, ?, ?, ?, ?)
+	`, livraison.AlerteID, livraison.Canal, livraison.Tentative, livraison.Succes,
+		valeurNullInt(livraison.CodeHTTP), valeurNullString(livraison.Erreur), livraison.Duree.Milliseconds(), versMicro(livraison.CreeA),
+	)
+	if violationSQLite(err, sqlite3.SQLITE_CONSTRAINT_FOREIGNKEY) {
+		return fmt.Errorf("alerte %d introuvable", livraison.AlerteID)
+	}
+	return err
+}
@@ -0,0 +1,194 @@
+/* Accès SQLite aux canaux de notification et au routage des alertes
+ * Projet de session A25
+ * By : Leandre Kanmegne
+ *
+ * Mêmes tables que pg_canaux.go : la config du canal et les types d'une route sont du JSON texte
+ * SQLite ne dit pas quelle clé étrangère a échoué : le moniteur et les canaux d'une route sont vérifiés avant l'insertion
+ */
+package repos
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+
+	"example.com/go-hello/src/internal/models"
+	sqlite3 "modernc.org/sqlite/lib"
+)
+
+// Traduit une violation d'unicité sur le nom en ErrCanalExistant
+func erreurCanalSQLite(err error) error {
+	if violationSQLite(err, sqlite3.SQLITE_CONSTRAINT_UNIQUE) {
+		return ErrCanalExistant
+	}
+	return err
+}
+
+// Ajoute un canal et le retourne avec son id
+func (s *SQLite) AjouterCanal(ctx context.Context, canal models.CanalNotification) (models.CanalNotification, error) {
+	config, err := texteJSON(canal.Config)
+	if err != nil {
+		return models.CanalNotification{}, err
+	}
+	ligne := s.db.QueryRowContext(ctx, `
+		INSERT INTO canaux (nom, type, actif, config)
+		VALUES (?, ?, ?, ?)
+		RETURNING `+colonnesCanal,
+		canal.Nom, canal.Type, canal.Actif, config,
+	)
+	cree, err := scannerCanal(lecteurDatesSQLite{ligne})
+	return cree, erreurCanalSQLite(err)
+}
+
+// Retourne tous les canaux
+func (s *SQLite) ListerCanaux(ctx context.Context) ([]models.CanalNotification, error) {
+	return s.listerCanaux(ctx, `SELECT `+colonnesCanal+` FROM canaux ORDER BY id ASC`)
+}
+
+// Exécute une requête qui retourne des canaux (colonnes de colonnesCanal)
+func (s *SQLite) listerCanaux(ctx context.Context, requete string, args ...any) ([]models.CanalNotification, error) {
+	rows, err := s.db.QueryContext(ctx, requete, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var canaux []models.CanalNotification
+	for rows.Next() {
+		canal, err := scannerCanal(lecteurDatesSQLite{rows})
+		if err != nil {
+			return nil, err
+		}
+		canaux = append(canaux, canal)
+	}
+	return canaux, rows.Err()
+}
+
+// Retourne un canal par son ID
+func (s *SQLite) ObtenirCanal(ctx context.Context, id int) (models.CanalNotification, error) {
+	canal, err := scannerCanal(lecteurDatesSQLite{s.db.QueryRowContext(ctx, `SELECT `+colonnesCanal+` FROM canaux WHERE id = ?`, id)})
+	if errors.Is(err, sql.ErrNoRows) {
+		return models.CanalNotification{}, ErrCanalIntrouvable
+	}
+	return canal, err
+}
+
+// Met à jour le nom, le type, l'activation et la config d'un canal
+func (s *SQLite) MettreAJourCanal(ctx context.Context, canal models.CanalNotification) error {
+	config, err := texteJSON(canal.Config)
+	if err != nil {
+		return err
+	}
+	resultat, err := s.db.ExecContext(ctx, `
+		UPDATE canaux SET nom = ?, type = ?, actif = ?, config = ? WHERE id = ?
+	`, canal.Nom, canal.Type, canal.Actif, config, canal.ID)
+	if err != nil {
+		return erreurCanalSQLite(err)
+	}
+	lignesAffectees, _ := resultat.RowsAffected()
+	if lignesAffectees == 0 {
+		return ErrCanalIntrouvable
+	}
+	return nil
+}
+
+// Supprime un canal (ses routes sont supprimées en cascade)
+func (s *SQLite) SupprimerCanal(ctx context.Context, id int) error {
+	resultat, err := s.db.ExecContext(ctx, `DELETE FROM canaux WHERE id = ?`, id)
+	if err != nil {
+		return err
+	}
+	lignesAffectees, _ := resultat.RowsAffected()
+	if lignesAffectees == 0 {
+		return ErrCanalIntrouvable
+	}
+	return nil
+}
+
+// Retourne les routes d'un moniteur
+func (s *SQLite) RoutesMoniteur(ctx context.Context, moniteurID int) ([]models.RouteNotification, error) {
+	rows, err := s.db.QueryContext(ctx, `
+		SELECT moniteur_id, canal_id, types FROM routes_notification
+		WHERE moniteur_id = ?
+		ORDER BY canal_id ASC
+	`, moniteurID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var routes []models.RouteNotification
+	for rows.Next() {
+		var route models.RouteNotification
+		var types []byte
+		if err := rows.Scan(&route.MoniteurID, &route.CanalID, &types); err != nil {
+			return nil, err
+		}
+		if err := json.Unmarshal(types, &route.Types); err != nil {
+			return nil, err
+		}
+		routes = append(routes, route)
+	}
+	return routes, rows.Err()
+}
+
+// Indique si une ligne existe pour cet id dans la table
+func existeSQLite(ctx context.Context, tx *sql.Tx, table string, id int) (bool, error) {
+	var existe bool
+	err := tx.QueryRowContext(ctx, `SELECT EXISTS (SELECT 1 FROM `+table+` WHERE id = ?)`, id).Scan(&existe)
+	return existe, err
+}
+
+// Remplace toutes les routes d'un moniteur
+func (s *SQLite) DefinirRoutesMoniteur(ctx context.Context, moniteurID int, routes []models.RouteNotification) error {
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	if _, err := tx.ExecContext(ctx, `DELETE FROM routes_notification WHERE moniteur_id = ?`, moniteurID); err != nil {
+		return err
+	}
+	if len(routes) > 0 {
+		existe, err := existeSQLite(ctx, tx, "moniteurs", moniteurID)
+		if err != nil {
+			return err
+		}
+		if !existe {
+			return ErrMoniteurIntrouvable
+		}
+	}
+	for _, route := range routes {
+		existe, err := existeSQLite(ctx, tx, "canaux", route.CanalID)
+		if err != nil {
+			return err
+		}
+		if !existe {
+			return ErrCanalIntrouvable
+		}
+		types, err := texteJSON(route.Types)
+		if err != nil {
+			return err
+		}
+		_, err = tx.ExecContext(ctx, `
+			INSERT INTO routes_notification (moniteur_id, canal_id, types) VALUES (?, ?, ?)
+		`, moniteurID, route.CanalID, types)
+		if err != nil {
+			return err
+		}
+	}
+	return tx.Commit()
+}
+
+// CanauxPourAlerte retourne les canaux actifs routés pour ce moniteur et ce type d'alerte
+func (s *SQLite) CanauxPourAlerte(ctx context.Context, moniteurID int, typeAlerte string) ([]models.CanalNotification, error) {
+	return s.listerCanaux(ctx, `
+		SELECT c.id, c.nom, c.type, c.actif, c.config, c.cree_a
+		FROM routes_notification AS r
+		JOIN canaux AS c ON c.id = r.canal_id
+		WHERE r.moniteur_id = ? AND c.actif AND EXISTS (SELECT 1 FROM json_each(r.types) WHERE value = ?)
+		ORDER BY c.id ASC
+	`, moniteurID, typeAlerte)
+}
@@ -0,0 +1,476 @@
+/* Historique SQLite : disponibilité, latences, consolidation et purge
+ * Projet de session A25
+ * By : Leandre Kanmegne
+ *
+ * Mêmes règles que pg_disponibilite.go, pg_latences.go, pg_consolidations.go et pg_retention.go
+ * Les durées sont calculées en SQL (LEAD, en microsecondes) ; SQLite n'a ni date_bin ni percentile_cont,
+ * les intervalles et les percentiles sont donc calculés en Go comme dans memoire_historique.go
+ * BEGIN IMMEDIATE (_txlock) remplace le SELECT ... FOR UPDATE du consolidateur : une seule écriture à la fois
+ */
+package repos
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"example.com/go-hello/src/internal/models"
+)
+
+// Retourne la table SQLite d'une granularité (même nom, sans le schéma monitoring)
+func tableSQLite(table string) string {
+	return strings.TrimPrefix(table, "monitoring.")
+}
+
+// Retourne la date jusqu'à laquelle une granularité est consolidée (zéro si jamais consolidée)
+func (s *SQLite) filigrane(ctx context.Context, c consolidation) (time.Time, error) {
+	var jusqua int64
+	err := s.db.QueryRowContext(ctx, `SELECT jusqua FROM consolidations WHERE granularite = ?`, c.granularite).Scan(&jusqua)
+	if errors.Is(err, sql.ErrNoRows) {
+		return time.Time{}, nil
+	}
+	if err != nil {
+		return time.Time{}, err
+	}
+	return depuisMicro(jusqua), nil
+}
+
+// Calcule le rapport de disponibilité d'un moniteur sur [depuis, jusqua[
+func (s *SQLite) DisponibiliteMoniteur(ctx context.Context, moniteurID int, depuis, jusqua time.Time) (models.RapportDisponibilite, error) {
+	rapport, err := s.durees(ctx, consolidations, moniteurID, depuis, jusqua)
+	if err != nil {
+		return models.RapportDisponibilite{}, err
+	}
+	rapport.Depuis, rapport.Jusqua = depuis, jusqua
+
+	err = s.db.QueryRowContext(ctx, `
+		SELECT COUNT(*)
+		FROM incidents
+		WHERE moniteur_id = ? AND ouvert_a < ? AND (ferme_a IS NULL OR ferme_a > ?)
+	`, moniteurID, versMicro(jusqua), versMicro(depuis)).Scan(&rapport.Incidents)
+	if err != nil {
+		return models.RapportDisponibilite{}, err
+	}
+
+	rapport.Calculer()
+	return rapport, nil
+}
+
+// Calcule les durées d'un moniteur sur [depuis, jusqua[ (voir Postgres.durees)
+func (s *SQLite) durees(ctx context.Context, niveaux []consolidation, moniteurID int, depuis, jusqua time.Time) (models.RapportDisponibilite, error) {
+	if len(niveaux) == 0 {
+		return s.dureesBrutes(ctx, moniteurID, depuis, jusqua)
+	}
+	c, plusFins := niveaux[len(niveaux)-1], niveaux[:len(niveaux)-1]
+
+	filigrane, err := s.filigrane(ctx, c)
+	if err != nil {
+		return models.RapportDisponibilite{}, err
+	}
+	debut, fin, ok := decouper(depuis, jusqua, filigrane, c.duree)
+	if !ok {
+		return s.durees(ctx, plusFins, moniteurID, depuis, jusqua)
+	}
+
+	var total models.RapportDisponibilite
+	for _, partie := range []func() (models.RapportDisponibilite, error){
+		func() (models.RapportDisponibilite, error) { return s.durees(ctx, plusFins, moniteurID, depuis, debut) },
+		func() (models.RapportDisponibilite, error) {
+			return s.dureesConsolidees(ctx, c, moniteurID, debut, fin)
+		},
+		func() (models.RapportDisponibilite, error) { return s.durees(ctx, plusFins, moniteurID, fin, jusqua) },
+	} {
+		rapport, err := partie()
+		if err != nil {
+			return models.RapportDisponibilite{}, err
+		}
+		total.Verifications += rapport.Verifications
+		total.SecondesObservees += rapport.SecondesObservees
+		total.SecondesIndisponibles += rapport.SecondesIndisponibles
+		total.SecondesMaintenance += rapport.SecondesMaintenance
+	}
+	return total, nil
+}
+
+// Calcule les durées d'un moniteur sur [depuis, jusqua[ à partir des statuts bruts (sans les incidents)
+func (s *SQLite) dureesBrutes(ctx context.Context, moniteurID int, depuis, jusqua time.Time) (models.RapportDisponibilite, error) {
+	var rapport models.RapportDisponibilite
+	if !depuis.Before(jusqua) {
+		return rapport, nil
+	}
+
+	// ?4 = intervalle de vérification par défaut (INTERVALLE_VERIFICATION_SECONDES)
+	// MIN et MAX à plusieurs arguments retournent NULL si l'un d'eux l'est : pas de statut suivant = fin de période
+	err := s.db.QueryRowContext(ctx, `
+		WITH moniteur AS (
+			SELECT id, 3 * COALESCE(intervalle_secondes, ?4) * 1000000 AS ecart_max
+			FROM moniteurs
+			WHERE id = ?1
+		),
+		periodes AS (
+			SELECT
+				s.verifie_a,
+				s.etat,
+				s.en_maintenance,
+				MAX(s.verifie_a, ?2) AS debut,
+				MIN(
+					COALESCE(LEAD(s.verifie_a) OVER (ORDER BY s.verifie_a, s.id), ?3),
+					s.verifie_a + moniteur.ecart_max,
+					?3
+				) AS fin
+			FROM statuts AS s
+			JOIN moniteur ON moniteur.id = s.moniteur_id
+			WHERE s.verifie_a >= ?2 - moniteur.ecart_max AND s.verifie_a < ?3
+		)
+		SELECT
+			COUNT(*) FILTER (WHERE verifie_a >= ?2),
+			CAST(ROUND(COALESCE(SUM(fin - debut) FILTER (WHERE fin > debut AND NOT en_maintenance), 0) / 1e6) AS INTEGER),
+			CAST(ROUND(COALESCE(SUM(fin - debut) FILTER (WHERE fin > debut AND NOT en_maintenance AND etat = 'down'), 0) / 1e6) AS INTEGER),
+			CAST(ROUND(COALESCE(SUM(fin - debut) FILTER (WHERE fin > debut AND en_maintenance), 0) / 1e6) AS INTEGER)
+		FROM periodes
+	`, moniteurID, versMicro(depuis), versMicro(jusqua), s.reglages.intervalleSecondes()).Scan(&rapport.Verifications, &rapport.SecondesObservees,
+		&rapport.SecondesIndisponibles, &rapport.SecondesMaintenance)
+	return rapport, err
+}
+
+// Retourne les durées consolidées d'un moniteur sur [depuis, jusqua[ (sans les incidents)
+func (s *SQLite) dureesConsolidees(ctx context.Context, c consolidation, moniteurID int, depuis, jusqua time.Time) (models.RapportDisponibilite, error) {
+	var rapport models.RapportDisponibilite
+	err := s.db.QueryRowContext(ctx, `
+		SELECT COALESCE(SUM(verifications), 0), COALESCE(SUM(secondes_observees), 0),
+			COALESCE(SUM(secondes_indisponibles), 0), COALESCE(SUM(secondes_maintenance), 0)
+		FROM `+tableSQLite(c.table)+`
+		WHERE moniteur_id = ? AND debut >= ? AND debut < ?
+	`, moniteurID, versMicro(depuis), versMicro(jusqua)).Scan(&rapport.Verifications, &rapport.SecondesObservees,
+		&rapport.SecondesIndisponibles, &rapport.SecondesMaintenance)
+	return rapport, err
+}
+
+// Retourne les agrégats de latence d'un moniteur sur [depuis, jusqua[, du plus ancien au plus récent
+// Les intervalles sans statut ne sont pas retournés
+func (s *SQLite) AgregatsLatence(ctx context.Context, moniteurID int, depuis, jusqua time.Time, intervalle time.Duration) ([]models.AgregatLatence, error) {
+	c, ok := consolidationPour(intervalle)
+	if !ok {
+		return s.agregatsBruts(ctx, moniteurID, depuis, jusqua, intervalle)
+	}
+	filigrane, err := s.filigrane(ctx, c)
+	if err != nil {
+		return nil, err
+	}
+	debut, fin, ok := decouper(depuis, jusqua, filigrane, c.duree)
+	if !ok {
+		return s.agregatsBruts(ctx, moniteurID, depuis, jusqua, intervalle)
+	}
+
+	avant, err := s.agregatsBruts(ctx, moniteurID, depuis, debut, intervalle)
+	if err != nil {
+		return nil, err
+	}
+	milieu, err := s.agregatsConsolides(ctx, c, moniteurID, debut, fin)
+	if err != nil {
+		return nil, err
+	}
+	apres, err := s.agregatsBruts(ctx, moniteurID, fin, jusqua, intervalle)
+	if err != nil {
+		return nil, err
+	}
+	return append(append(avant, milieu...), apres...), nil
+}
+
+// Calcule les agrégats de latence à partir des statuts bruts
+func (s *SQLite) agregatsBruts(ctx context.Context, moniteurID int, depuis, jusqua time.Time, intervalle time.Duration) ([]models.AgregatLatence, error) {
+	if !depuis.Before(jusqua) {
+		return nil, nil
+	}
+	rows, err := s.db.QueryContext(ctx, `
+		SELECT verifie_a, est_disponible, latence_ms
+		FROM statuts
+		WHERE moniteur_id = ? AND verifie_a >= ? AND verifie_a < ?
+		ORDER BY verifie_a, id
+	`, moniteurID, versMicro(depuis), versMicro(jusqua))
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	// les statuts sont triés : ceux d'un même intervalle se suivent
+	var agregats []models.AgregatLatence
+	var latences []float64
+	succes := 0
+	terminer := func() {
+		if len(agregats) == 0 {
+			return
+		}
+		dernier := &agregats[len(agregats)-1]
+		dernier.TauxSucces = float64(succes) / float64(dernier.Verifications)
+		remplirLatences(dernier, calculerStatistiques(latences))
+		latences, succes = nil, 0
+	}
+	for rows.Next() {
+		var verifieA int64
+		var disponible bool
+		var latence sql.NullFloat64
+		if err := rows.Scan(&verifieA, &disponible, &latence); err != nil {
+			return nil, err
+		}
+		debut := debutIntervalle(depuisMicro(verifieA), intervalle)
+		if len(agregats) == 0 || !agregats[len(agregats)-1].Debut.Equal(debut) {
+			terminer()
+			agregats = append(agregats, models.AgregatLatence{Debut: debut})
+		}
+		agregats[len(agregats)-1].Verifications++
+		if disponible {
+			succes++
+		}
+		// comme percentile_cont, les latences NULL sont ignorées
+		if latence.Valid {
+			latences = append(latences, latence.Float64)
+		}
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	terminer()
+	return agregats, nil
+}
+
+// Retourne les agrégats de latence consolidés d'un moniteur sur [depuis, jusqua[
+func (s *SQLite) agregatsConsolides(ctx context.Context, c consolidation, moniteurID int, depuis, jusqua time.Time) ([]models.AgregatLatence, error) {
+	rows, err := s.db.QueryContext(ctx, `
+		SELECT debut, verifications, CAST(succes AS REAL) / verifications,
+			latence_min_ms, latence_max_ms, latence_moyenne_ms,
+			latence_p50_ms, latence_p90_ms, latence_p95_ms, latence_p99_ms
+		FROM `+tableSQLite(c.table)+`
+		WHERE moniteur_id = ? AND debut >= ? AND debut < ? AND verifications > 0
+		ORDER BY debut
+	`, moniteurID, versMicro(depuis), versMicro(jusqua))
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var agregats []models.AgregatLatence
+	for rows.Next() {
+		agregat, err := scannerAgregat(lecteurDatesSQLite{rows})
+		if err != nil {
+			return nil, err
+		}
+		agregats = append(agregats, agregat)
+	}
+
+	return agregats, rows.Err()
+}
+
+// ConsoliderStatuts consolide les intervalles terminés avant maintenant - délai de grâce pour chaque granularité
+// Retourne le nombre de lignes consolidées (insérées ou mises à jour)
+func (s *SQLite) ConsoliderStatuts(ctx context.Context, maintenant time.Time) (int, error) {
+	total := 0
+	for _, c := range consolidations {
+		for {
+			lignes, termine, err := s.consoliderLot(ctx, c, maintenant)
+			total += lignes
+			if err != nil {
+				return total, fmt.Errorf("consolidation %s : %w", c.granularite, err)
+			}
+			if termine {
+				break
+			}
+		}
+	}
+	return total, nil
+}
+
+// Consolide un lot d'intervalles et avance le filigrane dans la même transaction
+// termine = true quand tous les intervalles terminés sont consolidés
+func (s *SQLite) consoliderLot(ctx context.Context, c consolidation, maintenant time.Time) (int, bool, error) {
+	// même délai de grâce que pg_consolidations.go
+	limite := maintenant.Add(-s.reglages.DelaiGrace).Truncate(c.duree)
+
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return 0, false, err
+	}
+	defer tx.Rollback()
+
+	var jusqua sql.NullInt64
+	err = tx.QueryRowContext(ctx, `SELECT jusqua FROM consolidations WHERE granularite = ?`, c.granularite).Scan(&jusqua)
+	if errors.Is(err, sql.ErrNoRows) {
+		// première consolidation : depuis le plus vieux statut
+		err = tx.QueryRowContext(ctx, `SELECT MIN(verifie_a) FROM statuts`).Scan(&jusqua)
+	}
+	if err != nil {
+		return 0, false, err
+	}
+	if !jusqua.Valid {
+		return 0, true, nil
+	}
+	debut := depuisMicro(jusqua.Int64).Truncate(c.duree)
+	if !debut.Before(limite) {
+		return 0, true, nil
+	}
+
+	fin := debut.Add(intervallesParLot * c.duree)
+	if fin.After(limite) {
+		fin = limite
+	}
+
+	// intervalle de vérification par défaut (INTERVALLE_VERIFICATION_SECONDES) en premier paramètre, comme DisponibiliteMoniteur
+	rows, err := tx.QueryContext(ctx, `
+		SELECT
+			s.moniteur_id,
+			s.verifie_a,
+			LEAD(s.verifie_a) OVER (PARTITION BY s.moniteur_id ORDER BY s.verifie_a, s.id),
+			3 * COALESCE(m.intervalle_secondes, ?) * 1000000,
+			s.est_disponible,
+			s.etat,
+			s.en_maintenance,
+			s.latence_ms
+		FROM statuts AS s
+		JOIN moniteurs AS m ON m.id = s.moniteur_id
+		WHERE s.verifie_a >= ? AND s.verifie_a < ?
+		ORDER BY s.moniteur_id, s.verifie_a, s.id
+	`, s.reglages.intervalleSecondes(), versMicro(debut), versMicro(fin))
+	if err != nil {
+		return 0, false, err
+	}
+
+	// les statuts sont triés : ceux d'un même moniteur et d'un même intervalle se suivent
+	type agregatLot struct {
+		moniteurID                            int
+		agregat                               agregatMemoire
+		latences                              []float64
+		observees, indisponibles, maintenance []float64
+	}
+	var lot []*agregatLot
+	for rows.Next() {
+		var moniteurID int
+		var verifieA, ecart int64
+		var suivant sql.NullInt64
+		var disponible, enMaintenance bool
+		var etat string
+		var latence sql.NullFloat64
+		if err := rows.Scan(&moniteurID, &verifieA, &suivant, &ecart, &disponible, &etat, &enMaintenance, &latence); err != nil {
+			rows.Close()
+			return 0, false, err
+		}
+
+		debutIntervalleCourant := debutIntervalle(depuisMicro(verifieA), c.duree)
+		if len(lot) == 0 || lot[len(lot)-1].moniteurID != moniteurID || !lot[len(lot)-1].agregat.debut.Equal(debutIntervalleCourant) {
+			lot = append(lot, &agregatLot{moniteurID: moniteurID, agregat: agregatMemoire{debut: debutIntervalleCourant}})
+		}
+		courant := lot[len(lot)-1]
+		courant.agregat.verifications++
+		if disponible {
+			courant.agregat.succes++
+		}
+		if etat == models.EtatDown {
+			courant.agregat.echecs++
+		}
+		if latence.Valid {
+			courant.latences = append(courant.latences, latence.Float64)
+		}
+
+		// le statut couvre le temps jusqu'au suivant, au plus 3 intervalles, coupé à la fin de l'intervalle
+		finStatut := min(verifieA+ecart, versMicro(debutIntervalleCourant.Add(c.duree)))
+		if suivant.Valid {
+			finStatut = min(finStatut, suivant.Int64)
+		}
+		secondes := float64(finStatut-verifieA) / 1e6
+		switch {
+		case enMaintenance:
+			courant.maintenance = append(courant.maintenance, secondes)
+		case etat == models.EtatDown:
+			courant.observees = append(courant.observees, secondes)
+			courant.indisponibles = append(courant.indisponibles, secondes)
+		default:
+			courant.observees = append(courant.observees, secondes)
+		}
+	}
+	if err := rows.Err(); err != nil {
+		rows.Close()
+		return 0, false, err
+	}
+	rows.Close()
+
+	for _, courant := range lot {
+		agregat := courant.agregat
+		stats := calculerStatistiques(courant.latences)
+		_, err := tx.ExecContext(ctx, `
+			INSERT INTO `+tableSQLite(c.table)+` (
+				moniteur_id, debut, verifications, succes, echecs,
+				secondes_observees, secondes_indisponibles, secondes_maintenance,
+				latence_min_ms, latence_max_ms, latence_moyenne_ms,
+				latence_p50_ms, latence_p90_ms, latence_p95_ms, latence_p99_ms
+			)
+			VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)
+			ON CONFLICT (moniteur_id, debut) DO UPDATE SET
+				verifications = excluded.verifications,
+				succes = excluded.succes,
+				echecs = excluded.echecs,
+				secondes_observees = excluded.secondes_observees,
+				secondes_indisponibles = excluded.secondes_indisponibles,
+				secondes_maintenance = excluded.secondes_maintenance,
+				latence_min_ms = excluded.latence_min_ms,
+				latence_max_ms = excluded.latence_max_ms,
+				latence_moyenne_ms = excluded.latence_moyenne_ms,
+				latence_p50_ms = excluded.latence_p50_ms,
+				latence_p90_ms = excluded.latence_p90_ms,
+				latence_p95_ms = excluded.latence_p95_ms,
+				latence_p99_ms = excluded.latence_p99_ms
+		`, courant.moniteurID, versMicro(agregat.debut), agregat.verifications, agregat.succes, agregat.echecs,
+			sommeArrondie(courant.observees), sommeArrondie(courant.indisponibles), sommeArrondie(courant.maintenance),
+			stats.min, stats.max, stats.moyenne, stats.p50, stats.p90, stats.p95, stats.p99,
+		)
+		if err != nil {
+			return 0, false, err
+		}
+	}
+
+	if _, err := tx.ExecContext(ctx, `
+		INSERT INTO consolidations (granularite, jusqua) VALUES (?, ?)
+		ON CONFLICT (granularite) DO UPDATE SET jusqua = excluded.jusqua
+	`, c.granularite, versMicro(fin)); err != nil {
+		return 0, false, err
+	}
+
+	return len(lot), !fin.Before(limite), tx.Commit()
+}
+
+// PurgerLot supprime au plus tailleLot lignes de la cible antérieures à avant
+// Retourne le nombre de lignes supprimées (inférieur à tailleLot quand il ne reste plus rien à purger)
+func (s *SQLite) PurgerLot(ctx context.Context, cible models.CiblePurge, avant time.Time, tailleLot int) (int64, error) {
+	source, ok := tablesPurge[cible]
+	if !ok {
+		return 0, fmt.Errorf("cible de purge inconnue : %q", cible)
+	}
+	table := tableSQLite(source.table)
+
+	if cible == models.CibleStatuts {
+		// ne jamais supprimer des statuts pas encore consolidés
+		for _, c := range consolidations {
+			filigrane, err := s.filigrane(ctx, c)
+			if err != nil {
+				return 0, err
+			}
+			if filigrane.Before(avant) {
+				avant = filigrane
+			}
+		}
+	}
+
+	resultat, err := s.db.ExecContext(ctx, `
+		DELETE FROM `+table+`
+		WHERE rowid IN (
+			SELECT rowid FROM `+table+`
+			WHERE `+source.colonne+` < ?
+			LIMIT ?
+		)
+	`, versMicro(avant), tailleLot)
+	if err != nil {
+		return 0, err
+	}
+	return resultat.RowsAffected()
+}
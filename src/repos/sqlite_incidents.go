@@ -0,0 +1,111 @@
+/* Accès SQLite aux incidents
+ * Projet de session A25
+ * By : Leandre Kanmegne
+ *
+ * Les incidents sont ouverts et fermés par le trigger de transition SQLite (src/database/migrations/sqlite)
+ * Mêmes filtres et même acquittement que pg_incidents.go, avec les dates en microsecondes
+ */
+package repos
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"strings"
+	"time"
+
+	"example.com/go-hello/src/internal/models"
+)
+
+// Sélection d'un incident avec son moniteur, dans l'ordre attendu par scannerIncident
+const selectionIncidentSQLite = `
+	SELECT i.id, i.moniteur_id, m.nom, m.url, i.ouvert_a, i.ferme_a, COALESCE(i.message_erreur, ''), COALESCE(i.code_http, 0),
+		i.acquitte_a, COALESCE(i.acquitte_par, ''), COALESCE(i.note_acquittement, '')
+	FROM incidents AS i
+	JOIN moniteurs AS m ON m.id = i.moniteur_id
+`
+
+// Retourne les incidents correspondant au filtre, du plus récent au plus ancien
+func (s *SQLite) ListerIncidents(ctx context.Context, filtre models.FiltreIncidents) ([]models.Incident, error) {
+	var conditions []string
+	var arguments []any
+
+	if filtre.MoniteurID != 0 {
+		conditions = append(conditions, "i.moniteur_id = ?")
+		arguments = append(arguments, filtre.MoniteurID)
+	}
+	if !filtre.Depuis.IsZero() {
+		conditions = append(conditions, "(i.ferme_a IS NULL OR i.ferme_a >= ?)")
+		arguments = append(arguments, versMicro(filtre.Depuis))
+	}
+	if !filtre.Jusqua.IsZero() {
+		conditions = append(conditions, "i.ouvert_a < ?")
+		arguments = append(arguments, versMicro(filtre.Jusqua))
+	}
+	if filtre.Ouverts != nil {
+		if *filtre.Ouverts {
+			conditions = append(conditions, "i.ferme_a IS NULL")
+		} else {
+			conditions = append(conditions, "i.ferme_a IS NOT NULL")
+		}
+	}
+
+	requete := selectionIncidentSQLite
+	if len(conditions) > 0 {
+		requete += " WHERE " + strings.Join(conditions, " AND ")
+	}
+	limite := filtre.Limite
+	if limite <= 0 {
+		limite = limiteIncidentsParDefaut
+	}
+	arguments = append(arguments, limite)
+	requete += " ORDER BY i.ouvert_a DESC, i.id DESC LIMIT ?"
+
+	rows, err := s.db.QueryContext(ctx, requete, arguments...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var incidents []models.Incident
+	for rows.Next() {
+		incident, err := scannerIncident(lecteurDatesSQLite{rows})
+		if err != nil {
+			return nil, err
+		}
+		incidents = append(incidents, incident)
+	}
+
+	return incidents, rows.Err()
+}
+
+// Retourne un incident par son ID
+func (s *SQLite) ObtenirIncident(ctx context.Context, id int) (models.Incident, error) {
+	incident, err := scannerIncident(lecteurDatesSQLite{s.db.QueryRowContext(ctx, selectionIncidentSQLite+` WHERE i.id = ?`, id)})
+	if errors.Is(err, sql.ErrNoRows) {
+		return models.Incident{}, ErrIncidentIntrouvable
+	}
+	return incident, err
+}
+
+// Acquitte un incident (une seule fois) et le retourne à jour
+func (s *SQLite) AcquitterIncident(ctx context.Context, id int, par, note string) (models.Incident, error) {
+	resultat, err := s.db.ExecContext(ctx, `
+		UPDATE incidents
+		SET acquitte_a = ?, acquitte_par = ?, note_acquittement = ?
+		WHERE id = ? AND acquitte_a IS NULL
+	`, versMicro(time.Now()), par, valeurNullString(note), id)
+	if err != nil {
+		return models.Incident{}, err
+	}
+
+	lignesAffectees, _ := resultat.RowsAffected()
+	if lignesAffectees == 0 {
+		// introuvable ou déjà acquitté
+		if _, err := s.ObtenirIncident(ctx, id); err != nil {
+			return models.Incident{}, err
+		}
+		return models.Incident{}, ErrIncidentAcquitte
+	}
+	return s.ObtenirIncident(ctx, id)
+}
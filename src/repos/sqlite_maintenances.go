@@ -0,0 +1,176 @@
+/* Accès SQLite aux fenêtres de maintenance
+ * Projet de session A25
+ * By : Leandre Kanmegne
+ *
+ * Mêmes tables et mêmes règles que pg_maintenances.go, avec les dates en microsecondes
+ * La liste des moniteurs d'une maintenance est construite avec json_group_array
+ */
+package repos
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"time"
+
+	"example.com/go-hello/src/internal/models"
+	sqlite3 "modernc.org/sqlite/lib"
+)
+
+// Sélection d'une maintenance avec la liste de ses moniteurs, dans l'ordre attendu par scannerMaintenance
+const selectionMaintenanceSQLite = `
+	SELECT m.id, m.nom, m.debut, m.fin, m.cron, m.duree_minutes, m.fuseau, m.cree_a,
+		(
+			SELECT json_group_array(mm.moniteur_id)
+			FROM (
+				SELECT moniteur_id FROM maintenances_moniteurs
+				WHERE maintenance_id = m.id
+				ORDER BY moniteur_id
+			) AS mm
+		)
+	FROM maintenances AS m
+`
+
+// Remplace les moniteurs liés à une maintenance
+func lierMoniteursMaintenanceSQLite(ctx context.Context, tx *sql.Tx, maintenanceID int, moniteurIDs []int) error {
+	if _, err := tx.ExecContext(ctx, `DELETE FROM maintenances_moniteurs WHERE maintenance_id = ?`, maintenanceID); err != nil {
+		return err
+	}
+	for _, moniteurID := range moniteurIDs {
+		_, err := tx.ExecContext(ctx, `
+			INSERT INTO maintenances_moniteurs (maintenance_id, moniteur_id) VALUES (?, ?)
+			ON CONFLICT DO NOTHING
+		`, maintenanceID, moniteurID)
+		if err != nil {
+			if violationSQLite(err, sqlite3.SQLITE_CONSTRAINT_FOREIGNKEY) {
+				return ErrMoniteurIntrouvable
+			}
+			return err
+		}
+	}
+	return nil
+}
+
+// Ajoute une maintenance et la retourne avec son id
+func (s *SQLite) AjouterMaintenance(ctx context.Context, maintenance models.Maintenance) (models.Maintenance, error) {
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return models.Maintenance{}, err
+	}
+	defer tx.Rollback()
+
+	var creeA int64
+	err = tx.QueryRowContext(ctx, `
+		INSERT INTO maintenances (nom, debut, fin, cron, duree_minutes, fuseau)
+		VALUES (?, ?, ?, ?, ?, ?)
+		RETURNING id, cree_a
+	`, maintenance.Nom, microNull(maintenance.Debut), microNull(maintenance.Fin),
+		valeurNullString(maintenance.Cron), valeurNullInt(maintenance.DureeMinutes), maintenance.Fuseau,
+	).Scan(&maintenance.ID, &creeA)
+	if err != nil {
+		return models.Maintenance{}, err
+	}
+	maintenance.CreeA = depuisMicro(creeA)
+	if err := lierMoniteursMaintenanceSQLite(ctx, tx, maintenance.ID, maintenance.MoniteurIDs); err != nil {
+		return models.Maintenance{}, err
+	}
+
+	return maintenance, tx.Commit()
+}
+
+// Retourne toutes les maintenances
+func (s *SQLite) ListerMaintenances(ctx context.Context) ([]models.Maintenance, error) {
+	rows, err := s.db.QueryContext(ctx, selectionMaintenanceSQLite+` ORDER BY m.id ASC`)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var maintenances []models.Maintenance
+	for rows.Next() {
+		maintenance, err := scannerMaintenance(lecteurDatesSQLite{rows})
+		if err != nil {
+			return nil, err
+		}
+		maintenances = append(maintenances, maintenance)
+	}
+
+	return maintenances, rows.Err()
+}
+
+// Retourne une maintenance par son ID
+func (s *SQLite) ObtenirMaintenance(ctx context.Context, id int) (models.Maintenance, error) {
+	maintenance, err := scannerMaintenance(lecteurDatesSQLite{s.db.QueryRowContext(ctx, selectionMaintenanceSQLite+` WHERE m.id = ?`, id)})
+	if errors.Is(err, sql.ErrNoRows) {
+		return models.Maintenance{}, ErrMaintenanceIntrouvable
+	}
+	return maintenance, err
+}
+
+// Met à jour une maintenance et ses moniteurs
+func (s *SQLite) MettreAJourMaintenance(ctx context.Context, maintenance models.Maintenance) error {
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	resultat, err := tx.ExecContext(ctx, `
+		UPDATE maintenances
+		SET nom = ?, debut = ?, fin = ?, cron = ?, duree_minutes = ?, fuseau = ?
+		WHERE id = ?
+	`, maintenance.Nom, microNull(maintenance.Debut), microNull(maintenance.Fin),
+		valeurNullString(maintenance.Cron), valeurNullInt(maintenance.DureeMinutes), maintenance.Fuseau, maintenance.ID,
+	)
+	if err != nil {
+		return err
+	}
+	lignesAffectees, _ := resultat.RowsAffected()
+	if lignesAffectees == 0 {
+		return ErrMaintenanceIntrouvable
+	}
+	if err := lierMoniteursMaintenanceSQLite(ctx, tx, maintenance.ID, maintenance.MoniteurIDs); err != nil {
+		return err
+	}
+
+	return tx.Commit()
+}
+
+// Supprime une maintenance
+func (s *SQLite) SupprimerMaintenance(ctx context.Context, id int) error {
+	resultat, err := s.db.ExecContext(ctx, `DELETE FROM maintenances WHERE id = ?`, id)
+	if err != nil {
+		return err
+	}
+
+	lignesAffectees, _ := resultat.RowsAffected()
+	if lignesAffectees == 0 {
+		return ErrMaintenanceIntrouvable
+	}
+	return nil
+}
+
+// Indique si une maintenance du moniteur couvre l'instant donné
+func enMaintenanceSQLite(ctx context.Context, tx *sql.Tx, moniteurID int, instant time.Time) (bool, error) {
+	rows, err := tx.QueryContext(ctx, selectionMaintenanceSQLite+`
+		JOIN maintenances_moniteurs AS lien ON lien.maintenance_id = m.id
+		WHERE lien.moniteur_id = ?1
+			AND (m.debut IS NULL OR m.debut <= ?2)
+			AND (m.fin IS NULL OR m.fin > ?2)
+	`, moniteurID, versMicro(instant))
+	if err != nil {
+		return false, err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		maintenance, err := scannerMaintenance(lecteurDatesSQLite{rows})
+		if err != nil {
+			return false, err
+		}
+		if maintenance.ActiveA(instant) {
+			return true, nil
+		}
+	}
+	return false, rows.Err()
+}
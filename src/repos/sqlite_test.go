@@ -0,0 +1,96 @@
+/* Contrat du dépôt SQLite
+ * Projet de session A25
+ * By : Leandre Kanmegne
+ *
+ * Chaque sous-test part d'un fichier neuf dans un dossier temporaire, migrations SQLite appliquées
+ */
+package repos
+
+import (
+	"context"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"example.com/go-hello/src/database"
+	"example.com/go-hello/src/internal/config"
+	"example.com/go-hello/src/internal/models"
+)
+
+var _ Repo = (*SQLite)(nil)
+
+// Ouvre une base SQLite migrée dans un dossier temporaire
+func nouvelleSQLiteTest(t *testing.T, reglages Reglages) *SQLite {
+	migrations, err := database.EmbarqueesSQLite()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	s, err := NouvelleSQLite(config.ConfigBaseDeDonnees{
+		URL:                   config.PrefixeSQLite + filepath.Join(t.TempDir(), "monitoring.db"),
+		MaxConnexionsOuvertes: 4,
+		MaxConnexionsIdle:     2,
+		DureeVieConnexion:     time.Minute,
+		TimeoutConnexion:      5 * time.Second,
+	}, reglages)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { s.Fermer() })
+
+	if _, err := database.NouveauMigrateurSQLite(s.DB(), migrations).Monter(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	return s
+}
+
+func TestSQLite_Contrat(t *testing.T) {
+	testerContrat(t, func(t *testing.T, reglages Reglages) depotContrat {
+		return nouvelleSQLiteTest(t, reglages)
+	})
+}
+
+// test : un certificat inchangé n'ajoute pas de ligne, seul vu_a avance
+func TestSQLite_CertificatInchange(t *testing.T) {
+	ctx := context.Background()
+	s := nouvelleSQLiteTest(t, Reglages{})
+	moniteur, err := s.CreerMoniteur(ctx, models.Moniteur{Nom: "TLS", URL: "https://tls.test", Actif: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	debut := time.Date(2025, 10, 1, 12, 0, 0, 0, time.UTC)
+	certificat := models.CertificatTLS{Sujet: "CN=tls.test", Emetteur: "CN=AC", ExpireA: debut.AddDate(0, 3, 0), Empreinte: "aa"}
+	enregistrer := func(minutes int) {
+		t.Helper()
+		copie := certificat
+		err := s.EnregistrerStatutMoniteur(ctx, models.StatutMoniteur{MoniteurID: moniteur.ID, URL: moniteur.URL, EstDisponible: true,
+			VerifieA: debut.Add(time.Duration(minutes) * time.Minute), Certificat: &copie})
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+	compter := func() (lignes int, vuA int64) {
+		t.Helper()
+		err := s.DB().QueryRowContext(ctx, `SELECT COUNT(*), MAX(vu_a) FROM certificats`).Scan(&lignes, &vuA)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return lignes, vuA
+	}
+
+	for minutes := range 3 {
+		enregistrer(minutes)
+	}
+	if lignes, vuA := compter(); lignes != 1 || vuA != versMicro(debut.Add(2*time.Minute)) {
+		t.Errorf("une ligne vue à la dernière vérification attendue, obtenu %d lignes (vu_a %d)", lignes, vuA)
+	}
+
+	// renouvellement : nouvelle empreinte et nouvelle expiration
+	certificat.ExpireA, certificat.Empreinte = debut.AddDate(0, 6, 0), "bb"
+	enregistrer(3)
+	enregistrer(4)
+	if lignes, _ := compter(); lignes != 2 {
+		t.Errorf("2 lignes attendues après le renouvellement, obtenu %d", lignes)
+	}
+}
@@ -17,7 +17,6 @@ const liste = document.getElementById('liste');
 const ligneVide = document.getElementById('ligne-vide');
 
 const LIMITE_PAR_DEFAUT = 50;
-const SEUIL_LENTE_MS = 800;
 let intervalId = null;
 let enCours = false;
 
@@ -54,6 +53,18 @@ function formaterHeure(dateISO) {
   }
 }
 
+// décrit les phases de la latence pour l'infobulle du badge
+function titreLatence(detail) {
+  if (!detail) return '';
+  return [
+    `DNS ${detail.dns_ms} ms`,
+    `Connexion ${detail.connexion_ms} ms`,
+    `TLS ${detail.tls_ms} ms`,
+    `Premier octet ${detail.premier_octet_ms} ms`,
+    `Transfert ${detail.transfert_ms} ms`,
+  ].join(' · ');
+}
+
 // crée une ligne de résultat dans la console
 function creerLigne(statut) {
   // supprime la ligne vide si c'est le premier résultat
@@ -67,16 +78,23 @@ function creerLigne(statut) {
     message_erreur,
     latence_ms,
     verifie_a,
+    etat,
+    certificat,
+    url_finale,
+    redirections,
+    detail_latence,
+    en_maintenance,
   } = statut;
 
-  // détermine le statut visuel
+  // détermine le statut visuel (l'état est calculé par le serveur)
+  const degrade = etat === 'degrade';
   let texteStatut = 'EN LIGNE';
   let classeStatut = 'ok';
-  if (!est_disponible) {
+  if (!est_disponible || etat === 'down') {
     texteStatut = 'HORS SERVICE';
     classeStatut = 'err';
-  } else if (latence_ms >= SEUIL_LENTE_MS) {
-    texteStatut = 'LENT';
+  } else if (degrade) {
+    texteStatut = 'DÉGRADÉ';
     classeStatut = 'warn';
   }
 
@@ -87,18 +105,48 @@ function creerLigne(statut) {
     <div>${formaterHeure(verifie_a)}</div>
     <div class="url" title="${url}">${url}</div>
     <div class="statut ${classeStatut}">${texteStatut}</div>
-    <div class="badge">${latence_ms ?? '—'} ms</div>
+    <div class="badge" title="${titreLatence(detail_latence)}">${latence_ms ?? '—'} ms</div>
     <div class="badge">${code_http ?? '—'}</div>
   `;
 
   // ajoute le message d'erreur si présent
-  if (!est_disponible && message_erreur) {
+  if ((!est_disponible || degrade) && message_erreur) {
     const sous = document.createElement('div');
     sous.className = 'sous-ligne';
     sous.textContent = message_erreur;
     ligne.appendChild(sous);
   }
 
+  // vérification faite pendant une fenêtre de maintenance (pas d'alerte)
+  if (en_maintenance) {
+    const sous = document.createElement('div');
+    sous.className = 'sous-ligne';
+    sous.textContent = '🛠️ En maintenance : alertes suspendues';
+    ligne.appendChild(sous);
+  }
+
+  // affiche l'expiration du certificat TLS
+  if (certificat && typeof certificat.expire_dans_jours === 'number') {
+    const jours = certificat.expire_dans_jours;
+    const sous = document.createElement('div');
+    sous.className = 'sous-ligne';
+    sous.title = `${certificat.sujet} — émis par ${certificat.emetteur}`;
+    sous.textContent =
+      jours < 0
+        ? `Certificat expiré depuis ${-jours} jour(s)`
+        : `Certificat : expire dans ${jours} jour(s)`;
+    ligne.appendChild(sous);
+  }
+
+  // affiche la destination finale après redirections
+  if (url_finale && redirections && redirections.length > 0) {
+    const sous = document.createElement('div');
+    sous.className = 'sous-ligne';
+    sous.title = [url, ...redirections].join(' → ');
+    sous.textContent = `Redirigé (${redirections.length}) vers ${url_finale}`;
+    ligne.appendChild(sous);
+  }
+
   // ajoute en haut de la liste
   if (liste.firstChild) {
     liste.insertBefore(ligne, liste.firstChild);